@@ -0,0 +1,72 @@
+// Command dynago-gen generates typed repositories for entity structs annotated with dynago struct tags.
+// For every type it writes <type>_dynago.go with the repository interface, its constructor, key builders and
+// sort prefix builders, and <type>_dynago_mock.go with a testify mock of the interface.
+//
+//	//go:generate go run github.com/KirkDiggler/go-projects/tools/dynago/cmd/dynago-gen -type Product,Order
+//
+// Flags:
+//
+//	-type    comma separated names of the entity structs, required
+//	-dir     directory of the package declaring them, defaults to the current directory
+//	-mock    generate the mocks, defaults to true
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/generator"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dynago-gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	types := flags.String("type", "", "comma separated names of the entity structs")
+	dir := flags.String("dir", ".", "directory of the package declaring the entities")
+	mocks := flags.Bool("mock", true, "generate a testify mock of each repository")
+
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: dynago-gen -type <Type[,Type]> [-dir <dir>] [-mock=false]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var typeNames []string
+	for _, name := range strings.Split(*types, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			typeNames = append(typeNames, name)
+		}
+	}
+
+	if len(typeNames) == 0 || flags.NArg() != 0 {
+		flags.Usage()
+
+		return exitUsage
+	}
+
+	_, err := generator.Write(&generator.Config{Dir: *dir, Types: typeNames, Mocks: *mocks})
+	if err != nil {
+		fmt.Fprintf(stderr, "dynago-gen: %s\n", err)
+
+		return exitFailure
+	}
+
+	return exitOK
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago"
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+	"github.com/KirkDiggler/go-projects/tools/dynago/tables"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// tableIndex is shown as the index of table mappings
+const tableIndex = "(table)"
+
+type commandFunc func(ctx context.Context, mapper *dynago.Mapper, args []string) (output, error)
+
+var commands = map[string]commandFunc{
+	"schema":       schemaCommand,
+	"repositories": repositoriesCommand,
+	"validate":     validateCommand,
+	"plan":         planCommand,
+	"decode":       decodeCommand,
+}
+
+// usageError is returned for bad arguments, the exit code tells it apart from failed calls
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func schemaCommand(ctx context.Context, mapper *dynago.Mapper, args []string) (output, error) {
+	if len(args) != 1 {
+		return nil, &usageError{message: "schema requires a repository name"}
+	}
+
+	stored, err := mapper.LoadSchema(ctx, args[0])
+	if err != nil {
+		if errors.Is(err, dynago.ErrSchemaNotFound) {
+			return nil, fmt.Errorf("no schema is persisted for the repository %s", args[0])
+		}
+
+		return nil, err
+	}
+
+	return &repositoriesView{Repositories: []*repositoryView{newRepositoryView(stored)}}, nil
+}
+
+func repositoriesCommand(ctx context.Context, mapper *dynago.Mapper, args []string) (output, error) {
+	if len(args) != 0 {
+		return nil, &usageError{message: "repositories does not take arguments"}
+	}
+
+	schemas, err := mapper.ListSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &repositoriesView{Repositories: []*repositoryView{}}
+	for _, stored := range schemas {
+		out.Repositories = append(out.Repositories, newRepositoryView(stored))
+	}
+
+	return out, nil
+}
+
+func validateCommand(ctx context.Context, mapper *dynago.Mapper, args []string) (output, error) {
+	if len(args) != 0 {
+		return nil, &usageError{message: "validate does not take arguments"}
+	}
+
+	tableDesc, err := mapper.TableDescription(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repoCfgs, err := repositoryConfigs(ctx, mapper)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &validationView{Table: aws.ToString(tableDesc.TableName), Issues: []*issueView{}}
+
+	for _, repoCfg := range repoCfgs {
+		repoCfg.TableDesc = tableDesc
+
+		for _, issue := range repositories.Validate(repoCfg).Issues {
+			out.Issues = append(out.Issues, &issueView{
+				Repository: repoCfg.Name,
+				Severity:   string(issue.Severity),
+				Index:      issue.Index,
+				Mapping:    issue.Mapping,
+				Message:    issue.Message,
+			})
+		}
+	}
+
+	return out, nil
+}
+
+func planCommand(ctx context.Context, mapper *dynago.Mapper, args []string) (output, error) {
+	if len(args) != 0 {
+		return nil, &usageError{message: "plan does not take arguments"}
+	}
+
+	tableDesc, err := mapper.TableDescription(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repoCfgs, err := repositoryConfigs(ctx, mapper)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &planView{Table: aws.ToString(tableDesc.TableName), Steps: []*stepView{}}
+
+	if len(repoCfgs) == 0 {
+		return out, nil
+	}
+
+	definition, err := tables.Build(&tables.Config{
+		TableName:    aws.ToString(tableDesc.TableName),
+		Repositories: repoCfgs,
+		TableDesc:    tableDesc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := tables.NewPlan(definition, tableDesc)
+
+	for _, index := range plan.CreateIndexes {
+		var keys []string
+		for _, element := range index.KeySchema {
+			keys = append(keys, fmt.Sprintf("%s %s", aws.ToString(element.AttributeName), element.KeyType))
+		}
+
+		projection := string(index.Projection.ProjectionType)
+		if len(index.Projection.NonKeyAttributes) != 0 {
+			projection += " " + strings.Join(index.Projection.NonKeyAttributes, ",")
+		}
+
+		out.Steps = append(out.Steps, &stepView{
+			Action: "create-index",
+			Index:  aws.ToString(index.IndexName),
+			Detail: fmt.Sprintf("%s, projection %s", strings.Join(keys, ", "), projection),
+		})
+	}
+
+	for _, conflict := range plan.Conflicts {
+		out.Steps = append(out.Steps, &stepView{Action: "conflict", Detail: conflict})
+	}
+
+	return out, nil
+}
+
+func decodeCommand(ctx context.Context, mapper *dynago.Mapper, args []string) (output, error) {
+	flags := flag.NewFlagSet("decode", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	repository := flags.String("repository", "", "repository whose mapping built the key")
+	mappingName := flags.String("mapping", "", "mapping that built the key, defaults to the table mapping")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, &usageError{message: "decode: " + err.Error()}
+	}
+
+	if flags.NArg() != 1 {
+		return nil, &usageError{message: "decode requires a key"}
+	}
+
+	key := flags.Arg(0)
+
+	if *repository == "" {
+		if *mappingName != "" {
+			return nil, &usageError{message: "decode -mapping requires -repository"}
+		}
+
+		decoded, err := mappings.DecodeKey(key, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return newDecodeView(key, "", decoded), nil
+	}
+
+	stored, err := mapper.LoadSchema(ctx, *repository)
+	if err != nil {
+		if errors.Is(err, dynago.ErrSchemaNotFound) {
+			return nil, fmt.Errorf("no schema is persisted for the repository %s", *repository)
+		}
+
+		return nil, err
+	}
+
+	mapping := stored.Schema.Table
+	if *mappingName != "" && *mappingName != mapping.Name {
+		index, ok := stored.Schema.Indexes[*mappingName]
+		if !ok {
+			return nil, fmt.Errorf("repository %s does not have the mapping %s", *repository, *mappingName)
+		}
+
+		mapping = index.Mapping
+	}
+
+	// the key may be either side, a lookup builds both from the same fields
+	decoded, err := mappings.DecodeKey(key, mapping.PartitionFields)
+	if err != nil {
+		decoded, err = mappings.DecodeKey(key, mapping.SortFields)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newDecodeView(key, mapping.Name, decoded), nil
+}
+
+// repositoryConfigs rebuilds the config of every repository with a persisted schema
+func repositoryConfigs(ctx context.Context, mapper *dynago.Mapper) ([]*repositories.Config, error) {
+	schemas, err := mapper.ListSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*repositories.Config, 0, len(schemas))
+	for _, stored := range schemas {
+		repoCfg, err := stored.RepositoryConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, repoCfg)
+	}
+
+	return out, nil
+}
+
+func newRepositoryView(stored *dynago.StoredSchema) *repositoryView {
+	out := &repositoryView{
+		Name:     stored.Name,
+		Version:  stored.Version,
+		Mappings: []*mappingView{newMappingView(tableIndex, stored.Schema.Table)},
+	}
+
+	for _, index := range sortedIndexes(stored.Schema.Indexes) {
+		view := newMappingView(index.Name, index.Mapping)
+		view.Projection = string(index.ProjectionType)
+		view.NonKeyAttributes = index.NonKeyAttributes
+
+		for _, condition := range index.Conditions {
+			view.Conditions = append(view.Conditions, conditionString(condition))
+		}
+
+		out.Mappings = append(out.Mappings, view)
+	}
+
+	return out
+}
+
+func newMappingView(index string, mapping *entities.Mapping) *mappingView {
+	return &mappingView{
+		Mapping:         mapping.Name,
+		Type:            string(mapping.Type),
+		Index:           index,
+		PartitionFields: mapping.PartitionFields,
+		SortFields:      mapping.SortFields,
+	}
+}
+
+func conditionString(condition *entities.Condition) string {
+	if len(condition.Values) == 0 {
+		return fmt.Sprintf("%s %s", condition.Field, condition.Operator)
+	}
+
+	return fmt.Sprintf("%s %s %s", condition.Field, condition.Operator, strings.Join(condition.Values, ","))
+}
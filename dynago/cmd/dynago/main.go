@@ -0,0 +1,152 @@
+// Command dynago inspects the schemas dynago persists in a table: the repositories, their mappings and the
+// index each mapping owns. It validates the mappings against the table, prints the plan to bring the table
+// in line with them and decodes raw partition and sort keys.
+//
+//	dynago -table my-table [-endpoint http://localhost:8000] [-region us-east-1] [-format table|json] <command>
+//
+// Commands:
+//
+//	schema <repository>      show the persisted schema of a repository
+//	repositories             list every repository and its mappings
+//	validate                 check every repository's mappings against DescribeTable
+//	plan                     print the changes the table needs to hold the mappings
+//	decode [-repository <name> [-mapping <name>]] <key>
+//	                         decode a pk or sk value into its field and value pairs
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// options are the global flags
+type options struct {
+	table    string
+	endpoint string
+	region   string
+	format   string
+}
+
+// connectFunc builds the dynamo client, replaced in tests
+type connectFunc func(ctx context.Context, opts *options) (dynamo.Interface, error)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr, connect))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, connectFn connectFunc) int {
+	opts := &options{}
+
+	flags := flag.NewFlagSet("dynago", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.table, "table", os.Getenv("DYNAGO_TABLE"), "table name, defaults to $DYNAGO_TABLE")
+	flags.StringVar(&opts.endpoint, "endpoint", os.Getenv("DYNAGO_ENDPOINT"), "endpoint override for a local DynamoDB, defaults to $DYNAGO_ENDPOINT")
+	flags.StringVar(&opts.region, "region", "", "AWS region, defaults to the shared config")
+	flags.StringVar(&opts.format, "format", formatTable, "output format, table or json")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: dynago -table <name> [flags] <schema|repositories|validate|plan|decode> [args]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+
+		return exitUsage
+	}
+
+	if opts.format != formatTable && opts.format != formatJSON {
+		fmt.Fprintf(stderr, "dynago: unknown format '%s'\n", opts.format)
+
+		return exitUsage
+	}
+
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "dynago: unknown command '%s'\n", flags.Arg(0))
+		flags.Usage()
+
+		return exitUsage
+	}
+
+	client, err := connectFn(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "dynago: %s\n", err)
+
+		return exitFailure
+	}
+
+	mapper, err := dynago.New(&dynago.Config{Client: client, TableName: opts.table})
+	if err != nil {
+		fmt.Fprintf(stderr, "dynago: %s\n", err)
+
+		return exitUsage
+	}
+
+	out, err := command(ctx, mapper, flags.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(stderr, "dynago: %s\n", err)
+
+		var usage *usageError
+		if errors.As(err, &usage) {
+			return exitUsage
+		}
+
+		return exitFailure
+	}
+
+	if err := write(stdout, opts.format, out); err != nil {
+		fmt.Fprintf(stderr, "dynago: %s\n", err)
+
+		return exitFailure
+	}
+
+	if failing, ok := out.(interface{ failed() bool }); ok && failing.failed() {
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+// connect builds a client from the shared AWS config, pointed at the endpoint when one is set
+func connect(ctx context.Context, opts *options) (dynamo.Interface, error) {
+	var loadOptions []func(*config.LoadOptions) error
+
+	if opts.region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(opts.region))
+	}
+
+	if opts.endpoint != "" {
+		loadOptions = append(loadOptions, config.WithEndpointResolver(aws.EndpointResolverFunc(
+			func(service, region string) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: opts.endpoint, SigningRegion: region}, nil
+			})))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamo.NewClient(&dynamo.ClientConfig{AWSClient: dynamodb.NewFromConfig(cfg)})
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoscan "github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestTableDesc() *types.TableDescription {
+	return &types.TableDescription{
+		TableName: aws.String("my-table"),
+		KeySchema: []types.KeySchemaElement{{
+			AttributeName: aws.String("pk"),
+			KeyType:       types.KeyTypeHash,
+		}, {
+			AttributeName: aws.String("sk"),
+			KeyType:       types.KeyTypeRange,
+		}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{{
+			IndexName:  aws.String("GSI1pk-GSI1sk-Index"),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String("GSI1pk"),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String("GSI1sk"),
+				KeyType:       types.KeyTypeRange,
+			}},
+		}},
+	}
+}
+
+func productsSchema() *entities.Schema {
+	return &entities.Schema{
+		Table: &entities.Mapping{
+			Name:            "by-id",
+			Type:            entities.MappingType_Lookup,
+			PartitionFields: []string{"id"},
+			SortFields:      []string{"id"},
+		},
+		Indexes: map[string]*entities.Index{
+			"by-category": {
+				Name:           "GSI1pk-GSI1sk-Index",
+				ProjectionType: entities.PropjectionTypeKeysOnly,
+				Mapping: &entities.Mapping{
+					Name:            "by-category",
+					Type:            entities.MappingType_Query,
+					PartitionFields: []string{"category"},
+					SortFields:      []string{"name"},
+				},
+			},
+		},
+	}
+}
+
+func schemaItem(t *testing.T, name string, schema *entities.Schema) map[string]types.AttributeValue {
+	marshalled, err := attributevalue.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]types.AttributeValue{
+		"pk":      &types.AttributeValueMemberS{Value: "_SCHEMA#" + name},
+		"sk":      &types.AttributeValueMemberS{Value: "_SCHEMA#" + name},
+		"_type":   &types.AttributeValueMemberS{Value: "_schema"},
+		"schema":  marshalled,
+		"version": &types.AttributeValueMemberN{Value: "2"},
+	}
+}
+
+func runCommand(client *dynamo.Mock, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+
+	connectFn := func(ctx context.Context, opts *options) (dynamo.Interface, error) {
+		return client, nil
+	}
+
+	code := run(context.Background(), append([]string{"-table", "my-table"}, args...), &stdout, &stderr, connectFn)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func expectTable(client *dynamo.Mock, tableDesc *types.TableDescription) {
+	client.On("DescribeTable", mock.Anything, "my-table").
+		Return(&describetable.Result{Table: tableDesc}, nil)
+}
+
+func expectSchemas(client *dynamo.Mock, items ...map[string]types.AttributeValue) {
+	client.On("Scan", mock.Anything, "my-table", mock.Anything).
+		Return(&dynamoscan.Result{Items: items}, nil)
+}
+
+func TestRun(t *testing.T) {
+	t.Run("it rejects unknown commands", func(t *testing.T) {
+		code, _, stderr := runCommand(&dynamo.Mock{}, "drop")
+
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, "unknown command 'drop'")
+	})
+	t.Run("it lists the repositories and the index of each mapping", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		expectTable(client, newTestTableDesc())
+		expectSchemas(client, schemaItem(t, "Products", productsSchema()))
+
+		code, stdout, _ := runCommand(client, "repositories")
+
+		assert.Equal(t, exitOK, code)
+		assert.Equal(t, ""+
+			"REPOSITORY  VERSION  MAPPING      TYPE    INDEX                PARTITION  SORT  PROJECTION  CONDITIONS\n"+
+			"Products    2        by-id        lookup  (table)              id         id    -           -\n"+
+			"Products    2        by-category  query   GSI1pk-GSI1sk-Index  category   name  KEYS_ONLY   -\n", stdout)
+	})
+	t.Run("it shows the schema of a repository as json", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		expectTable(client, newTestTableDesc())
+		client.On("GetItem", mock.Anything, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{Item: schemaItem(t, "Products", productsSchema())}, nil)
+
+		code, stdout, _ := runCommand(client, "-format", "json", "schema", "Products")
+
+		var actual repositoriesView
+		assert.Equal(t, exitOK, code)
+		assert.Nil(t, json.Unmarshal([]byte(stdout), &actual))
+		assert.Equal(t, "Products", actual.Repositories[0].Name)
+		assert.Equal(t, "GSI1pk-GSI1sk-Index", actual.Repositories[0].Mappings[1].Index)
+	})
+	t.Run("it fails validation when an index does not project what a mapping needs", func(t *testing.T) {
+		schema := productsSchema()
+		schema.Indexes["by-category"].ProjectionType = entities.PropjectionTypeAll
+
+		client := &dynamo.Mock{}
+		expectTable(client, newTestTableDesc())
+		expectSchemas(client, schemaItem(t, "Products", schema))
+
+		code, stdout, _ := runCommand(client, "validate")
+
+		assert.Equal(t, exitFailure, code)
+		assert.Contains(t, stdout, "Products    ERROR     GSI1pk-GSI1sk-Index  by-category  the mapping needs every attribute")
+	})
+	t.Run("it plans the indexes the mappings need", func(t *testing.T) {
+		tableDesc := newTestTableDesc()
+		tableDesc.GlobalSecondaryIndexes = nil
+
+		client := &dynamo.Mock{}
+		expectTable(client, tableDesc)
+		expectSchemas(client, schemaItem(t, "Products", productsSchema()))
+
+		code, stdout, _ := runCommand(client, "plan")
+
+		assert.Equal(t, exitOK, code)
+		assert.Equal(t, ""+
+			"ACTION        INDEX                DETAIL\n"+
+			"create-index  GSI1pk-GSI1sk-Index  GSI1pk HASH, GSI1sk RANGE, projection KEYS_ONLY\n", stdout)
+	})
+	t.Run("it decodes a key with the fields of a mapping", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		expectTable(client, newTestTableDesc())
+		client.On("GetItem", mock.Anything, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{Item: schemaItem(t, "Products", productsSchema())}, nil)
+
+		code, stdout, _ := runCommand(client, "decode", "-repository", "Products", "-mapping", "by-category", "CATEGORY#SHOES#KIDS")
+
+		assert.Equal(t, exitOK, code)
+		assert.Equal(t, ""+
+			"FIELD     VALUE\n"+
+			"category  SHOES#KIDS\n", stdout)
+	})
+	t.Run("it decodes a key into pairs without a repository", func(t *testing.T) {
+		code, stdout, _ := runCommand(&dynamo.Mock{}, "decode", "PRODUCT#ID#ABC")
+
+		assert.Equal(t, exitOK, code)
+		assert.Equal(t, ""+
+			"FIELD     VALUE\n"+
+			"(prefix)  PRODUCT\n"+
+			"ID        ABC\n", stdout)
+	})
+}
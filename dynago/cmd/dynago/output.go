@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// output is the result of a command, written as JSON or as a table of its rows
+type output interface {
+	header() []string
+	rows() [][]string
+}
+
+func write(w io.Writer, format string, out output) error {
+	if format == formatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(out)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(out.header(), "\t"))
+
+	for _, row := range out.rows() {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+type repositoriesView struct {
+	Repositories []*repositoryView `json:"repositories"`
+}
+
+type repositoryView struct {
+	Name     string         `json:"name"`
+	Version  int64          `json:"version"`
+	Mappings []*mappingView `json:"mappings"`
+}
+
+type mappingView struct {
+	Mapping          string   `json:"mapping"`
+	Type             string   `json:"type"`
+	Index            string   `json:"index"`
+	PartitionFields  []string `json:"partition_fields"`
+	SortFields       []string `json:"sort_fields"`
+	Projection       string   `json:"projection,omitempty"`
+	NonKeyAttributes []string `json:"non_key_attributes,omitempty"`
+	Conditions       []string `json:"conditions,omitempty"`
+}
+
+func (v *repositoriesView) header() []string {
+	return []string{"REPOSITORY", "VERSION", "MAPPING", "TYPE", "INDEX", "PARTITION", "SORT", "PROJECTION", "CONDITIONS"}
+}
+
+func (v *repositoriesView) rows() [][]string {
+	var out [][]string
+
+	for _, repository := range v.Repositories {
+		for _, mapping := range repository.Mappings {
+			projection := mapping.Projection
+			if len(mapping.NonKeyAttributes) != 0 {
+				projection += " " + strings.Join(mapping.NonKeyAttributes, ",")
+			}
+
+			out = append(out, []string{
+				repository.Name,
+				strconv.FormatInt(repository.Version, 10),
+				mapping.Mapping,
+				mapping.Type,
+				mapping.Index,
+				strings.Join(mapping.PartitionFields, ","),
+				strings.Join(mapping.SortFields, ","),
+				orDash(projection),
+				orDash(strings.Join(mapping.Conditions, " AND ")),
+			})
+		}
+	}
+
+	return out
+}
+
+type validationView struct {
+	Table  string       `json:"table"`
+	Issues []*issueView `json:"issues"`
+}
+
+type issueView struct {
+	Repository string `json:"repository"`
+	Severity   string `json:"severity"`
+	Index      string `json:"index,omitempty"`
+	Mapping    string `json:"mapping,omitempty"`
+	Message    string `json:"message"`
+}
+
+func (v *validationView) header() []string {
+	return []string{"REPOSITORY", "SEVERITY", "INDEX", "MAPPING", "MESSAGE"}
+}
+
+func (v *validationView) rows() [][]string {
+	out := make([][]string, 0, len(v.Issues))
+	for _, issue := range v.Issues {
+		out = append(out, []string{issue.Repository, issue.Severity, orDash(issue.Index), orDash(issue.Mapping), issue.Message})
+	}
+
+	return out
+}
+
+// failed makes validate exit non zero when there are errors, warnings pass
+func (v *validationView) failed() bool {
+	for _, issue := range v.Issues {
+		if issue.Severity == "ERROR" {
+			return true
+		}
+	}
+
+	return false
+}
+
+type planView struct {
+	Table string      `json:"table"`
+	Steps []*stepView `json:"steps"`
+}
+
+type stepView struct {
+	Action string `json:"action"`
+	Index  string `json:"index,omitempty"`
+	Detail string `json:"detail"`
+}
+
+func (v *planView) header() []string {
+	return []string{"ACTION", "INDEX", "DETAIL"}
+}
+
+func (v *planView) rows() [][]string {
+	out := make([][]string, 0, len(v.Steps))
+	for _, step := range v.Steps {
+		out = append(out, []string{step.Action, orDash(step.Index), step.Detail})
+	}
+
+	return out
+}
+
+// failed makes plan exit non zero when the table can not be changed to hold the mappings
+func (v *planView) failed() bool {
+	for _, step := range v.Steps {
+		if step.Action == "conflict" {
+			return true
+		}
+	}
+
+	return false
+}
+
+type decodeView struct {
+	Key     string              `json:"key"`
+	Mapping string              `json:"mapping,omitempty"`
+	Prefix  string              `json:"prefix,omitempty"`
+	Fields  []*decodedFieldView `json:"fields"`
+}
+
+type decodedFieldView struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func newDecodeView(key, mapping string, decoded *mappings.DecodedKey) *decodeView {
+	out := &decodeView{Key: key, Mapping: mapping, Prefix: decoded.Prefix, Fields: []*decodedFieldView{}}
+	for _, field := range decoded.Fields {
+		out.Fields = append(out.Fields, &decodedFieldView{Field: field.Field, Value: field.Value})
+	}
+
+	return out
+}
+
+func (v *decodeView) header() []string {
+	return []string{"FIELD", "VALUE"}
+}
+
+func (v *decodeView) rows() [][]string {
+	var out [][]string
+
+	if v.Prefix != "" {
+		out = append(out, []string{"(prefix)", v.Prefix})
+	}
+
+	for _, field := range v.Fields {
+		out = append(out, []string{field.Field, field.Value})
+	}
+
+	return out
+}
+
+// sortedIndexes returns the index entities in mapping name order
+func sortedIndexes(indexes map[string]*entities.Index) []*entities.Index {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	out := make([]*entities.Index, 0, len(names))
+	for _, name := range names {
+		out = append(out, indexes[name])
+	}
+
+	return out
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
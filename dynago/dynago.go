@@ -1,18 +1,52 @@
 package dynago
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
 
 	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-const minTableNameLength = 3
+const (
+	minTableNameLength = 3
+
+	// SchemaEntityType is written to repositories.EntityTypeAttribute on the items persisting repository schemas
+	SchemaEntityType = "_schema"
+
+	schemaAttribute        = "schema"
+	schemaVersionAttribute = "version"
+
+	// maxSchemaAttempts bounds how often a repository is built again on the schema another writer saved
+	maxSchemaAttempts = 3
+)
 
-type dynago struct {
+// ErrSchemaConflict is returned by NewRepository when the persisted schema kept being changed by other writers
+// while the repository was built, e.g. by instances of a service starting together
+var ErrSchemaConflict = errors.New("dynago: the persisted schema was changed by another writer")
+
+// Mapper
+//
+// The entry point of dynago. It describes the table once and builds repositories on it, loading and
+// persisting the schema of each repository in the table so index assignments stay stable
+type Mapper struct {
 	client    dynamo.Interface
 	tableName string
+
+	mu        sync.Mutex
 	tableDesc *types.TableDescription
 	// TODO: should we store a data prefix here
 }
@@ -22,7 +56,7 @@ type Config struct {
 	TableName string
 }
 
-func New(cfg *Config) (*dynago, error) {
+func New(cfg *Config) (*Mapper, error) {
 	if cfg == nil {
 		return nil, errors.New("dynago.NewMapper requires Config")
 	}
@@ -35,12 +69,192 @@ func New(cfg *Config) (*dynago, error) {
 		return nil, errors.New(fmt.Sprintf("dynago.NewMapper requires Config.TableName with at least %d characters", minTableNameLength))
 	}
 
-	// TODO should I calle describe table here?
-
-	return &dynago{
+	return &Mapper{
 		client:    cfg.Client,
 		tableName: cfg.TableName,
 	}, nil
 }
 
-// TODO: verify the table keys are not compound
+// TableDescription
+//
+// Describes the table on first use and caches the description, see Refresh
+func (m *Mapper) TableDescription(ctx context.Context) (*types.TableDescription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.tableDesc != nil {
+		return m.tableDesc, nil
+	}
+
+	return m.describe(ctx)
+}
+
+// Refresh
+//
+// Describes the table again, e.g. after an index was added
+func (m *Mapper) Refresh(ctx context.Context) (*types.TableDescription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.describe(ctx)
+}
+
+func (m *Mapper) describe(ctx context.Context) (*types.TableDescription, error) {
+	result, err := m.client.DescribeTable(ctx, m.tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Table == nil {
+		return nil, fmt.Errorf("dynago: table %s was not described", m.tableName)
+	}
+
+	m.tableDesc = result.Table
+
+	return m.tableDesc, nil
+}
+
+// NewRepository
+//
+// Builds a repository on the table with the given mappings
+func (m *Mapper) NewRepository(ctx context.Context, name string, tableMapping mappings.Interface, indexMappings ...*mappings.Index) (repositories.Interface, error) {
+	return m.NewRepositoryFromConfig(ctx, &repositories.Config{
+		Name:          name,
+		TableMapping:  tableMapping,
+		IndexMappings: indexMappings,
+	})
+}
+
+// NewRepositoryFromConfig
+//
+// Builds a repository from a config without the Client, TableDesc and SchemaMapping, the mapper sets them.
+// The schema persisted for the repository name is loaded, and saved again when the repository changed it.
+// When another writer saves the schema first the repository is built again on that schema
+func (m *Mapper) NewRepositoryFromConfig(ctx context.Context, cfg *repositories.Config) (repositories.Interface, error) {
+	if cfg == nil {
+		return nil, errors.New("dynago.NewRepositoryFromConfig requires a Config")
+	}
+
+	if cfg.Name == "" {
+		return nil, errors.New("dynago.NewRepositoryFromConfig requires Config.Name")
+	}
+
+	tableDesc, err := m.TableDescription(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := schemaKey(tableDesc, cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// another writer saving the schema first is retried on the schema it saved
+	for attempt := 1; ; attempt++ {
+		repo, err := m.buildRepository(ctx, cfg, tableDesc, key)
+		if !errors.Is(err, ErrSchemaConflict) || attempt == maxSchemaAttempts {
+			return repo, err
+		}
+	}
+}
+
+// buildRepository builds the repository on the schema persisted under key and saves the schema when it changed
+func (m *Mapper) buildRepository(ctx context.Context, cfg *repositories.Config, tableDesc *types.TableDescription, key map[string]types.AttributeValue) (repositories.Interface, error) {
+	stored, err := m.client.GetItem(ctx, m.tableName,
+		dynamogetitem.WithKey(key),
+		dynamogetitem.WithConsistentRead(aws.Bool(true)))
+	if err != nil {
+		return nil, err
+	}
+
+	var schema *entities.Schema
+	if storedSchema, ok := stored.Item[schemaAttribute]; ok {
+		schema = &entities.Schema{}
+		if err := attributevalue.Unmarshal(storedSchema, schema); err != nil {
+			return nil, fmt.Errorf("dynago: schema of repository %s: %s", cfg.Name, err)
+		}
+	}
+
+	repoCfg := *cfg
+	repoCfg.Client = m.client
+	repoCfg.TableDesc = tableDesc
+	repoCfg.SchemaMapping = schema
+
+	repo, err := repositories.New(&repoCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := attributevalue.Marshal(repo.Schema())
+	if err != nil {
+		return nil, err
+	}
+
+	if reflect.DeepEqual(updated, stored.Item[schemaAttribute]) {
+		return repo, nil
+	}
+
+	if err := m.saveSchema(ctx, key, updated, stored.Item[schemaVersionAttribute]); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// saveSchema writes the schema item if its version is still the version that was read
+func (m *Mapper) saveSchema(ctx context.Context, key map[string]types.AttributeValue, schema, version types.AttributeValue) error {
+	current := int64(0)
+	if number, ok := version.(*types.AttributeValueMemberN); ok {
+		parsed, err := strconv.ParseInt(number.Value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("dynago: schema version: %s", err)
+		}
+
+		current = parsed
+	}
+
+	condition := expression.AttributeNotExists(expression.Name(schemaVersionAttribute))
+	if current != 0 {
+		condition = expression.Name(schemaVersionAttribute).Equal(expression.Value(current))
+	}
+
+	item := map[string]types.AttributeValue{
+		repositories.EntityTypeAttribute: &types.AttributeValueMemberS{Value: SchemaEntityType},
+		schemaAttribute:                  schema,
+		schemaVersionAttribute:           &types.AttributeValueMemberN{Value: strconv.FormatInt(current+1, 10)},
+	}
+
+	for k, v := range key {
+		item[k] = v
+	}
+
+	_, err := m.client.PutItem(ctx, m.tableName,
+		dynamoputitem.WithItem(item),
+		dynamoputitem.WithFilterConditionBuilder(&condition))
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return ErrSchemaConflict
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// schemaKey builds the key of the item persisting a repository's schema, _SCHEMA#NAME in the table keys
+func schemaKey(tableDesc *types.TableDescription, name string) (map[string]types.AttributeValue, error) {
+	value := &types.AttributeValueMemberS{Value: mappings.BuildPrefix(SchemaEntityType) + name}
+
+	out := make(map[string]types.AttributeValue)
+	for _, element := range tableDesc.KeySchema {
+		out[aws.ToString(element.AttributeName)] = value
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("dynago: table %s does not have a key schema", aws.ToString(tableDesc.TableName))
+	}
+
+	return out, nil
+}
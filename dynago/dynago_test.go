@@ -0,0 +1,259 @@
+package dynago
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestTableDesc() *types.TableDescription {
+	return &types.TableDescription{
+		TableName: aws.String("my-table"),
+		KeySchema: []types.KeySchemaElement{{
+			AttributeName: aws.String("pk"),
+			KeyType:       types.KeyTypeHash,
+		}, {
+			AttributeName: aws.String("sk"),
+			KeyType:       types.KeyTypeRange,
+		}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{{
+			IndexName:  aws.String("GSI1pk-GSI1sk-Index"),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String("GSI1pk"),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String("GSI1sk"),
+				KeyType:       types.KeyTypeRange,
+			}},
+		}},
+	}
+}
+
+func newTestMappings() (mappings.Interface, *mappings.Index) {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "table",
+		Fields:      []string{"id"},
+	})
+
+	queryByCategoryMapping, _ := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     "queryByCategory",
+		PartitionFields: []string{"category"},
+		SortFields:      []string{"name"},
+	})
+
+	return tableMapping, &mappings.Index{Mapping: queryByCategoryMapping}
+}
+
+func schemaKeyOf(name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "_SCHEMA#" + name},
+		"sk": &types.AttributeValueMemberS{Value: "_SCHEMA#" + name},
+	}
+}
+
+func newTestMapper(t *testing.T, client *dynamo.Mock) *Mapper {
+	mapper, err := New(&Config{Client: client, TableName: "my-table"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.On("DescribeTable", mock.Anything, "my-table").
+		Return(&describetable.Result{Table: newTestTableDesc()}, nil).Once()
+
+	return mapper
+}
+
+func TestNew(t *testing.T) {
+	t.Run("it requires a client", func(t *testing.T) {
+		_, err := New(&Config{TableName: "my-table"})
+
+		assert.Equal(t, errors.New("dynago.NewMapper requires Config.Client"), err)
+	})
+	t.Run("it requires a table name", func(t *testing.T) {
+		_, err := New(&Config{Client: &dynamo.Mock{}, TableName: "my"})
+
+		assert.Equal(t, errors.New("dynago.NewMapper requires Config.TableName with at least 3 characters"), err)
+	})
+}
+
+func TestMapper_TableDescription(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it describes the table once", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		first, err := mapper.TableDescription(ctx)
+		assert.Nil(t, err)
+
+		second, err := mapper.TableDescription(ctx)
+		assert.Nil(t, err)
+
+		assert.Same(t, first, second)
+		client.AssertNumberOfCalls(t, "DescribeTable", 1)
+	})
+}
+
+func TestMapper_NewRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it persists the schema of a new repository", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		client.On("GetItem", ctx, "my-table", dynamogetitem.NewOptions(
+			dynamogetitem.WithKey(schemaKeyOf("Products")),
+			dynamogetitem.WithConsistentRead(aws.Bool(true)))).
+			Return(&dynamogetitem.Result{}, nil)
+
+		var saved *dynamoputitem.Options
+		client.On("PutItem", ctx, "my-table", mock.MatchedBy(func(options *dynamoputitem.Options) bool {
+			saved = options
+
+			return true
+		})).Return(&dynamoputitem.Result{}, nil)
+
+		tableMapping, byCategory := newTestMappings()
+
+		repo, err := mapper.NewRepository(ctx, "Products", tableMapping, byCategory)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, repo)
+
+		var schema entities.Schema
+		assert.Nil(t, attributevalue.Unmarshal(saved.Item[schemaAttribute], &schema))
+		assert.Equal(t, "GSI1pk-GSI1sk-Index", schema.Indexes["queryByCategory"].Name)
+		assert.Equal(t, &types.AttributeValueMemberN{Value: "1"}, saved.Item[schemaVersionAttribute])
+		assert.Equal(t, &types.AttributeValueMemberS{Value: SchemaEntityType}, saved.Item["_type"])
+	})
+	t.Run("it does not save an unchanged schema", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		tableMapping, byCategory := newTestMappings()
+
+		stored, _ := attributevalue.Marshal(&entities.Schema{
+			Table: tableMapping.ToEntity(),
+			Indexes: map[string]*entities.Index{
+				"queryByCategory": {Name: "GSI1pk-GSI1sk-Index", Mapping: byCategory.Mapping.ToEntity()},
+			},
+		})
+
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{Item: map[string]types.AttributeValue{
+				schemaAttribute:        stored,
+				schemaVersionAttribute: &types.AttributeValueMemberN{Value: "3"},
+			}}, nil)
+
+		_, err := mapper.NewRepository(ctx, "Products", tableMapping, byCategory)
+
+		assert.Nil(t, err)
+		client.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("it builds the repository on the schema another writer saved first", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		tableMapping, byCategory := newTestMappings()
+
+		saved, _ := attributevalue.Marshal(&entities.Schema{
+			Table: tableMapping.ToEntity(),
+			Indexes: map[string]*entities.Index{
+				"queryByCategory": {Name: "GSI1pk-GSI1sk-Index", Mapping: byCategory.Mapping.ToEntity()},
+			},
+		})
+
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{}, nil).Once()
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}).Once()
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{Item: map[string]types.AttributeValue{
+				schemaAttribute:        saved,
+				schemaVersionAttribute: &types.AttributeValueMemberN{Value: "1"},
+			}}, nil).Once()
+
+		repo, err := mapper.NewRepository(ctx, "Products", tableMapping, byCategory)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, repo)
+		client.AssertExpectations(t)
+	})
+	t.Run("it builds the repository on the index another writer assigned first", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper, err := New(&Config{Client: client, TableName: "my-table"})
+		assert.Nil(t, err)
+
+		tableDesc := newTestTableDesc()
+		tableDesc.GlobalSecondaryIndexes = append(tableDesc.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String("GSI2pk-GSI2sk-Index"),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String("GSI2pk"),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String("GSI2sk"),
+				KeyType:       types.KeyTypeRange,
+			}},
+		})
+
+		client.On("DescribeTable", mock.Anything, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil).Once()
+
+		tableMapping, byCategory := newTestMappings()
+
+		saved, _ := attributevalue.Marshal(&entities.Schema{
+			Table: tableMapping.ToEntity(),
+			Indexes: map[string]*entities.Index{
+				"queryByCategory": {Name: "GSI2pk-GSI2sk-Index", Mapping: byCategory.Mapping.ToEntity()},
+			},
+		})
+
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{}, nil).Once()
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}).Once()
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{Item: map[string]types.AttributeValue{
+				schemaAttribute:        saved,
+				schemaVersionAttribute: &types.AttributeValueMemberN{Value: "1"},
+			}}, nil).Once()
+
+		repo, err := mapper.NewRepository(ctx, "Products", tableMapping, byCategory)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "GSI2pk-GSI2sk-Index", repo.Schema().Indexes["queryByCategory"].Name)
+		assert.Equal(t, "", byCategory.Name)
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns ErrSchemaConflict when the schema keeps being saved by other writers", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{}, nil)
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")})
+
+		tableMapping, byCategory := newTestMappings()
+
+		_, err := mapper.NewRepository(ctx, "Products", tableMapping, byCategory)
+
+		assert.Equal(t, ErrSchemaConflict, err)
+		client.AssertNumberOfCalls(t, "PutItem", maxSchemaAttempts)
+	})
+}
@@ -0,0 +1,18 @@
+package entities
+
+type ConditionOperator string
+
+const (
+	ConditionOperator_Equal     ConditionOperator = "EQ"
+	ConditionOperator_NotEqual  ConditionOperator = "NE"
+	ConditionOperator_In        ConditionOperator = "IN"
+	ConditionOperator_Exists    ConditionOperator = "EXISTS"
+	ConditionOperator_NotExists ConditionOperator = "NOT_EXISTS"
+)
+
+// Condition is a predicate on a single attribute of an item, values are compared as strings
+type Condition struct {
+	Field    string            `dynamodbav:"field"`
+	Operator ConditionOperator `dynamodbav:"operator"`
+	Values   []string          `dynamodbav:"values"`
+}
@@ -5,10 +5,17 @@ type ProjectionType string
 const (
 	PropjectionTypeAll      ProjectionType = "ALL"
 	PropjectionTypeKeysOnly ProjectionType = "KEYS_ONLY"
+	PropjectionTypeInclude  ProjectionType = "INCLUDE"
 )
 
 type Index struct {
-	Name           string // Gets set when loading from entity TODO: find a better way to keep this internal
-	ProjectionType ProjectionType
-	Mapping        *Mapping
+	Name           string         `dynamodbav:"name"` // Gets set when loading from entity TODO: find a better way to keep this internal
+	ProjectionType ProjectionType `dynamodbav:"projection_type"`
+	Mapping        *Mapping       `dynamodbav:"mapping"`
+
+	// NonKeyAttributes are the attributes projected onto the index along with the keys when ProjectionType is INCLUDE
+	NonKeyAttributes []string `dynamodbav:"non_key_attributes,omitempty"`
+
+	// Conditions must all match for an item to be written to the index, an empty list includes every item
+	Conditions []*Condition `dynamodbav:"conditions,omitempty"`
 }
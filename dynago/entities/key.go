@@ -0,0 +1,11 @@
+package entities
+
+// Key identifies an entity by the key values a repository built for it
+type Key struct {
+	EntityType string `dynamodbav:"entity_type"`
+	Partition  string `dynamodbav:"partition"`
+	Sort       string `dynamodbav:"sort"`
+
+	// Values are the table mapping field values the key was built from
+	Values map[string]interface{} `dynamodbav:"values"`
+}
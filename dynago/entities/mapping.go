@@ -6,6 +6,8 @@ const (
 	MappingType_Lookup = "lookup"
 	MappingType_List   = "list"
 	MappingType_Query  = "query"
+
+	MappingType_Relationship = "relationship"
 )
 
 type Mapping struct {
@@ -0,0 +1,11 @@
+package entities
+
+// OutboxEvent is a domain event written to the outbox in the same transaction as the entity change
+type OutboxEvent struct {
+	// ID identifies the event to its consumers, a random id is generated when it is empty.
+	// An event id can only be written once
+	ID string `dynamodbav:"id"`
+
+	Type    string      `dynamodbav:"type"`
+	Payload interface{} `dynamodbav:"payload"`
+}
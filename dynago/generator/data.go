@@ -0,0 +1,233 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+)
+
+// templateData is what the templates render for one entity
+type templateData struct {
+	Package    string
+	Entity     string
+	Repository string
+	Impl       string
+
+	VersionField string
+
+	Table   *mappingData
+	Indexes []*mappingData
+
+	// Queries get a QueryBy method, the table mapping when it is a query followed by the index mappings
+	Queries []*mappingData
+}
+
+// mappingData is a mapping with the names of everything generated for it
+type mappingData struct {
+	Name string
+	Var  string
+
+	Lookup bool
+
+	// Method queries the mapping, empty for the table mapping
+	Method string
+
+	// KeyFunc builds the partition key, the full key for the table mapping
+	KeyFunc   string
+	KeyParams []*paramData
+
+	PartitionFields []string
+	SortFields      []string
+
+	// SortInterface is implemented by every level of Sort, empty when the mapping has no sort fields to query by
+	SortInterface string
+	Sort          []*sortLevel
+
+	ProjectionConst  string
+	NonKeyAttributes []string
+}
+
+type paramData struct {
+	Attribute string
+	Param     string
+	Type      string
+}
+
+// sortLevel is a sort prefix holding the first sort fields, the next level is built by a method named after the next field
+type sortLevel struct {
+	Type  string
+	Func  string
+	Field *paramData
+	Next  *sortLevel
+}
+
+func newTemplateData(found *entity) (*templateData, error) {
+	out := &templateData{
+		Package:      found.Package,
+		Entity:       found.Name,
+		Repository:   found.Name + "Repository",
+		Impl:         lowerFirst(found.Name) + "Repository",
+		VersionField: found.mappings.VersionField,
+	}
+
+	table, err := newMappingData(found, found.mappings.Table, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Table = table
+
+	if !table.Lookup {
+		query, err := newMappingData(found, found.mappings.Table, mappingPascal(table.Name), false)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Queries = append(out.Queries, query)
+	}
+
+	for _, index := range found.mappings.Indexes {
+		data, err := newMappingData(found, index.Mapping, mappingPascal(index.Mapping.GetName()), false)
+		if err != nil {
+			return nil, err
+		}
+
+		data.ProjectionConst = projectionConst(index.ProjectionType)
+		data.NonKeyAttributes = index.NonKeyAttributes
+
+		out.Indexes = append(out.Indexes, data)
+		out.Queries = append(out.Queries, data)
+	}
+
+	if err := checkNames(out); err != nil {
+		return nil, fmt.Errorf("%s: %s", found.Name, err)
+	}
+
+	return out, nil
+}
+
+func newMappingData(found *entity, mapping mappings.Interface, pascal string, isTable bool) (*mappingData, error) {
+	out := &mappingData{
+		Name:            mapping.GetName(),
+		Var:             "tableMapping",
+		Lookup:          mapping.GetType() == entities.MappingType_Lookup,
+		KeyFunc:         found.Name + "Key",
+		PartitionFields: mapping.GetPartitionFields(),
+		SortFields:      mapping.GetSortFields(),
+	}
+
+	keyFields := mapping.GetPartitionFields()
+
+	if isTable {
+		// Get and Delete need the whole key, a lookup builds both sides from the same fields
+		if !out.Lookup {
+			keyFields = append(append([]string{}, keyFields...), mapping.GetSortFields()...)
+		}
+	} else {
+		out.Var = lowerFirst(pascal) + "Mapping"
+		out.Method = "QueryBy" + pascal
+		out.KeyFunc = found.Name + pascal + "Key"
+	}
+
+	for _, attribute := range keyFields {
+		param, err := newParamData(found, attribute)
+		if err != nil {
+			return nil, err
+		}
+
+		out.KeyParams = append(out.KeyParams, param)
+	}
+
+	if isTable || out.Lookup || len(out.SortFields) == 0 {
+		return out, nil
+	}
+
+	prefix := found.Name + pascal
+	out.SortInterface = prefix + "Sort"
+
+	typeName := prefix + "Sort"
+
+	var previous *sortLevel
+	for idx, attribute := range out.SortFields {
+		param, err := newParamData(found, attribute)
+		if err != nil {
+			return nil, err
+		}
+
+		fieldName := found.fields[attribute].goName
+		typeName += fieldName
+
+		level := &sortLevel{Type: typeName, Func: fieldName, Field: param}
+		if idx == 0 {
+			level.Func = prefix + fieldName
+		} else {
+			previous.Next = level
+		}
+
+		out.Sort = append(out.Sort, level)
+		previous = level
+	}
+
+	return out, nil
+}
+
+func newParamData(found *entity, attribute string) (*paramData, error) {
+	fieldData, ok := found.fields[attribute]
+	if !ok {
+		return nil, fmt.Errorf("%s does not have a field for the attribute '%s'", found.Name, attribute)
+	}
+
+	if fieldData.goType == "" {
+		return nil, fmt.Errorf("%s.%s can not be used in a key", found.Name, fieldData.goName)
+	}
+
+	return &paramData{Attribute: attribute, Param: paramName(fieldData.goName), Type: fieldData.goType}, nil
+}
+
+// checkNames fails when two of the generated declarations would share a name
+func checkNames(data *templateData) error {
+	seen := map[string]bool{
+		data.Repository:          true,
+		data.Impl:                true,
+		data.Repository + "Mock": true,
+		"New" + data.Repository:  true,
+		"Wrap" + data.Repository: true,
+		data.Entity + "Mappings": true,
+		data.Entity:              true,
+		data.Table.KeyFunc:       true,
+	}
+
+	methods := make(map[string]bool)
+
+	for _, index := range data.Queries {
+		if methods[index.Method] {
+			return fmt.Errorf("mapping %s generates the method %s which is already declared, rename the mapping", index.Name, index.Method)
+		}
+
+		methods[index.Method] = true
+
+		names := []string{index.KeyFunc}
+		if index.SortInterface != "" {
+			names = append(names, index.SortInterface)
+		}
+
+		for _, level := range index.Sort {
+			names = append(names, level.Type)
+		}
+
+		if len(index.Sort) != 0 {
+			names = append(names, index.Sort[0].Func)
+		}
+
+		for _, name := range names {
+			if seen[name] {
+				return fmt.Errorf("mapping %s generates %s which is already declared, rename the mapping", index.Name, name)
+			}
+
+			seen[name] = true
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+// Code generated by dynago-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago"
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+)
+
+// OrderRepository
+//
+// Reads and writes Order entities with the mappings declared on its fields
+type OrderRepository interface {
+	Put(ctx context.Context, entity *Order) error
+	Get(ctx context.Context, customerID string, orderID string) (*Order, error)
+	Delete(ctx context.Context, customerID string, orderID string) error
+	QueryByCustomer(ctx context.Context, customerID string, sortPrefix OrderCustomerSort, opts ...func(*query.Options)) ([]*Order, repositories.Cursor, error)
+	QueryByStatus(ctx context.Context, status OrderStatus, sortPrefix OrderStatusSort, opts ...func(*query.Options)) ([]*Order, repositories.Cursor, error)
+
+	// Untyped returns the repository the typed methods call
+	Untyped() repositories.Interface
+}
+
+// OrderMappings builds the table and index mappings declared on Order
+func OrderMappings() (mappings.Interface, []*mappings.Index, error) {
+	tableMapping, err := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     "by-customer",
+		PartitionFields: []string{"customer_id"},
+		SortFields:      []string{"order_id"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statusMapping, err := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     "by-status",
+		PartitionFields: []string{"status"},
+		SortFields:      []string{"customer_id"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tableMapping, []*mappings.Index{{
+		Mapping:        statusMapping,
+		ProjectionType: entities.PropjectionTypeAll,
+	}}, nil
+}
+
+// NewOrderRepository builds the OrderRepository on the mapper's table, configFuncs can set the other repositories.Config options
+func NewOrderRepository(ctx context.Context, mapper *dynago.Mapper, configFuncs ...func(*repositories.Config)) (OrderRepository, error) {
+	tableMapping, indexMappings, err := OrderMappings()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &repositories.Config{
+		Name:          "Order",
+		TableMapping:  tableMapping,
+		IndexMappings: indexMappings,
+	}
+
+	for _, configFunc := range configFuncs {
+		configFunc(cfg)
+	}
+
+	repo, err := mapper.NewRepositoryFromConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return WrapOrderRepository(repo)
+}
+
+// WrapOrderRepository wraps a repository built with the mappings of OrderMappings
+func WrapOrderRepository(repo repositories.Interface) (OrderRepository, error) {
+	typed, err := repositories.NewRepository[*Order](repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &orderRepository{repo: typed}, nil
+}
+
+// OrderKey builds the key of the table mapping by-customer
+func OrderKey(customerID string, orderID string) repositories.Key {
+	return repositories.Key{"customer_id": customerID, "order_id": orderID}
+}
+
+// OrderCustomerKey builds the partition key of the mapping by-customer
+func OrderCustomerKey(customerID string) repositories.Key {
+	return repositories.Key{"customer_id": customerID}
+}
+
+// OrderCustomerSort is a sort key prefix of the mapping by-customer, start one with OrderCustomerOrderID
+type OrderCustomerSort interface {
+	sortKey() repositories.Key
+}
+
+// OrderCustomerSortOrderID is a sort key prefix of the mapping by-customer
+type OrderCustomerSortOrderID struct {
+	key repositories.Key
+}
+
+// OrderCustomerOrderID starts a sort key prefix of the mapping by-customer
+func OrderCustomerOrderID(orderID string) OrderCustomerSortOrderID {
+	return OrderCustomerSortOrderID{key: repositories.Key{"order_id": orderID}}
+}
+
+func (s OrderCustomerSortOrderID) sortKey() repositories.Key {
+	return s.key
+}
+
+// OrderStatusKey builds the partition key of the mapping by-status
+func OrderStatusKey(status OrderStatus) repositories.Key {
+	return repositories.Key{"status": status}
+}
+
+// OrderStatusSort is a sort key prefix of the mapping by-status, start one with OrderStatusCustomerID
+type OrderStatusSort interface {
+	sortKey() repositories.Key
+}
+
+// OrderStatusSortCustomerID is a sort key prefix of the mapping by-status
+type OrderStatusSortCustomerID struct {
+	key repositories.Key
+}
+
+// OrderStatusCustomerID starts a sort key prefix of the mapping by-status
+func OrderStatusCustomerID(customerID string) OrderStatusSortCustomerID {
+	return OrderStatusSortCustomerID{key: repositories.Key{"customer_id": customerID}}
+}
+
+func (s OrderStatusSortCustomerID) sortKey() repositories.Key {
+	return s.key
+}
+
+type orderRepository struct {
+	repo *repositories.Repository[*Order]
+}
+
+func (r *orderRepository) Put(ctx context.Context, entity *Order) error {
+	return r.repo.Put(ctx, entity)
+}
+
+func (r *orderRepository) Get(ctx context.Context, customerID string, orderID string) (*Order, error) {
+	return r.repo.Get(ctx, OrderKey(customerID, orderID))
+}
+
+func (r *orderRepository) Delete(ctx context.Context, customerID string, orderID string) error {
+	return r.repo.Delete(ctx, OrderKey(customerID, orderID))
+}
+
+func (r *orderRepository) QueryByCustomer(ctx context.Context, customerID string, sortPrefix OrderCustomerSort, opts ...func(*query.Options)) ([]*Order, repositories.Cursor, error) {
+	queryOptions := append(append([]func(*query.Options){}, opts...), query.WithPartitionKey(OrderCustomerKey(customerID)))
+	if sortPrefix != nil {
+		queryOptions = append(queryOptions, query.WithSortKey(sortPrefix.sortKey()))
+	}
+
+	return r.repo.Query(ctx, "by-customer", queryOptions...)
+}
+
+func (r *orderRepository) QueryByStatus(ctx context.Context, status OrderStatus, sortPrefix OrderStatusSort, opts ...func(*query.Options)) ([]*Order, repositories.Cursor, error) {
+	queryOptions := append(append([]func(*query.Options){}, opts...), query.WithPartitionKey(OrderStatusKey(status)))
+	if sortPrefix != nil {
+		queryOptions = append(queryOptions, query.WithSortKey(sortPrefix.sortKey()))
+	}
+
+	return r.repo.Query(ctx, "by-status", queryOptions...)
+}
+
+func (r *orderRepository) Untyped() repositories.Interface {
+	return r.repo.Untyped()
+}
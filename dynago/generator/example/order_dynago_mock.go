@@ -0,0 +1,62 @@
+// Code generated by dynago-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/stretchr/testify/mock"
+)
+
+// OrderRepositoryMock is a testify mock of OrderRepository, query options are passed to Called as *query.Options
+type OrderRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *OrderRepositoryMock) Put(ctx context.Context, entity *Order) error {
+	args := m.Called(ctx, entity)
+
+	return args.Error(0)
+}
+
+func (m *OrderRepositoryMock) Get(ctx context.Context, customerID string, orderID string) (*Order, error) {
+	args := m.Called(ctx, customerID, orderID)
+
+	entity, _ := args.Get(0).(*Order)
+
+	return entity, args.Error(1)
+}
+
+func (m *OrderRepositoryMock) Delete(ctx context.Context, customerID string, orderID string) error {
+	args := m.Called(ctx, customerID, orderID)
+
+	return args.Error(0)
+}
+
+func (m *OrderRepositoryMock) QueryByCustomer(ctx context.Context, customerID string, sortPrefix OrderCustomerSort, opts ...func(*query.Options)) ([]*Order, repositories.Cursor, error) {
+	args := m.Called(ctx, customerID, sortPrefix, query.NewOptions(opts...))
+
+	found, _ := args.Get(0).([]*Order)
+	cursor, _ := args.Get(1).(repositories.Cursor)
+
+	return found, cursor, args.Error(2)
+}
+
+func (m *OrderRepositoryMock) QueryByStatus(ctx context.Context, status OrderStatus, sortPrefix OrderStatusSort, opts ...func(*query.Options)) ([]*Order, repositories.Cursor, error) {
+	args := m.Called(ctx, status, sortPrefix, query.NewOptions(opts...))
+
+	found, _ := args.Get(0).([]*Order)
+	cursor, _ := args.Get(1).(repositories.Cursor)
+
+	return found, cursor, args.Error(2)
+}
+
+func (m *OrderRepositoryMock) Untyped() repositories.Interface {
+	args := m.Called()
+
+	repo, _ := args.Get(0).(repositories.Interface)
+
+	return repo
+}
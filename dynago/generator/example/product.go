@@ -0,0 +1,23 @@
+// Package example declares entities the generator is run against, the generated repositories are committed
+// so the build checks they compile
+package example
+
+//go:generate go run ../../cmd/dynago-gen -type Product,Order
+
+type Product struct {
+	ID       string `dynamodbav:"id" dynago:"lookup=by-id,table"`
+	Category string `dynamodbav:"category" dynago:"query=by-category"`
+	Brand    string `dynamodbav:"brand" dynago:"query=by-category,sort=2"`
+	Name     string `dynamodbav:"name" dynago:"query=by-category,sort=1;lookup=by-name,projection=include,include=price"`
+	Price    int64  `dynamodbav:"price"`
+	Version  int64  `dynamodbav:"version" dynago:"version"`
+}
+
+type OrderStatus string
+
+type Order struct {
+	CustomerID string      `dynamodbav:"customer_id" dynago:"query=by-customer,table;query=by-status,sort=2"`
+	OrderID    string      `dynamodbav:"order_id" dynago:"query=by-customer,sort"`
+	Status     OrderStatus `dynamodbav:"status" dynago:"query=by-status"`
+	Total      int64       `dynamodbav:"total"`
+}
@@ -0,0 +1,188 @@
+// Code generated by dynago-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago"
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+)
+
+// ProductRepository
+//
+// Reads and writes Product entities with the mappings declared on its fields
+type ProductRepository interface {
+	Put(ctx context.Context, entity *Product) error
+	Get(ctx context.Context, id string) (*Product, error)
+	Delete(ctx context.Context, id string) error
+	QueryByCategory(ctx context.Context, category string, sortPrefix ProductCategorySort, opts ...func(*query.Options)) ([]*Product, repositories.Cursor, error)
+	QueryByName(ctx context.Context, name string, opts ...func(*query.Options)) ([]*Product, repositories.Cursor, error)
+
+	// Untyped returns the repository the typed methods call
+	Untyped() repositories.Interface
+}
+
+// ProductMappings builds the table and index mappings declared on Product
+func ProductMappings() (mappings.Interface, []*mappings.Index, error) {
+	tableMapping, err := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "by-id",
+		Fields:      []string{"id"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	categoryMapping, err := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     "by-category",
+		PartitionFields: []string{"category"},
+		SortFields:      []string{"name", "brand"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nameMapping, err := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "by-name",
+		Fields:      []string{"name"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tableMapping, []*mappings.Index{{
+		Mapping:        categoryMapping,
+		ProjectionType: entities.PropjectionTypeAll,
+	}, {
+		Mapping:          nameMapping,
+		ProjectionType:   entities.PropjectionTypeInclude,
+		NonKeyAttributes: []string{"price"},
+	}}, nil
+}
+
+// NewProductRepository builds the ProductRepository on the mapper's table, configFuncs can set the other repositories.Config options
+func NewProductRepository(ctx context.Context, mapper *dynago.Mapper, configFuncs ...func(*repositories.Config)) (ProductRepository, error) {
+	tableMapping, indexMappings, err := ProductMappings()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &repositories.Config{
+		Name:          "Product",
+		TableMapping:  tableMapping,
+		IndexMappings: indexMappings,
+		VersionField:  "version",
+	}
+
+	for _, configFunc := range configFuncs {
+		configFunc(cfg)
+	}
+
+	repo, err := mapper.NewRepositoryFromConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return WrapProductRepository(repo)
+}
+
+// WrapProductRepository wraps a repository built with the mappings of ProductMappings
+func WrapProductRepository(repo repositories.Interface) (ProductRepository, error) {
+	typed, err := repositories.NewRepository[*Product](repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &productRepository{repo: typed}, nil
+}
+
+// ProductKey builds the key of the table mapping by-id
+func ProductKey(id string) repositories.Key {
+	return repositories.Key{"id": id}
+}
+
+// ProductCategoryKey builds the partition key of the mapping by-category
+func ProductCategoryKey(category string) repositories.Key {
+	return repositories.Key{"category": category}
+}
+
+// ProductCategorySort is a sort key prefix of the mapping by-category, start one with ProductCategoryName
+type ProductCategorySort interface {
+	sortKey() repositories.Key
+}
+
+// ProductCategorySortName is a sort key prefix of the mapping by-category
+type ProductCategorySortName struct {
+	key repositories.Key
+}
+
+// ProductCategoryName starts a sort key prefix of the mapping by-category
+func ProductCategoryName(name string) ProductCategorySortName {
+	return ProductCategorySortName{key: repositories.Key{"name": name}}
+}
+
+// Brand adds brand to the prefix
+func (s ProductCategorySortName) Brand(brand string) ProductCategorySortNameBrand {
+	key := repositories.Key{"brand": brand}
+	for field, value := range s.key {
+		key[field] = value
+	}
+
+	return ProductCategorySortNameBrand{key: key}
+}
+
+func (s ProductCategorySortName) sortKey() repositories.Key {
+	return s.key
+}
+
+// ProductCategorySortNameBrand is a sort key prefix of the mapping by-category
+type ProductCategorySortNameBrand struct {
+	key repositories.Key
+}
+
+func (s ProductCategorySortNameBrand) sortKey() repositories.Key {
+	return s.key
+}
+
+// ProductNameKey builds the partition key of the mapping by-name
+func ProductNameKey(name string) repositories.Key {
+	return repositories.Key{"name": name}
+}
+
+type productRepository struct {
+	repo *repositories.Repository[*Product]
+}
+
+func (r *productRepository) Put(ctx context.Context, entity *Product) error {
+	return r.repo.Put(ctx, entity)
+}
+
+func (r *productRepository) Get(ctx context.Context, id string) (*Product, error) {
+	return r.repo.Get(ctx, ProductKey(id))
+}
+
+func (r *productRepository) Delete(ctx context.Context, id string) error {
+	return r.repo.Delete(ctx, ProductKey(id))
+}
+
+func (r *productRepository) QueryByCategory(ctx context.Context, category string, sortPrefix ProductCategorySort, opts ...func(*query.Options)) ([]*Product, repositories.Cursor, error) {
+	queryOptions := append(append([]func(*query.Options){}, opts...), query.WithPartitionKey(ProductCategoryKey(category)))
+	if sortPrefix != nil {
+		queryOptions = append(queryOptions, query.WithSortKey(sortPrefix.sortKey()))
+	}
+
+	return r.repo.Query(ctx, "by-category", queryOptions...)
+}
+
+func (r *productRepository) QueryByName(ctx context.Context, name string, opts ...func(*query.Options)) ([]*Product, repositories.Cursor, error) {
+	queryOptions := append(append([]func(*query.Options){}, opts...), query.WithPartitionKey(ProductNameKey(name)))
+
+	return r.repo.Query(ctx, "by-name", queryOptions...)
+}
+
+func (r *productRepository) Untyped() repositories.Interface {
+	return r.repo.Untyped()
+}
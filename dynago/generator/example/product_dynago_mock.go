@@ -0,0 +1,62 @@
+// Code generated by dynago-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/stretchr/testify/mock"
+)
+
+// ProductRepositoryMock is a testify mock of ProductRepository, query options are passed to Called as *query.Options
+type ProductRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ProductRepositoryMock) Put(ctx context.Context, entity *Product) error {
+	args := m.Called(ctx, entity)
+
+	return args.Error(0)
+}
+
+func (m *ProductRepositoryMock) Get(ctx context.Context, id string) (*Product, error) {
+	args := m.Called(ctx, id)
+
+	entity, _ := args.Get(0).(*Product)
+
+	return entity, args.Error(1)
+}
+
+func (m *ProductRepositoryMock) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (m *ProductRepositoryMock) QueryByCategory(ctx context.Context, category string, sortPrefix ProductCategorySort, opts ...func(*query.Options)) ([]*Product, repositories.Cursor, error) {
+	args := m.Called(ctx, category, sortPrefix, query.NewOptions(opts...))
+
+	found, _ := args.Get(0).([]*Product)
+	cursor, _ := args.Get(1).(repositories.Cursor)
+
+	return found, cursor, args.Error(2)
+}
+
+func (m *ProductRepositoryMock) QueryByName(ctx context.Context, name string, opts ...func(*query.Options)) ([]*Product, repositories.Cursor, error) {
+	args := m.Called(ctx, name, query.NewOptions(opts...))
+
+	found, _ := args.Get(0).([]*Product)
+	cursor, _ := args.Get(1).(repositories.Cursor)
+
+	return found, cursor, args.Error(2)
+}
+
+func (m *ProductRepositoryMock) Untyped() repositories.Interface {
+	args := m.Called()
+
+	repo, _ := args.Get(0).(repositories.Interface)
+
+	return repo
+}
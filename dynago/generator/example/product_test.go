@@ -0,0 +1,161 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestIndex(number string) types.GlobalSecondaryIndexDescription {
+	return types.GlobalSecondaryIndexDescription{
+		IndexName:  aws.String("GSI" + number + "pk-GSI" + number + "sk-Index"),
+		Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		KeySchema: []types.KeySchemaElement{{
+			AttributeName: aws.String("GSI" + number + "pk"),
+			KeyType:       types.KeyTypeHash,
+		}, {
+			AttributeName: aws.String("GSI" + number + "sk"),
+			KeyType:       types.KeyTypeRange,
+		}},
+	}
+}
+
+func newTestProductRepository(t *testing.T, client *dynamo.Mock) ProductRepository {
+	tableMapping, indexMappings, err := ProductMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := repositories.New(&repositories.Config{
+		Name:   "Product",
+		Client: client,
+		TableDesc: &types.TableDescription{
+			TableName: aws.String("my-table"),
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String("pk"),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String("sk"),
+				KeyType:       types.KeyTypeRange,
+			}},
+			GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{newTestIndex("1"), newTestIndex("2")},
+		},
+		TableMapping:  tableMapping,
+		IndexMappings: indexMappings,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := WrapProductRepository(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return out
+}
+
+func TestProductRepository_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it builds the table key from the typed fields", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestProductRepository(t, client)
+
+		client.On("GetItem", ctx, "my-table",
+			dynamogetitem.NewOptions(dynamogetitem.WithKey(map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: "ID#ABC"},
+				"sk": &types.AttributeValueMemberS{Value: "ID#ABC"},
+			}))).
+			Return(&dynamogetitem.Result{Item: map[string]types.AttributeValue{
+				"id":   &types.AttributeValueMemberS{Value: "abc"},
+				"name": &types.AttributeValueMemberS{Value: "air"},
+			}}, nil)
+
+		actual, err := fixture.Get(ctx, "abc")
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Product{ID: "abc", Name: "air"}, actual)
+	})
+}
+
+func TestProductRepository_QueryByCategory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it queries the partition with the sort prefix in field order", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestProductRepository(t, client)
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("CATEGORY#SHOES")).
+			And(expression.Key("GSI1sk").BeginsWith("NAME#AIR#BRAND#NIKE"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"),
+				dynamoquery.WithLimit(10))).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{{
+				"id": &types.AttributeValueMemberS{Value: "abc"},
+			}}}, nil)
+
+		actual, cursor, err := fixture.QueryByCategory(ctx, "shoes", ProductCategoryName("air").Brand("nike"), query.WithLimit(10))
+
+		assert.Nil(t, err)
+		assert.Nil(t, cursor)
+		assert.Equal(t, []*Product{{ID: "abc"}}, actual)
+	})
+	t.Run("it queries the whole partition without a sort prefix", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestProductRepository(t, client)
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("CATEGORY#SHOES"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"))).
+			Return(&dynamoquery.Result{}, nil)
+
+		actual, _, err := fixture.QueryByCategory(ctx, "shoes", nil)
+
+		assert.Nil(t, err)
+		assert.Empty(t, actual)
+	})
+}
+
+func TestProductRepositoryMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it returns the stubbed entities", func(t *testing.T) {
+		fixture := &ProductRepositoryMock{}
+		fixture.On("QueryByCategory", ctx, "shoes", ProductCategoryName("air"), mock.Anything).
+			Return([]*Product{{ID: "abc"}}, nil, nil)
+
+		actual, cursor, err := fixture.QueryByCategory(ctx, "shoes", ProductCategoryName("air"))
+
+		assert.Nil(t, err)
+		assert.Nil(t, cursor)
+		assert.Equal(t, []*Product{{ID: "abc"}}, actual)
+	})
+	t.Run("it returns the stubbed error", func(t *testing.T) {
+		fixture := &ProductRepositoryMock{}
+		fixture.On("Get", ctx, "abc").Return(nil, errors.New("boom"))
+
+		actual, err := fixture.Get(ctx, "abc")
+
+		assert.Equal(t, errors.New("boom"), err)
+		assert.Nil(t, actual)
+	})
+}
@@ -0,0 +1,312 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+)
+
+const (
+	// GeneratedSuffix ends the name of every generated file, generated files are skipped when reading the package
+	GeneratedSuffix = "_dynago.go"
+
+	// MockSuffix ends the name of the generated mock files
+	MockSuffix = "_dynago_mock.go"
+
+	structTagName    = "dynago"
+	attributeTagName = "dynamodbav"
+)
+
+// Config
+//
+// The package and entities to generate repositories for
+type Config struct {
+	// Dir is the directory of the package declaring the entities
+	Dir string
+
+	// Types are the names of the entity structs
+	Types []string
+
+	// Mocks generates a testify mock of each repository interface into a file of its own
+	Mocks bool
+}
+
+// File is a generated source file, Name is relative to Config.Dir
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// entity is an entity struct read from source
+type entity struct {
+	Name    string
+	Package string
+
+	// fields by attribute name
+	fields map[string]*field
+
+	mappings *mappings.StructMappings
+}
+
+type field struct {
+	goName    string
+	attribute string
+	goType    string
+}
+
+// Generate
+//
+// Reads the entity structs from the package source and returns the typed repository of each: an interface with
+// Put, Get, Delete and a QueryBy method for every index mapping, key builders, sort prefix builders that only
+// allow the sort fields in order, a constructor registering the mappings and optionally a mock.
+// The mappings are read from the dynago struct tags, see mappings.FromStruct
+func Generate(cfg *Config) ([]*File, error) {
+	if cfg == nil {
+		return nil, errors.New("generator.Generate requires a Config")
+	}
+
+	if len(cfg.Types) == 0 {
+		return nil, errors.New("generator.Config.Types requires at least one type")
+	}
+
+	found, err := parseEntities(cfg.Dir, cfg.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*File
+
+	for _, typeName := range cfg.Types {
+		data, err := newTemplateData(found[typeName])
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := render(repositoryTemplate, data)
+		if err != nil {
+			return nil, fmt.Errorf("generating %s: %s", typeName, err)
+		}
+
+		base := fileBase(typeName)
+		out = append(out, &File{Name: base + GeneratedSuffix, Content: content})
+
+		if !cfg.Mocks {
+			continue
+		}
+
+		content, err = render(mockTemplate, data)
+		if err != nil {
+			return nil, fmt.Errorf("generating the mock of %s: %s", typeName, err)
+		}
+
+		out = append(out, &File{Name: base + MockSuffix, Content: content})
+	}
+
+	return out, nil
+}
+
+// Write generates the files and writes them to Config.Dir
+func Write(cfg *Config) ([]*File, error) {
+	files, err := Generate(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(cfg.Dir, file.Name), file.Content, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func parseEntities(dir string, typeNames []string) (map[string]*entity, error) {
+	fset := token.NewFileSet()
+
+	packages, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		name := info.Name()
+
+		return !strings.HasSuffix(name, "_test.go") && !strings.HasSuffix(name, GeneratedSuffix) && !strings.HasSuffix(name, MockSuffix)
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(packages) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(packages))
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range typeNames {
+		wanted[name] = true
+	}
+
+	out := make(map[string]*entity)
+
+	for _, pkg := range packages {
+		fileNames := make([]string, 0, len(pkg.Files))
+		for name := range pkg.Files {
+			fileNames = append(fileNames, name)
+		}
+
+		sort.Strings(fileNames)
+
+		for _, fileName := range fileNames {
+			for _, decl := range pkg.Files[fileName].Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					if !wanted[typeSpec.Name.Name] {
+						continue
+					}
+
+					structType, ok := typeSpec.Type.(*ast.StructType)
+					if !ok {
+						return nil, fmt.Errorf("type %s is not a struct", typeSpec.Name.Name)
+					}
+
+					parsed, err := parseEntity(pkg.Name, typeSpec.Name.Name, structType)
+					if err != nil {
+						return nil, err
+					}
+
+					out[parsed.Name] = parsed
+				}
+			}
+		}
+	}
+
+	for _, name := range typeNames {
+		if _, ok := out[name]; !ok {
+			return nil, fmt.Errorf("type %s was not found in %s", name, dir)
+		}
+	}
+
+	return out, nil
+}
+
+func parseEntity(packageName, name string, structType *ast.StructType) (*entity, error) {
+	out := &entity{Name: name, Package: packageName, fields: make(map[string]*field)}
+
+	var tagFields []*mappings.TagField
+
+	for _, astField := range structType.Fields.List {
+		var tag reflect.StructTag
+		if astField.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(astField.Tag.Value, "`"))
+		}
+
+		dynagoTag, tagged := tag.Lookup(structTagName)
+
+		if len(astField.Names) == 0 {
+			if tagged {
+				return nil, fmt.Errorf("%s: embedded fields can not declare dynago mappings", name)
+			}
+
+			continue
+		}
+
+		for _, fieldName := range astField.Names {
+			if !fieldName.IsExported() {
+				continue
+			}
+
+			attribute := fieldName.Name
+			if value, ok := tag.Lookup(attributeTagName); ok {
+				tagName := strings.Split(value, ",")[0]
+				if tagName == "-" {
+					continue
+				}
+
+				if tagName != "" {
+					attribute = tagName
+				}
+			}
+
+			goType, err := keyType(astField.Type)
+			if err != nil && tagged && strings.TrimSpace(dynagoTag) != "" {
+				return nil, fmt.Errorf("%s.%s: %s", name, fieldName.Name, err)
+			}
+
+			out.fields[attribute] = &field{goName: fieldName.Name, attribute: attribute, goType: goType}
+
+			if tagged && strings.TrimSpace(dynagoTag) != "" {
+				tagFields = append(tagFields, &mappings.TagField{Name: attribute, Tag: dynagoTag})
+			}
+		}
+	}
+
+	structMappings, err := mappings.FromTags(tagFields)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+
+	out.mappings = structMappings
+
+	return out, nil
+}
+
+// keyType returns the source of a field type that can be used in a key: a builtin string, bool or integer,
+// a type declared in the same package, or a pointer to one of them
+func keyType(expr ast.Expr) (string, error) {
+	switch typed := expr.(type) {
+	case *ast.Ident:
+		switch typed.Name {
+		case "float32", "float64", "complex64", "complex128", "byte", "rune", "error", "any":
+			return "", fmt.Errorf("type %s can not be used in a key", typed.Name)
+		}
+
+		return typed.Name, nil
+	case *ast.StarExpr:
+		inner, err := keyType(typed.X)
+		if err != nil {
+			return "", err
+		}
+
+		return "*" + inner, nil
+	default:
+		return "", errors.New("only builtin types and types declared in the same package can be used in a key")
+	}
+}
+
+func render(source string, data *templateData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := parsedTemplate(source).Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s\n%s", err, buf.String())
+	}
+
+	return formatted, nil
+}
+
+func projectionConst(projectionType entities.ProjectionType) string {
+	switch projectionType {
+	case entities.PropjectionTypeKeysOnly:
+		return "entities.PropjectionTypeKeysOnly"
+	case entities.PropjectionTypeInclude:
+		return "entities.PropjectionTypeInclude"
+	default:
+		return "entities.PropjectionTypeAll"
+	}
+}
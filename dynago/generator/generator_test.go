@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestPackage(t *testing.T, source string) string {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "entity.go"), []byte("package entity\n\n"+source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	t.Run("it generates the committed example", func(t *testing.T) {
+		actual, err := Generate(&Config{Dir: "example", Types: []string{"Product", "Order"}, Mocks: true})
+
+		assert.Nil(t, err)
+		assert.Len(t, actual, 4)
+
+		for _, file := range actual {
+			expected, err := os.ReadFile(filepath.Join("example", file.Name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, string(expected), string(file.Content), "run go generate in the example package after changing the templates")
+		}
+	})
+	t.Run("it requires a type", func(t *testing.T) {
+		_, err := Generate(&Config{Dir: "example"})
+
+		assert.Equal(t, errors.New("generator.Config.Types requires at least one type"), err)
+	})
+	t.Run("it fails when a type is not declared", func(t *testing.T) {
+		_, err := Generate(&Config{Dir: "example", Types: []string{"Customer"}})
+
+		assert.Equal(t, errors.New("type Customer was not found in example"), err)
+	})
+	t.Run("it returns the mapping errors of the tags", func(t *testing.T) {
+		dir := writeTestPackage(t, "type Entity struct {\n"+
+			"\tID string `dynamodbav:\"id\" dynago:\"lookup=by-id\"`\n"+
+			"}\n")
+
+		_, err := Generate(&Config{Dir: dir, Types: []string{"Entity"}})
+
+		assert.Equal(t, errors.New("Entity: mappings.FromStruct requires exactly one mapping declared with the table option"), err)
+	})
+	t.Run("it rejects key fields of types that can not be used in a key", func(t *testing.T) {
+		dir := writeTestPackage(t, "type Entity struct {\n"+
+			"\tID    string  `dynamodbav:\"id\" dynago:\"lookup=by-id,table\"`\n"+
+			"\tPrice float64 `dynamodbav:\"price\" dynago:\"lookup=by-price\"`\n"+
+			"}\n")
+
+		_, err := Generate(&Config{Dir: dir, Types: []string{"Entity"}})
+
+		assert.Equal(t, errors.New("Entity.Price: type float64 can not be used in a key"), err)
+	})
+	t.Run("it rejects mappings that generate the same method", func(t *testing.T) {
+		dir := writeTestPackage(t, "type Entity struct {\n"+
+			"\tID       string `dynamodbav:\"id\" dynago:\"lookup=by-id,table\"`\n"+
+			"\tCategory string `dynamodbav:\"category\" dynago:\"lookup=by-category;lookup=category\"`\n"+
+			"}\n")
+
+		_, err := Generate(&Config{Dir: dir, Types: []string{"Entity"}})
+
+		assert.Equal(t, errors.New("Entity: mapping category generates the method QueryByCategory which is already declared, rename the mapping"), err)
+	})
+	t.Run("it leaves out the mock", func(t *testing.T) {
+		actual, err := Generate(&Config{Dir: "example", Types: []string{"Product"}})
+
+		assert.Nil(t, err)
+		assert.Len(t, actual, 1)
+		assert.Equal(t, "product_dynago.go", actual[0].Name)
+	})
+}
+
+func TestNames(t *testing.T) {
+	t.Run("it drops the leading query words of a mapping name", func(t *testing.T) {
+		assert.Equal(t, "Category", mappingPascal("by-category"))
+		assert.Equal(t, "Category", mappingPascal("queryByCategory"))
+		assert.Equal(t, "CustomerStatus", mappingPascal("customer_status"))
+		assert.Equal(t, "By", mappingPascal("by"))
+	})
+	t.Run("it lowers the leading initialism of a field", func(t *testing.T) {
+		assert.Equal(t, "id", lowerFirst("ID"))
+		assert.Equal(t, "customerID", lowerFirst("CustomerID"))
+		assert.Equal(t, "urlPath", lowerFirst("URLPath"))
+	})
+	t.Run("it renames parameters that clash with keywords and generated identifiers", func(t *testing.T) {
+		assert.Equal(t, "typeValue", paramName("Type"))
+		assert.Equal(t, "ctxValue", paramName("Ctx"))
+		assert.Equal(t, "name", paramName("Name"))
+	})
+	t.Run("it names files in snake case", func(t *testing.T) {
+		assert.Equal(t, "product_item", fileBase("ProductItem"))
+		assert.Equal(t, "http_client", fileBase("HTTPClient"))
+	})
+}
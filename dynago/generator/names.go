@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// reservedParams are identifiers the generated methods use, parameters named after them get a suffix
+var reservedParams = map[string]bool{
+	"ctx":          true,
+	"opts":         true,
+	"sortPrefix":   true,
+	"entity":       true,
+	"args":         true,
+	"cursor":       true,
+	"key":          true,
+	"r":            true,
+	"m":            true,
+	"s":            true,
+	"context":      true,
+	"mock":         true,
+	"query":        true,
+	"repositories": true,
+}
+
+// words splits a name on separators and case changes, "by-category" and "byCategory" both give [by category]
+func words(name string) []string {
+	var out []string
+	var current []rune
+
+	flush := func() {
+		if len(current) != 0 {
+			out = append(out, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+
+	runes := []rune(name)
+	for idx, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(current) != 0 {
+			prevLower := !unicode.IsUpper(runes[idx-1])
+			nextLower := idx+1 < len(runes) && unicode.IsLower(runes[idx+1])
+
+			if prevLower || nextLower {
+				flush()
+			}
+		}
+
+		current = append(current, r)
+	}
+
+	flush()
+
+	return out
+}
+
+// mappingPascal names the methods and types of a mapping, a leading query, lookup or by is dropped
+// so "by-category" gives Category
+func mappingPascal(mappingName string) string {
+	all := words(mappingName)
+
+	trimmed := all
+	for len(trimmed) != 0 && (trimmed[0] == "by" || trimmed[0] == "query" || trimmed[0] == "lookup") {
+		trimmed = trimmed[1:]
+	}
+
+	if len(trimmed) == 0 {
+		trimmed = all
+	}
+
+	var out strings.Builder
+	for _, word := range trimmed {
+		out.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+
+	return out.String()
+}
+
+// lowerFirst lowers the leading initialism of a Go name, ID gives id and URLPath gives urlPath
+func lowerFirst(name string) string {
+	runes := []rune(name)
+
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+
+	switch {
+	case upper == 0:
+		return name
+	case upper == 1 || upper == len(runes):
+		// a single capital or a name that is all initialism
+	case unicode.IsLetter(runes[upper]):
+		// the last capital starts the next word
+		upper--
+	}
+
+	for idx := 0; idx < upper; idx++ {
+		runes[idx] = unicode.ToLower(runes[idx])
+	}
+
+	return string(runes)
+}
+
+// paramName is the parameter for a field, keywords and identifiers the generated code uses get a suffix
+func paramName(goName string) string {
+	out := lowerFirst(goName)
+	if token.IsKeyword(out) || reservedParams[out] {
+		out += "Value"
+	}
+
+	return out
+}
+
+// fileBase is the snake case file name of a type, ProductItem gives product_item
+func fileBase(typeName string) string {
+	return strings.Join(words(typeName), "_")
+}
@@ -0,0 +1,280 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var templateFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+	"quoteAll": func(values []string) string {
+		quoted := make([]string, len(values))
+		for idx, value := range values {
+			quoted[idx] = strconv.Quote(value)
+		}
+
+		return strings.Join(quoted, ", ")
+	},
+}
+
+func parsedTemplate(source string) *template.Template {
+	return template.Must(template.New("").Funcs(templateFuncs).Parse(source))
+}
+
+const header = `// Code generated by dynago-gen. DO NOT EDIT.
+
+package {{.Package}}
+`
+
+const repositoryTemplate = header + `
+import (
+	"context"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago"
+{{- if .Indexes}}
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+{{- end}}
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+{{- if .Queries}}
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+{{- end}}
+)
+
+{{define "params"}}{{range $idx, $param := .}}{{if $idx}}, {{end}}{{$param.Param}} {{$param.Type}}{{end}}{{end}}
+{{- define "args"}}{{range $idx, $param := .}}{{if $idx}}, {{end}}{{$param.Param}}{{end}}{{end}}
+{{- define "key"}}repositories.Key{ {{- range $idx, $param := .}}{{if $idx}}, {{end}}{{quote $param.Attribute}}: {{$param.Param}}{{end -}} }{{end}}
+
+{{- $entity := .Entity}}
+{{- $repository := .Repository}}
+{{- $impl := .Impl}}
+
+// {{.Repository}}
+//
+// Reads and writes {{.Entity}} entities with the mappings declared on its fields
+type {{.Repository}} interface {
+	Put(ctx context.Context, entity *{{.Entity}}) error
+	Get(ctx context.Context, {{template "params" .Table.KeyParams}}) (*{{.Entity}}, error)
+	Delete(ctx context.Context, {{template "params" .Table.KeyParams}}) error
+{{- range .Queries}}
+	{{.Method}}(ctx context.Context, {{template "params" .KeyParams}}{{if .SortInterface}}, sortPrefix {{.SortInterface}}{{end}}, opts ...func(*query.Options)) ([]*{{$entity}}, repositories.Cursor, error)
+{{- end}}
+
+	// Untyped returns the repository the typed methods call
+	Untyped() repositories.Interface
+}
+
+// {{.Entity}}Mappings builds the table and index mappings declared on {{.Entity}}
+func {{.Entity}}Mappings() (mappings.Interface, []*mappings.Index, error) {
+	{{template "mapping" .Table}}
+{{- range .Indexes}}
+
+	{{template "mapping" .}}
+{{- end}}
+
+	return tableMapping, []*mappings.Index{ {{- range .Indexes}}{
+		Mapping:        {{.Var}},
+		ProjectionType: {{.ProjectionConst}},
+{{- if .NonKeyAttributes}}
+		NonKeyAttributes: []string{ {{- quoteAll .NonKeyAttributes -}} },
+{{- end}}
+	}, {{end -}} }, nil
+}
+
+{{- define "mapping"}}{{.Var}}, err := {{if .Lookup}}mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: {{quote .Name}},
+		Fields:      []string{ {{- quoteAll .PartitionFields -}} },
+	}){{else}}mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     {{quote .Name}},
+		PartitionFields: []string{ {{- quoteAll .PartitionFields -}} },
+		SortFields:      []string{ {{- quoteAll .SortFields -}} },
+	}){{end}}
+	if err != nil {
+		return nil, nil, err
+	}
+{{- end}}
+
+// New{{.Repository}} builds the {{.Repository}} on the mapper's table, configFuncs can set the other repositories.Config options
+func New{{.Repository}}(ctx context.Context, mapper *dynago.Mapper, configFuncs ...func(*repositories.Config)) ({{.Repository}}, error) {
+	tableMapping, indexMappings, err := {{.Entity}}Mappings()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &repositories.Config{
+		Name:          {{quote .Entity}},
+		TableMapping:  tableMapping,
+		IndexMappings: indexMappings,
+{{- if .VersionField}}
+		VersionField:  {{quote .VersionField}},
+{{- end}}
+	}
+
+	for _, configFunc := range configFuncs {
+		configFunc(cfg)
+	}
+
+	repo, err := mapper.NewRepositoryFromConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return Wrap{{.Repository}}(repo)
+}
+
+// Wrap{{.Repository}} wraps a repository built with the mappings of {{.Entity}}Mappings
+func Wrap{{.Repository}}(repo repositories.Interface) ({{.Repository}}, error) {
+	typed, err := repositories.NewRepository[*{{.Entity}}](repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &{{.Impl}}{repo: typed}, nil
+}
+
+// {{.Table.KeyFunc}} builds the key of the table mapping {{.Table.Name}}
+func {{.Table.KeyFunc}}({{template "params" .Table.KeyParams}}) repositories.Key {
+	return {{template "key" .Table.KeyParams}}
+}
+{{- range $index := .Queries}}
+
+// {{.KeyFunc}} builds the partition key of the mapping {{.Name}}
+func {{.KeyFunc}}({{template "params" .KeyParams}}) repositories.Key {
+	return {{template "key" .KeyParams}}
+}
+{{- if .SortInterface}}
+
+// {{.SortInterface}} is a sort key prefix of the mapping {{.Name}}, start one with {{(index .Sort 0).Func}}
+type {{.SortInterface}} interface {
+	sortKey() repositories.Key
+}
+{{- range .Sort}}
+
+// {{.Type}} is a sort key prefix of the mapping {{$index.Name}}
+type {{.Type}} struct {
+	key repositories.Key
+}
+{{- if eq .Func (index $index.Sort 0).Func}}
+
+// {{.Func}} starts a sort key prefix of the mapping {{$index.Name}}
+func {{.Func}}({{.Field.Param}} {{.Field.Type}}) {{.Type}} {
+	return {{.Type}}{key: repositories.Key{ {{- quote .Field.Attribute}}: {{.Field.Param -}} }}
+}
+{{- end}}
+{{- $level := .}}
+{{- with .Next}}
+
+// {{.Func}} adds {{.Field.Attribute}} to the prefix
+func (s {{$level.Type}}) {{.Func}}({{.Field.Param}} {{.Field.Type}}) {{.Type}} {
+	key := repositories.Key{ {{- quote .Field.Attribute}}: {{.Field.Param -}} }
+	for field, value := range s.key {
+		key[field] = value
+	}
+
+	return {{.Type}}{key: key}
+}
+{{- end}}
+
+func (s {{.Type}}) sortKey() repositories.Key {
+	return s.key
+}
+{{- end}}
+{{- end}}
+{{- end}}
+
+type {{.Impl}} struct {
+	repo *repositories.Repository[*{{.Entity}}]
+}
+
+func (r *{{.Impl}}) Put(ctx context.Context, entity *{{.Entity}}) error {
+	return r.repo.Put(ctx, entity)
+}
+
+func (r *{{.Impl}}) Get(ctx context.Context, {{template "params" .Table.KeyParams}}) (*{{.Entity}}, error) {
+	return r.repo.Get(ctx, {{.Table.KeyFunc}}({{template "args" .Table.KeyParams}}))
+}
+
+func (r *{{.Impl}}) Delete(ctx context.Context, {{template "params" .Table.KeyParams}}) error {
+	return r.repo.Delete(ctx, {{.Table.KeyFunc}}({{template "args" .Table.KeyParams}}))
+}
+{{- range .Queries}}
+
+func (r *{{$impl}}) {{.Method}}(ctx context.Context, {{template "params" .KeyParams}}{{if .SortInterface}}, sortPrefix {{.SortInterface}}{{end}}, opts ...func(*query.Options)) ([]*{{$entity}}, repositories.Cursor, error) {
+	queryOptions := append(append([]func(*query.Options){}, opts...), query.WithPartitionKey({{.KeyFunc}}({{template "args" .KeyParams}})))
+{{- if .SortInterface}}
+	if sortPrefix != nil {
+		queryOptions = append(queryOptions, query.WithSortKey(sortPrefix.sortKey()))
+	}
+{{- end}}
+
+	return r.repo.Query(ctx, {{quote .Name}}, queryOptions...)
+}
+{{- end}}
+
+func (r *{{.Impl}}) Untyped() repositories.Interface {
+	return r.repo.Untyped()
+}
+`
+
+const mockTemplate = header + `
+import (
+	"context"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+{{- if .Queries}}
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+{{- end}}
+	"github.com/stretchr/testify/mock"
+)
+
+{{define "params"}}{{range $idx, $param := .}}{{if $idx}}, {{end}}{{$param.Param}} {{$param.Type}}{{end}}{{end}}
+{{- define "args"}}{{range $idx, $param := .}}{{if $idx}}, {{end}}{{$param.Param}}{{end}}{{end}}
+
+{{- $entity := .Entity}}
+{{- $mock := print .Repository "Mock"}}
+
+// {{$mock}} is a testify mock of {{.Repository}}, query options are passed to Called as *query.Options
+type {{$mock}} struct {
+	mock.Mock
+}
+
+func (m *{{$mock}}) Put(ctx context.Context, entity *{{.Entity}}) error {
+	args := m.Called(ctx, entity)
+
+	return args.Error(0)
+}
+
+func (m *{{$mock}}) Get(ctx context.Context, {{template "params" .Table.KeyParams}}) (*{{.Entity}}, error) {
+	args := m.Called(ctx, {{template "args" .Table.KeyParams}})
+
+	entity, _ := args.Get(0).(*{{.Entity}})
+
+	return entity, args.Error(1)
+}
+
+func (m *{{$mock}}) Delete(ctx context.Context, {{template "params" .Table.KeyParams}}) error {
+	args := m.Called(ctx, {{template "args" .Table.KeyParams}})
+
+	return args.Error(0)
+}
+{{- range .Queries}}
+
+func (m *{{$mock}}) {{.Method}}(ctx context.Context, {{template "params" .KeyParams}}{{if .SortInterface}}, sortPrefix {{.SortInterface}}{{end}}, opts ...func(*query.Options)) ([]*{{$entity}}, repositories.Cursor, error) {
+	args := m.Called(ctx, {{template "args" .KeyParams}}{{if .SortInterface}}, sortPrefix{{end}}, query.NewOptions(opts...))
+
+	found, _ := args.Get(0).([]*{{$entity}})
+	cursor, _ := args.Get(1).(repositories.Cursor)
+
+	return found, cursor, args.Error(2)
+}
+{{- end}}
+
+func (m *{{$mock}}) Untyped() repositories.Interface {
+	args := m.Called()
+
+	repo, _ := args.Get(0).(repositories.Interface)
+
+	return repo
+}
+`
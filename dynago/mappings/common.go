@@ -22,7 +22,14 @@ func attributeValueToString(value types.AttributeValue) string {
 		return value.Value
 	case *types.AttributeValueMemberBOOL:
 		return strconv.FormatBool(value.Value)
+	case *types.AttributeValueMemberN:
+		return value.Value
 	default:
 		return ""
 	}
 }
+
+// BuildPrefix formats a value to be placed in front of built key values, e.g. the entity type of a repository
+func BuildPrefix(value string) string {
+	return setCasing(value) + getFieldSeparator()
+}
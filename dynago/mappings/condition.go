@@ -0,0 +1,105 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Includes reports whether the item matches every condition of the index
+func (i *Index) Includes(item map[string]types.AttributeValue) bool {
+	for _, condition := range i.Conditions {
+		if !MatchCondition(condition, item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchCondition
+//
+// Evaluates a condition against an item. String, number and boolean attributes are compared by their string value
+func MatchCondition(condition *entities.Condition, item map[string]types.AttributeValue) bool {
+	value, exists := item[condition.Field]
+	if _, isNull := value.(*types.AttributeValueMemberNULL); isNull {
+		exists = false
+	}
+
+	switch condition.Operator {
+	case entities.ConditionOperator_Exists:
+		return exists
+	case entities.ConditionOperator_NotExists:
+		return !exists
+	case entities.ConditionOperator_Equal:
+		return exists && attributeValueToString(value) == condition.Values[0]
+	case entities.ConditionOperator_NotEqual:
+		return !exists || attributeValueToString(value) != condition.Values[0]
+	case entities.ConditionOperator_In:
+		if !exists {
+			return false
+		}
+
+		for _, candidate := range condition.Values {
+			if attributeValueToString(value) == candidate {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return false
+	}
+}
+
+// ValidateConditions checks each condition has a field and the number of values its operator needs
+func ValidateConditions(conditions []*entities.Condition) error {
+	for _, condition := range conditions {
+		if condition == nil || condition.Field == "" {
+			return errors.New("a condition requires a Field")
+		}
+
+		switch condition.Operator {
+		case entities.ConditionOperator_Exists, entities.ConditionOperator_NotExists:
+			if len(condition.Values) != 0 {
+				return fmt.Errorf("condition %s on field %s does not take values", condition.Operator, condition.Field)
+			}
+		case entities.ConditionOperator_Equal, entities.ConditionOperator_NotEqual:
+			if len(condition.Values) != 1 {
+				return fmt.Errorf("condition %s on field %s requires exactly one value", condition.Operator, condition.Field)
+			}
+		case entities.ConditionOperator_In:
+			if len(condition.Values) == 0 {
+				return fmt.Errorf("condition %s on field %s requires at least one value", condition.Operator, condition.Field)
+			}
+		default:
+			return fmt.Errorf("condition on field %s has an unknown operator '%s'", condition.Field, condition.Operator)
+		}
+	}
+
+	return nil
+}
+
+// ConditionsEqual reports whether two condition lists are the same, in order
+func ConditionsEqual(a, b []*entities.Condition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx].Field != b[idx].Field || a[idx].Operator != b[idx].Operator || len(a[idx].Values) != len(b[idx].Values) {
+			return false
+		}
+
+		for valueIdx := range a[idx].Values {
+			if a[idx].Values[valueIdx] != b[idx].Values[valueIdx] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
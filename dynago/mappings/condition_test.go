@@ -0,0 +1,64 @@
+package mappings
+
+import (
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex_Includes(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"status":   &types.AttributeValueMemberS{Value: "active"},
+		"featured": &types.AttributeValueMemberBOOL{Value: true},
+		"deleted":  &types.AttributeValueMemberNULL{Value: true},
+	}
+
+	t.Run("it includes every item without conditions", func(t *testing.T) {
+		assert.True(t, (&Index{}).Includes(item))
+	})
+	t.Run("it requires every condition to match", func(t *testing.T) {
+		index := &Index{Conditions: []*entities.Condition{
+			{Field: "status", Operator: entities.ConditionOperator_In, Values: []string{"active", "pending"}},
+			{Field: "featured", Operator: entities.ConditionOperator_Equal, Values: []string{"true"}},
+		}}
+
+		assert.True(t, index.Includes(item))
+
+		index.Conditions = append(index.Conditions, &entities.Condition{
+			Field: "status", Operator: entities.ConditionOperator_NotEqual, Values: []string{"active"},
+		})
+
+		assert.False(t, index.Includes(item))
+	})
+	t.Run("it treats null attributes as missing", func(t *testing.T) {
+		index := &Index{Conditions: []*entities.Condition{
+			{Field: "deleted", Operator: entities.ConditionOperator_NotExists},
+			{Field: "status", Operator: entities.ConditionOperator_Exists},
+		}}
+
+		assert.True(t, index.Includes(item))
+	})
+}
+
+func TestValidateConditions(t *testing.T) {
+	t.Run("it requires a field", func(t *testing.T) {
+		err := ValidateConditions([]*entities.Condition{{Operator: entities.ConditionOperator_Exists}})
+
+		assert.EqualError(t, err, "a condition requires a Field")
+	})
+	t.Run("it checks the values of the operator", func(t *testing.T) {
+		err := ValidateConditions([]*entities.Condition{
+			{Field: "status", Operator: entities.ConditionOperator_Equal, Values: []string{"a", "b"}},
+		})
+
+		assert.EqualError(t, err, "condition EQ on field status requires exactly one value")
+	})
+	t.Run("it rejects unknown operators", func(t *testing.T) {
+		err := ValidateConditions([]*entities.Condition{{Field: "status", Operator: "LIKE"}})
+
+		assert.EqualError(t, err, "condition on field status has an unknown operator 'LIKE'")
+	})
+}
@@ -0,0 +1,106 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DecodedKey
+//
+// The field and value pairs a partition or sort key was built from. Values are in the casing they
+// were written with, the original casing is not recoverable
+type DecodedKey struct {
+	// Prefix is placed in front of the fields, e.g. the entity type of the repository
+	Prefix string
+
+	Fields []*DecodedField
+}
+
+type DecodedField struct {
+	Field string
+	Value string
+}
+
+// DecodeKey
+//
+// Splits a built key back into its fields. With the mapping's fields, in key order, values may contain the
+// separator and a prefix in front of the first field is returned as Prefix. Fields follow each other with a
+// separator in sort and lookup keys and without one in query partition keys, both are decoded. Sort keys can
+// hold fewer fields than the mapping, they are built up to the first missing value. Without fields the key is
+// split into pairs, a leading unpaired segment is the prefix
+func DecodeKey(key string, fields []string) (*DecodedKey, error) {
+	if key == "" {
+		return nil, errors.New("mappings.DecodeKey requires a key")
+	}
+
+	if len(fields) == 0 {
+		return decodePairs(key), nil
+	}
+
+	separator := getFieldSeparator()
+	out := &DecodedKey{}
+
+	first := setCasing(fields[0]) + separator
+	start := strings.Index(key, first)
+
+	switch {
+	case start < 0:
+		return nil, fmt.Errorf("key %s does not contain the field %s", key, fields[0])
+	case start > 0:
+		if !strings.HasSuffix(key[:start], separator) {
+			return nil, fmt.Errorf("key %s does not start with the field %s", key, fields[0])
+		}
+
+		out.Prefix = strings.TrimSuffix(key[:start], separator)
+	}
+
+	rest := key[start:]
+
+	for idx, field := range fields {
+		name := setCasing(field) + separator
+		if !strings.HasPrefix(rest, name) {
+			return nil, fmt.Errorf("key %s does not have the field %s in position %d", key, field, idx+1)
+		}
+
+		rest = rest[len(name):]
+
+		end, skip := len(rest), 0
+		if idx+1 < len(fields) {
+			next := setCasing(fields[idx+1]) + separator
+
+			// query partition keys do not separate the fields
+			if found := strings.Index(rest, separator+next); found >= 0 {
+				end, skip = found, len(separator)
+			} else if found := strings.Index(rest, next); found >= 0 {
+				end = found
+			}
+		}
+
+		out.Fields = append(out.Fields, &DecodedField{Field: field, Value: rest[:end]})
+
+		if end == len(rest) {
+			return out, nil
+		}
+
+		rest = rest[end+skip:]
+	}
+
+	return nil, fmt.Errorf("key %s has more segments than the fields %s", key, strings.Join(fields, ", "))
+}
+
+func decodePairs(key string) *DecodedKey {
+	segments := strings.Split(key, getFieldSeparator())
+	out := &DecodedKey{}
+
+	if len(segments)%2 == 1 {
+		out.Prefix = segments[0]
+		segments = segments[1:]
+	}
+
+	for idx := 0; idx < len(segments); idx += 2 {
+		out.Fields = append(out.Fields, &DecodedField{Field: segments[idx], Value: segments[idx+1]})
+	}
+
+	return out
+}
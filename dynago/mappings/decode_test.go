@@ -0,0 +1,94 @@
+package mappings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeKey(t *testing.T) {
+	t.Run("it splits a key into pairs without fields", func(t *testing.T) {
+		actual, err := DecodeKey("PRODUCT#CATEGORY#SHOES#NAME#AIR MAX", nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &DecodedKey{
+			Prefix: "PRODUCT",
+			Fields: []*DecodedField{{Field: "CATEGORY", Value: "SHOES"}, {Field: "NAME", Value: "AIR MAX"}},
+		}, actual)
+	})
+	t.Run("it keeps separators in values when the fields are known", func(t *testing.T) {
+		actual, err := DecodeKey("ORDER#CATEGORY#SHOES#KIDS#NAME#AIR MAX", []string{"category", "name"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &DecodedKey{
+			Prefix: "ORDER",
+			Fields: []*DecodedField{{Field: "category", Value: "SHOES#KIDS"}, {Field: "name", Value: "AIR MAX"}},
+		}, actual)
+	})
+	t.Run("it decodes a sort key built from the leading fields", func(t *testing.T) {
+		actual, err := DecodeKey("NAME#AIR MAX", []string{"name", "brand"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []*DecodedField{{Field: "name", Value: "AIR MAX"}}, actual.Fields)
+	})
+	t.Run("it decodes a query partition key built from several fields", func(t *testing.T) {
+		mapping, err := NewQuery(&QueryConfig{
+			MappingName:     "byCategoryAndBrand",
+			PartitionFields: []string{"category", "brand"},
+			SortFields:      []string{"name"},
+		})
+		assert.Nil(t, err)
+
+		key, err := mapping.BuildPartitionValues(context.Background(), map[string]types.AttributeValue{
+			"category": &types.AttributeValueMemberS{Value: "shoes"},
+			"brand":    &types.AttributeValueMemberS{Value: "acme"},
+		})
+		assert.Nil(t, err)
+
+		actual, err := DecodeKey(key, mapping.GetPartitionFields())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "CATEGORY#SHOESBRAND#ACME", key)
+		assert.Equal(t, []*DecodedField{{Field: "category", Value: "SHOES"}, {Field: "brand", Value: "ACME"}}, actual.Fields)
+	})
+	t.Run("it returns an error when the key was not built from the fields", func(t *testing.T) {
+		_, err := DecodeKey("ID#ABC", []string{"category"})
+
+		assert.EqualError(t, err, "key ID#ABC does not contain the field category")
+	})
+}
+
+func TestFromEntity(t *testing.T) {
+	t.Run("it rebuilds each mapping type", func(t *testing.T) {
+		for _, entity := range []*entities.Mapping{{
+			Name:            "by-id",
+			Type:            entities.MappingType_Lookup,
+			PartitionFields: []string{"id"},
+			SortFields:      []string{"id"},
+		}, {
+			Name:            "by-category",
+			Type:            entities.MappingType_Query,
+			PartitionFields: []string{"category"},
+			SortFields:      []string{"name"},
+		}, {
+			Name:            "order-items",
+			Type:            entities.MappingType_Relationship,
+			PartitionFields: []string{"order_id"},
+			SortFields:      []string{"item_id"},
+		}} {
+			actual, err := FromEntity(entity)
+
+			assert.Nil(t, err)
+			assert.Equal(t, entity, actual.ToEntity())
+		}
+	})
+	t.Run("it rejects unknown types", func(t *testing.T) {
+		_, err := FromEntity(&entities.Mapping{Name: "all", Type: entities.MappingType_List})
+
+		assert.EqualError(t, err, "mapping all has the unsupported type 'list'")
+	})
+}
@@ -0,0 +1,62 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+)
+
+// FromEntity
+//
+// Rebuilds a mapping from its persisted entity, e.g. to inspect the schema of a repository without its code.
+// The parent of a relationship is rebuilt as a lookup on the partition fields, which builds the same partition values
+func FromEntity(entity *entities.Mapping) (Interface, error) {
+	if entity == nil {
+		return nil, errors.New("mappings.FromEntity requires a Mapping")
+	}
+
+	switch entity.Type {
+	case entities.MappingType_Lookup:
+		return NewLookup(&LookupConfig{MappingName: entity.Name, Fields: entity.PartitionFields})
+	case entities.MappingType_Query:
+		return NewQuery(&QueryConfig{
+			MappingName:     entity.Name,
+			PartitionFields: entity.PartitionFields,
+			SortFields:      entity.SortFields,
+		})
+	case entities.MappingType_Relationship:
+		parent, err := NewLookup(&LookupConfig{MappingName: entity.Name, Fields: entity.PartitionFields})
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRelationship(&RelationshipConfig{
+			MappingName: entity.Name,
+			Parent:      parent,
+			SortFields:  entity.SortFields,
+		})
+	default:
+		return nil, fmt.Errorf("mapping %s has the unsupported type '%s'", entity.Name, entity.Type)
+	}
+}
+
+// IndexFromEntity rebuilds an index mapping from its persisted entity, Name is the index it is assigned to
+func IndexFromEntity(entity *entities.Index) (*Index, error) {
+	if entity == nil {
+		return nil, errors.New("mappings.IndexFromEntity requires an Index")
+	}
+
+	mapping, err := FromEntity(entity.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Index{
+		Name:             entity.Name,
+		ProjectionType:   entity.ProjectionType,
+		Mapping:          mapping,
+		NonKeyAttributes: entity.NonKeyAttributes,
+		Conditions:       entity.Conditions,
+	}, nil
+}
@@ -1,9 +1,51 @@
 package mappings
 
-import "github.com/KirkDiggler/go-projects/tools/dynago/entities"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+)
 
 type Index struct {
 	Name           string
 	ProjectionType entities.ProjectionType
 	Mapping        Interface
+
+	// NonKeyAttributes are projected onto the index along with the keys, required when ProjectionType is INCLUDE
+	NonKeyAttributes []string
+
+	// Conditions make the index sparse, its key attributes are only written when every condition matches the item
+	Conditions []*entities.Condition
+}
+
+// ValidateProjection checks NonKeyAttributes are declared for the INCLUDE projection and only for it
+func (i *Index) ValidateProjection() error {
+	switch i.ProjectionType {
+	case "", entities.PropjectionTypeAll, entities.PropjectionTypeKeysOnly:
+		if len(i.NonKeyAttributes) != 0 {
+			return fmt.Errorf("NonKeyAttributes can only be used with the %s projection", entities.PropjectionTypeInclude)
+		}
+	case entities.PropjectionTypeInclude:
+		if len(i.NonKeyAttributes) == 0 {
+			return fmt.Errorf("the %s projection requires NonKeyAttributes", entities.PropjectionTypeInclude)
+		}
+	default:
+		return fmt.Errorf("unknown projection type '%s'", i.ProjectionType)
+	}
+
+	seen := make(map[string]bool)
+	for _, attribute := range i.NonKeyAttributes {
+		if attribute == "" {
+			return errors.New("NonKeyAttributes can not contain an empty attribute")
+		}
+
+		if seen[attribute] {
+			return fmt.Errorf("non key attribute %s is declared more than once", attribute)
+		}
+
+		seen[attribute] = true
+	}
+
+	return nil
 }
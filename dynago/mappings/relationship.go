@@ -0,0 +1,118 @@
+package mappings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	requiredRelationshipConfig         = "mappings.NewRelationship requires a RelationshipConfig"
+	requiredRelationshipMappingNameMsg = "mappings.NewRelationship requires RelationshipConfig.MappingName to be set"
+	requiredRelationshipParentMsg      = "mappings.NewRelationship requires RelationshipConfig.Parent to be set"
+	requiredRelationshipSortFieldsMsg  = "mappings.NewRelationship requires RelationshipConfig.SortFields to be set"
+)
+
+// relationship places a child entity in the partition of its parent.
+// The partition value is built by the parent mapping so parent and children always share it
+type relationship struct {
+	mappingName string
+	parent      Interface
+	sortFields  []string
+}
+
+type RelationshipConfig struct {
+	MappingName string
+
+	// Parent is the table mapping of the parent repository. The child entity must have the parent's partition fields
+	Parent Interface
+
+	// SortFields identify the child within the parent's partition
+	SortFields []string
+}
+
+// NewRelationship
+//
+// Builds the table mapping for the child side of a one-to-many relationship. Give the child repository
+// an EntityType so its sort keys are prefixed and the parent can tell its children apart
+func NewRelationship(cfg *RelationshipConfig) (Interface, error) {
+	if cfg == nil {
+		return nil, errors.New(requiredRelationshipConfig)
+	}
+
+	if strings.TrimSpace(cfg.MappingName) == "" {
+		return nil, errors.New(requiredRelationshipMappingNameMsg)
+	}
+
+	if cfg.Parent == nil {
+		return nil, errors.New(requiredRelationshipParentMsg)
+	}
+
+	if len(cfg.SortFields) == 0 {
+		return nil, errors.New(requiredRelationshipSortFieldsMsg)
+	}
+
+	return &relationship{
+		mappingName: cfg.MappingName,
+		parent:      cfg.Parent,
+		sortFields:  cfg.SortFields,
+	}, nil
+}
+
+func (m *relationship) BuildPartitionValues(ctx context.Context, values map[string]types.AttributeValue) (string, error) {
+	return m.parent.BuildPartitionValues(ctx, values)
+}
+
+func (m *relationship) BuildSortValues(ctx context.Context, values map[string]types.AttributeValue) (string, error) {
+	var sb = strings.Builder{}
+	for _, field := range m.sortFields {
+		if _, ok := values[field]; !ok {
+			break // only build values in order
+		}
+
+		_, err := fmt.Fprintf(&sb, "%s%s%s%s",
+			setCasing(field),
+			getFieldSeparator(),
+			setCasing(attributeValueToString(values[field])),
+			getFieldSeparator())
+		if err != nil {
+			return "", fmt.Errorf("error returned when formatting sort field. original error: %s", err)
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "", nil
+	}
+
+	return sb.String()[:sb.Len()-1], nil
+}
+
+func (m *relationship) GetName() string {
+	return m.mappingName
+}
+
+func (m *relationship) GetType() entities.MappingType {
+	return entities.MappingType_Relationship
+}
+
+func (m *relationship) GetPartitionFields() []string {
+	return m.parent.GetPartitionFields()
+}
+
+func (m *relationship) GetSortFields() []string {
+	return m.sortFields
+}
+
+func (m *relationship) ToEntity() *entities.Mapping {
+	return &entities.Mapping{
+		Name:            m.mappingName,
+		Type:            entities.MappingType_Relationship,
+		PartitionFields: m.parent.GetPartitionFields(),
+		SortFields:      m.sortFields,
+	}
+}
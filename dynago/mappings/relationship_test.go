@@ -0,0 +1,70 @@
+package mappings
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRelationship(t *testing.T) {
+	parent, _ := NewLookup(&LookupConfig{
+		MappingName: "order",
+		Fields:      []string{"order_id"},
+	})
+
+	t.Run("it requires a config", func(t *testing.T) {
+		_, err := NewRelationship(nil)
+
+		assert.Equal(t, errors.New(requiredRelationshipConfig), err)
+	})
+	t.Run("it requires a parent", func(t *testing.T) {
+		_, err := NewRelationship(&RelationshipConfig{
+			MappingName: "line-items",
+			SortFields:  []string{"line_id"},
+		})
+
+		assert.Equal(t, errors.New(requiredRelationshipParentMsg), err)
+	})
+	t.Run("it requires sort fields", func(t *testing.T) {
+		_, err := NewRelationship(&RelationshipConfig{
+			MappingName: "line-items",
+			Parent:      parent,
+		})
+
+		assert.Equal(t, errors.New(requiredRelationshipSortFieldsMsg), err)
+	})
+	t.Run("it uses the parent's partition", func(t *testing.T) {
+		ctx := context.Background()
+		values := map[string]types.AttributeValue{
+			"order_id": &types.AttributeValueMemberS{Value: "o-1"},
+			"line_id":  &types.AttributeValueMemberN{Value: "3"},
+		}
+
+		fixture, err := NewRelationship(&RelationshipConfig{
+			MappingName: "line-items",
+			Parent:      parent,
+			SortFields:  []string{"line_id"},
+		})
+		assert.Nil(t, err)
+
+		expectedPartition, _ := parent.BuildPartitionValues(ctx, values)
+		actualPartition, err := fixture.BuildPartitionValues(ctx, values)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedPartition, actualPartition)
+
+		actualSort, err := fixture.BuildSortValues(ctx, values)
+		assert.Nil(t, err)
+		assert.Equal(t, "LINE_ID#3", actualSort)
+
+		assert.Equal(t, &entities.Mapping{
+			Name:            "line-items",
+			Type:            entities.MappingType_Relationship,
+			PartitionFields: []string{"order_id"},
+			SortFields:      []string{"line_id"},
+		}, fixture.ToEntity())
+	})
+}
@@ -0,0 +1,553 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+)
+
+const (
+	structTagName     = "dynago"
+	attributeTagName  = "dynamodbav"
+	declarationSep    = ";"
+	declarationOptSep = ","
+
+	tagOptionTable      = "table"
+	tagOptionPartition  = "partition"
+	tagOptionSort       = "sort"
+	tagOptionOrder      = "order"
+	tagOptionProjection = "projection"
+	tagOptionInclude    = "include"
+	includeSep          = "|"
+	tagDeclVersion      = "version"
+
+	requiredStructEntityMsg       = "mappings.FromStruct requires an entity"
+	requiredStructTypeMsg         = "mappings.FromStruct requires a struct or a pointer to a struct"
+	requiredStructTableMappingMsg = "mappings.FromStruct requires exactly one mapping declared with the table option"
+)
+
+// StructMappings
+//
+// The mappings declared by the dynago struct tags of an entity
+type StructMappings struct {
+	Table   Interface
+	Indexes []*Index
+
+	// VersionField is the attribute name of the field tagged dynago:"version", empty when there is none
+	VersionField string
+}
+
+// tagField is a field that was referenced by a declaration
+type tagField struct {
+	name     string
+	position int
+}
+
+// tagMapping collects every declaration found for a single mapping name
+type tagMapping struct {
+	name            string
+	mappingType     entities.MappingType
+	isTable         bool
+	projectionType  entities.ProjectionType
+	includes        []string
+	partitionFields []*tagField
+	sortFields      []*tagField
+}
+
+// FromStruct
+//
+// Builds the table mapping and index mappings declared on an entity's fields using the dynago struct tag.
+// Field names are taken from the dynamodbav tag so they match the attributes written to dynamo.
+//
+//	type Product struct {
+//		ID       string `dynamodbav:"id" dynago:"lookup=by-id,table"`
+//		Category string `dynamodbav:"category" dynago:"query=by-category"`
+//		Brand    string `dynamodbav:"brand" dynago:"query=by-category,sort=2"`
+//		Name     string `dynamodbav:"name" dynago:"query=by-category,sort=1"`
+//	}
+//
+// Each declaration is separated by a semicolon and starts with <lookup|query>=<mapping name>, followed by options:
+//   - table: the mapping is used for the table keys, exactly one mapping must declare it
+//   - partition[=N], sort[=N]: query mappings only, the key and position the field is used in. Defaults to partition
+//   - order=N: lookup mappings only, the position of the field in the key
+//   - projection=<all|keys_only|include>: the projection type when the mapping is used on an index
+//   - include=<attr|attr>: the non key attributes projected onto the index, sets the projection to include
+//
+// Positions default to the order the fields are declared in.
+//
+// A field tagged with the version declaration, dynago:"version", holds the optimistic locking version of the entity.
+func FromStruct(entity interface{}) (*StructMappings, error) {
+	if entity == nil {
+		return nil, errors.New(requiredStructEntityMsg)
+	}
+
+	entityType := reflect.TypeOf(entity)
+	for entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+
+	if entityType.Kind() != reflect.Struct {
+		return nil, errors.New(requiredStructTypeMsg)
+	}
+
+	var fields []*TagField
+
+	err := walkStructFields(entityType, func(field reflect.StructField, name string) error {
+		tag, ok := field.Tag.Lookup(structTagName)
+		if !ok || strings.TrimSpace(tag) == "" {
+			return nil
+		}
+
+		if !isSupportedKind(field.Type) {
+			return fmt.Errorf("field '%s' has unsupported type %s for a dynago mapping", name, field.Type)
+		}
+
+		fields = append(fields, &TagField{Name: name, Tag: tag})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versionField, err := VersionField(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := FromTags(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	out.VersionField = versionField
+
+	return out, nil
+}
+
+// TagField
+//
+// The attribute name and dynago tag of a struct field, for tools that read entities from source
+type TagField struct {
+	Name string
+	Tag  string
+}
+
+// FromTags
+//
+// Builds the mappings declared by the dynago tags of the fields, in field order, see FromStruct.
+// Field types are not checked, the caller has to check they can be used in a key
+func FromTags(fields []*TagField) (*StructMappings, error) {
+	tagMappings := make(map[string]*tagMapping)
+	var order []string
+
+	out := &StructMappings{}
+
+	for _, field := range fields {
+		for _, declaration := range strings.Split(field.Tag, declarationSep) {
+			if strings.TrimSpace(declaration) == "" {
+				continue
+			}
+
+			if strings.TrimSpace(declaration) == tagDeclVersion {
+				if out.VersionField != "" {
+					return nil, fmt.Errorf("fields '%s' and '%s' are both tagged as the version", out.VersionField, field.Name)
+				}
+
+				out.VersionField = field.Name
+
+				continue
+			}
+
+			mapping, parseErr := parseDeclaration(tagMappings, field.Name, declaration)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+
+			if _, seen := tagMappings[mapping.name]; !seen {
+				tagMappings[mapping.name] = mapping
+				order = append(order, mapping.name)
+			}
+		}
+	}
+
+	for _, name := range order {
+		mapping, buildErr := tagMappings[name].build()
+		if buildErr != nil {
+			return nil, buildErr
+		}
+
+		if tagMappings[name].isTable {
+			if out.Table != nil {
+				return nil, errors.New(requiredStructTableMappingMsg)
+			}
+
+			out.Table = mapping
+
+			continue
+		}
+
+		index := &Index{
+			ProjectionType:   tagMappings[name].projectionType,
+			NonKeyAttributes: tagMappings[name].includes,
+			Mapping:          mapping,
+		}
+
+		if projErr := index.ValidateProjection(); projErr != nil {
+			return nil, fmt.Errorf("mapping %s: %s", name, projErr)
+		}
+
+		out.Indexes = append(out.Indexes, index)
+	}
+
+	if out.Table == nil {
+		return nil, errors.New(requiredStructTableMappingMsg)
+	}
+
+	return out, nil
+}
+
+// VersionField
+//
+// Returns the attribute name of the integer field tagged dynago:"version", or an empty string when no field is tagged
+func VersionField(entity interface{}) (string, error) {
+	if entity == nil {
+		return "", nil
+	}
+
+	entityType := reflect.TypeOf(entity)
+	for entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+
+	if entityType.Kind() != reflect.Struct {
+		return "", nil
+	}
+
+	out := ""
+	err := walkStructFields(entityType, func(field reflect.StructField, name string) error {
+		found := false
+		for _, declaration := range strings.Split(field.Tag.Get(structTagName), declarationSep) {
+			if strings.TrimSpace(declaration) == tagDeclVersion {
+				found = true
+			}
+		}
+
+		if !found {
+			return nil
+		}
+
+		if !isIntegerKind(field.Type) {
+			return fmt.Errorf("version field '%s' must be an integer, found %s", name, field.Type)
+		}
+
+		if out != "" {
+			return fmt.Errorf("fields '%s' and '%s' are both tagged as the version", out, name)
+		}
+
+		out = name
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
+
+// ValidateEntity
+//
+// Checks that every field referenced by the mappings exists on the entity with a type that can be used in a key.
+// Use it at startup to catch misspelled field names in mappings that were built by hand.
+func ValidateEntity(entity interface{}, mappings ...Interface) error {
+	if entity == nil {
+		return errors.New("mappings.ValidateEntity requires an entity")
+	}
+
+	entityType := reflect.TypeOf(entity)
+	for entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+
+	if entityType.Kind() != reflect.Struct {
+		return errors.New("mappings.ValidateEntity requires a struct or a pointer to a struct")
+	}
+
+	fieldTypes := make(map[string]reflect.Type)
+	_ = walkStructFields(entityType, func(field reflect.StructField, name string) error {
+		fieldTypes[name] = field.Type
+
+		return nil
+	})
+
+	for _, mapping := range mappings {
+		if mapping == nil {
+			continue
+		}
+
+		fields := append(append([]string{}, mapping.GetPartitionFields()...), mapping.GetSortFields()...)
+		for _, field := range fields {
+			fieldType, ok := fieldTypes[field]
+			if !ok {
+				return fmt.Errorf("mapping %s references field '%s' which was not found on %s",
+					mapping.GetName(), field, entityType.Name())
+			}
+
+			if !isSupportedKind(fieldType) {
+				return fmt.Errorf("mapping %s references field '%s' with unsupported type %s",
+					mapping.GetName(), field, fieldType)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseDeclaration(existing map[string]*tagMapping, fieldName, declaration string) (*tagMapping, error) {
+	parts := strings.Split(declaration, declarationOptSep)
+
+	mappingType, mappingName, err := splitOption(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("field '%s' has an invalid dynago declaration '%s': %s", fieldName, declaration, err)
+	}
+
+	if mappingName == "" {
+		return nil, fmt.Errorf("field '%s' has a dynago declaration without a mapping name", fieldName)
+	}
+
+	mapping, ok := existing[mappingName]
+	if !ok {
+		mapping = &tagMapping{
+			name:           mappingName,
+			projectionType: entities.PropjectionTypeAll,
+		}
+
+		switch mappingType {
+		case entities.MappingType_Lookup:
+			mapping.mappingType = entities.MappingType_Lookup
+		case entities.MappingType_Query:
+			mapping.mappingType = entities.MappingType_Query
+		default:
+			return nil, fmt.Errorf("field '%s' declares unknown mapping type '%s'", fieldName, mappingType)
+		}
+	} else if string(mapping.mappingType) != mappingType {
+		return nil, fmt.Errorf("field '%s' declares mapping %s as %s but it was already declared as %s",
+			fieldName, mappingName, mappingType, mapping.mappingType)
+	}
+
+	isSort := false
+	position := 0
+
+	for _, option := range parts[1:] {
+		key, value, optErr := splitOption(option)
+		if optErr != nil {
+			return nil, fmt.Errorf("field '%s' has an invalid option '%s' on mapping %s", fieldName, option, mappingName)
+		}
+
+		switch key {
+		case tagOptionTable:
+			mapping.isTable = true
+		case tagOptionProjection:
+			projectionType, projErr := parseProjectionType(value)
+			if projErr != nil {
+				return nil, fmt.Errorf("field '%s' on mapping %s: %s", fieldName, mappingName, projErr)
+			}
+
+			mapping.projectionType = projectionType
+		case tagOptionInclude:
+			for _, attribute := range strings.Split(value, includeSep) {
+				if attribute = strings.TrimSpace(attribute); attribute != "" {
+					mapping.includes = append(mapping.includes, attribute)
+				}
+			}
+
+			mapping.projectionType = entities.PropjectionTypeInclude
+		case tagOptionPartition, tagOptionSort, tagOptionOrder:
+			if mapping.mappingType == entities.MappingType_Lookup && key != tagOptionOrder {
+				return nil, fmt.Errorf("field '%s' uses option %s which is not supported by lookup mapping %s",
+					fieldName, key, mappingName)
+			}
+
+			if mapping.mappingType == entities.MappingType_Query && key == tagOptionOrder {
+				return nil, fmt.Errorf("field '%s' uses option %s which is not supported by query mapping %s, use partition=N or sort=N",
+					fieldName, key, mappingName)
+			}
+
+			isSort = key == tagOptionSort
+
+			if value != "" {
+				position, err = strconv.Atoi(value)
+				if err != nil || position < 1 {
+					return nil, fmt.Errorf("field '%s' has an invalid position '%s' on mapping %s", fieldName, value, mappingName)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("field '%s' has an unknown option '%s' on mapping %s", fieldName, key, mappingName)
+		}
+	}
+
+	if isSort {
+		if position == 0 {
+			position = len(mapping.sortFields) + 1
+		}
+
+		mapping.sortFields = append(mapping.sortFields, &tagField{name: fieldName, position: position})
+	} else {
+		if position == 0 {
+			position = len(mapping.partitionFields) + 1
+		}
+
+		mapping.partitionFields = append(mapping.partitionFields, &tagField{name: fieldName, position: position})
+	}
+
+	return mapping, nil
+}
+
+func (m *tagMapping) build() (Interface, error) {
+	partitionFields, err := orderFields(m.name, m.partitionFields)
+	if err != nil {
+		return nil, err
+	}
+
+	sortFields, err := orderFields(m.name, m.sortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m.mappingType {
+	case entities.MappingType_Lookup:
+		return NewLookup(&LookupConfig{
+			MappingName: m.name,
+			Fields:      partitionFields,
+		})
+	default:
+		return NewQuery(&QueryConfig{
+			MappingName:     m.name,
+			PartitionFields: partitionFields,
+			SortFields:      sortFields,
+		})
+	}
+}
+
+func orderFields(mappingName string, fields []*tagField) ([]string, error) {
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].position < fields[j].position
+	})
+
+	out := make([]string, len(fields))
+	for idx, field := range fields {
+		if idx > 0 && fields[idx-1].position == field.position {
+			return nil, fmt.Errorf("mapping %s has fields '%s' and '%s' at the same position %d",
+				mappingName, fields[idx-1].name, field.name, field.position)
+		}
+
+		out[idx] = field.name
+	}
+
+	return out, nil
+}
+
+func splitOption(option string) (string, string, error) {
+	option = strings.TrimSpace(option)
+	if option == "" {
+		return "", "", errors.New("empty option")
+	}
+
+	parts := strings.SplitN(option, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], "", nil
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+func parseProjectionType(value string) (entities.ProjectionType, error) {
+	switch strings.ToUpper(value) {
+	case string(entities.PropjectionTypeAll):
+		return entities.PropjectionTypeAll, nil
+	case string(entities.PropjectionTypeKeysOnly):
+		return entities.PropjectionTypeKeysOnly, nil
+	case string(entities.PropjectionTypeInclude):
+		return entities.PropjectionTypeInclude, nil
+	default:
+		return "", fmt.Errorf("unknown projection type '%s'", value)
+	}
+}
+
+// walkStructFields calls fn with every exported field and the attribute name it is marshaled to.
+// Anonymous embedded structs are flattened the same way attributevalue.MarshalMap does.
+func walkStructFields(structType reflect.Type, fn func(field reflect.StructField, name string) error) error {
+	for idx := 0; idx < structType.NumField(); idx++ {
+		field := structType.Field(idx)
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(attributeTagName); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && fieldType.Kind() == reflect.Struct && name == field.Name {
+			if err := walkStructFields(fieldType, fn); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if err := fn(field, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isSupportedKind(fieldType reflect.Type) bool {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isIntegerKind(fieldType reflect.Type) bool {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
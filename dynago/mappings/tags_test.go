@@ -0,0 +1,215 @@
+package mappings
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type taggedBase struct {
+	Tenant string `dynamodbav:"tenant" dynago:"query=by-tenant"`
+}
+
+type taggedProduct struct {
+	taggedBase
+	ID       string  `dynamodbav:"id" dynago:"lookup=by-id,table"`
+	Category string  `dynamodbav:"category" dynago:"query=by-category,projection=keys_only"`
+	Brand    string  `dynamodbav:"brand" dynago:"query=by-category,sort=2"`
+	Name     string  `dynamodbav:"name" dynago:"query=by-category,sort=1;query=by-tenant,sort"`
+	Price    float64 `dynamodbav:"price"`
+	Version  int64   `dynamodbav:"version" dynago:"version"`
+}
+
+func TestFromStruct(t *testing.T) {
+	t.Run("it requires an entity", func(t *testing.T) {
+		_, err := FromStruct(nil)
+
+		assert.Equal(t, errors.New(requiredStructEntityMsg), err)
+	})
+	t.Run("it requires a struct", func(t *testing.T) {
+		_, err := FromStruct("product")
+
+		assert.Equal(t, errors.New(requiredStructTypeMsg), err)
+	})
+	t.Run("it builds the table and index mappings", func(t *testing.T) {
+		actual, err := FromStruct(&taggedProduct{})
+
+		assert.Nil(t, err)
+		assert.NotNil(t, actual)
+
+		assert.Equal(t, &entities.Mapping{
+			Name:            "by-id",
+			Type:            entities.MappingType_Lookup,
+			PartitionFields: []string{"id"},
+			SortFields:      []string{"id"},
+		}, actual.Table.ToEntity())
+
+		assert.Len(t, actual.Indexes, 2)
+
+		assert.Equal(t, entities.PropjectionTypeAll, actual.Indexes[0].ProjectionType)
+		assert.Equal(t, &entities.Mapping{
+			Name:            "by-tenant",
+			Type:            entities.MappingType_Query,
+			PartitionFields: []string{"tenant"},
+			SortFields:      []string{"name"},
+		}, actual.Indexes[0].Mapping.ToEntity())
+
+		assert.Equal(t, entities.PropjectionTypeKeysOnly, actual.Indexes[1].ProjectionType)
+		assert.Equal(t, &entities.Mapping{
+			Name:            "by-category",
+			Type:            entities.MappingType_Query,
+			PartitionFields: []string{"category"},
+			SortFields:      []string{"name", "brand"},
+		}, actual.Indexes[1].Mapping.ToEntity())
+
+		assert.Equal(t, "version", actual.VersionField)
+	})
+	t.Run("it declares the attributes of an include projection", func(t *testing.T) {
+		type entity struct {
+			ID     string `dynamodbav:"id" dynago:"lookup=by-id,table"`
+			Status string `dynamodbav:"status" dynago:"query=by-status,include=name|price"`
+			Name   string `dynamodbav:"name" dynago:"query=by-status,sort"`
+		}
+
+		actual, err := FromStruct(entity{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, entities.PropjectionTypeInclude, actual.Indexes[0].ProjectionType)
+		assert.Equal(t, []string{"name", "price"}, actual.Indexes[0].NonKeyAttributes)
+	})
+	t.Run("it requires the attributes of an include projection", func(t *testing.T) {
+		type entity struct {
+			ID     string `dynamodbav:"id" dynago:"lookup=by-id,table"`
+			Status string `dynamodbav:"status" dynago:"query=by-status,projection=include"`
+			Name   string `dynamodbav:"name" dynago:"query=by-status,sort"`
+		}
+
+		_, err := FromStruct(entity{})
+
+		assert.EqualError(t, err, "mapping by-status: the INCLUDE projection requires NonKeyAttributes")
+	})
+	t.Run("it requires the version field to be an integer", func(t *testing.T) {
+		type entity struct {
+			ID      string `dynamodbav:"id" dynago:"lookup=by-id,table"`
+			Version string `dynamodbav:"version" dynago:"version"`
+		}
+
+		_, err := FromStruct(entity{})
+
+		assert.Equal(t, errors.New("version field 'version' must be an integer, found string"), err)
+	})
+	t.Run("it requires a table mapping", func(t *testing.T) {
+		type entity struct {
+			ID string `dynamodbav:"id" dynago:"lookup=by-id"`
+		}
+
+		_, err := FromStruct(entity{})
+
+		assert.Equal(t, errors.New(requiredStructTableMappingMsg), err)
+	})
+	t.Run("it rejects unsupported field types", func(t *testing.T) {
+		type entity struct {
+			ID    string   `dynamodbav:"id" dynago:"lookup=by-id,table"`
+			Price float64  `dynamodbav:"price" dynago:"query=by-price"`
+			Tags  []string `dynamodbav:"tags"`
+		}
+
+		_, err := FromStruct(entity{})
+
+		assert.Equal(t, errors.New("field 'price' has unsupported type float64 for a dynago mapping"), err)
+	})
+	t.Run("it rejects duplicate positions", func(t *testing.T) {
+		type entity struct {
+			ID       string `dynamodbav:"id" dynago:"lookup=by-id,table"`
+			Category string `dynamodbav:"category" dynago:"query=by-category"`
+			Brand    string `dynamodbav:"brand" dynago:"query=by-category,sort=1"`
+			Name     string `dynamodbav:"name" dynago:"query=by-category,sort=1"`
+		}
+
+		_, err := FromStruct(entity{})
+
+		assert.Equal(t, errors.New("mapping by-category has fields 'brand' and 'name' at the same position 1"), err)
+	})
+	t.Run("it rejects sort options on lookup mappings", func(t *testing.T) {
+		type entity struct {
+			ID string `dynamodbav:"id" dynago:"lookup=by-id,table,sort"`
+		}
+
+		_, err := FromStruct(entity{})
+
+		assert.Equal(t, errors.New("field 'id' uses option sort which is not supported by lookup mapping by-id"), err)
+	})
+	t.Run("it rejects unknown mapping types", func(t *testing.T) {
+		type entity struct {
+			ID string `dynamodbav:"id" dynago:"list=by-id,table"`
+		}
+
+		_, err := FromStruct(entity{})
+
+		assert.Equal(t, errors.New("field 'id' declares unknown mapping type 'list'"), err)
+	})
+}
+
+func TestValidateEntity(t *testing.T) {
+	t.Run("it passes when every field exists", func(t *testing.T) {
+		mapping, _ := NewQuery(&QueryConfig{
+			MappingName:     "by-category",
+			PartitionFields: []string{"category"},
+			SortFields:      []string{"name", "tenant"},
+		})
+
+		assert.Nil(t, ValidateEntity(&taggedProduct{}, mapping))
+	})
+	t.Run("it reports misspelled fields", func(t *testing.T) {
+		mapping, _ := NewLookup(&LookupConfig{
+			MappingName: "by-id",
+			Fields:      []string{"ID"},
+		})
+
+		err := ValidateEntity(&taggedProduct{}, mapping)
+
+		assert.Equal(t, errors.New("mapping by-id references field 'ID' which was not found on taggedProduct"), err)
+	})
+	t.Run("it reports unsupported field types", func(t *testing.T) {
+		mapping, _ := NewLookup(&LookupConfig{
+			MappingName: "by-price",
+			Fields:      []string{"price"},
+		})
+
+		err := ValidateEntity(&taggedProduct{}, mapping)
+
+		assert.Equal(t, errors.New("mapping by-price references field 'price' with unsupported type float64"), err)
+	})
+}
+
+func TestFromTags(t *testing.T) {
+	t.Run("it builds the mappings from the tags of fields read from source", func(t *testing.T) {
+		actual, err := FromTags([]*TagField{
+			{Name: "id", Tag: "lookup=by-id,table"},
+			{Name: "category", Tag: "query=by-category"},
+			{Name: "name", Tag: "query=by-category,sort"},
+			{Name: "version", Tag: "version"},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "by-id", actual.Table.GetName())
+		assert.Equal(t, "version", actual.VersionField)
+		assert.Equal(t, &entities.Mapping{
+			Name:            "by-category",
+			Type:            entities.MappingType_Query,
+			PartitionFields: []string{"category"},
+			SortFields:      []string{"name"},
+		}, actual.Indexes[0].Mapping.ToEntity())
+	})
+	t.Run("it rejects two version fields", func(t *testing.T) {
+		_, err := FromTags([]*TagField{
+			{Name: "id", Tag: "lookup=by-id,table;version"},
+			{Name: "revision", Tag: "version"},
+		})
+
+		assert.Equal(t, errors.New("fields 'id' and 'revision' are both tagged as the version"), err)
+	})
+}
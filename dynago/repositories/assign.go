@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// UnassignedMappingsError
+//
+// Returned by New when there are not enough global secondary indexes for the index mappings
+type UnassignedMappingsError struct {
+	Mappings []string
+}
+
+func (e *UnassignedMappingsError) Error() string {
+	return fmt.Sprintf("repositories: no global secondary index is available for the index mappings %s",
+		strings.Join(e.Mappings, ", "))
+}
+
+// assignIndexes sets the index name of every index mapping and records new assignments on the schema entity.
+// Mappings keep the index they were assigned to before. A mapping with a Name is pinned to that index,
+// the rest are assigned in name order to the available indexes in name order, preferring an index whose
+// projection holds the attributes the mapping declares
+func assignIndexes(schema *entities.Schema, tableDesc *types.TableDescription, indexMappings []*mappings.Index, reservedIndexes map[string]bool) error {
+	descriptions := make(map[string]types.GlobalSecondaryIndexDescription)
+	for _, index := range tableDesc.GlobalSecondaryIndexes {
+		descriptions[aws.ToString(index.IndexName)] = index
+	}
+
+	// GSI name to the mapping it is assigned to
+	used := make(map[string]string)
+	for mappingName, existing := range schema.Indexes {
+		used[existing.Name] = mappingName
+	}
+
+	var unpinned []*mappings.Index
+
+	for _, index := range indexMappings {
+		mappingName := index.Mapping.GetName()
+
+		if existing, ok := schema.Indexes[mappingName]; ok {
+			if index.Name != "" && index.Name != existing.Name {
+				return fmt.Errorf("index mapping %s is pinned to %s but is assigned to %s", mappingName, index.Name, existing.Name)
+			}
+
+			if _, ok := descriptions[existing.Name]; !ok {
+				return fmt.Errorf("index mapping %s is assigned to %s which is not on the table", mappingName, existing.Name)
+			}
+
+			index.Name = existing.Name
+
+			// sort fields may have been appended
+			existing.Mapping = index.Mapping.ToEntity()
+
+			continue
+		}
+
+		if index.Name == "" {
+			unpinned = append(unpinned, index)
+
+			continue
+		}
+
+		if _, ok := descriptions[index.Name]; !ok {
+			return fmt.Errorf("index mapping %s is pinned to %s which is not on the table", mappingName, index.Name)
+		}
+
+		if reservedIndexes[index.Name] {
+			return fmt.Errorf("index mapping %s is pinned to %s which is reserved for edges", mappingName, index.Name)
+		}
+
+		if owner, ok := used[index.Name]; ok {
+			return fmt.Errorf("index mapping %s is pinned to %s which is assigned to %s", mappingName, index.Name, owner)
+		}
+
+		used[index.Name] = mappingName
+		recordIndex(schema, index)
+	}
+
+	var available []string
+	for name, description := range descriptions {
+		if name == "" || reservedIndexes[name] || used[name] != "" || !hasRangeKey(description.KeySchema) {
+			continue
+		}
+
+		available = append(available, name)
+	}
+
+	sort.Strings(available)
+
+	sort.SliceStable(unpinned, func(i, j int) bool {
+		return unpinned[i].Mapping.GetName() < unpinned[j].Mapping.GetName()
+	})
+
+	var unassigned []string
+
+	for _, index := range unpinned {
+		if len(available) == 0 {
+			unassigned = append(unassigned, index.Mapping.GetName())
+
+			continue
+		}
+
+		choice := 0
+		for idx, name := range available {
+			if projectionSatisfies(index, descriptions[name].Projection) {
+				choice = idx
+
+				break
+			}
+		}
+
+		index.Name = available[choice]
+		available = append(available[:choice], available[choice+1:]...)
+
+		recordIndex(schema, index)
+	}
+
+	if len(unassigned) != 0 {
+		return &UnassignedMappingsError{Mappings: unassigned}
+	}
+
+	return nil
+}
+
+func recordIndex(schema *entities.Schema, index *mappings.Index) {
+	schema.Indexes[index.Mapping.GetName()] = &entities.Index{
+		Name:             index.Name,
+		ProjectionType:   index.ProjectionType,
+		NonKeyAttributes: index.NonKeyAttributes,
+		Conditions:       index.Conditions,
+		Mapping:          index.Mapping.ToEntity(),
+	}
+}
+
+func hasRangeKey(keySchema []types.KeySchemaElement) bool {
+	for _, element := range keySchema {
+		if element.KeyType == types.KeyTypeRange {
+			return true
+		}
+	}
+
+	return false
+}
+
+// projectionSatisfies reports whether the projection holds the attributes the index mapping declares
+func projectionSatisfies(index *mappings.Index, projection *types.Projection) bool {
+	report := &ValidationReport{}
+	validateProjection(report, "", index, projection)
+
+	return len(report.Issues) == 0
+}
@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAssignTableDesc(projections map[string]types.ProjectionType, order ...string) *types.TableDescription {
+	tableDesc := newTestTableDesc()
+	tableDesc.GlobalSecondaryIndexes = nil
+
+	for _, name := range order {
+		tableDesc.GlobalSecondaryIndexes = append(tableDesc.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String(name),
+			Projection: &types.Projection{ProjectionType: projections[name]},
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String(name + "pk"),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String(name + "sk"),
+				KeyType:       types.KeyTypeRange,
+			}},
+		})
+	}
+
+	return tableDesc
+}
+
+func newAssignQuery(name string) *mappings.Index {
+	mapping, _ := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     name,
+		PartitionFields: []string{"category"},
+		SortFields:      []string{"name"},
+	})
+
+	return &mappings.Index{Mapping: mapping}
+}
+
+func newAssignRepo(tableDesc *types.TableDescription, schema *entities.Schema, indexMappings ...*mappings.Index) (*repoImpl, error) {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "table",
+		Fields:      []string{"id"},
+	})
+
+	repo, err := New(&Config{
+		Name:          "MyEntity",
+		Client:        &dynamo.Mock{},
+		TableDesc:     tableDesc,
+		SchemaMapping: schema,
+		TableMapping:  tableMapping,
+		IndexMappings: indexMappings,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.(*repoImpl), nil
+}
+
+func TestNew_AssignIndexes(t *testing.T) {
+	all := map[string]types.ProjectionType{
+		"GSI1": types.ProjectionTypeAll,
+		"GSI2": types.ProjectionTypeAll,
+		"GSI3": types.ProjectionTypeAll,
+	}
+
+	t.Run("it assigns mappings in name order to indexes in name order", func(t *testing.T) {
+		byType, byCategory := newAssignQuery("byType"), newAssignQuery("byCategory")
+
+		actual, err := newAssignRepo(newAssignTableDesc(all, "GSI3", "GSI2", "GSI1"), nil, byType, byCategory)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "GSI1", actual.Schema().Indexes["byCategory"].Name)
+		assert.Equal(t, "GSI2", actual.Schema().Indexes["byType"].Name)
+	})
+	t.Run("it leaves the index mappings of the caller unassigned", func(t *testing.T) {
+		byType, byCategory := newAssignQuery("byType"), newAssignQuery("byCategory")
+
+		_, err := newAssignRepo(newAssignTableDesc(all, "GSI1", "GSI2"), nil, byType, byCategory)
+		assert.Nil(t, err)
+
+		actual, err := newAssignRepo(newAssignTableDesc(all, "GSI1", "GSI2"), &entities.Schema{
+			Table: &entities.Mapping{
+				Name:            "table",
+				Type:            entities.MappingType_Lookup,
+				PartitionFields: []string{"id"},
+				SortFields:      []string{"id"},
+			},
+			Indexes: map[string]*entities.Index{
+				"byCategory": {Name: "GSI2", Mapping: byCategory.Mapping.ToEntity()},
+				"byType":     {Name: "GSI1", Mapping: byType.Mapping.ToEntity()},
+			},
+		}, byType, byCategory)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", byCategory.Name)
+		assert.Equal(t, "", byType.Name)
+		assert.Equal(t, "GSI2", actual.Schema().Indexes["byCategory"].Name)
+		assert.Equal(t, "GSI1", actual.Schema().Indexes["byType"].Name)
+	})
+	t.Run("it assigns a pinned mapping to its index", func(t *testing.T) {
+		byType, byCategory := newAssignQuery("byType"), newAssignQuery("byCategory")
+		byCategory.Name = "GSI2"
+
+		actual, err := newAssignRepo(newAssignTableDesc(all, "GSI1", "GSI2"), nil, byType, byCategory)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "GSI2", actual.Schema().Indexes["byCategory"].Name)
+		assert.Equal(t, "GSI1", actual.Schema().Indexes["byType"].Name)
+	})
+	t.Run("it requires a pinned index to exist", func(t *testing.T) {
+		byCategory := newAssignQuery("byCategory")
+		byCategory.Name = "GSI9"
+
+		_, err := newAssignRepo(newAssignTableDesc(all, "GSI1"), nil, byCategory)
+
+		assert.Equal(t, errors.New("index mapping byCategory is pinned to GSI9 which is not on the table"), err)
+	})
+	t.Run("it does not assign an index already used by an existing mapping", func(t *testing.T) {
+		byType, byCategory := newAssignQuery("byType"), newAssignQuery("byCategory")
+
+		schema := &entities.Schema{
+			Table: &entities.Mapping{
+				Name:            "table",
+				Type:            entities.MappingType_Lookup,
+				PartitionFields: []string{"id"},
+				SortFields:      []string{"id"},
+			},
+			Indexes: map[string]*entities.Index{
+				"byCategory": {Name: "GSI1", Mapping: byCategory.Mapping.ToEntity()},
+			},
+		}
+
+		actual, err := newAssignRepo(newAssignTableDesc(all, "GSI1", "GSI2"), schema, byType, byCategory)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "GSI1", actual.Schema().Indexes["byCategory"].Name)
+		assert.Equal(t, "GSI2", actual.Schema().Indexes["byType"].Name)
+	})
+	t.Run("it prefers an index with a projection the mapping needs", func(t *testing.T) {
+		byCategory := newAssignQuery("byCategory")
+		byCategory.ProjectionType = entities.PropjectionTypeAll
+
+		actual, err := newAssignRepo(newAssignTableDesc(map[string]types.ProjectionType{
+			"GSI1": types.ProjectionTypeKeysOnly,
+			"GSI2": types.ProjectionTypeAll,
+		}, "GSI1", "GSI2"), nil, byCategory)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "GSI2", actual.Schema().Indexes["byCategory"].Name)
+	})
+	t.Run("it reports the mappings that could not be assigned", func(t *testing.T) {
+		_, err := newAssignRepo(newAssignTableDesc(all, "GSI1"), nil,
+			newAssignQuery("byType"), newAssignQuery("byCategory"), newAssignQuery("byBrand"))
+
+		assert.Equal(t, &UnassignedMappingsError{Mappings: []string{"byCategory", "byType"}}, err)
+	})
+}
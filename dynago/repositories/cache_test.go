@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/cache"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/stretchr/testify/assert"
+)
+
+func withCache(t *testing.T, client *dynamo.Mock) func(*Config) {
+	cached, err := cache.NewClient(&cache.ClientConfig{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func(cfg *Config) {
+		cfg.Client = cached
+	}
+}
+
+func TestRepoImpl_Get_Cache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it serves a repeated get from the cache until a put", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withCache(t, client))
+
+		client.On("GetItem", ctx, "my-table", dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{Item: hookTestItem("AIR MAX")}, nil).Once()
+		client.On("PutItem", ctx, "my-table", dynamoputitem.NewOptions(dynamoputitem.WithItem(hookTestItem("AIR FORCE")))).
+			Return(&dynamoputitem.Result{}, nil)
+		client.On("GetItem", ctx, "my-table", dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{Item: hookTestItem("AIR FORCE")}, nil).Once()
+
+		for i := 0; i < 2; i++ {
+			entity := &testEntity{}
+			_, err := fixture.Get(ctx, getitem.WithKey(map[string]interface{}{"id": "abc"}), getitem.WithEntity(entity))
+
+			assert.Nil(t, err)
+			assert.Equal(t, "AIR MAX", entity.Name)
+		}
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testEntity{ID: "abc", Category: "shoes", Name: "AIR FORCE"}))
+		assert.Nil(t, err)
+
+		entity := &testEntity{}
+		_, err = fixture.Get(ctx, getitem.WithKey(map[string]interface{}{"id": "abc"}), getitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "AIR FORCE", entity.Name)
+		client.AssertExpectations(t)
+	})
+}
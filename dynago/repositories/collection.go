@@ -0,0 +1,282 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getcollection"
+
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	requiresRelationshipEntityTypeMsg = "repositories.Config.EntityType is required when Relationships are set"
+	requiresEntityPointerMsg          = "Entity must be a pointer to a struct"
+)
+
+// Relationship
+//
+// A one-to-many relationship to a child repository whose table mapping was built with mappings.NewRelationship
+// from this repository's table mapping. Children share the parent's partition so GetCollection loads
+// the parent and its children with a single query
+//
+//	type Order struct {
+//		ID        string      `dynamodbav:"id"`
+//		LineItems []*LineItem `dynamodbav:"-"`
+//	}
+type Relationship struct {
+	// Name is used to load a subset of the relationships
+	Name string
+
+	// EntityType of the child repository
+	EntityType string
+
+	// Field is the Go name of the slice field on the parent struct the children are appended to.
+	// Tag it with `dynamodbav:"-"` so the children are not written with the parent
+	Field string
+}
+
+// GetCollection
+//
+// Loads the parent entity and the children of its relationships, appending each child to the relationship's field
+func (r *repoImpl) GetCollection(ctx context.Context, collectionOptions ...func(*getcollection.Options)) (*getcollection.Result, error) {
+	options := getcollection.NewOptions(collectionOptions...)
+
+	if options.Key == nil {
+		return nil, errors.New(requiresKeyMsg)
+	}
+
+	if options.Entity == nil {
+		return nil, errors.New(requiresEntityMsg)
+	}
+
+	relationships, err := r.selectRelationships(options.Relationships)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := marshalValues(options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionValue, err := r.schemaMapping.Table.BuildPartitionValues(ctx, values)
+	if err != nil {
+		return nil, fmt.Errorf("mapping %s: %s", r.schemaMapping.Table.GetName(), err)
+	}
+
+	keyCondition := expression.Key(r.tableKeys.partition).Equal(expression.Value(partitionValue))
+
+	var childTypes []expression.OperandBuilder
+	for _, relationship := range relationships {
+		childTypes = append(childTypes, expression.Value(relationship.EntityType))
+	}
+
+	typeFilter := expression.Name(EntityTypeAttribute).In(expression.Value(r.entityType), childTypes...)
+	filter := r.notDeletedCondition(&typeFilter, false)
+
+	var items []map[string]types.AttributeValue
+	var cursor map[string]types.AttributeValue
+
+	for {
+		dynamoOptions := []dynamoquery.OptionFunc{
+			dynamoquery.WithKeyConditionBuilder(&keyCondition),
+			dynamoquery.WithFilterConditionBuilder(filter),
+		}
+
+		if options.ConsistentRead {
+			dynamoOptions = append(dynamoOptions, dynamoquery.WithConsistentRead(aws.Bool(true)))
+		}
+
+		if cursor != nil {
+			dynamoOptions = append(dynamoOptions, dynamoquery.WithExclusiveStartKey(cursor))
+		}
+
+		result, err := r.client.Query(ctx, r.tableName, dynamoOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, result.Items...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+
+		cursor = result.LastEvaluatedKey
+	}
+
+	loaded, err := assembleCollection(options.Entity, r.entityType, relationships, items)
+	if err != nil {
+		return nil, err
+	}
+
+	if loaded == nil {
+		return nil, ErrNotFound
+	}
+
+	if err := r.runAfterLoad(ctx, items, loaded); err != nil {
+		return nil, err
+	}
+
+	return &getcollection.Result{Items: items}, nil
+}
+
+// selectRelationships returns the configured relationships matching names, or all of them when names is empty
+func (r *repoImpl) selectRelationships(names []string) ([]*Relationship, error) {
+	if len(r.relationships) == 0 {
+		return nil, fmt.Errorf("repository %s does not have any relationships", r.name)
+	}
+
+	if len(names) == 0 {
+		return r.relationships, nil
+	}
+
+	out := make([]*Relationship, 0, len(names))
+	for _, name := range names {
+		found := false
+
+		for _, relationship := range r.relationships {
+			if relationship.Name == name {
+				out = append(out, relationship)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("relationship %s was not found on repository %s", name, r.name)
+		}
+	}
+
+	return out, nil
+}
+
+func validateRelationships(entityType string, relationships []*Relationship) error {
+	if len(relationships) == 0 {
+		return nil
+	}
+
+	if entityType == "" {
+		return errors.New(requiresRelationshipEntityTypeMsg)
+	}
+
+	names := make(map[string]bool)
+	for _, relationship := range relationships {
+		if relationship == nil || relationship.Name == "" || relationship.EntityType == "" || relationship.Field == "" {
+			return errors.New("repositories.Config.Relationships require a Name, EntityType and Field")
+		}
+
+		if names[relationship.Name] {
+			return fmt.Errorf("relationship %s is declared more than once", relationship.Name)
+		}
+
+		if relationship.EntityType == entityType {
+			return fmt.Errorf("relationship %s can not use the parent entity type %s", relationship.Name, entityType)
+		}
+
+		names[relationship.Name] = true
+	}
+
+	return nil
+}
+
+// assembleCollection unmarshals the parent item into entity and appends each child item to its relationship field.
+// It returns the entity each item was loaded into by the index of the item, nil when the parent item was not found
+func assembleCollection(entity interface{}, parentType string, relationships []*Relationship, items []map[string]types.AttributeValue) (func(idx int) interface{}, error) {
+	entityValue := reflect.ValueOf(entity)
+	if entityValue.Kind() != reflect.Ptr || entityValue.Elem().Kind() != reflect.Struct {
+		return nil, errors.New(requiresEntityPointerMsg)
+	}
+
+	fields := make(map[string]reflect.Value)
+	for _, relationship := range relationships {
+		field := entityValue.Elem().FieldByName(relationship.Field)
+		if !field.IsValid() || field.Kind() != reflect.Slice || !field.CanSet() {
+			return nil, fmt.Errorf("relationship %s requires %s to have an exported slice field %s",
+				relationship.Name, entityValue.Elem().Type().Name(), relationship.Field)
+		}
+
+		field.Set(reflect.Zero(field.Type()))
+		fields[relationship.EntityType] = field
+	}
+
+	type position struct {
+		field reflect.Value
+		idx   int
+	}
+
+	// children are resolved once every item was appended, appending may move the elements
+	children := make(map[int]position)
+	parent := -1
+
+	for idx, item := range items {
+		entityType, ok := item[EntityTypeAttribute].(*types.AttributeValueMemberS)
+		if !ok {
+			continue
+		}
+
+		if entityType.Value == parentType {
+			if err := attributevalue.UnmarshalMap(item, entity); err != nil {
+				return nil, err
+			}
+
+			parent = idx
+
+			continue
+		}
+
+		field, ok := fields[entityType.Value]
+		if !ok {
+			continue
+		}
+
+		elemType := field.Type().Elem()
+		isPtr := elemType.Kind() == reflect.Ptr
+		if isPtr {
+			elemType = elemType.Elem()
+		}
+
+		child := reflect.New(elemType)
+		if err := attributevalue.UnmarshalMap(item, child.Interface()); err != nil {
+			return nil, fmt.Errorf("entity type %s: %s", entityType.Value, err)
+		}
+
+		if isPtr {
+			field.Set(reflect.Append(field, child))
+		} else {
+			field.Set(reflect.Append(field, child.Elem()))
+		}
+
+		children[idx] = position{field: field, idx: field.Len() - 1}
+	}
+
+	if parent == -1 {
+		return nil, nil
+	}
+
+	return func(idx int) interface{} {
+		if idx == parent {
+			return entity
+		}
+
+		child, ok := children[idx]
+		if !ok {
+			return nil
+		}
+
+		element := child.field.Index(child.idx)
+		if element.Kind() == reflect.Ptr {
+			return element.Interface()
+		}
+
+		return element.Addr().Interface()
+	}, nil
+}
@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getcollection"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testLineItem struct {
+	OrderID string `dynamodbav:"order_id"`
+	LineID  int    `dynamodbav:"line_id"`
+}
+
+type testNote struct {
+	Text string `dynamodbav:"text"`
+}
+
+type testOrder struct {
+	OrderID   string          `dynamodbav:"order_id"`
+	Status    string          `dynamodbav:"status"`
+	LineItems []*testLineItem `dynamodbav:"-"`
+	Notes     []testNote      `dynamodbav:"-"`
+}
+
+func newTestOrderRepo(t *testing.T, client *dynamo.Mock) *repoImpl {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "order",
+		Fields:      []string{"order_id"},
+	})
+
+	repo, err := New(&Config{
+		Name:       "Orders",
+		Client:     client,
+		EntityType: "order",
+		TableDesc: &types.TableDescription{
+			TableName: aws.String("my-table"),
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String("pk"),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String("sk"),
+				KeyType:       types.KeyTypeRange,
+			}},
+		},
+		TableMapping: tableMapping,
+		Relationships: []*Relationship{{
+			Name:       "line-items",
+			EntityType: "line_item",
+			Field:      "LineItems",
+		}, {
+			Name:       "notes",
+			EntityType: "note",
+			Field:      "Notes",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repo.(*repoImpl)
+}
+
+func TestNew_Relationships(t *testing.T) {
+	t.Run("it requires an entity type", func(t *testing.T) {
+		tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+			MappingName: "order",
+			Fields:      []string{"order_id"},
+		})
+
+		_, err := New(&Config{
+			Name:         "Orders",
+			Client:       &dynamo.Mock{},
+			TableDesc:    newTestTableDesc(),
+			TableMapping: tableMapping,
+			Relationships: []*Relationship{{
+				Name:       "line-items",
+				EntityType: "line_item",
+				Field:      "LineItems",
+			}},
+		})
+
+		assert.Equal(t, errors.New(requiresRelationshipEntityTypeMsg), err)
+	})
+}
+
+func TestRepoImpl_GetCollection(t *testing.T) {
+	ctx := context.Background()
+	keyCondition := expression.Key("pk").Equal(expression.Value("ORDER_ID#O-1"))
+
+	parentItem := map[string]types.AttributeValue{
+		EntityTypeAttribute: &types.AttributeValueMemberS{Value: "order"},
+		"order_id":          &types.AttributeValueMemberS{Value: "o-1"},
+		"status":            &types.AttributeValueMemberS{Value: "open"},
+	}
+
+	lineItem := func(lineID string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			EntityTypeAttribute: &types.AttributeValueMemberS{Value: "line_item"},
+			"order_id":          &types.AttributeValueMemberS{Value: "o-1"},
+			"line_id":           &types.AttributeValueMemberN{Value: lineID},
+		}
+	}
+
+	t.Run("it assembles the parent and every relationship across pages", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestOrderRepo(t, client)
+
+		filter := expression.Name(EntityTypeAttribute).In(expression.Value("order"),
+			expression.Value("line_item"), expression.Value("note"))
+		cursor := map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: "ORDER_ID#O-1"},
+			"sk": &types.AttributeValueMemberS{Value: "LINE_ITEM#LINE_ID#1"},
+		}
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithFilterConditionBuilder(&filter))).
+			Return(&dynamoquery.Result{
+				Items:            []map[string]types.AttributeValue{lineItem("1")},
+				LastEvaluatedKey: cursor,
+			}, nil)
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithFilterConditionBuilder(&filter),
+				dynamoquery.WithExclusiveStartKey(cursor))).
+			Return(&dynamoquery.Result{
+				Items: []map[string]types.AttributeValue{lineItem("2"), {
+					EntityTypeAttribute: &types.AttributeValueMemberS{Value: "note"},
+					"text":              &types.AttributeValueMemberS{Value: "leave at door"},
+				}, parentItem},
+			}, nil)
+
+		fixtureRepo, _ := NewRepository[*testOrder](fixture)
+
+		actual, err := fixtureRepo.GetCollection(ctx, Key{"order_id": "o-1"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &testOrder{
+			OrderID: "o-1",
+			Status:  "open",
+			LineItems: []*testLineItem{
+				{OrderID: "o-1", LineID: 1},
+				{OrderID: "o-1", LineID: 2},
+			},
+			Notes: []testNote{{Text: "leave at door"}},
+		}, actual)
+		client.AssertExpectations(t)
+	})
+	t.Run("it filters to the requested relationships", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestOrderRepo(t, client)
+
+		filter := expression.Name(EntityTypeAttribute).In(expression.Value("order"), expression.Value("note"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithFilterConditionBuilder(&filter))).
+			Return(&dynamoquery.Result{
+				Items: []map[string]types.AttributeValue{parentItem},
+			}, nil)
+
+		fixtureRepo, _ := NewRepository[testOrder](fixture)
+
+		actual, err := fixtureRepo.GetCollection(ctx, Key{"order_id": "o-1"}, "notes")
+
+		assert.Nil(t, err)
+		assert.Equal(t, testOrder{OrderID: "o-1", Status: "open"}, actual)
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns ErrNotFound without the parent", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestOrderRepo(t, client)
+
+		client.On("Query", ctx, "my-table", mock.Anything).
+			Return(&dynamoquery.Result{
+				Items: []map[string]types.AttributeValue{lineItem("1")},
+			}, nil)
+
+		_, err := fixture.GetCollection(ctx,
+			getcollection.WithKey(map[string]interface{}{"order_id": "o-1"}),
+			getcollection.WithEntity(&testOrder{}))
+
+		assert.Equal(t, ErrNotFound, err)
+	})
+	t.Run("it returns an error for unknown relationships", func(t *testing.T) {
+		fixture := newTestOrderRepo(t, &dynamo.Mock{})
+
+		_, err := fixture.GetCollection(ctx,
+			getcollection.WithKey(map[string]interface{}{"order_id": "o-1"}),
+			getcollection.WithRelationships("payments"),
+			getcollection.WithEntity(&testOrder{}))
+
+		assert.Equal(t, errors.New("relationship payments was not found on repository Orders"), err)
+	})
+}
@@ -0,0 +1,342 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteedge"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putedge"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/queryedges"
+
+	dynamodeleteitem "github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// EdgeEntityType is written to EntityTypeAttribute on every edge item
+	EdgeEntityType = "_edge"
+
+	edgeNameAttribute     = "_edge_name"
+	edgeFromTypeAttribute = "_from_type"
+	edgeFromAttribute     = "_from"
+	edgeToTypeAttribute   = "_to_type"
+	edgeToAttribute       = "_to"
+
+	edgePrefix    = "edge"
+	edgeSeparator = "#"
+
+	requiresEdgeNameMsg = "an edge Name is required"
+	requiresEdgeFromMsg = "an edge From key is required"
+	requiresEdgeToMsg   = "an edge To key is required"
+)
+
+// EdgeConfig
+//
+// Edges are items linking two entities, stored in the source entity's partition with the inverted keys written
+// to IndexName so they can be traversed in both directions. Repositories on both sides of an edge must use the
+// same IndexName
+type EdgeConfig struct {
+	// IndexName is the global secondary index holding the inverted edge keys.
+	// It is reserved for edges and is not assigned to index mappings
+	IndexName string
+}
+
+func validateEdgeConfig(cfg *EdgeConfig, tableKeys *keyAttributes, indexKeys map[string]*keyAttributes) error {
+	if cfg.IndexName == "" {
+		return errors.New("repositories.Config.Edges.IndexName is required")
+	}
+
+	if tableKeys.sort == "" {
+		return errors.New("repositories.Config.Edges requires a table with a sort key")
+	}
+
+	attrs, ok := indexKeys[cfg.IndexName]
+	if !ok {
+		return fmt.Errorf("edge index %s was not found on the table", cfg.IndexName)
+	}
+
+	if attrs.sort == "" {
+		return fmt.Errorf("edge index %s requires a sort key", cfg.IndexName)
+	}
+
+	return nil
+}
+
+// BuildKey
+//
+// Builds the table key of an entity from the values of the table mapping fields.
+// It is used as the target of an edge written by another repository
+func (r *repoImpl) BuildKey(ctx context.Context, key map[string]interface{}) (*entities.Key, error) {
+	if key == nil {
+		return nil, errors.New(requiresKeyMsg)
+	}
+
+	values, err := marshalValues(key)
+	if err != nil {
+		return nil, err
+	}
+
+	built, err := buildKey(ctx, r.schemaMapping.Table, r.tableKeys, r.sortPrefix, values)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &entities.Key{
+		EntityType: r.entityType,
+		Partition:  attributeValueString(built[r.tableKeys.partition]),
+		Values:     key,
+	}
+
+	if r.tableKeys.sort != "" {
+		out.Sort = attributeValueString(built[r.tableKeys.sort])
+	}
+
+	return out, nil
+}
+
+// notEdgeCondition adds a filter skipping the edge items stored in the entity partitions to condition,
+// when the repository writes edges
+func (r *repoImpl) notEdgeCondition(condition *expression.ConditionBuilder) *expression.ConditionBuilder {
+	if r.edges == nil {
+		return condition
+	}
+
+	notEdge := expression.AttributeNotExists(expression.Name(EntityTypeAttribute)).
+		Or(expression.Name(EntityTypeAttribute).NotEqual(expression.Value(EdgeEntityType)))
+	if condition != nil {
+		notEdge = notEdge.And(*condition)
+	}
+
+	return &notEdge
+}
+
+// PutEdge
+//
+// Writes an edge from an entity of this repository to the target key, along with any denormalized attributes
+func (r *repoImpl) PutEdge(ctx context.Context, edgeOptions ...func(*putedge.Options)) (*putedge.Result, error) {
+	options := putedge.NewOptions(edgeOptions...)
+
+	if err := r.requireEdges(options.Name, options.From, options.To); err != nil {
+		return nil, err
+	}
+
+	from, err := r.BuildKey(ctx, options.From)
+	if err != nil {
+		return nil, err
+	}
+
+	item := make(map[string]types.AttributeValue)
+	if options.Entity != nil {
+		item, err = attributevalue.MarshalMap(options.Entity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fromValues, err := attributevalue.Marshal(from.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	toValues, err := attributevalue.Marshal(options.To.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	item[EntityTypeAttribute] = &types.AttributeValueMemberS{Value: EdgeEntityType}
+	item[edgeNameAttribute] = &types.AttributeValueMemberS{Value: options.Name}
+	item[edgeFromTypeAttribute] = &types.AttributeValueMemberS{Value: from.EntityType}
+	item[edgeFromAttribute] = fromValues
+	item[edgeToTypeAttribute] = &types.AttributeValueMemberS{Value: options.To.EntityType}
+	item[edgeToAttribute] = toValues
+
+	for k, v := range r.buildEdgeKeys(options.Name, from, options.To) {
+		item[k] = v
+	}
+
+	_, err = r.client.PutItem(ctx, r.tableName, dynamoputitem.WithItem(item))
+	if err != nil {
+		return nil, err
+	}
+
+	return &putedge.Result{Item: item}, nil
+}
+
+// DeleteEdge removes the edge between the entity of this repository and the target key
+func (r *repoImpl) DeleteEdge(ctx context.Context, edgeOptions ...func(*deleteedge.Options)) (*deleteedge.Result, error) {
+	options := deleteedge.NewOptions(edgeOptions...)
+
+	if err := r.requireEdges(options.Name, options.From, options.To); err != nil {
+		return nil, err
+	}
+
+	from, err := r.BuildKey(ctx, options.From)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := r.buildEdgeKeys(options.Name, from, options.To)
+
+	_, err = r.client.DeleteItem(ctx, r.tableName, dynamodeleteitem.WithKey(map[string]types.AttributeValue{
+		r.tableKeys.partition: keys[r.tableKeys.partition],
+		r.tableKeys.sort:      keys[r.tableKeys.sort],
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &deleteedge.Result{}, nil
+}
+
+// QueryEdges
+//
+// Traverses the named edges of an entity of this repository. Outgoing edges are read from the entity's partition,
+// incoming edges are read from the inverted index
+func (r *repoImpl) QueryEdges(ctx context.Context, edgeOptions ...func(*queryedges.Options)) (*queryedges.Result, error) {
+	options := queryedges.NewOptions(edgeOptions...)
+
+	if r.edges == nil {
+		return nil, fmt.Errorf("repository %s does not have Edges configured", r.name)
+	}
+
+	if options.Name == "" {
+		return nil, errors.New(requiresEdgeNameMsg)
+	}
+
+	key, err := r.BuildKey(ctx, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyCondition expression.KeyConditionBuilder
+	dynamoOptions := make([]dynamoquery.OptionFunc, 0)
+
+	switch options.Direction {
+	case queryedges.DirectionOutgoing:
+		keyCondition = expression.Key(r.tableKeys.partition).Equal(expression.Value(key.Partition)).
+			And(expression.Key(r.tableKeys.sort).BeginsWith(buildEdgePrefix(options.Name) + key.Sort + edgeSeparator))
+	case queryedges.DirectionIncoming:
+		indexKeys := r.indexKeys[r.edges.IndexName]
+
+		keyCondition = expression.Key(indexKeys.partition).Equal(expression.Value(edgeIdentity(key))).
+			And(expression.Key(indexKeys.sort).BeginsWith(buildEdgePrefix(options.Name)))
+
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithIndexName(r.edges.IndexName))
+	default:
+		return nil, fmt.Errorf("unknown edge direction %s", options.Direction)
+	}
+
+	dynamoOptions = append(dynamoOptions, dynamoquery.WithKeyConditionBuilder(&keyCondition))
+
+	if options.Limit > 0 {
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithLimit(options.Limit))
+	}
+
+	if options.Cursor != nil {
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithExclusiveStartKey(options.Cursor))
+	}
+
+	result, err := r.client.Query(ctx, r.tableName, dynamoOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Entities != nil {
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, options.Entities); err != nil {
+			return nil, err
+		}
+	}
+
+	err = r.runAfterLoad(ctx, result.Items, func(idx int) interface{} {
+		if options.Entities != nil {
+			return sliceElement(options.Entities, idx)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make([]*queryedges.Edge, len(result.Items))
+	for idx, item := range result.Items {
+		edge := &queryedges.Edge{
+			Name:     attributeValueString(item[edgeNameAttribute]),
+			FromType: attributeValueString(item[edgeFromTypeAttribute]),
+			ToType:   attributeValueString(item[edgeToTypeAttribute]),
+			Item:     item,
+		}
+
+		if from, ok := item[edgeFromAttribute].(*types.AttributeValueMemberM); ok {
+			edge.From = from.Value
+		}
+
+		if to, ok := item[edgeToAttribute].(*types.AttributeValueMemberM); ok {
+			edge.To = to.Value
+		}
+
+		edges[idx] = edge
+	}
+
+	return &queryedges.Result{
+		Edges:  edges,
+		Cursor: result.LastEvaluatedKey,
+	}, nil
+}
+
+func (r *repoImpl) requireEdges(name string, from map[string]interface{}, to *entities.Key) error {
+	if r.edges == nil {
+		return fmt.Errorf("repository %s does not have Edges configured", r.name)
+	}
+
+	if name == "" {
+		return errors.New(requiresEdgeNameMsg)
+	}
+
+	if from == nil {
+		return errors.New(requiresEdgeFromMsg)
+	}
+
+	if to == nil {
+		return errors.New(requiresEdgeToMsg)
+	}
+
+	return nil
+}
+
+// buildEdgeKeys places the edge in the source's partition, the inverted index partition is the target's identity
+func (r *repoImpl) buildEdgeKeys(name string, from, to *entities.Key) map[string]types.AttributeValue {
+	prefix := buildEdgePrefix(name)
+	indexKeys := r.indexKeys[r.edges.IndexName]
+
+	return map[string]types.AttributeValue{
+		r.tableKeys.partition: &types.AttributeValueMemberS{Value: from.Partition},
+		r.tableKeys.sort:      &types.AttributeValueMemberS{Value: prefix + from.Sort + edgeSeparator + edgeIdentity(to)},
+		indexKeys.partition:   &types.AttributeValueMemberS{Value: edgeIdentity(to)},
+		indexKeys.sort:        &types.AttributeValueMemberS{Value: prefix + edgeIdentity(from)},
+	}
+}
+
+func buildEdgePrefix(name string) string {
+	return mappings.BuildPrefix(edgePrefix) + mappings.BuildPrefix(name)
+}
+
+// edgeIdentity is unique for an entity across repositories sharing the table
+func edgeIdentity(key *entities.Key) string {
+	return key.Partition + edgeSeparator + key.Sort
+}
+
+func attributeValueString(value types.AttributeValue) string {
+	if value, ok := value.(*types.AttributeValueMemberS); ok {
+		return value.Value
+	}
+
+	return ""
+}
@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamodeleteitem "github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteedge"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putedge"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/queryedges"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testMembership struct {
+	Role string `dynamodbav:"role"`
+}
+
+func newTestEdgeRepo(t *testing.T, client *dynamo.Mock, entityType string) *repoImpl {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "table",
+		Fields:      []string{"id"},
+	})
+
+	repo, err := New(&Config{
+		Name:         entityType,
+		Client:       client,
+		EntityType:   entityType,
+		TableDesc:    newTestTableDesc(),
+		TableMapping: tableMapping,
+		Edges: &EdgeConfig{
+			IndexName: "GSI1pk-GSI1sk-Index",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repo.(*repoImpl)
+}
+
+func TestNew_Edges(t *testing.T) {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "table",
+		Fields:      []string{"id"},
+	})
+
+	t.Run("it requires the edge index to exist", func(t *testing.T) {
+		_, err := New(&Config{
+			Name:         "users",
+			Client:       &dynamo.Mock{},
+			TableDesc:    newTestTableDesc(),
+			TableMapping: tableMapping,
+			Edges: &EdgeConfig{
+				IndexName: "GSI9pk-GSI9sk-Index",
+			},
+		})
+
+		assert.Equal(t, errors.New("edge index GSI9pk-GSI9sk-Index was not found on the table"), err)
+	})
+	t.Run("it reserves the edge index", func(t *testing.T) {
+		queryByCategoryMapping, _ := mappings.NewQuery(&mappings.QueryConfig{
+			MappingName:     "queryByCategory",
+			PartitionFields: []string{"category"},
+			SortFields:      []string{"name"},
+		})
+
+		_, err := New(&Config{
+			Name:          "users",
+			Client:        &dynamo.Mock{},
+			TableDesc:     newTestTableDesc(),
+			TableMapping:  tableMapping,
+			SchemaMapping: &entities.Schema{Table: tableMapping.ToEntity()},
+			IndexMappings: []*mappings.Index{{
+				ProjectionType: entities.PropjectionTypeAll,
+				Mapping:        queryByCategoryMapping,
+			}},
+			Edges: &EdgeConfig{
+				IndexName: "GSI1pk-GSI1sk-Index",
+			},
+		})
+
+		assert.Equal(t, errors.New("requested index count 1 exceeds available indexes of 0"), err)
+	})
+}
+
+func TestRepoImpl_PutEdge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it requires edges to be configured", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{})
+
+		_, err := fixture.PutEdge(ctx, putedge.WithName("member_of"))
+
+		assert.Equal(t, errors.New("repository MyEntity does not have Edges configured"), err)
+	})
+	t.Run("it writes the edge with inverted index keys", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		users := newTestEdgeRepo(t, client, "user")
+		groups := newTestEdgeRepo(t, client, "group")
+
+		group, err := groups.BuildKey(ctx, Key{"id": "g1"})
+		assert.Nil(t, err)
+
+		expectedItem := map[string]types.AttributeValue{
+			"role":                &types.AttributeValueMemberS{Value: "admin"},
+			EntityTypeAttribute:   &types.AttributeValueMemberS{Value: EdgeEntityType},
+			edgeNameAttribute:     &types.AttributeValueMemberS{Value: "member_of"},
+			edgeFromTypeAttribute: &types.AttributeValueMemberS{Value: "user"},
+			edgeFromAttribute: &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: "u1"},
+			}},
+			edgeToTypeAttribute: &types.AttributeValueMemberS{Value: "group"},
+			edgeToAttribute: &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: "g1"},
+			}},
+			"pk":     &types.AttributeValueMemberS{Value: "ID#U1"},
+			"sk":     &types.AttributeValueMemberS{Value: "EDGE#MEMBER_OF#USER#ID#U1#ID#G1#GROUP#ID#G1"},
+			"GSI1pk": &types.AttributeValueMemberS{Value: "ID#G1#GROUP#ID#G1"},
+			"GSI1sk": &types.AttributeValueMemberS{Value: "EDGE#MEMBER_OF#ID#U1#USER#ID#U1"},
+		}
+
+		client.On("PutItem", ctx, "my-table",
+			dynamoputitem.NewOptions(dynamoputitem.WithItem(expectedItem))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		_, err = users.PutEdge(ctx,
+			putedge.WithName("member_of"),
+			putedge.WithFrom(Key{"id": "u1"}),
+			putedge.WithTo(group),
+			putedge.WithEntity(&testMembership{Role: "admin"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestRepoImpl_DeleteEdge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it deletes the edge item", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		users := newTestEdgeRepo(t, client, "user")
+		groups := newTestEdgeRepo(t, client, "group")
+
+		group, _ := groups.BuildKey(ctx, Key{"id": "g1"})
+
+		client.On("DeleteItem", ctx, "my-table",
+			dynamodeleteitem.NewOptions(dynamodeleteitem.WithKey(map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: "ID#U1"},
+				"sk": &types.AttributeValueMemberS{Value: "EDGE#MEMBER_OF#USER#ID#U1#ID#G1#GROUP#ID#G1"},
+			}))).
+			Return(&dynamodeleteitem.Result{}, nil)
+
+		_, err := users.DeleteEdge(ctx,
+			deleteedge.WithName("member_of"),
+			deleteedge.WithFrom(Key{"id": "u1"}),
+			deleteedge.WithTo(group))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestRepoImpl_QueryEdges(t *testing.T) {
+	ctx := context.Background()
+
+	edgeItem := map[string]types.AttributeValue{
+		"role":                &types.AttributeValueMemberS{Value: "admin"},
+		EntityTypeAttribute:   &types.AttributeValueMemberS{Value: EdgeEntityType},
+		edgeNameAttribute:     &types.AttributeValueMemberS{Value: "member_of"},
+		edgeFromTypeAttribute: &types.AttributeValueMemberS{Value: "user"},
+		edgeFromAttribute: &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: "u1"},
+		}},
+		edgeToTypeAttribute: &types.AttributeValueMemberS{Value: "group"},
+		edgeToAttribute: &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: "g1"},
+		}},
+	}
+
+	t.Run("it traverses outgoing edges from the entity's partition", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		users := newTestEdgeRepo(t, client, "user")
+
+		keyCondition := expression.Key("pk").Equal(expression.Value("ID#U1")).
+			And(expression.Key("sk").BeginsWith("EDGE#MEMBER_OF#USER#ID#U1#"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(dynamoquery.WithKeyConditionBuilder(&keyCondition))).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{edgeItem}}, nil)
+
+		var memberships []*testMembership
+		actual, err := users.QueryEdges(ctx,
+			queryedges.WithName("member_of"),
+			queryedges.WithKey(Key{"id": "u1"}),
+			queryedges.WithEntities(&memberships))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []*testMembership{{Role: "admin"}}, memberships)
+		assert.Equal(t, &queryedges.Edge{
+			Name:     "member_of",
+			FromType: "user",
+			From:     map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "u1"}},
+			ToType:   "group",
+			To:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "g1"}},
+			Item:     edgeItem,
+		}, actual.Edges[0])
+		client.AssertExpectations(t)
+	})
+	t.Run("it traverses incoming edges from the inverted index", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		groups := newTestEdgeRepo(t, client, "group")
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("ID#G1#GROUP#ID#G1")).
+			And(expression.Key("GSI1sk").BeginsWith("EDGE#MEMBER_OF#"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"),
+				dynamoquery.WithKeyConditionBuilder(&keyCondition))).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{edgeItem}}, nil)
+
+		actual, err := groups.QueryEdges(ctx,
+			queryedges.WithName("member_of"),
+			queryedges.WithKey(Key{"id": "g1"}),
+			queryedges.WithDirection(queryedges.DirectionIncoming))
+
+		assert.Nil(t, err)
+		assert.Len(t, actual.Edges, 1)
+		assert.Equal(t, "user", actual.Edges[0].FromType)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestRepoImpl_Query_Edges(t *testing.T) {
+	ctx := context.Background()
+
+	notEdge := expression.AttributeNotExists(expression.Name(EntityTypeAttribute)).
+		Or(expression.Name(EntityTypeAttribute).NotEqual(expression.Value(EdgeEntityType)))
+
+	withoutEdges := mock.MatchedBy(func(options *dynamoquery.Options) bool {
+		return assert.ObjectsAreEqual(&notEdge, options.FilterConditionBuilder)
+	})
+
+	t.Run("it skips edge items in polymorphic queries of the entity partition", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		users := newTestEdgeRepo(t, client, "user")
+
+		registry := NewRegistry()
+		_ = registry.Register("user", testEntity{})
+
+		client.On("Query", ctx, "my-table", withoutEdges).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{{
+				EntityTypeAttribute: &types.AttributeValueMemberS{Value: "user"},
+				"id":                &types.AttributeValueMemberS{Value: "u1"},
+			}}}, nil)
+
+		actual, err := users.Query(ctx,
+			query.WithMappingName("table"),
+			query.WithPartitionKey(map[string]interface{}{"id": "u1"}),
+			query.AsPolymorphic(registry))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{&testEntity{ID: "u1"}}, actual.Entities)
+		client.AssertExpectations(t)
+	})
+	t.Run("it skips edge items in queries of a repository without an entity type", func(t *testing.T) {
+		tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+			MappingName: "table",
+			Fields:      []string{"id"},
+		})
+
+		client := &dynamo.Mock{}
+		users, err := New(&Config{
+			Name:         "users",
+			Client:       client,
+			TableDesc:    newTestTableDesc(),
+			TableMapping: tableMapping,
+			Edges:        &EdgeConfig{IndexName: "GSI1pk-GSI1sk-Index"},
+		})
+		assert.Nil(t, err)
+
+		client.On("Query", ctx, "my-table", withoutEdges).Return(&dynamoquery.Result{}, nil)
+
+		_, err = users.Query(ctx,
+			query.WithMappingName("table"),
+			query.WithPartitionKey(map[string]interface{}{"id": "u1"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type HookType string
+
+const (
+	// HookBeforePut runs before an entity is written, an error aborts the write
+	HookBeforePut HookType = "BEFORE_PUT"
+
+	// HookAfterPut runs after an entity was written
+	HookAfterPut HookType = "AFTER_PUT"
+
+	// HookBeforeDelete runs before an item is deleted or purged, an error aborts the delete
+	HookBeforeDelete HookType = "BEFORE_DELETE"
+
+	// HookAfterDelete runs after an item was deleted or purged
+	HookAfterDelete HookType = "AFTER_DELETE"
+
+	// HookAfterLoad runs for every item returned by Get, Query, GetCollection, QueryEdges and Restore
+	HookAfterLoad HookType = "AFTER_LOAD"
+)
+
+// HookFunc
+//
+// A lifecycle hook, hooks of the same type run in the order they were registered
+type HookFunc func(ctx context.Context, event *HookEvent) error
+
+// HookEvent
+//
+// What a hook can see of the call it runs in
+type HookEvent struct {
+	Type HookType
+
+	// Repository is the name of the repository running the hook
+	Repository string
+
+	// Entity is the entity being written or loaded, nil for deletes and for items loaded without an entity.
+	// BeforePut hooks can change it, the item is marshaled again after they ran
+	Entity interface{}
+
+	// Item is the item being written or loaded. On BeforePut it is computed from the entity before the
+	// version, timestamps and expiry are set, on AfterPut it is the item as it was written
+	Item map[string]types.AttributeValue
+
+	// Keys holds the computed key attributes, the table key and the keys of every index the item is written to.
+	// Deletes only have the table key
+	Keys map[string]types.AttributeValue
+
+	// OldImage is the stored item read before a put or delete, nil when there was none. It is read with a
+	// consistent read before the write, use a VersionField to guard against concurrent writes
+	OldImage map[string]types.AttributeValue
+}
+
+// HookError
+//
+// Returned when a hook fails. A failed Before hook aborted the call, when an After hook fails the write
+// already happened
+type HookError struct {
+	Type HookType
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("repositories: %s hook: %s", e.Type, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// Hooks
+//
+// The lifecycle hooks of a repository, pass them as Config.Hooks
+//
+//	hooks := repositories.NewHooks()
+//	hooks.Register(repositories.HookBeforePut, func(ctx context.Context, event *repositories.HookEvent) error {
+//		product := event.Entity.(*Product)
+//		if product.Price < 0 {
+//			return errors.New("a price can not be negative")
+//		}
+//
+//		product.Slug = slugify(product.Name)
+//
+//		return nil
+//	})
+//	hooks.Register(repositories.HookAfterPut, func(ctx context.Context, event *repositories.HookEvent) error {
+//		return publisher.Publish(ctx, "product.saved", event.Item)
+//	})
+type Hooks struct {
+	mu    sync.RWMutex
+	hooks map[HookType][]HookFunc
+}
+
+func NewHooks() *Hooks {
+	return &Hooks{
+		hooks: make(map[HookType][]HookFunc),
+	}
+}
+
+// Register adds a hook to run after the hooks of the same type already registered
+func (h *Hooks) Register(hookType HookType, hook HookFunc) error {
+	switch hookType {
+	case HookBeforePut, HookAfterPut, HookBeforeDelete, HookAfterDelete, HookAfterLoad:
+	default:
+		return fmt.Errorf("Hooks.Register unknown hook type '%s'", hookType)
+	}
+
+	if hook == nil {
+		return errors.New("Hooks.Register requires a hook")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hooks[hookType] = append(h.hooks[hookType], hook)
+
+	return nil
+}
+
+// has reports whether a hook of any of the types is registered, nil hooks have none
+func (h *Hooks) has(hookTypes ...HookType) bool {
+	if h == nil {
+		return false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, hookType := range hookTypes {
+		if len(h.hooks[hookType]) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run calls the hooks of the event type in order and stops at the first error
+func (h *Hooks) run(ctx context.Context, event *HookEvent) error {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.RLock()
+	hooks := h.hooks[event.Type]
+	h.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, event); err != nil {
+			return &HookError{Type: event.Type, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// runAfterLoad calls the AfterLoad hooks for every item, entities is the slice the items were unmarshaled into
+// or the entities decoded by a Registry, nil when there are none
+func (r *repoImpl) runAfterLoad(ctx context.Context, items []map[string]types.AttributeValue, entities func(idx int) interface{}) error {
+	if !r.hooks.has(HookAfterLoad) {
+		return nil
+	}
+
+	for idx, item := range items {
+		event := &HookEvent{Type: HookAfterLoad, Repository: r.name, Item: item}
+		if entities != nil {
+			event.Entity = entities(idx)
+		}
+
+		if err := r.hooks.run(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sliceElement returns a pointer to the element idx of the slice slicePtr points to
+func sliceElement(slicePtr interface{}, idx int) interface{} {
+	slice := reflect.ValueOf(slicePtr)
+	for slice.Kind() == reflect.Ptr {
+		slice = slice.Elem()
+	}
+
+	if slice.Kind() != reflect.Slice || idx >= slice.Len() {
+		return nil
+	}
+
+	element := slice.Index(idx)
+	if element.Kind() == reflect.Ptr || element.Kind() == reflect.Interface {
+		return element.Interface()
+	}
+
+	return element.Addr().Interface()
+}
@@ -0,0 +1,360 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getcollection"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/queryedges"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/restoreitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamodeleteitem "github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func withHooks(hooks *Hooks) func(*Config) {
+	return func(cfg *Config) {
+		cfg.Hooks = hooks
+	}
+}
+
+func hookTestItem(name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":       &types.AttributeValueMemberS{Value: "abc"},
+		"category": &types.AttributeValueMemberS{Value: "shoes"},
+		"name":     &types.AttributeValueMemberS{Value: name},
+		"pk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"sk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"GSI1pk":   &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+		"GSI1sk":   &types.AttributeValueMemberS{Value: "NAME#" + name},
+	}
+}
+
+func TestHooks_Register(t *testing.T) {
+	t.Run("it rejects an unknown hook type", func(t *testing.T) {
+		err := NewHooks().Register("BEFORE_GET", func(ctx context.Context, event *HookEvent) error {
+			return nil
+		})
+
+		assert.Equal(t, errors.New("Hooks.Register unknown hook type 'BEFORE_GET'"), err)
+	})
+	t.Run("it requires a hook", func(t *testing.T) {
+		err := NewHooks().Register(HookAfterPut, nil)
+
+		assert.Equal(t, errors.New("Hooks.Register requires a hook"), err)
+	})
+}
+
+func TestRepoImpl_Put_Hooks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it writes the changes a BeforePut hook makes to the entity", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var before *HookEvent
+		hooks := NewHooks()
+		_ = hooks.Register(HookBeforePut, func(ctx context.Context, event *HookEvent) error {
+			before = event
+			event.Entity.(*testEntity).Name = "AIR MAX"
+
+			return nil
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		expectExisting(client, ctx, hookTestItem("AIR"))
+		client.On("PutItem", ctx, "my-table",
+			dynamoputitem.NewOptions(dynamoputitem.WithItem(hookTestItem("AIR MAX")))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testEntity{ID: "abc", Category: "shoes", Name: "Air Max"}
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, HookBeforePut, before.Type)
+		assert.Equal(t, "MyEntity", before.Repository)
+		assert.Equal(t, entity, before.Entity)
+		assert.Equal(t, map[string]types.AttributeValue{
+			"pk":     &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"sk":     &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"GSI1pk": &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+			"GSI1sk": &types.AttributeValueMemberS{Value: "NAME#AIR MAX"},
+		}, before.Keys)
+		assert.Equal(t, hookTestItem("AIR"), before.OldImage)
+		client.AssertExpectations(t)
+	})
+	t.Run("it does not write when a BeforePut hook fails", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		invalid := errors.New("a name is required")
+
+		hooks := NewHooks()
+		_ = hooks.Register(HookBeforePut, func(ctx context.Context, event *HookEvent) error {
+			return invalid
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		expectExisting(client, ctx, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testEntity{ID: "abc", Category: "shoes"}))
+
+		assert.Equal(t, &HookError{Type: HookBeforePut, Err: invalid}, err)
+		assert.True(t, errors.Is(err, invalid))
+		client.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("it runs AfterPut hooks with the written item", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var after *HookEvent
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterPut, func(ctx context.Context, event *HookEvent) error {
+			after = event
+
+			return nil
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		expectExisting(client, ctx, nil)
+		client.On("PutItem", ctx, "my-table",
+			dynamoputitem.NewOptions(dynamoputitem.WithItem(hookTestItem("AIR MAX")))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testEntity{ID: "abc", Category: "shoes", Name: "AIR MAX"}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, HookAfterPut, after.Type)
+		assert.Equal(t, hookTestItem("AIR MAX"), after.Item)
+		assert.Nil(t, after.OldImage)
+	})
+}
+
+func TestRepoImpl_Delete_Hooks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it does not delete when a BeforeDelete hook fails", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		inUse := errors.New("the product is in use")
+
+		hooks := NewHooks()
+		_ = hooks.Register(HookBeforeDelete, func(ctx context.Context, event *HookEvent) error {
+			return inUse
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		expectExisting(client, ctx, hookTestItem("AIR"))
+
+		_, err := fixture.Delete(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.True(t, errors.Is(err, inUse))
+		client.AssertNotCalled(t, "DeleteItem", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("it runs AfterDelete hooks with the old image", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var after *HookEvent
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterDelete, func(ctx context.Context, event *HookEvent) error {
+			after = event
+
+			return nil
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		expectExisting(client, ctx, hookTestItem("AIR"))
+		client.On("DeleteItem", ctx, "my-table",
+			dynamodeleteitem.NewOptions(dynamodeleteitem.WithKey(key1()))).
+			Return(&dynamodeleteitem.Result{}, nil)
+
+		_, err := fixture.Purge(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &HookEvent{
+			Type:       HookAfterDelete,
+			Repository: "MyEntity",
+			Keys:       key1(),
+			OldImage:   hookTestItem("AIR"),
+		}, after)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestRepoImpl_AfterLoadHooks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it runs AfterLoad hooks with the entity of Get", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterLoad, func(ctx context.Context, event *HookEvent) error {
+			event.Entity.(*testEntity).Name += " (loaded)"
+
+			return nil
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		client.On("GetItem", ctx, "my-table",
+			dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{Item: hookTestItem("AIR")}, nil)
+
+		actual := &testEntity{}
+		_, err := fixture.Get(ctx,
+			getitem.WithKey(map[string]interface{}{"id": "abc"}),
+			getitem.WithEntity(actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "AIR (loaded)", actual.Name)
+	})
+	t.Run("it runs AfterLoad hooks for every entity of Query", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var loaded []interface{}
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterLoad, func(ctx context.Context, event *HookEvent) error {
+			loaded = append(loaded, event.Entity)
+
+			return nil
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("CATEGORY#SHOES"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"))).
+			Return(&dynamoquery.Result{
+				Items: []map[string]types.AttributeValue{hookTestItem("AIR"), hookTestItem("MAX")},
+			}, nil)
+
+		var actual []testEntity
+		_, err := fixture.Query(ctx,
+			query.WithMappingName("queryByCategory"),
+			query.WithPartitionKey(map[string]interface{}{"category": "shoes"}),
+			query.WithEntities(&actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{&actual[0], &actual[1]}, loaded)
+	})
+	t.Run("it runs AfterLoad hooks for the parent and children of GetCollection", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var loaded []interface{}
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterLoad, func(ctx context.Context, event *HookEvent) error {
+			loaded = append(loaded, event.Entity)
+
+			return nil
+		})
+
+		fixture := newTestOrderRepo(t, client)
+		fixture.hooks = hooks
+
+		client.On("Query", ctx, "my-table", mock.Anything).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{{
+				EntityTypeAttribute: &types.AttributeValueMemberS{Value: "line_item"},
+				"line_id":           &types.AttributeValueMemberN{Value: "1"},
+			}, {
+				EntityTypeAttribute: &types.AttributeValueMemberS{Value: "note"},
+				"text":              &types.AttributeValueMemberS{Value: "leave at door"},
+			}, {
+				EntityTypeAttribute: &types.AttributeValueMemberS{Value: "order"},
+				"order_id":          &types.AttributeValueMemberS{Value: "o-1"},
+			}}}, nil)
+
+		actual := &testOrder{}
+		_, err := fixture.GetCollection(ctx,
+			getcollection.WithKey(map[string]interface{}{"order_id": "o-1"}),
+			getcollection.WithEntity(actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{actual.LineItems[0], &actual.Notes[0], actual}, loaded)
+	})
+	t.Run("it runs AfterLoad hooks for the entities of QueryEdges", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var loaded []interface{}
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterLoad, func(ctx context.Context, event *HookEvent) error {
+			loaded = append(loaded, event.Entity)
+
+			return nil
+		})
+
+		fixture := newTestEdgeRepo(t, client, "user")
+		fixture.hooks = hooks
+
+		client.On("Query", ctx, "my-table", mock.Anything).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{{
+				"role": &types.AttributeValueMemberS{Value: "admin"},
+			}}}, nil)
+
+		var actual []*testMembership
+		_, err := fixture.QueryEdges(ctx,
+			queryedges.WithName("member_of"),
+			queryedges.WithKey(Key{"id": "u1"}),
+			queryedges.WithEntities(&actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{actual[0]}, loaded)
+	})
+	t.Run("it runs AfterLoad hooks with the entity of Restore", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterLoad, func(ctx context.Context, event *HookEvent) error {
+			event.Entity.(*testEntity).Name += " (loaded)"
+
+			return nil
+		})
+
+		fixture := newTestRepo(t, client, withSoftDelete(), withHooks(hooks))
+
+		expectExisting(client, ctx, tombstoneTestItem())
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Return(&dynamoputitem.Result{}, nil)
+
+		actual := &testEntity{}
+		_, err := fixture.Restore(ctx,
+			restoreitem.WithKey(map[string]interface{}{"id": "abc"}),
+			restoreitem.WithEntity(actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Air Max (loaded)", actual.Name)
+	})
+	t.Run("it returns the error of an AfterLoad hook", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		failed := errors.New("failed")
+
+		hooks := NewHooks()
+		_ = hooks.Register(HookAfterLoad, func(ctx context.Context, event *HookEvent) error {
+			return failed
+		})
+
+		fixture := newTestRepo(t, client, withHooks(hooks))
+
+		client.On("GetItem", ctx, "my-table",
+			dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{Item: hookTestItem("AIR")}, nil)
+
+		_, err := fixture.Get(ctx, getitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Equal(t, &HookError{Type: HookAfterLoad, Err: failed}, err)
+	})
+}
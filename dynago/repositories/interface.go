@@ -3,9 +3,33 @@ package repositories
 import (
 	"context"
 
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteedge"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getcollection"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putedge"
 	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/queryedges"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/restoreitem"
 )
 
 type Interface interface {
 	Put(context.Context, ...func(*putitem.Options)) (*putitem.Result, error)
+	Get(context.Context, ...func(*getitem.Options)) (*getitem.Result, error)
+	Query(context.Context, ...func(*query.Options)) (*query.Result, error)
+	Delete(context.Context, ...func(*deleteitem.Options)) (*deleteitem.Result, error)
+	GetCollection(context.Context, ...func(*getcollection.Options)) (*getcollection.Result, error)
+	Restore(context.Context, ...func(*restoreitem.Options)) (*restoreitem.Result, error)
+	Purge(context.Context, ...func(*deleteitem.Options)) (*deleteitem.Result, error)
+
+	// Schema returns the schema entity recording the index each index mapping is assigned to. Persist it and pass
+	// it back as Config.SchemaMapping so the assignments stay the same
+	Schema() *entities.Schema
+
+	BuildKey(ctx context.Context, key map[string]interface{}) (*entities.Key, error)
+	PutEdge(context.Context, ...func(*putedge.Options)) (*putedge.Result, error)
+	DeleteEdge(context.Context, ...func(*deleteedge.Options)) (*deleteedge.Result, error)
+	QueryEdges(context.Context, ...func(*queryedges.Options)) (*queryedges.Result, error)
 }
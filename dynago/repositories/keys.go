@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// keyAttributes are the attribute names dynamo uses for the partition and sort key of the table or an index
+type keyAttributes struct {
+	partition string
+	sort      string
+}
+
+func keyAttributesFromSchema(keySchema []types.KeySchemaElement) (*keyAttributes, error) {
+	out := &keyAttributes{}
+
+	for _, element := range keySchema {
+		if element.AttributeName == nil {
+			continue
+		}
+
+		switch element.KeyType {
+		case types.KeyTypeHash:
+			out.partition = *element.AttributeName
+		case types.KeyTypeRange:
+			out.sort = *element.AttributeName
+		}
+	}
+
+	if out.partition == "" {
+		return nil, errors.New("key schema does not have a partition key")
+	}
+
+	return out, nil
+}
+
+// indexKeyAttributes returns the key attribute names for every global secondary index by index name
+func indexKeyAttributes(tableDesc *types.TableDescription) (map[string]*keyAttributes, error) {
+	out := make(map[string]*keyAttributes)
+
+	for _, index := range tableDesc.GlobalSecondaryIndexes {
+		if index.IndexName == nil {
+			continue
+		}
+
+		attrs, err := keyAttributesFromSchema(index.KeySchema)
+		if err != nil {
+			return nil, fmt.Errorf("index %s: %s", *index.IndexName, err)
+		}
+
+		out[*index.IndexName] = attrs
+	}
+
+	return out, nil
+}
+
+// buildKey builds the partition and sort attributes of a mapping from the given values.
+// sortPrefix is placed in front of the sort value, it is empty unless the repository has an entity type
+func buildKey(ctx context.Context, mapping mappings.Interface, attrs *keyAttributes, sortPrefix string, values map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	partitionValue, err := mapping.BuildPartitionValues(ctx, values)
+	if err != nil {
+		return nil, fmt.Errorf("mapping %s: %s", mapping.GetName(), err)
+	}
+
+	out := map[string]types.AttributeValue{
+		attrs.partition: &types.AttributeValueMemberS{Value: partitionValue},
+	}
+
+	if attrs.sort == "" {
+		return out, nil
+	}
+
+	sortValue, err := mapping.BuildSortValues(ctx, values)
+	if err != nil {
+		return nil, fmt.Errorf("mapping %s: %s", mapping.GetName(), err)
+	}
+
+	out[attrs.sort] = &types.AttributeValueMemberS{Value: sortPrefix + sortValue}
+
+	return out, nil
+}
+
+// buildKeyCondition builds the key condition to query a mapping. Sort values are matched as a prefix,
+// when there are no sort values a non empty sortPrefix still restricts the results to that prefix
+func buildKeyCondition(ctx context.Context, mapping mappings.Interface, attrs *keyAttributes, sortPrefix string, partitionValues, sortValues map[string]types.AttributeValue) (*expression.KeyConditionBuilder, error) {
+	partitionValue, err := mapping.BuildPartitionValues(ctx, partitionValues)
+	if err != nil {
+		return nil, fmt.Errorf("mapping %s: %s", mapping.GetName(), err)
+	}
+
+	keyCondition := expression.Key(attrs.partition).Equal(expression.Value(partitionValue))
+
+	if attrs.sort == "" {
+		return &keyCondition, nil
+	}
+
+	sortValue := ""
+	if len(sortValues) != 0 {
+		sortValue, err = mapping.BuildSortValues(ctx, sortValues)
+		if err != nil {
+			return nil, fmt.Errorf("mapping %s: %s", mapping.GetName(), err)
+		}
+	}
+
+	if sortPrefix+sortValue != "" {
+		keyCondition = keyCondition.And(expression.Key(attrs.sort).BeginsWith(sortPrefix + sortValue))
+	}
+
+	return &keyCondition, nil
+}
+
+func marshalValues(input map[string]interface{}) (map[string]types.AttributeValue, error) {
+	if input == nil {
+		return map[string]types.AttributeValue{}, nil
+	}
+
+	return attributevalue.MarshalMap(input)
+}
+
+// rawValue passes an attribute value through expression.Value unchanged,
+// which would otherwise marshal the attribute value struct itself
+type rawValue struct {
+	value types.AttributeValue
+}
+
+func (v rawValue) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return v.value, nil
+}
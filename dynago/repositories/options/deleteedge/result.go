@@ -0,0 +1,4 @@
+package deleteedge
+
+type Result struct {
+}
@@ -0,0 +1,42 @@
+package deleteitem
+
+import (
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+type Options struct {
+	// Key holds the values of the table mapping fields, map[fieldName]value
+	Key                    map[string]interface{}
+	FilterConditionBuilder *expression.ConditionBuilder
+
+	// Events are written to the outbox in the same transaction as the item, see repositories.OutboxConfig
+	Events []*entities.OutboxEvent
+}
+
+func NewOptions(input ...func(*Options)) *Options {
+	out := &Options{}
+	for _, fn := range input {
+		fn(out)
+	}
+
+	return out
+}
+
+func WithKey(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Key = input
+	}
+}
+
+func WithFilterConditionBuilder(input *expression.ConditionBuilder) func(*Options) {
+	return func(args *Options) {
+		args.FilterConditionBuilder = input
+	}
+}
+
+func WithEvents(input ...*entities.OutboxEvent) func(*Options) {
+	return func(args *Options) {
+		args.Events = append(args.Events, input...)
+	}
+}
@@ -0,0 +1,4 @@
+package deleteitem
+
+type Result struct {
+}
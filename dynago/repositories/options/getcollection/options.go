@@ -0,0 +1,47 @@
+package getcollection
+
+type Options struct {
+	// Key holds the values of the parent's table mapping fields, map[fieldName]value
+	Key map[string]interface{}
+
+	// Relationships limits the children loaded to the named relationships, all relationships are loaded when empty
+	Relationships []string
+
+	ConsistentRead bool
+
+	// Entity is a pointer to the parent struct, children are appended to the relationship fields
+	Entity interface{}
+}
+
+func NewOptions(input ...func(*Options)) *Options {
+	out := &Options{}
+	for _, fn := range input {
+		fn(out)
+	}
+
+	return out
+}
+
+func WithKey(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Key = input
+	}
+}
+
+func WithRelationships(input ...string) func(*Options) {
+	return func(args *Options) {
+		args.Relationships = input
+	}
+}
+
+func WithConsistentRead(input bool) func(*Options) {
+	return func(args *Options) {
+		args.ConsistentRead = input
+	}
+}
+
+func WithEntity(input interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Entity = input
+	}
+}
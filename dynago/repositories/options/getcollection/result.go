@@ -0,0 +1,8 @@
+package getcollection
+
+import "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+type Result struct {
+	// Items holds the parent and child items in sort key order
+	Items []map[string]types.AttributeValue
+}
@@ -0,0 +1,47 @@
+package getitem
+
+type Options struct {
+	// Key holds the values of the table mapping fields, map[fieldName]value
+	Key map[string]interface{}
+
+	ConsistentRead bool
+
+	// Entity is unmarshaled with the returned item
+	Entity interface{}
+
+	// IncludeDeleted returns an item soft deleted by a repository with SoftDelete instead of ErrNotFound
+	IncludeDeleted bool
+}
+
+func NewOptions(input ...func(*Options)) *Options {
+	out := &Options{}
+	for _, fn := range input {
+		fn(out)
+	}
+
+	return out
+}
+
+func WithKey(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Key = input
+	}
+}
+
+func WithConsistentRead(input bool) func(*Options) {
+	return func(args *Options) {
+		args.ConsistentRead = input
+	}
+}
+
+func WithEntity(input interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Entity = input
+	}
+}
+
+func WithIncludeDeleted(input bool) func(*Options) {
+	return func(args *Options) {
+		args.IncludeDeleted = input
+	}
+}
@@ -0,0 +1,50 @@
+package putedge
+
+import "github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+type Options struct {
+	// Name of the edge, e.g. member_of
+	Name string
+
+	// From holds the values of this repository's table mapping fields for the source entity
+	From map[string]interface{}
+
+	// To is the target entity's key, built with the BuildKey method of the target's repository
+	To *entities.Key
+
+	// Entity is marshaled onto the edge item as denormalized attributes, optional
+	Entity interface{}
+}
+
+func NewOptions(input ...func(*Options)) *Options {
+	out := &Options{}
+	for _, fn := range input {
+		fn(out)
+	}
+
+	return out
+}
+
+func WithName(input string) func(*Options) {
+	return func(args *Options) {
+		args.Name = input
+	}
+}
+
+func WithFrom(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.From = input
+	}
+}
+
+func WithTo(input *entities.Key) func(*Options) {
+	return func(args *Options) {
+		args.To = input
+	}
+}
+
+func WithEntity(input interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Entity = input
+	}
+}
@@ -0,0 +1,7 @@
+package putedge
+
+import "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+type Result struct {
+	Item map[string]types.AttributeValue
+}
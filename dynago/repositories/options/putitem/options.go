@@ -1,10 +1,16 @@
 package putitem
 
-import "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+import (
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
 
 type Options struct {
-	Entity interface{}
+	Entity                 interface{}
 	FilterConditionBuilder *expression.ConditionBuilder
+
+	// Events are written to the outbox in the same transaction as the item, see repositories.OutboxConfig
+	Events []*entities.OutboxEvent
 }
 
 func NewOptions(input ...func(*Options)) *Options {
@@ -27,3 +33,9 @@ func WithFilterConditionBuilder(input *expression.ConditionBuilder) func(*Option
 		args.FilterConditionBuilder = input
 	}
 }
+
+func WithEvents(input ...*entities.OutboxEvent) func(*Options) {
+	return func(args *Options) {
+		args.Events = append(args.Events, input...)
+	}
+}
@@ -1,4 +1,8 @@
 package putitem
 
+import "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
 type Result struct {
+	// Item is the item written to dynamo including the computed key attributes
+	Item map[string]types.AttributeValue
 }
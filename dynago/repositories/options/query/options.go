@@ -0,0 +1,125 @@
+package query
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Registry decodes an item into the Go type registered for its entity type
+type Registry interface {
+	Decode(item map[string]types.AttributeValue) (interface{}, error)
+}
+
+type Options struct {
+	// MappingName selects the table mapping or one of the index mappings, defaults to the table mapping
+	MappingName string
+
+	// PartitionKey holds the values of the mapping's partition fields, map[fieldName]value
+	PartitionKey map[string]interface{}
+
+	// SortKey holds values for the leading sort fields of the mapping, the built value is used as a begins_with prefix
+	SortKey map[string]interface{}
+
+	FilterConditionBuilder *expression.ConditionBuilder
+	Limit                  int32
+	Cursor                 map[string]types.AttributeValue
+	ScanForward            *bool
+
+	// Entities is a pointer to a slice the returned items are unmarshaled into
+	Entities interface{}
+
+	// Registry decodes each item into Result.Entities and returns every entity type found in the partition
+	Registry Registry
+
+	// IncludeDeleted returns items soft deleted by a repository with SoftDelete
+	IncludeDeleted bool
+
+	// FetchThrough reads the full items from the table when the queried index does not project every attribute
+	FetchThrough bool
+}
+
+func NewOptions(input ...func(*Options)) *Options {
+	out := &Options{}
+	for _, fn := range input {
+		fn(out)
+	}
+
+	return out
+}
+
+func WithMappingName(input string) func(*Options) {
+	return func(args *Options) {
+		args.MappingName = input
+	}
+}
+
+func WithPartitionKey(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.PartitionKey = input
+	}
+}
+
+func WithSortKey(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.SortKey = input
+	}
+}
+
+func WithFilterConditionBuilder(input *expression.ConditionBuilder) func(*Options) {
+	return func(args *Options) {
+		args.FilterConditionBuilder = input
+	}
+}
+
+func WithLimit(input int32) func(*Options) {
+	return func(args *Options) {
+		args.Limit = input
+	}
+}
+
+// WithCursor
+//
+// input is the Cursor returned by a previous page
+func WithCursor(input map[string]types.AttributeValue) func(*Options) {
+	return func(args *Options) {
+		args.Cursor = input
+	}
+}
+
+func WithScanForward(input bool) func(*Options) {
+	return func(args *Options) {
+		args.ScanForward = &input
+	}
+}
+
+func WithEntities(input interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Entities = input
+	}
+}
+
+// AsPolymorphic
+//
+// Returns every entity type stored under the partition, decoding each item with the registry into Result.Entities.
+// A SortKey can not be used since the entity type prefix of the sort key is not applied
+func AsPolymorphic(input Registry) func(*Options) {
+	return func(args *Options) {
+		args.Registry = input
+	}
+}
+
+func WithIncludeDeleted(input bool) func(*Options) {
+	return func(args *Options) {
+		args.IncludeDeleted = input
+	}
+}
+
+// WithFetchThrough
+//
+// Follows a query of a KEYS_ONLY or INCLUDE index with a BatchGetItem on the table so full items are returned,
+// in the order of the query
+func WithFetchThrough(input bool) func(*Options) {
+	return func(args *Options) {
+		args.FetchThrough = input
+	}
+}
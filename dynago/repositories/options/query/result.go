@@ -0,0 +1,16 @@
+package query
+
+import "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+type Result struct {
+	Items []map[string]types.AttributeValue
+
+	// Entities holds the decoded items of a polymorphic query
+	Entities []interface{}
+
+	// Cursor is set when there are more results, pass it to WithCursor to fetch the next page
+	Cursor map[string]types.AttributeValue
+
+	// Projected is true when the items only hold the attributes projected onto the queried index
+	Projected bool
+}
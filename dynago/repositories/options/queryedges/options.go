@@ -0,0 +1,76 @@
+package queryedges
+
+import "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+type Direction string
+
+const (
+	// DirectionOutgoing returns the edges written from the entity
+	DirectionOutgoing Direction = "outgoing"
+
+	// DirectionIncoming returns the edges pointing to the entity, read from the inverted index
+	DirectionIncoming Direction = "incoming"
+)
+
+type Options struct {
+	Name string
+
+	// Key holds the values of this repository's table mapping fields for the entity being traversed from
+	Key map[string]interface{}
+
+	// Direction defaults to DirectionOutgoing
+	Direction Direction
+
+	Limit  int32
+	Cursor map[string]types.AttributeValue
+
+	// Entities is a pointer to a slice the edge items are unmarshaled into, including their denormalized attributes
+	Entities interface{}
+}
+
+func NewOptions(input ...func(*Options)) *Options {
+	out := &Options{
+		Direction: DirectionOutgoing,
+	}
+	for _, fn := range input {
+		fn(out)
+	}
+
+	return out
+}
+
+func WithName(input string) func(*Options) {
+	return func(args *Options) {
+		args.Name = input
+	}
+}
+
+func WithKey(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Key = input
+	}
+}
+
+func WithDirection(input Direction) func(*Options) {
+	return func(args *Options) {
+		args.Direction = input
+	}
+}
+
+func WithLimit(input int32) func(*Options) {
+	return func(args *Options) {
+		args.Limit = input
+	}
+}
+
+func WithCursor(input map[string]types.AttributeValue) func(*Options) {
+	return func(args *Options) {
+		args.Cursor = input
+	}
+}
+
+func WithEntities(input interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Entities = input
+	}
+}
@@ -0,0 +1,25 @@
+package queryedges
+
+import "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+type Edge struct {
+	Name string
+
+	FromType string
+	// From holds the table mapping values of the source entity
+	From map[string]types.AttributeValue
+
+	ToType string
+	// To holds the table mapping values of the target entity
+	To map[string]types.AttributeValue
+
+	// Item is the edge item including its denormalized attributes
+	Item map[string]types.AttributeValue
+}
+
+type Result struct {
+	Edges []*Edge
+
+	// Cursor is set when there are more results, pass it to WithCursor to fetch the next page
+	Cursor map[string]types.AttributeValue
+}
@@ -0,0 +1,30 @@
+package restoreitem
+
+type Options struct {
+	// Key holds the values of the table mapping fields, map[fieldName]value
+	Key map[string]interface{}
+
+	// Entity is unmarshaled with the deleted item and written again, it is required
+	Entity interface{}
+}
+
+func NewOptions(input ...func(*Options)) *Options {
+	out := &Options{}
+	for _, fn := range input {
+		fn(out)
+	}
+
+	return out
+}
+
+func WithKey(input map[string]interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Key = input
+	}
+}
+
+func WithEntity(input interface{}) func(*Options) {
+	return func(args *Options) {
+		args.Entity = input
+	}
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// OutboxEntityType is written to EntityTypeAttribute on every outbox item
+	OutboxEntityType = "_outbox"
+
+	outboxIDAttribute         = "_event_id"
+	outboxEventTypeAttribute  = "_event_type"
+	outboxPayloadAttribute    = "_payload"
+	outboxSourceTypeAttribute = "_source_type"
+	outboxSourcePKAttribute   = "_source_pk"
+	outboxSourceSKAttribute   = "_source_sk"
+	outboxCreatedAtAttribute  = "_created_at"
+	outboxSentAtAttribute     = "_sent_at"
+
+	outboxPrefix = "outbox"
+
+	// outboxPending is the partition of the outbox index holding the events that were not sent
+	outboxPending = "PENDING"
+
+	// outboxTimeFormat keeps the sort keys of the outbox index in the order the events were written
+	outboxTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+	requiresEventTypeMsg = "an outbox event Type is required"
+)
+
+// OutboxConfig
+//
+// The outbox stores the events attached to a Put or Delete as items written in the same transaction as the entity,
+// so an event exists if and only if the change it describes was written. Pending events are written to IndexName,
+// where an OutboxRelay reads them
+type OutboxConfig struct {
+	// IndexName is the global secondary index listing the pending events.
+	// It is reserved for the outbox and is not assigned to index mappings
+	IndexName string
+}
+
+// DuplicateEventError
+//
+// Returned by Put and Delete when an event with the same ID was already written to the outbox
+type DuplicateEventError struct {
+	ID string
+}
+
+func (e *DuplicateEventError) Error() string {
+	return fmt.Sprintf("repositories: the outbox event %s was already written", e.ID)
+}
+
+func validateOutboxConfig(cfg *OutboxConfig, tableKeys *keyAttributes, indexKeys map[string]*keyAttributes) error {
+	if cfg.IndexName == "" {
+		return errors.New("repositories.Config.Outbox.IndexName is required")
+	}
+
+	attrs, ok := indexKeys[cfg.IndexName]
+	if !ok {
+		return fmt.Errorf("outbox index %s was not found on the table", cfg.IndexName)
+	}
+
+	if attrs.sort == "" {
+		return fmt.Errorf("outbox index %s requires a sort key", cfg.IndexName)
+	}
+
+	return nil
+}
+
+// buildOutboxItems builds the outbox item of every event, source is the table key of the item the events describe
+func (r *repoImpl) buildOutboxItems(source map[string]types.AttributeValue, events []*entities.OutboxEvent) ([]map[string]types.AttributeValue, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	if r.outbox == nil {
+		return nil, fmt.Errorf("repository %s does not have an Outbox configured", r.name)
+	}
+
+	sourceType := r.entityType
+	if sourceType == "" {
+		sourceType = r.name
+	}
+
+	createdAt := r.clock.Now().UTC()
+	indexAttrs := r.indexKeys[r.outbox.IndexName]
+
+	out := make([]map[string]types.AttributeValue, 0, len(events))
+
+	for idx, event := range events {
+		if event == nil || event.Type == "" {
+			return nil, fmt.Errorf("Events[%d]: %s", idx, requiresEventTypeMsg)
+		}
+
+		id := event.ID
+		if id == "" {
+			var err error
+
+			id, err = newEventID()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		payload, err := attributevalue.Marshal(event.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("Events[%d]: %s", idx, err)
+		}
+
+		item := map[string]types.AttributeValue{
+			EntityTypeAttribute:       &types.AttributeValueMemberS{Value: OutboxEntityType},
+			outboxIDAttribute:         &types.AttributeValueMemberS{Value: id},
+			outboxEventTypeAttribute:  &types.AttributeValueMemberS{Value: event.Type},
+			outboxPayloadAttribute:    payload,
+			outboxSourceTypeAttribute: &types.AttributeValueMemberS{Value: sourceType},
+			outboxSourcePKAttribute:   source[r.tableKeys.partition],
+			outboxCreatedAtAttribute:  &types.AttributeValueMemberS{Value: createdAt.Format(time.RFC3339Nano)},
+		}
+
+		if r.tableKeys.sort != "" {
+			item[outboxSourceSKAttribute] = source[r.tableKeys.sort]
+		}
+
+		for k, v := range outboxKey(r.tableKeys, id) {
+			item[k] = v
+		}
+
+		item[indexAttrs.partition] = &types.AttributeValueMemberS{Value: mappings.BuildPrefix(outboxPrefix) + outboxPending}
+		item[indexAttrs.sort] = &types.AttributeValueMemberS{Value: mappings.BuildPrefix(createdAt.Format(outboxTimeFormat)) + id}
+
+		out = append(out, item)
+	}
+
+	return out, nil
+}
+
+// outboxKey builds the table key of an outbox item, OUTBOX#ID
+func outboxKey(tableKeys *keyAttributes, id string) map[string]types.AttributeValue {
+	key := mappings.BuildPrefix(outboxPrefix) + id
+
+	out := map[string]types.AttributeValue{
+		tableKeys.partition: &types.AttributeValueMemberS{Value: key},
+	}
+
+	if tableKeys.sort != "" {
+		out[tableKeys.sort] = &types.AttributeValueMemberS{Value: key}
+	}
+
+	return out
+}
+
+func newEventID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
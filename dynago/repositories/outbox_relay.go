@@ -0,0 +1,328 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamodeleteitem "github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	dynamoupdateitem "github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	defaultOutboxBatchSize    = 25
+	defaultOutboxPollInterval = time.Second
+
+	requiresRelayConfigMsg    = "repositories.NewOutboxRelay requires an OutboxRelayConfig"
+	requiresRelayPublisherMsg = "repositories.OutboxRelayConfig.Publisher is required"
+	requiresRelayOutboxMsg    = "repositories.OutboxRelayConfig.Outbox is required"
+)
+
+// OutboxMessage
+//
+// An event read from the outbox
+type OutboxMessage struct {
+	ID        string
+	Type      string
+	CreatedAt time.Time
+
+	// Source is the key of the item the event was written with, its Values are not set
+	Source *entities.Key
+
+	// Payload is the event payload as it was marshaled, see UnmarshalPayload
+	Payload types.AttributeValue
+}
+
+// UnmarshalPayload unmarshals the payload into out
+func (m *OutboxMessage) UnmarshalPayload(out interface{}) error {
+	return attributevalue.Unmarshal(m.Payload, out)
+}
+
+// Publisher
+//
+// Delivers outbox messages, to a queue or a topic. A message can be published more than once so consumers should
+// skip the ids they already handled
+type Publisher interface {
+	Publish(ctx context.Context, message *OutboxMessage) error
+}
+
+// PublisherFunc adapts a function to a Publisher
+type PublisherFunc func(ctx context.Context, message *OutboxMessage) error
+
+func (f PublisherFunc) Publish(ctx context.Context, message *OutboxMessage) error {
+	return f(ctx, message)
+}
+
+// OutboxRelayConfig
+//
+// The outbox to read, the same table and OutboxConfig as the repositories writing the events
+type OutboxRelayConfig struct {
+	Client    dynamo.Interface
+	TableDesc *types.TableDescription
+	Outbox    *OutboxConfig
+	Publisher Publisher
+
+	// BatchSize is the number of pending events read at a time, defaults to 25
+	BatchSize int32
+
+	// PollInterval is the wait before reading the outbox again once it was emptied, defaults to 1s
+	PollInterval time.Duration
+
+	// DeleteSent deletes the events once they are published instead of marking them sent
+	DeleteSent bool
+
+	// Clock supplies the time events are marked sent at, defaults to the system clock
+	Clock Clock
+}
+
+// OutboxRelay
+//
+// Publishes the pending events of an outbox in the order they were written and marks them sent. An event is only
+// marked sent after it was published, so an event is published at least once
+type OutboxRelay struct {
+	client       dynamo.Interface
+	tableName    string
+	tableKeys    *keyAttributes
+	indexName    string
+	indexKeys    *keyAttributes
+	publisher    Publisher
+	batchSize    int32
+	pollInterval time.Duration
+	deleteSent   bool
+	clock        Clock
+}
+
+func NewOutboxRelay(cfg *OutboxRelayConfig) (*OutboxRelay, error) {
+	if cfg == nil {
+		return nil, errors.New(requiresRelayConfigMsg)
+	}
+
+	if cfg.Client == nil {
+		return nil, errors.New(requiresConfigClientMsg)
+	}
+
+	if cfg.TableDesc == nil {
+		return nil, errors.New(requiresConfigTableDescMsg)
+	}
+
+	if cfg.TableDesc.TableName == nil {
+		return nil, errors.New(requiresTableNameMsg)
+	}
+
+	if cfg.Outbox == nil {
+		return nil, errors.New(requiresRelayOutboxMsg)
+	}
+
+	if cfg.Publisher == nil {
+		return nil, errors.New(requiresRelayPublisherMsg)
+	}
+
+	tableKeys, err := keyAttributesFromSchema(cfg.TableDesc.KeySchema)
+	if err != nil {
+		return nil, fmt.Errorf("table %s: %s", *cfg.TableDesc.TableName, err)
+	}
+
+	indexKeys, err := indexKeyAttributes(cfg.TableDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateOutboxConfig(cfg.Outbox, tableKeys, indexKeys); err != nil {
+		return nil, err
+	}
+
+	relay := &OutboxRelay{
+		client:       cfg.Client,
+		tableName:    *cfg.TableDesc.TableName,
+		tableKeys:    tableKeys,
+		indexName:    cfg.Outbox.IndexName,
+		indexKeys:    indexKeys[cfg.Outbox.IndexName],
+		publisher:    cfg.Publisher,
+		batchSize:    cfg.BatchSize,
+		pollInterval: cfg.PollInterval,
+		deleteSent:   cfg.DeleteSent,
+		clock:        cfg.Clock,
+	}
+
+	if relay.batchSize <= 0 {
+		relay.batchSize = defaultOutboxBatchSize
+	}
+
+	if relay.pollInterval <= 0 {
+		relay.pollInterval = defaultOutboxPollInterval
+	}
+
+	if relay.clock == nil {
+		relay.clock = systemClock{}
+	}
+
+	return relay, nil
+}
+
+// Run
+//
+// Relays pending events until ctx is cancelled or publishing fails. Cancelling ctx returns nil
+func (o *OutboxRelay) Run(ctx context.Context) error {
+	for {
+		sent, err := o.RelayPending(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if sent == int(o.batchSize) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(o.pollInterval):
+		}
+	}
+}
+
+// RelayPending
+//
+// Publishes one batch of pending events and returns how many were sent. It stops at the first event that fails to
+// publish, that event and the ones after it are left pending
+func (o *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	pending := expression.Key(o.indexKeys.partition).Equal(expression.Value(mappings.BuildPrefix(outboxPrefix) + outboxPending))
+
+	result, err := o.client.Query(ctx, o.tableName,
+		dynamoquery.WithKeyConditionBuilder(&pending),
+		dynamoquery.WithIndexName(o.indexName),
+		dynamoquery.WithLimit(o.batchSize))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+
+	for _, indexItem := range result.Items {
+		// the index is eventually consistent, the item is read again so events already sent are skipped
+		item, err := o.getPending(ctx, o.tableKeyOf(indexItem))
+		if err != nil {
+			return sent, err
+		}
+
+		if item == nil {
+			continue
+		}
+
+		message, err := o.toMessage(item)
+		if err != nil {
+			return sent, err
+		}
+
+		if err := o.publisher.Publish(ctx, message); err != nil {
+			return sent, fmt.Errorf("outbox event %s: %w", message.ID, err)
+		}
+
+		if err := o.markSent(ctx, o.tableKeyOf(item)); err != nil {
+			return sent, fmt.Errorf("outbox event %s: %w", message.ID, err)
+		}
+
+		sent++
+	}
+
+	return sent, nil
+}
+
+// getPending reads an outbox item, nil when it was sent or deleted since the index was read
+func (o *OutboxRelay) getPending(ctx context.Context, key map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	result, err := o.client.GetItem(ctx, o.tableName,
+		dynamogetitem.WithKey(key),
+		dynamogetitem.WithConsistentRead(aws.Bool(true)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Item) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := result.Item[outboxSentAtAttribute]; ok {
+		return nil, nil
+	}
+
+	return result.Item, nil
+}
+
+// markSent removes the event from the outbox index, or deletes it with DeleteSent.
+// An event another relay already marked sent is left as it is
+func (o *OutboxRelay) markSent(ctx context.Context, key map[string]types.AttributeValue) error {
+	pending := expression.AttributeExists(expression.Name(o.indexKeys.partition))
+
+	var err error
+	if o.deleteSent {
+		_, err = o.client.DeleteItem(ctx, o.tableName,
+			dynamodeleteitem.WithKey(key),
+			dynamodeleteitem.WithFilterConditionBuilder(&pending))
+	} else {
+		update := expression.Set(expression.Name(outboxSentAtAttribute), expression.Value(o.clock.Now().UTC())).
+			Remove(expression.Name(o.indexKeys.partition)).
+			Remove(expression.Name(o.indexKeys.sort))
+
+		_, err = o.client.UpdateItem(ctx, o.tableName,
+			dynamoupdateitem.WithKey(key),
+			dynamoupdateitem.WithUpdateBuilder(&update),
+			dynamoupdateitem.WithFilterConditionBuilder(&pending))
+	}
+
+	if err != nil && !isConditionFailure(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (o *OutboxRelay) toMessage(item map[string]types.AttributeValue) (*OutboxMessage, error) {
+	message := &OutboxMessage{
+		ID:      uniqueValueString(item[outboxIDAttribute]),
+		Type:    uniqueValueString(item[outboxEventTypeAttribute]),
+		Payload: item[outboxPayloadAttribute],
+		Source: &entities.Key{
+			EntityType: uniqueValueString(item[outboxSourceTypeAttribute]),
+			Partition:  uniqueValueString(item[outboxSourcePKAttribute]),
+			Sort:       uniqueValueString(item[outboxSourceSKAttribute]),
+		},
+	}
+
+	if createdAt := uniqueValueString(item[outboxCreatedAtAttribute]); createdAt != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("outbox event %s: %s", message.ID, err)
+		}
+
+		message.CreatedAt = parsed
+	}
+
+	return message, nil
+}
+
+func (o *OutboxRelay) tableKeyOf(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	out := map[string]types.AttributeValue{
+		o.tableKeys.partition: item[o.tableKeys.partition],
+	}
+
+	if o.tableKeys.sort != "" {
+		out[o.tableKeys.sort] = item[o.tableKeys.sort]
+	}
+
+	return out
+}
@@ -0,0 +1,342 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	dynamoupdateitem "github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testOutboxIndex = "GSI2pk-GSI2sk-Index"
+
+func newTestOutboxTableDesc() *types.TableDescription {
+	tableDesc := newTestTableDesc()
+	tableDesc.GlobalSecondaryIndexes = append(tableDesc.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+		IndexName: aws.String(testOutboxIndex),
+		Projection: &types.Projection{
+			ProjectionType: types.ProjectionTypeKeysOnly,
+		},
+		KeySchema: []types.KeySchemaElement{{
+			AttributeName: aws.String("GSI2pk"),
+			KeyType:       types.KeyTypeHash,
+		}, {
+			AttributeName: aws.String("GSI2sk"),
+			KeyType:       types.KeyTypeRange,
+		}},
+	})
+
+	return tableDesc
+}
+
+func withOutbox() func(*Config) {
+	return func(cfg *Config) {
+		cfg.TableDesc = newTestOutboxTableDesc()
+		cfg.Clock = &fixedClock{now: testNow}
+		cfg.Outbox = &OutboxConfig{IndexName: testOutboxIndex}
+	}
+}
+
+func outboxTestItem(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		EntityTypeAttribute:       &types.AttributeValueMemberS{Value: OutboxEntityType},
+		outboxIDAttribute:         &types.AttributeValueMemberS{Value: id},
+		outboxEventTypeAttribute:  &types.AttributeValueMemberS{Value: "product.saved"},
+		outboxPayloadAttribute:    &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "Air Max"}}},
+		outboxSourceTypeAttribute: &types.AttributeValueMemberS{Value: "MyEntity"},
+		outboxSourcePKAttribute:   &types.AttributeValueMemberS{Value: "ID#ABC"},
+		outboxSourceSKAttribute:   &types.AttributeValueMemberS{Value: "ID#ABC"},
+		outboxCreatedAtAttribute:  &types.AttributeValueMemberS{Value: "2021-12-29T18:00:00Z"},
+		"pk":                      &types.AttributeValueMemberS{Value: "OUTBOX#" + id},
+		"sk":                      &types.AttributeValueMemberS{Value: "OUTBOX#" + id},
+		"GSI2pk":                  &types.AttributeValueMemberS{Value: "OUTBOX#PENDING"},
+		"GSI2sk":                  &types.AttributeValueMemberS{Value: "2021-12-29T18:00:00.000000000Z#" + id},
+	}
+}
+
+func outboxTestEvent(id string) *entities.OutboxEvent {
+	return &entities.OutboxEvent{
+		ID:      id,
+		Type:    "product.saved",
+		Payload: map[string]string{"name": "Air Max"},
+	}
+}
+
+func TestNew_Outbox(t *testing.T) {
+	t.Run("it requires the outbox index to exist", func(t *testing.T) {
+		tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+			MappingName: "table",
+			Fields:      []string{"id"},
+		})
+
+		_, err := New(&Config{
+			Name:         "MyEntity",
+			Client:       &dynamo.Mock{},
+			TableDesc:    newTestTableDesc(),
+			TableMapping: tableMapping,
+			Outbox:       &OutboxConfig{IndexName: testOutboxIndex},
+		})
+
+		assert.Equal(t, errors.New("outbox index GSI2pk-GSI2sk-Index was not found on the table"), err)
+	})
+}
+
+func TestRepoImpl_Put_Outbox(t *testing.T) {
+	ctx := context.Background()
+	entity := &testEntity{ID: "abc", Category: "shoes", Name: "AIR MAX"}
+
+	t.Run("it writes the events in the transaction of the item", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withOutbox())
+
+		notExists := expression.AttributeNotExists(expression.Name("pk"))
+
+		client.On("TransactWriteItems", ctx, transactwriteitems.NewOptions(
+			transactwriteitems.WithPut("my-table", hookTestItem("AIR MAX"), nil),
+			transactwriteitems.WithPut("my-table", outboxTestItem("evt-1"), &notExists))).
+			Return(&transactwriteitems.Result{}, nil)
+
+		_, err := fixture.Put(ctx,
+			putitem.WithEntity(entity),
+			putitem.WithEvents(outboxTestEvent("evt-1")))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns the created timestamp that was kept in the transaction", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withOutbox(), withTimestamps("created_at", ""))
+
+		stored := hookTestItem("AIR MAX")
+		stored["created_at"] = &types.AttributeValueMemberS{Value: "2021-12-28T18:00:00Z"}
+		stored["updated_at"] = &types.AttributeValueMemberS{Value: "0001-01-01T00:00:00Z"}
+
+		expectExisting(client, ctx, stored)
+
+		notExists := expression.AttributeNotExists(expression.Name("pk"))
+
+		client.On("TransactWriteItems", ctx, transactwriteitems.NewOptions(
+			transactwriteitems.WithPut("my-table", stored, fixture.createdGuard(stored, nil)),
+			transactwriteitems.WithPut("my-table", outboxTestItem("evt-1"), &notExists))).
+			Return(&transactwriteitems.Result{}, nil)
+
+		actual := &testStampedEntity{testEntity: *entity}
+
+		result, err := fixture.Put(ctx,
+			putitem.WithEntity(actual),
+			putitem.WithEvents(outboxTestEvent("evt-1")))
+
+		assert.Nil(t, err)
+		assert.Equal(t, stored["created_at"], result.Item["created_at"])
+		assert.Equal(t, testNow.Add(-24*time.Hour), actual.CreatedAt)
+		client.AssertExpectations(t)
+	})
+	t.Run("it requires an outbox", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{})
+
+		_, err := fixture.Put(ctx,
+			putitem.WithEntity(entity),
+			putitem.WithEvents(outboxTestEvent("evt-1")))
+
+		assert.Equal(t, errors.New("repository MyEntity does not have an Outbox configured"), err)
+	})
+	t.Run("it requires an event type", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{}, withOutbox())
+
+		_, err := fixture.Put(ctx,
+			putitem.WithEntity(entity),
+			putitem.WithEvents(&entities.OutboxEvent{ID: "evt-1"}))
+
+		assert.Equal(t, errors.New("Events[0]: "+requiresEventTypeMsg), err)
+	})
+	t.Run("it returns a DuplicateEventError when the event was already written", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withOutbox())
+
+		client.On("TransactWriteItems", ctx, mock.Anything).
+			Return(nil, &types.TransactionCanceledException{
+				Message: aws.String("Transaction cancelled"),
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("None")},
+					{Code: aws.String(conditionalCheckFailedCode)},
+				},
+			})
+
+		_, err := fixture.Put(ctx,
+			putitem.WithEntity(entity),
+			putitem.WithEvents(outboxTestEvent("evt-1")))
+
+		assert.Equal(t, &DuplicateEventError{ID: "evt-1"}, err)
+	})
+}
+
+func TestRepoImpl_Delete_Outbox(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it writes the events in the transaction of the delete", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withOutbox())
+
+		notExists := expression.AttributeNotExists(expression.Name("pk"))
+
+		client.On("TransactWriteItems", ctx, transactwriteitems.NewOptions(
+			transactwriteitems.WithDelete("my-table", key1(), nil),
+			transactwriteitems.WithPut("my-table", outboxTestItem("evt-1"), &notExists))).
+			Return(&transactwriteitems.Result{}, nil)
+
+		_, err := fixture.Delete(ctx,
+			deleteitem.WithKey(map[string]interface{}{"id": "abc"}),
+			deleteitem.WithEvents(outboxTestEvent("evt-1")))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
+
+func newTestRelay(t *testing.T, client *dynamo.Mock, publisher Publisher) *OutboxRelay {
+	relay, err := NewOutboxRelay(&OutboxRelayConfig{
+		Client:    client,
+		TableDesc: newTestOutboxTableDesc(),
+		Outbox:    &OutboxConfig{IndexName: testOutboxIndex},
+		Publisher: publisher,
+		Clock:     &fixedClock{now: testNow},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return relay
+}
+
+func expectPending(client *dynamo.Mock, ctx context.Context, ids ...string) {
+	pending := expression.Key("GSI2pk").Equal(expression.Value("OUTBOX#PENDING"))
+
+	items := make([]map[string]types.AttributeValue, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, outboxKey(&keyAttributes{partition: "pk", sort: "sk"}, id))
+	}
+
+	client.On("Query", ctx, "my-table", dynamoquery.NewOptions(
+		dynamoquery.WithKeyConditionBuilder(&pending),
+		dynamoquery.WithIndexName(testOutboxIndex),
+		dynamoquery.WithLimit(25))).
+		Return(&dynamoquery.Result{Items: items}, nil)
+}
+
+func expectOutboxItem(client *dynamo.Mock, ctx context.Context, id string, item map[string]types.AttributeValue) {
+	client.On("GetItem", ctx, "my-table", dynamogetitem.NewOptions(
+		dynamogetitem.WithKey(outboxKey(&keyAttributes{partition: "pk", sort: "sk"}, id)),
+		dynamogetitem.WithConsistentRead(aws.Bool(true)))).
+		Return(&dynamogetitem.Result{Item: item}, nil)
+}
+
+func TestNewOutboxRelay(t *testing.T) {
+	t.Run("it requires a publisher", func(t *testing.T) {
+		_, err := NewOutboxRelay(&OutboxRelayConfig{
+			Client:    &dynamo.Mock{},
+			TableDesc: newTestOutboxTableDesc(),
+			Outbox:    &OutboxConfig{IndexName: testOutboxIndex},
+		})
+
+		assert.Equal(t, errors.New(requiresRelayPublisherMsg), err)
+	})
+}
+
+func TestOutboxRelay_RelayPending(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it publishes the pending events in order and marks them sent", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var published []*OutboxMessage
+		fixture := newTestRelay(t, client, PublisherFunc(func(ctx context.Context, message *OutboxMessage) error {
+			published = append(published, message)
+
+			return nil
+		}))
+
+		expectPending(client, ctx, "evt-1", "evt-2")
+		expectOutboxItem(client, ctx, "evt-1", outboxTestItem("evt-1"))
+		expectOutboxItem(client, ctx, "evt-2", outboxTestItem("evt-2"))
+
+		update := expression.Set(expression.Name(outboxSentAtAttribute), expression.Value(testNow)).
+			Remove(expression.Name("GSI2pk")).
+			Remove(expression.Name("GSI2sk"))
+		pending := expression.AttributeExists(expression.Name("GSI2pk"))
+
+		for _, id := range []string{"evt-1", "evt-2"} {
+			client.On("UpdateItem", ctx, "my-table", dynamoupdateitem.NewOptions(
+				dynamoupdateitem.WithKey(outboxKey(&keyAttributes{partition: "pk", sort: "sk"}, id)),
+				dynamoupdateitem.WithUpdateBuilder(&update),
+				dynamoupdateitem.WithFilterConditionBuilder(&pending))).
+				Return(&dynamoupdateitem.Result{}, nil)
+		}
+
+		sent, err := fixture.RelayPending(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, sent)
+		assert.Equal(t, "evt-1", published[0].ID)
+		assert.Equal(t, "evt-2", published[1].ID)
+		assert.Equal(t, "product.saved", published[0].Type)
+		assert.Equal(t, testNow, published[0].CreatedAt)
+		assert.Equal(t, &entities.Key{EntityType: "MyEntity", Partition: "ID#ABC", Sort: "ID#ABC"}, published[0].Source)
+
+		payload := map[string]string{}
+		assert.Nil(t, published[0].UnmarshalPayload(&payload))
+		assert.Equal(t, map[string]string{"name": "Air Max"}, payload)
+		client.AssertExpectations(t)
+	})
+	t.Run("it skips events sent since the index was read", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRelay(t, client, PublisherFunc(func(ctx context.Context, message *OutboxMessage) error {
+			t.Fatalf("the event %s was published again", message.ID)
+
+			return nil
+		}))
+
+		sentItem := outboxTestItem("evt-1")
+		sentItem[outboxSentAtAttribute] = &types.AttributeValueMemberS{Value: "2021-12-29T18:00:01Z"}
+
+		expectPending(client, ctx, "evt-1")
+		expectOutboxItem(client, ctx, "evt-1", sentItem)
+
+		sent, err := fixture.RelayPending(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, sent)
+	})
+	t.Run("it leaves the events pending from the first that fails to publish", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		unavailable := errors.New("unavailable")
+
+		fixture := newTestRelay(t, client, PublisherFunc(func(ctx context.Context, message *OutboxMessage) error {
+			return unavailable
+		}))
+
+		expectPending(client, ctx, "evt-1", "evt-2")
+		expectOutboxItem(client, ctx, "evt-1", outboxTestItem("evt-1"))
+
+		sent, err := fixture.RelayPending(ctx)
+
+		assert.Equal(t, 0, sent)
+		assert.True(t, errors.Is(err, unavailable))
+		assert.EqualError(t, err, "outbox event evt-1: unavailable")
+		client.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
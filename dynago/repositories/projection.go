@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// maxBatchGetKeys is the number of keys dynamo accepts in a single BatchGetItem
+	maxBatchGetKeys = 100
+
+	maxBatchGetAttempts = 5
+	batchGetBackoff     = 50 * time.Millisecond
+)
+
+// indexProjections returns the projection of every global secondary index by index name
+func indexProjections(tableDesc *types.TableDescription) map[string]*types.Projection {
+	out := make(map[string]*types.Projection)
+
+	for _, index := range tableDesc.GlobalSecondaryIndexes {
+		if index.IndexName == nil || index.Projection == nil {
+			continue
+		}
+
+		out[*index.IndexName] = index.Projection
+	}
+
+	return out
+}
+
+// isProjected reports whether items queried from the index hold only part of the entity.
+// The table and indexes projecting every attribute are not projected
+func (r *repoImpl) isProjected(indexName string) bool {
+	if indexName == "" {
+		return false
+	}
+
+	projection, ok := r.projections[indexName]
+
+	return ok && projection.ProjectionType != types.ProjectionTypeAll
+}
+
+// fetchThrough reads the full items from the table for items queried from a projected index, keeping the
+// order of the query. Items removed from the table since they were queried are dropped
+func (r *repoImpl) fetchThrough(ctx context.Context, items []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(items))
+	seen := make(map[string]bool)
+
+	for _, item := range items {
+		key := r.tableKeyOf(item)
+
+		id := r.keyString(key)
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		keys = append(keys, key)
+	}
+
+	fetched := make(map[string]map[string]types.AttributeValue, len(keys))
+
+	for start := 0; start < len(keys); start += maxBatchGetKeys {
+		end := start + maxBatchGetKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		if err := r.batchGet(ctx, keys[start:end], fetched); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]map[string]types.AttributeValue, 0, len(items))
+	for _, item := range items {
+		if full, ok := fetched[r.keyString(r.tableKeyOf(item))]; ok {
+			out = append(out, full)
+		}
+	}
+
+	return out, nil
+}
+
+// batchGet reads keys into fetched by their key string, retrying unprocessed keys with a backoff
+func (r *repoImpl) batchGet(ctx context.Context, keys []map[string]types.AttributeValue, fetched map[string]map[string]types.AttributeValue) error {
+	request := batchgetitem.WithKeys(r.tableName, keys...)
+
+	for attempt := 1; ; attempt++ {
+		result, err := r.client.BatchGetItem(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range result.Responses[r.tableName] {
+			fetched[r.keyString(r.tableKeyOf(item))] = item
+		}
+
+		unprocessed := len(result.UnprocessedKeys[r.tableName].Keys)
+		if unprocessed == 0 {
+			return nil
+		}
+
+		if attempt == maxBatchGetAttempts {
+			return fmt.Errorf("repositories: %d keys were still unprocessed after %d BatchGetItem attempts", unprocessed, attempt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(batchGetBackoff << (attempt - 1)):
+		}
+
+		request = batchgetitem.WithRequestItems(result.UnprocessedKeys)
+	}
+}
+
+// keyString identifies an item by the values of its table key
+func (r *repoImpl) keyString(key map[string]types.AttributeValue) string {
+	out := uniqueValueString(key[r.tableKeys.partition])
+	if r.tableKeys.sort != "" {
+		out += "\x00" + uniqueValueString(key[r.tableKeys.sort])
+	}
+
+	return out
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func withKeysOnlyIndex() func(*Config) {
+	return func(cfg *Config) {
+		cfg.TableDesc.GlobalSecondaryIndexes[0].Projection = &types.Projection{
+			ProjectionType: types.ProjectionTypeKeysOnly,
+		}
+
+		cfg.IndexMappings[0].ProjectionType = entities.PropjectionTypeKeysOnly
+	}
+}
+
+func projectedTestItem(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk":     &types.AttributeValueMemberS{Value: "ID#" + id},
+		"sk":     &types.AttributeValueMemberS{Value: "ID#" + id},
+		"GSI1pk": &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+		"GSI1sk": &types.AttributeValueMemberS{Value: "NAME#" + id},
+	}
+}
+
+func fullTestItem(id, name string) map[string]types.AttributeValue {
+	item := projectedTestItem(id)
+	item["id"] = &types.AttributeValueMemberS{Value: id}
+	item["category"] = &types.AttributeValueMemberS{Value: "shoes"}
+	item["name"] = &types.AttributeValueMemberS{Value: name}
+
+	return item
+}
+
+func tableKeyFor(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "ID#" + id},
+		"sk": &types.AttributeValueMemberS{Value: "ID#" + id},
+	}
+}
+
+func TestRepoImpl_Query_Projection(t *testing.T) {
+	ctx := context.Background()
+
+	queryByCategory := []func(*query.Options){
+		query.WithMappingName("queryByCategory"),
+		query.WithPartitionKey(map[string]interface{}{"category": "shoes"}),
+	}
+
+	t.Run("it flags items of a keys only index as projected", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withKeysOnlyIndex())
+
+		client.On("Query", ctx, "my-table", mock.Anything).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{projectedTestItem("B")}}, nil)
+
+		actual, err := fixture.Query(ctx, queryByCategory...)
+
+		assert.Nil(t, err)
+		assert.True(t, actual.Projected)
+		assert.Equal(t, []map[string]types.AttributeValue{projectedTestItem("B")}, actual.Items)
+	})
+	t.Run("it does not flag an index projecting every attribute", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		client.On("Query", ctx, "my-table", mock.Anything).
+			Return(&dynamoquery.Result{}, nil)
+
+		actual, err := fixture.Query(ctx, append(queryByCategory, query.WithFetchThrough(true))...)
+
+		assert.Nil(t, err)
+		assert.False(t, actual.Projected)
+		client.AssertNotCalled(t, "BatchGetItem", mock.Anything, mock.Anything)
+	})
+	t.Run("it fetches the full items in query order", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withKeysOnlyIndex())
+
+		client.On("Query", ctx, "my-table", mock.Anything).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{
+				projectedTestItem("B"),
+				projectedTestItem("A"),
+				projectedTestItem("C"),
+			}}, nil)
+
+		client.On("BatchGetItem", ctx, batchgetitem.NewOptions(
+			batchgetitem.WithKeys("my-table", tableKeyFor("B"), tableKeyFor("A"), tableKeyFor("C")))).
+			Return(&batchgetitem.Result{
+				Responses: map[string][]map[string]types.AttributeValue{
+					"my-table": {fullTestItem("A", "Air Max"), fullTestItem("B", "Blazer")},
+				},
+			}, nil)
+
+		var shoes []testEntity
+
+		actual, err := fixture.Query(ctx, append(queryByCategory,
+			query.WithFetchThrough(true),
+			query.WithEntities(&shoes))...)
+
+		assert.Nil(t, err)
+		assert.False(t, actual.Projected)
+		assert.Equal(t, []map[string]types.AttributeValue{
+			fullTestItem("B", "Blazer"),
+			fullTestItem("A", "Air Max"),
+		}, actual.Items)
+		assert.Equal(t, []testEntity{
+			{ID: "B", Category: "shoes", Name: "Blazer"},
+			{ID: "A", Category: "shoes", Name: "Air Max"},
+		}, shoes)
+		client.AssertExpectations(t)
+	})
+	t.Run("it retries unprocessed keys", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withKeysOnlyIndex())
+
+		client.On("Query", ctx, "my-table", mock.Anything).
+			Return(&dynamoquery.Result{Items: []map[string]types.AttributeValue{
+				projectedTestItem("A"),
+				projectedTestItem("B"),
+			}}, nil)
+
+		unprocessed := map[string]types.KeysAndAttributes{
+			"my-table": {Keys: []map[string]types.AttributeValue{tableKeyFor("B")}},
+		}
+
+		client.On("BatchGetItem", ctx, batchgetitem.NewOptions(
+			batchgetitem.WithKeys("my-table", tableKeyFor("A"), tableKeyFor("B")))).
+			Return(&batchgetitem.Result{
+				Responses:       map[string][]map[string]types.AttributeValue{"my-table": {fullTestItem("A", "Air Max")}},
+				UnprocessedKeys: unprocessed,
+			}, nil)
+
+		client.On("BatchGetItem", ctx, batchgetitem.NewOptions(batchgetitem.WithRequestItems(unprocessed))).
+			Return(&batchgetitem.Result{
+				Responses: map[string][]map[string]types.AttributeValue{"my-table": {fullTestItem("B", "Blazer")}},
+			}, nil)
+
+		actual, err := fixture.Query(ctx, append(queryByCategory, query.WithFetchThrough(true))...)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []map[string]types.AttributeValue{
+			fullTestItem("A", "Air Max"),
+			fullTestItem("B", "Blazer"),
+		}, actual.Items)
+		client.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Registry
+//
+// Maps entity types to Go types so items of different repositories sharing a table can be decoded from one query
+//
+//	registry := repositories.NewRegistry()
+//	registry.Register("ORDER", Order{})
+//	registry.Register("LINE_ITEM", LineItem{})
+//
+//	result, err := repo.Query(ctx,
+//		query.WithMappingName("by-customer"),
+//		query.WithPartitionKey(repositories.Key{"customer_id": "123"}),
+//		query.AsPolymorphic(registry))
+//
+//	for _, entity := range result.Entities {
+//		switch entity := entity.(type) {
+//		case *Order:
+//		case *LineItem:
+//		}
+//	}
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// Register
+//
+// prototype is a struct or pointer to a struct, decoded items are returned as a pointer to that struct
+func (r *Registry) Register(entityType string, prototype interface{}) error {
+	if entityType == "" {
+		return errors.New("Registry.Register requires an entity type")
+	}
+
+	if prototype == nil {
+		return errors.New("Registry.Register requires a prototype")
+	}
+
+	prototypeType := reflect.TypeOf(prototype)
+	for prototypeType.Kind() == reflect.Ptr {
+		prototypeType = prototypeType.Elem()
+	}
+
+	if prototypeType.Kind() != reflect.Struct {
+		return fmt.Errorf("entity type %s must be registered with a struct, got %s", entityType, prototypeType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.types[entityType]; ok && existing != prototypeType {
+		return fmt.Errorf("entity type %s is already registered to %s", entityType, existing)
+	}
+
+	r.types[entityType] = prototypeType
+
+	return nil
+}
+
+// Decode
+//
+// Reads EntityTypeAttribute from the item and unmarshals it into a new instance of the registered type
+func (r *Registry) Decode(item map[string]types.AttributeValue) (interface{}, error) {
+	entityType, ok := item[EntityTypeAttribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, fmt.Errorf("item does not have a %s attribute", EntityTypeAttribute)
+	}
+
+	r.mu.RLock()
+	registered, ok := r.types[entityType.Value]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("entity type %s is not registered", entityType.Value)
+	}
+
+	out := reflect.New(registered)
+	if err := attributevalue.UnmarshalMap(item, out.Interface()); err != nil {
+		return nil, fmt.Errorf("entity type %s: %s", entityType.Value, err)
+	}
+
+	return out.Interface(), nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Register(t *testing.T) {
+	t.Run("it requires a struct", func(t *testing.T) {
+		err := NewRegistry().Register("product", "product")
+
+		assert.Equal(t, errors.New("entity type product must be registered with a struct, got string"), err)
+	})
+	t.Run("it does not allow an entity type to change types", func(t *testing.T) {
+		type other struct{}
+
+		fixture := NewRegistry()
+
+		assert.Nil(t, fixture.Register("product", testEntity{}))
+		assert.Nil(t, fixture.Register("product", &testEntity{}))
+		assert.NotNil(t, fixture.Register("product", other{}))
+	})
+}
+
+func TestRegistry_Decode(t *testing.T) {
+	fixture := NewRegistry()
+	_ = fixture.Register("product", testEntity{})
+
+	t.Run("it requires the entity type attribute", func(t *testing.T) {
+		_, err := fixture.Decode(map[string]types.AttributeValue{})
+
+		assert.Equal(t, errors.New("item does not have a _type attribute"), err)
+	})
+	t.Run("it requires the entity type to be registered", func(t *testing.T) {
+		_, err := fixture.Decode(map[string]types.AttributeValue{
+			EntityTypeAttribute: &types.AttributeValueMemberS{Value: "review"},
+		})
+
+		assert.Equal(t, errors.New("entity type review is not registered"), err)
+	})
+	t.Run("it decodes into the registered type", func(t *testing.T) {
+		actual, err := fixture.Decode(map[string]types.AttributeValue{
+			EntityTypeAttribute: &types.AttributeValueMemberS{Value: "product"},
+			"id":                &types.AttributeValueMemberS{Value: "abc"},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &testEntity{ID: "abc"}, actual)
+	})
+}
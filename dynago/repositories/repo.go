@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
 
@@ -11,22 +13,59 @@ import (
 
 	"github.com/KirkDiggler/go-projects/tools/dynago/schemas"
 
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getitem"
 	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
 
 	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamodeleteitem "github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// ErrNotFound is returned by Get when no item exists for the key
+var ErrNotFound = errors.New("repositories: item not found")
+
+// EntityTypeAttribute is the attribute every Put writes the repository's entity type to
+const EntityTypeAttribute = "_type"
+
 type repoImpl struct {
 	name          string
+	entityType    string
+	sortPrefix    string
 	client        dynamo.Interface
+	tableName     string
+	tableKeys     *keyAttributes
+	indexKeys     map[string]*keyAttributes
+	projections   map[string]*types.Projection
+	schema        *entities.Schema
 	schemaMapping *schemas.Mapping
+	relationships []*Relationship
+	edges         *EdgeConfig
+	outbox        *OutboxConfig
+	uniqueFields  []string
+	versionField  string
+	timestamps    *TimestampConfig
+	ttl           *TTLConfig
+	clock         Clock
+	softDelete    bool
+	hooks         *Hooks
 }
 
 type Config struct {
 	Name   string
 	Client dynamo.Interface
 
+	// EntityType is written to EntityTypeAttribute on every Put and prefixes the sort key of the table
+	// and index mappings so repositories can share a table without colliding. Optional
+	EntityType string
+
 	TableDesc *types.TableDescription
 
 	// Load the existing mapping if it exists
@@ -34,17 +73,65 @@ type Config struct {
 
 	TableMapping  mappings.Interface
 	IndexMappings []*mappings.Index
+
+	// Relationships to child repositories that store their entities in this repository's partitions
+	Relationships []*Relationship
+
+	// Edges enables many-to-many edges between entities, optional
+	Edges *EdgeConfig
+
+	// Outbox enables events written in the same transaction as a Put or Delete, optional
+	Outbox *OutboxConfig
+
+	// UniqueFields are attribute names whose values can only be used by one entity of this repository.
+	// Put and Delete run in a transaction that claims and releases a marker item for each value. Values are
+	// compared case insensitively, as the marker keys are uppercased like every other key value
+	UniqueFields []string
+
+	// VersionField is the attribute name of an integer version used for optimistic locking. Every Put increments it
+	// and fails with ErrVersionConflict when the stored version changed. A Put with a FilterConditionBuilder returns
+	// the condition failure as is instead, as either condition may have failed. Entities can tag the field with
+	// dynago:"version" instead
+	VersionField string
+
+	// Timestamps stamps created and updated attributes on every Put, optional
+	Timestamps *TimestampConfig
+
+	// TTL writes an expiry attribute on every Put, optional
+	TTL *TTLConfig
+
+	// Clock supplies the time for Timestamps and TTL, defaults to the system clock
+	Clock Clock
+
+	// SoftDelete keeps deleted items as tombstones that Get and Query skip, see Restore and Purge
+	SoftDelete bool
+
+	// Hooks run before and after writes and after loads, optional
+	Hooks *Hooks
 }
 
 const (
 	requiresConfigMsg          = "repositories.New requires a Config"
 	requiresConfigNameMsg      = "repositories.Config.Name is required"
+	requiresConfigClientMsg    = "repositories.Config.Client is required"
 	requiresConfigTableDescMsg = "repositories.Config.TableDesc is required"
 	requiresConfigTableMapping = "repositories.Config.TableMapping is required"
+	requiresTableNameMsg       = "repositories.Config.TableDesc.TableName is required"
+	invalidEntityTypeMsg       = "repositories.Config.EntityType can not contain '#'"
+	requiresIndexMappingMsg    = "repositories.Config.IndexMappings require a Mapping"
+
+	requiresEntityMsg     = "an Entity is required"
+	polymorphicSortKeyMsg = "a SortKey can not be used with a Registry, polymorphic queries match every entity type"
+	requiresKeyMsg        = "a Key is required"
+
+	// maxCreatedAttempts is how many times a Put is written when inserts keep changing the created timestamp
+	maxCreatedAttempts = 3
 )
 
 // New
 //
+// Builds a repository for the table described by Config.TableDesc, assigning index mappings to the
+// available global secondary indexes
 func New(cfg *Config) (Interface, error) {
 	if cfg == nil {
 		return nil, errors.New(requiresConfigMsg)
@@ -54,6 +141,10 @@ func New(cfg *Config) (Interface, error) {
 		return nil, errors.New(requiresConfigNameMsg)
 	}
 
+	if cfg.Client == nil {
+		return nil, errors.New(requiresConfigClientMsg)
+	}
+
 	// TODO: should we lazily load this if it is missing?
 	if cfg.TableDesc == nil {
 		return nil, errors.New(requiresConfigTableDescMsg)
@@ -63,20 +154,637 @@ func New(cfg *Config) (Interface, error) {
 		return nil, errors.New(requiresConfigTableMapping)
 	}
 
-	schemaMapping, err := updateEntity(cfg.SchemaMapping, cfg.TableDesc, cfg.TableMapping, cfg.IndexMappings)
+	if strings.Contains(cfg.EntityType, "#") {
+		return nil, errors.New(invalidEntityTypeMsg)
+	}
+
+	if err := validateRelationships(cfg.EntityType, cfg.Relationships); err != nil {
+		return nil, err
+	}
+
+	for _, index := range cfg.IndexMappings {
+		if index == nil || index.Mapping == nil {
+			return nil, errors.New(requiresIndexMappingMsg)
+		}
+
+		if err := index.ValidateProjection(); err != nil {
+			return nil, fmt.Errorf("index mapping %s: %s", index.Mapping.GetName(), err)
+		}
+
+		if err := mappings.ValidateConditions(index.Conditions); err != nil {
+			return nil, fmt.Errorf("index mapping %s: %s", index.Mapping.GetName(), err)
+		}
+	}
+
+	// indexes are assigned to copies of the index mappings, the mappings of the caller keep the names it set
+	copied := *cfg
+	copied.IndexMappings = copyIndexMappings(cfg.IndexMappings)
+	cfg = &copied
+
+	if err := validateUniqueFields(cfg.UniqueFields); err != nil {
+		return nil, err
+	}
+
+	if err := validateTimestamps(cfg.Timestamps); err != nil {
+		return nil, err
+	}
+
+	if err := validateTTL(cfg.TTL); err != nil {
+		return nil, err
+	}
+
+	if cfg.TableDesc.TableName == nil {
+		return nil, errors.New(requiresTableNameMsg)
+	}
+
+	tableKeys, err := keyAttributesFromSchema(cfg.TableDesc.KeySchema)
+	if err != nil {
+		return nil, fmt.Errorf("table %s: %s", *cfg.TableDesc.TableName, err)
+	}
+
+	indexKeys, err := indexKeyAttributes(cfg.TableDesc)
 	if err != nil {
 		return nil, err
 	}
 
+	reservedIndexes := make(map[string]bool)
+	if cfg.Edges != nil {
+		if err := validateEdgeConfig(cfg.Edges, tableKeys, indexKeys); err != nil {
+			return nil, err
+		}
+
+		reservedIndexes[cfg.Edges.IndexName] = true
+	}
+
+	if cfg.Outbox != nil {
+		if err := validateOutboxConfig(cfg.Outbox, tableKeys, indexKeys); err != nil {
+			return nil, err
+		}
+
+		reservedIndexes[cfg.Outbox.IndexName] = true
+	}
+
+	schema, schemaMapping, err := updateEntity(cfg.SchemaMapping, cfg.TableDesc, cfg.TableMapping, cfg.IndexMappings, reservedIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Validate(cfg).Err(); err != nil {
+		return nil, err
+	}
+
+	sortPrefix := ""
+	if cfg.EntityType != "" {
+		sortPrefix = mappings.BuildPrefix(cfg.EntityType)
+	}
+
+	clock := cfg.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
 	return &repoImpl{
 		name:          cfg.Name,
+		entityType:    cfg.EntityType,
+		sortPrefix:    sortPrefix,
 		client:        cfg.Client,
+		tableName:     *cfg.TableDesc.TableName,
+		tableKeys:     tableKeys,
+		indexKeys:     indexKeys,
+		projections:   indexProjections(cfg.TableDesc),
+		schema:        schema,
 		schemaMapping: schemaMapping,
+		relationships: cfg.Relationships,
+		edges:         cfg.Edges,
+		outbox:        cfg.Outbox,
+		uniqueFields:  cfg.UniqueFields,
+		versionField:  cfg.VersionField,
+		timestamps:    cfg.Timestamps,
+		ttl:           cfg.TTL,
+		clock:         clock,
+		softDelete:    cfg.SoftDelete,
+		hooks:         cfg.Hooks,
 	}, nil
 }
 
-func (r *repoImpl) Put(context.Context, ...func(*putitem.Options)) (*putitem.Result, error) {
-	return nil, errors.New("not implemented")
+// Schema
+//
+// Returns the schema entity with the table mapping and the index every index mapping is assigned to
+func (r *repoImpl) Schema() *entities.Schema {
+	return r.schema
+}
+
+// Put
+//
+// Marshals the entity and writes it with the key attributes of the table and every assigned index mapping.
+// The version, timestamps and expiry set by the repository are copied back to the entity when it is a pointer
+func (r *repoImpl) Put(ctx context.Context, putOptions ...func(*putitem.Options)) (*putitem.Result, error) {
+	options := putitem.NewOptions(putOptions...)
+
+	if options.Entity == nil {
+		return nil, errors.New(requiresEntityMsg)
+	}
+
+	item, keys, err := r.buildPutItem(ctx, options.Entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing map[string]types.AttributeValue
+	if len(r.uniqueFields) != 0 || r.hooks.has(HookBeforePut, HookAfterPut) || r.stampsCreated() {
+		existing, err = r.getExisting(ctx, r.tableKeyOf(item))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.hooks.has(HookBeforePut) {
+		event := &HookEvent{
+			Type:       HookBeforePut,
+			Repository: r.name,
+			Entity:     options.Entity,
+			Item:       item,
+			Keys:       keys,
+			OldImage:   existing,
+		}
+
+		if err := r.hooks.run(ctx, event); err != nil {
+			return nil, err
+		}
+
+		// the hooks may have changed the entity
+		tableKey := r.tableKeyOf(item)
+
+		item, keys, err = r.buildPutItem(ctx, options.Entity)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(tableKey, r.tableKeyOf(item)) {
+			existing, err = r.getExisting(ctx, r.tableKeyOf(item))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := r.stampItem(item, existing); err != nil {
+		return nil, err
+	}
+
+	condition := options.FilterConditionBuilder
+
+	versionField, err := r.resolveVersionField(options.Entity)
+	if err != nil {
+		return nil, err
+	}
+
+	if versionField != "" {
+		versionCondition, err := applyVersion(item, versionField)
+		if err != nil {
+			return nil, err
+		}
+
+		if condition != nil {
+			combined := versionCondition.And(*condition)
+			versionCondition = &combined
+		}
+
+		condition = versionCondition
+	}
+
+	outboxItems, err := r.buildOutboxItems(r.tableKeyOf(item), options.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	written, err := r.writeItem(ctx, item, existing, condition, outboxItems)
+
+	// an insert between the read and the write changed the created timestamp, it is read and stamped again
+	for attempt := 1; attempt < maxCreatedAttempts && r.stampsCreated() && isConditionFailure(err); attempt++ {
+		stored, readErr := r.getExisting(ctx, r.tableKeyOf(item))
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		if !r.createdChanged(existing, stored) {
+			break
+		}
+
+		existing = stored
+
+		if err := r.stampItem(item, existing); err != nil {
+			return nil, err
+		}
+
+		written, err = r.writeItem(ctx, item, existing, condition, outboxItems)
+	}
+
+	if err != nil {
+		// with a condition of the caller the failure can not be told apart from a version conflict
+		if versionField != "" && options.FilterConditionBuilder == nil && isConditionFailure(err) {
+			return nil, ErrVersionConflict
+		}
+
+		return nil, err
+	}
+
+	// attributes set by the repository are copied to the entity so it matches what was written
+	stamped := r.stampedAttributes(written, versionField)
+	if len(stamped) != 0 && reflect.ValueOf(options.Entity).Kind() == reflect.Ptr {
+		if err := attributevalue.UnmarshalMap(stamped, options.Entity); err != nil {
+			return nil, err
+		}
+	}
+
+	err = r.hooks.run(ctx, &HookEvent{
+		Type:       HookAfterPut,
+		Repository: r.name,
+		Entity:     options.Entity,
+		Item:       written,
+		Keys:       keys,
+		OldImage:   existing,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &putitem.Result{Item: written}, nil
+}
+
+// buildPutItem marshals the entity with its entity type and the keys of the table and every index it is written to
+func (r *repoImpl) buildPutItem(ctx context.Context, entity interface{}) (map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(entity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if r.entityType != "" {
+		item[EntityTypeAttribute] = &types.AttributeValueMemberS{Value: r.entityType}
+	}
+
+	// writing an entity again brings it back from a soft delete
+	if r.softDelete {
+		delete(item, DeletedAttribute)
+		delete(item, DeletedAtAttribute)
+	}
+
+	keys, err := r.buildItemKeys(ctx, item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for k, v := range keys {
+		item[k] = v
+	}
+
+	return item, keys, nil
+}
+
+// writeItem writes the item with a put. Items with unique fields or outbox items are written in a transaction
+// guarded by existing, the item read before the write, as is the created timestamp. The item as it was stored is
+// returned
+func (r *repoImpl) writeItem(ctx context.Context, item, existing map[string]types.AttributeValue, condition *expression.ConditionBuilder, outboxItems []map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	condition = r.createdGuard(existing, condition)
+
+	if len(r.uniqueFields) != 0 || len(outboxItems) != 0 {
+		if err := r.putTransaction(ctx, item, existing, condition, outboxItems); err != nil {
+			return nil, err
+		}
+
+		return item, nil
+	}
+
+	dynamoOptions := []dynamoputitem.OptionFunc{
+		dynamoputitem.WithItem(item),
+	}
+
+	if condition != nil {
+		dynamoOptions = append(dynamoOptions, dynamoputitem.WithFilterConditionBuilder(condition))
+	}
+
+	_, err := r.client.PutItem(ctx, r.tableName, dynamoOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Get
+//
+// Loads a single item by the values of the table mapping fields
+func (r *repoImpl) Get(ctx context.Context, getOptions ...func(*getitem.Options)) (*getitem.Result, error) {
+	options := getitem.NewOptions(getOptions...)
+
+	if options.Key == nil {
+		return nil, errors.New(requiresKeyMsg)
+	}
+
+	key, err := r.buildTableKey(ctx, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamoOptions := []dynamogetitem.OptionFunc{
+		dynamogetitem.WithKey(key),
+	}
+
+	if options.ConsistentRead {
+		dynamoOptions = append(dynamoOptions, dynamogetitem.WithConsistentRead(aws.Bool(true)))
+	}
+
+	result, err := r.client.GetItem(ctx, r.tableName, dynamoOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Item) == 0 {
+		return nil, ErrNotFound
+	}
+
+	if r.softDelete && !options.IncludeDeleted && isDeleted(result.Item) {
+		return nil, ErrNotFound
+	}
+
+	if options.Entity != nil {
+		if err := attributevalue.UnmarshalMap(result.Item, options.Entity); err != nil {
+			return nil, err
+		}
+	}
+
+	err = r.runAfterLoad(ctx, []map[string]types.AttributeValue{result.Item}, func(int) interface{} {
+		return options.Entity
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &getitem.Result{Item: result.Item}, nil
+}
+
+// Query
+//
+// Queries the table mapping or an index mapping. The partition fields must all be provided,
+// sort fields are matched as a prefix in the order the mapping declares them.
+// Items of an index that does not project every attribute are partial unless WithFetchThrough is used
+func (r *repoImpl) Query(ctx context.Context, queryOptions ...func(*query.Options)) (*query.Result, error) {
+	options := query.NewOptions(queryOptions...)
+
+	mapping, attrs, indexName, err := r.resolveMapping(options.MappingName)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionValues, err := marshalValues(options.PartitionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sortValues, err := marshalValues(options.SortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// polymorphic queries return every entity type so the entity type prefix is not used
+	sortPrefix := r.sortPrefix
+	if options.Registry != nil {
+		if len(sortValues) != 0 {
+			return nil, errors.New(polymorphicSortKeyMsg)
+		}
+
+		sortPrefix = ""
+	}
+
+	keyCondition, err := buildKeyCondition(ctx, mapping, attrs, sortPrefix, partitionValues, sortValues)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamoOptions := []dynamoquery.OptionFunc{
+		dynamoquery.WithKeyConditionBuilder(keyCondition),
+	}
+
+	if indexName != "" {
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithIndexName(indexName))
+	}
+
+	filter := r.notDeletedCondition(options.FilterConditionBuilder, options.IncludeDeleted)
+
+	// edge items share the partitions of the table, the edge index is reserved so other indexes never hold them
+	if indexName == "" {
+		filter = r.notEdgeCondition(filter)
+	}
+
+	if filter != nil {
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithFilterConditionBuilder(filter))
+	}
+
+	if options.Limit > 0 {
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithLimit(options.Limit))
+	}
+
+	if options.Cursor != nil {
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithExclusiveStartKey(options.Cursor))
+	}
+
+	if options.ScanForward != nil {
+		dynamoOptions = append(dynamoOptions, dynamoquery.WithScanIndexForward(*options.ScanForward))
+	}
+
+	result, err := r.client.Query(ctx, r.tableName, dynamoOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	items := result.Items
+	projected := r.isProjected(indexName)
+
+	if projected && options.FetchThrough && len(items) != 0 {
+		items, err = r.fetchThrough(ctx, items)
+		if err != nil {
+			return nil, err
+		}
+
+		// the item may have been soft deleted since the index was read
+		if r.softDelete && !options.IncludeDeleted {
+			items = withoutDeleted(items)
+		}
+
+		projected = false
+	}
+
+	if options.Entities != nil {
+		if err := attributevalue.UnmarshalListOfMaps(items, options.Entities); err != nil {
+			return nil, err
+		}
+	}
+
+	var decoded []interface{}
+	if options.Registry != nil {
+		decoded = make([]interface{}, len(items))
+
+		for idx, item := range items {
+			decoded[idx], err = options.Registry.Decode(item)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = r.runAfterLoad(ctx, items, func(idx int) interface{} {
+		if decoded != nil {
+			return decoded[idx]
+		}
+
+		if options.Entities != nil {
+			return sliceElement(options.Entities, idx)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &query.Result{
+		Items:     items,
+		Entities:  decoded,
+		Cursor:    result.LastEvaluatedKey,
+		Projected: projected,
+	}, nil
+}
+
+// Delete
+//
+// Removes a single item by the values of the table mapping fields. With SoftDelete the item is kept as a tombstone
+// that is dropped from every index, use Purge to remove it
+func (r *repoImpl) Delete(ctx context.Context, deleteOptions ...func(*deleteitem.Options)) (*deleteitem.Result, error) {
+	options := deleteitem.NewOptions(deleteOptions...)
+
+	if options.Key == nil {
+		return nil, errors.New(requiresKeyMsg)
+	}
+
+	key, err := r.buildTableKey(ctx, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.deleteItem(ctx, key, options.FilterConditionBuilder, options.Events, r.softDelete); err != nil {
+		return nil, err
+	}
+
+	return &deleteitem.Result{}, nil
+}
+
+// deleteItem runs the delete hooks around a soft or hard delete of the item, the events are written with the delete
+func (r *repoImpl) deleteItem(ctx context.Context, key map[string]types.AttributeValue, condition *expression.ConditionBuilder, events []*entities.OutboxEvent, soft bool) error {
+	outboxItems, err := r.buildOutboxItems(key, events)
+	if err != nil {
+		return err
+	}
+
+	var existing map[string]types.AttributeValue
+
+	if (!soft && len(r.uniqueFields) != 0) || r.hooks.has(HookBeforeDelete, HookAfterDelete) {
+		existing, err = r.getExisting(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	event := &HookEvent{Type: HookBeforeDelete, Repository: r.name, Keys: key, OldImage: existing}
+	if err := r.hooks.run(ctx, event); err != nil {
+		return err
+	}
+
+	if soft {
+		err = r.softDeleteItem(ctx, key, condition, outboxItems)
+	} else {
+		err = r.hardDelete(ctx, key, existing, condition, outboxItems)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return r.hooks.run(ctx, &HookEvent{Type: HookAfterDelete, Repository: r.name, Keys: key, OldImage: existing})
+}
+
+// hardDelete removes the item, releasing the unique value markers of existing and writing the outbox items
+func (r *repoImpl) hardDelete(ctx context.Context, key, existing map[string]types.AttributeValue, condition *expression.ConditionBuilder, outboxItems []map[string]types.AttributeValue) error {
+	if len(r.uniqueFields) != 0 || len(outboxItems) != 0 {
+		return r.deleteTransaction(ctx, key, existing, condition, outboxItems)
+	}
+
+	dynamoOptions := []dynamodeleteitem.OptionFunc{
+		dynamodeleteitem.WithKey(key),
+	}
+
+	if condition != nil {
+		dynamoOptions = append(dynamoOptions, dynamodeleteitem.WithFilterConditionBuilder(condition))
+	}
+
+	_, err := r.client.DeleteItem(ctx, r.tableName, dynamoOptions...)
+
+	return err
+}
+
+func (r *repoImpl) buildTableKey(ctx context.Context, input map[string]interface{}) (map[string]types.AttributeValue, error) {
+	values, err := marshalValues(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildKey(ctx, r.schemaMapping.Table, r.tableKeys, r.sortPrefix, values)
+}
+
+// buildItemKeys builds the table key and the keys of every index mapping that has been assigned an index
+// and whose conditions match the item
+func (r *repoImpl) buildItemKeys(ctx context.Context, item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	out, err := buildKey(ctx, r.schemaMapping.Table, r.tableKeys, r.sortPrefix, item)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range r.schemaMapping.Indexes {
+		attrs, ok := r.indexKeys[index.Name]
+		if !ok || !index.Includes(item) {
+			continue
+		}
+
+		indexKey, err := buildKey(ctx, index.Mapping, attrs, r.sortPrefix, item)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range indexKey {
+			out[k] = v
+		}
+	}
+
+	return out, nil
+}
+
+// resolveMapping finds the mapping by name along with its key attributes and index name.
+// The table mapping is used when mappingName is empty
+func (r *repoImpl) resolveMapping(mappingName string) (mappings.Interface, *keyAttributes, string, error) {
+	if mappingName == "" || mappingName == r.schemaMapping.Table.GetName() {
+		return r.schemaMapping.Table, r.tableKeys, "", nil
+	}
+
+	index, ok := r.schemaMapping.Indexes[mappingName]
+	if !ok {
+		return nil, nil, "", fmt.Errorf("mapping %s was not found on repository %s", mappingName, r.name)
+	}
+
+	attrs, ok := r.indexKeys[index.Name]
+	if !ok {
+		return nil, nil, "", fmt.Errorf("mapping %s is not assigned to an index", mappingName)
+	}
+
+	return index.Mapping, attrs, index.Name, nil
 }
 
 func prepareSchema(schema *entities.Schema) *entities.Schema {
@@ -98,62 +806,34 @@ func prepareSchema(schema *entities.Schema) *entities.Schema {
 	return schema
 }
 
-func updateEntity(existingEntity *entities.Schema, tableDesc *types.TableDescription, tableMapping mappings.Interface, indexMappings []*mappings.Index) (*schemas.Mapping, error) {
-	if validErr := mappingIsValid(existingEntity, tableDesc, tableMapping, indexMappings); validErr != nil {
-		return nil, validErr
+// updateEntity checks the mappings are compatible with the existing schema entity and assigns new index mappings
+// to indexes, recording the assignments on the returned entity
+func updateEntity(existingEntity *entities.Schema, tableDesc *types.TableDescription, tableMapping mappings.Interface, indexMappings []*mappings.Index, reservedIndexes map[string]bool) (*entities.Schema, *schemas.Mapping, error) {
+	if validErr := mappingIsValid(existingEntity, tableDesc, tableMapping, indexMappings, reservedIndexes); validErr != nil {
+		return nil, nil, validErr
 	}
 
-	existingEntity = prepareSchema(existingEntity)
-
-	// Get a list of indexes names that are not currently assigned
-	var availableIndexes []string
-	for _, index := range tableDesc.GlobalSecondaryIndexes {
-		if index.IndexName == nil {
-			continue
-		}
-
-		if _, ok := existingEntity.Indexes[*index.IndexName]; !ok {
-			availableIndexes = append(availableIndexes, *index.IndexName)
-		}
+	if existingEntity == nil {
+		existingEntity = &entities.Schema{Table: tableMapping.ToEntity()}
 	}
 
-	// table has to match to pass validation so we move onto the indexes
-	// Indexes have passed partition field checks so they match,
-	// we will focus on checking sort keys and new index mappings
-	for _, index := range indexMappings {
-		found := false
-
-		// Scan existing indexes to see if they have a matching mapping name
-		for _, v := range existingEntity.Indexes {
-			if v.Mapping.Name == index.Mapping.GetName() {
-
-				found = true
-				break
-			}
-		}
+	existingEntity = prepareSchema(existingEntity)
 
-		if !found {
-			if len(availableIndexes) > 0 {
-				existingEntity.Indexes[index.Mapping.GetName()] = &entities.Index{
-					Name:           availableIndexes[0],
-					ProjectionType: index.ProjectionType,
-					Mapping: &entities.Mapping{
-						Name:            index.Mapping.GetName(),
-						Type:            index.Mapping.GetType(),
-						PartitionFields: index.Mapping.GetPartitionFields(),
-						SortFields:      index.Mapping.GetSortFields(),
-					},
-				}
+	if err := assignIndexes(existingEntity, tableDesc, indexMappings, reservedIndexes); err != nil {
+		return nil, nil, err
+	}
 
-				index.Name = availableIndexes[0]
+	return existingEntity, buildNewMapping(tableMapping, indexMappings), nil
+}
 
-				// pop the first one off
-				availableIndexes = availableIndexes[1:]
-			}
-		}
+func copyIndexMappings(indexMappings []*mappings.Index) []*mappings.Index {
+	out := make([]*mappings.Index, len(indexMappings))
+	for idx, index := range indexMappings {
+		copied := *index
+		out[idx] = &copied
 	}
 
-	return buildNewMapping(tableMapping, indexMappings), nil
+	return out
 }
 
 func buildNewMapping(tableMapping mappings.Interface, indexMappings []*mappings.Index) *schemas.Mapping {
@@ -171,12 +851,12 @@ func buildNewMapping(tableMapping mappings.Interface, indexMappings []*mappings.
 
 // Checks if the existing mapping is compatible with current mappings
 // TableMapping must match exactly
-func mappingIsValid(existing *entities.Schema, tableDesc *types.TableDescription, tableMapping mappings.Interface, indexMappings []*mappings.Index) error {
+func mappingIsValid(existing *entities.Schema, tableDesc *types.TableDescription, tableMapping mappings.Interface, indexMappings []*mappings.Index, reservedIndexes map[string]bool) error {
 	if existing == nil {
 		return nil
 	}
 
-	tableIndexCount := len(tableDesc.GlobalSecondaryIndexes)
+	tableIndexCount := len(tableDesc.GlobalSecondaryIndexes) - len(reservedIndexes)
 	requestedIndexCount := len(indexMappings)
 
 	if tableIndexCount < requestedIndexCount {
@@ -205,12 +885,18 @@ func mappingIsValid(existing *entities.Schema, tableDesc *types.TableDescription
 	for k, v := range existing.Indexes {
 		found := false
 		for _, index := range indexMappings {
-			if len(v.Mapping.PartitionFields) != len(index.Mapping.GetPartitionFields()) {
-				return fmt.Errorf("mapping %s has changed the number of partition fields", index.Mapping.GetName())
-			}
-
 			if v.Mapping.Name == index.Mapping.GetName() {
 				found = true
+
+				if len(v.Mapping.PartitionFields) != len(index.Mapping.GetPartitionFields()) {
+					return fmt.Errorf("mapping %s has changed the number of partition fields", index.Mapping.GetName())
+				}
+
+				// items already written were included with the existing conditions
+				if !mappings.ConditionsEqual(v.Conditions, index.Conditions) {
+					return fmt.Errorf("index mapping %s has changed its conditions", index.Mapping.GetName())
+				}
+
 				for idx, field := range v.Mapping.PartitionFields {
 					if field != index.Mapping.GetPartitionFields()[idx] {
 						return fmt.Errorf("index mapping %s partition field mismatch, existing %s != requested %s",
@@ -233,7 +919,7 @@ func mappingIsValid(existing *entities.Schema, tableDesc *types.TableDescription
 
 		if !found {
 			return fmt.Errorf("existing index mapping %s assigned to Global Secondary Index %s was not found",
-				v.Name, k)
+				k, v.Name)
 		}
 	}
 
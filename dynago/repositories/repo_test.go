@@ -1,15 +1,25 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
 
 	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamodeleteitem "github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
 	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -347,3 +357,323 @@ func TestNew(t *testing.T) {
 		assert.Equal(t, index1Name, actual.(*repoImpl).schemaMapping.Indexes[queryByCategoryMapping.GetName()].Name, index1Name)
 	})
 }
+
+type testEntity struct {
+	ID       string `dynamodbav:"id"`
+	Category string `dynamodbav:"category"`
+	Name     string `dynamodbav:"name"`
+}
+
+func newTestTableDesc() *types.TableDescription {
+	return &types.TableDescription{
+		KeySchema: []types.KeySchemaElement{{
+			AttributeName: aws.String("pk"),
+			KeyType:       types.KeyTypeHash,
+		}, {
+			AttributeName: aws.String("sk"),
+			KeyType:       types.KeyTypeRange,
+		}},
+		TableName: aws.String("my-table"),
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{{
+			IndexName: aws.String("GSI1pk-GSI1sk-Index"),
+			Projection: &types.Projection{
+				ProjectionType: types.ProjectionTypeAll,
+			},
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String("GSI1pk"),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String("GSI1sk"),
+				KeyType:       types.KeyTypeRange,
+			}},
+		}},
+	}
+}
+
+func newTestRepo(t *testing.T, client *dynamo.Mock, configFuncs ...func(*Config)) *repoImpl {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "table",
+		Fields:      []string{"id"},
+	})
+
+	queryByCategoryMapping, _ := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     "queryByCategory",
+		PartitionFields: []string{"category"},
+		SortFields:      []string{"name"},
+	})
+
+	cfg := &Config{
+		Name:         "MyEntity",
+		Client:       client,
+		TableDesc:    newTestTableDesc(),
+		TableMapping: tableMapping,
+		IndexMappings: []*mappings.Index{{
+			ProjectionType: entities.PropjectionTypeAll,
+			Mapping:        queryByCategoryMapping,
+		}},
+	}
+
+	for _, configFunc := range configFuncs {
+		configFunc(cfg)
+	}
+
+	repo, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repo.(*repoImpl)
+}
+
+func TestRepoImpl_Put(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it requires an entity", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{})
+
+		_, err := fixture.Put(ctx)
+
+		assert.Equal(t, errors.New(requiresEntityMsg), err)
+	})
+	t.Run("it writes the table and index keys", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		expectedItem := map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "abc"},
+			"category": &types.AttributeValueMemberS{Value: "shoes"},
+			"name":     &types.AttributeValueMemberS{Value: "Air Max"},
+			"pk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"sk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"GSI1pk":   &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+			"GSI1sk":   &types.AttributeValueMemberS{Value: "NAME#AIR MAX"},
+		}
+
+		client.On("PutItem", ctx, "my-table",
+			dynamoputitem.NewOptions(dynamoputitem.WithItem(expectedItem))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		actual, err := fixture.Put(ctx, putitem.WithEntity(&testEntity{
+			ID:       "abc",
+			Category: "shoes",
+			Name:     "Air Max",
+		}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, expectedItem, actual.Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it writes the entity type and prefixes the sort keys", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withEntityType("product"))
+
+		expectedItem := map[string]types.AttributeValue{
+			"id":                &types.AttributeValueMemberS{Value: "abc"},
+			"category":          &types.AttributeValueMemberS{Value: "shoes"},
+			"name":              &types.AttributeValueMemberS{Value: "Air Max"},
+			EntityTypeAttribute: &types.AttributeValueMemberS{Value: "product"},
+			"pk":                &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"sk":                &types.AttributeValueMemberS{Value: "PRODUCT#ID#ABC"},
+			"GSI1pk":            &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+			"GSI1sk":            &types.AttributeValueMemberS{Value: "PRODUCT#NAME#AIR MAX"},
+		}
+
+		client.On("PutItem", ctx, "my-table",
+			dynamoputitem.NewOptions(dynamoputitem.WithItem(expectedItem))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testEntity{
+			ID:       "abc",
+			Category: "shoes",
+			Name:     "Air Max",
+		}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestRepoImpl_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it requires a key", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{})
+
+		_, err := fixture.Get(ctx)
+
+		assert.Equal(t, errors.New(requiresKeyMsg), err)
+	})
+	t.Run("it returns ErrNotFound when the item does not exist", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		client.On("GetItem", ctx, "my-table",
+			dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{}, nil)
+
+		_, err := fixture.Get(ctx, getitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Equal(t, ErrNotFound, err)
+	})
+	t.Run("it unmarshals the entity", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		client.On("GetItem", ctx, "my-table",
+			dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{Item: map[string]types.AttributeValue{
+				"id":   &types.AttributeValueMemberS{Value: "abc"},
+				"name": &types.AttributeValueMemberS{Value: "Air Max"},
+			}}, nil)
+
+		actual := &testEntity{}
+		_, err := fixture.Get(ctx,
+			getitem.WithKey(map[string]interface{}{"id": "abc"}),
+			getitem.WithEntity(actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &testEntity{ID: "abc", Name: "Air Max"}, actual)
+	})
+}
+
+func TestRepoImpl_Query(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it queries an index mapping with a sort prefix", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("CATEGORY#SHOES")).
+			And(expression.Key("GSI1sk").BeginsWith("NAME#AIR"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"),
+				dynamoquery.WithLimit(10))).
+			Return(&dynamoquery.Result{
+				Items: []map[string]types.AttributeValue{{
+					"id": &types.AttributeValueMemberS{Value: "abc"},
+				}},
+				LastEvaluatedKey: key1(),
+			}, nil)
+
+		var actual []*testEntity
+		result, err := fixture.Query(ctx,
+			query.WithMappingName("queryByCategory"),
+			query.WithPartitionKey(map[string]interface{}{"category": "shoes"}),
+			query.WithSortKey(map[string]interface{}{"name": "air"}),
+			query.WithLimit(10),
+			query.WithEntities(&actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []*testEntity{{ID: "abc"}}, actual)
+		assert.Equal(t, key1(), result.Cursor)
+		client.AssertExpectations(t)
+	})
+	t.Run("it restricts queries to the entity type", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withEntityType("product"))
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("CATEGORY#SHOES")).
+			And(expression.Key("GSI1sk").BeginsWith("PRODUCT#"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"))).
+			Return(&dynamoquery.Result{}, nil)
+
+		_, err := fixture.Query(ctx,
+			query.WithMappingName("queryByCategory"),
+			query.WithPartitionKey(map[string]interface{}{"category": "shoes"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it decodes every entity type of a polymorphic query", func(t *testing.T) {
+		type review struct {
+			Rating int `dynamodbav:"rating"`
+		}
+
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withEntityType("product"))
+
+		registry := NewRegistry()
+		_ = registry.Register("product", testEntity{})
+		_ = registry.Register("review", &review{})
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("CATEGORY#SHOES"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"))).
+			Return(&dynamoquery.Result{
+				Items: []map[string]types.AttributeValue{{
+					EntityTypeAttribute: &types.AttributeValueMemberS{Value: "product"},
+					"id":                &types.AttributeValueMemberS{Value: "abc"},
+				}, {
+					EntityTypeAttribute: &types.AttributeValueMemberS{Value: "review"},
+					"rating":            &types.AttributeValueMemberN{Value: "5"},
+				}},
+			}, nil)
+
+		result, err := fixture.Query(ctx,
+			query.WithMappingName("queryByCategory"),
+			query.WithPartitionKey(map[string]interface{}{"category": "shoes"}),
+			query.AsPolymorphic(registry))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{&testEntity{ID: "abc"}, &review{Rating: 5}}, result.Entities)
+	})
+	t.Run("it does not allow a sort key on polymorphic queries", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{}, withEntityType("product"))
+
+		_, err := fixture.Query(ctx,
+			query.WithMappingName("queryByCategory"),
+			query.WithPartitionKey(map[string]interface{}{"category": "shoes"}),
+			query.WithSortKey(map[string]interface{}{"name": "air"}),
+			query.AsPolymorphic(NewRegistry()))
+
+		assert.Equal(t, errors.New(polymorphicSortKeyMsg), err)
+	})
+	t.Run("it returns an error for unknown mappings", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{})
+
+		_, err := fixture.Query(ctx, query.WithMappingName("queryByBrand"))
+
+		assert.Equal(t, errors.New("mapping queryByBrand was not found on repository MyEntity"), err)
+	})
+}
+
+func TestRepoImpl_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it deletes by the table key", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		client.On("DeleteItem", ctx, "my-table",
+			dynamodeleteitem.NewOptions(dynamodeleteitem.WithKey(key1()))).
+			Return(&dynamodeleteitem.Result{}, nil)
+
+		_, err := fixture.Delete(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
+
+func key1() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"sk": &types.AttributeValueMemberS{Value: "ID#ABC"},
+	}
+}
+
+func withEntityType(entityType string) func(*Config) {
+	return func(cfg *Config) {
+		cfg.EntityType = entityType
+	}
+}
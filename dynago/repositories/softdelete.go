@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/restoreitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	dynamoupdateitem "github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// DeletedAttribute is set to true on items deleted by a repository with SoftDelete
+	DeletedAttribute = "_deleted"
+
+	// DeletedAtAttribute holds the time an item was soft deleted
+	DeletedAtAttribute = "_deleted_at"
+)
+
+// Restore
+//
+// Brings back an item deleted by a repository with SoftDelete. The item is unmarshaled into the entity and written
+// again by Put, with its index keys, version, timestamps and put hooks
+func (r *repoImpl) Restore(ctx context.Context, restoreOptions ...func(*restoreitem.Options)) (*restoreitem.Result, error) {
+	options := restoreitem.NewOptions(restoreOptions...)
+
+	if !r.softDelete {
+		return nil, fmt.Errorf("repository %s does not have SoftDelete enabled", r.name)
+	}
+
+	if options.Key == nil {
+		return nil, errors.New(requiresKeyMsg)
+	}
+
+	if options.Entity == nil {
+		return nil, errors.New(requiresEntityMsg)
+	}
+
+	key, err := r.buildTableKey(ctx, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := r.getExisting(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDeleted(item) {
+		return nil, ErrNotFound
+	}
+
+	if err := attributevalue.UnmarshalMap(item, options.Entity); err != nil {
+		return nil, err
+	}
+
+	// the item may have been restored or written again since it was read
+	stillDeleted := expression.Name(DeletedAttribute).Equal(expression.Value(true))
+
+	result, err := r.Put(ctx,
+		putitem.WithEntity(options.Entity),
+		putitem.WithFilterConditionBuilder(&stillDeleted))
+	if err != nil {
+		if isConditionFailure(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	err = r.runAfterLoad(ctx, []map[string]types.AttributeValue{result.Item}, func(int) interface{} {
+		return options.Entity
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &restoreitem.Result{Item: result.Item}, nil
+}
+
+// Purge
+//
+// Removes an item for good, including items deleted by a repository with SoftDelete
+func (r *repoImpl) Purge(ctx context.Context, deleteOptions ...func(*deleteitem.Options)) (*deleteitem.Result, error) {
+	options := deleteitem.NewOptions(deleteOptions...)
+
+	if options.Key == nil {
+		return nil, errors.New(requiresKeyMsg)
+	}
+
+	key, err := r.buildTableKey(ctx, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.deleteItem(ctx, key, options.FilterConditionBuilder, options.Events, false); err != nil {
+		return nil, err
+	}
+
+	return &deleteitem.Result{}, nil
+}
+
+// softDeleteItem marks the item as deleted and removes its index key attributes so it drops out of every index.
+// The outbox items are written in the same transaction
+func (r *repoImpl) softDeleteItem(ctx context.Context, key map[string]types.AttributeValue, condition *expression.ConditionBuilder, outboxItems []map[string]types.AttributeValue) error {
+	update := expression.Set(expression.Name(DeletedAttribute), expression.Value(true)).
+		Set(expression.Name(DeletedAtAttribute), expression.Value(r.clock.Now()))
+
+	for _, attribute := range r.indexKeyAttributeNames() {
+		update = update.Remove(expression.Name(attribute))
+	}
+
+	live := expression.AttributeExists(expression.Name(r.tableKeys.partition)).
+		And(expression.AttributeNotExists(expression.Name(DeletedAttribute)))
+	if condition != nil {
+		live = live.And(*condition)
+	}
+
+	var err error
+	if len(outboxItems) != 0 {
+		transaction := &transactItems{}
+		transaction.add(transactwriteitems.WithUpdate(r.tableName, key, &update, &live), "", "")
+		transaction.addOutboxItems(r.tableName, r.tableKeys, outboxItems)
+
+		_, err = r.client.TransactWriteItems(ctx, transaction.options...)
+		if err != nil && !isConditionFailure(err) {
+			return transaction.err(err, nil)
+		}
+	} else {
+		_, err = r.client.UpdateItem(ctx, r.tableName,
+			dynamoupdateitem.WithKey(key),
+			dynamoupdateitem.WithUpdateBuilder(&update),
+			dynamoupdateitem.WithFilterConditionBuilder(&live))
+	}
+
+	if err != nil {
+		if condition == nil && isConditionFailure(err) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// indexKeyAttributeNames returns the sorted key attribute names of every index assigned to an index mapping
+func (r *repoImpl) indexKeyAttributeNames() []string {
+	seen := make(map[string]bool)
+	var out []string
+
+	for _, index := range r.schemaMapping.Indexes {
+		attrs, ok := r.indexKeys[index.Name]
+		if !ok {
+			continue
+		}
+
+		for _, attribute := range []string{attrs.partition, attrs.sort} {
+			if attribute != "" && !seen[attribute] {
+				seen[attribute] = true
+				out = append(out, attribute)
+			}
+		}
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// notDeletedCondition adds the soft delete filter to condition when the repository uses SoftDelete
+func (r *repoImpl) notDeletedCondition(condition *expression.ConditionBuilder, includeDeleted bool) *expression.ConditionBuilder {
+	if !r.softDelete || includeDeleted {
+		return condition
+	}
+
+	notDeleted := expression.AttributeNotExists(expression.Name(DeletedAttribute))
+	if condition != nil {
+		notDeleted = notDeleted.And(*condition)
+	}
+
+	return &notDeleted
+}
+
+func isDeleted(item map[string]types.AttributeValue) bool {
+	deleted, ok := item[DeletedAttribute].(*types.AttributeValueMemberBOOL)
+
+	return ok && deleted.Value
+}
+
+func withoutDeleted(items []map[string]types.AttributeValue) []map[string]types.AttributeValue {
+	out := make([]map[string]types.AttributeValue, 0, len(items))
+	for _, item := range items {
+		if !isDeleted(item) {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,335 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/restoreitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamodeleteitem "github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	dynamoupdateitem "github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func withSoftDelete() func(*Config) {
+	return func(cfg *Config) {
+		cfg.Clock = &fixedClock{now: testNow}
+		cfg.SoftDelete = true
+	}
+}
+
+func tombstoneTestItem() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":               &types.AttributeValueMemberS{Value: "abc"},
+		"category":         &types.AttributeValueMemberS{Value: "shoes"},
+		"name":             &types.AttributeValueMemberS{Value: "Air Max"},
+		"pk":               &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"sk":               &types.AttributeValueMemberS{Value: "ID#ABC"},
+		DeletedAttribute:   &types.AttributeValueMemberBOOL{Value: true},
+		DeletedAtAttribute: &types.AttributeValueMemberS{Value: "2021-12-29T18:00:00Z"},
+	}
+}
+
+func TestRepoImpl_Put_SoftDelete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it writes a soft deleted entity again as a live item", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete(), withTimestamps("created_at", ""))
+
+		client.On("UpdateItem", ctx, "my-table", mock.Anything).Return(&dynamoupdateitem.Result{}, nil)
+		expectExisting(client, ctx, tombstoneTestItem())
+
+		stored := &dynamogetitem.Result{}
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Run(func(args mock.Arguments) {
+				stored.Item = args.Get(2).(*dynamoputitem.Options).Item
+			}).
+			Return(&dynamoputitem.Result{}, nil)
+		client.On("GetItem", ctx, "my-table", dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(stored, nil)
+
+		_, err := fixture.Delete(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+		assert.Nil(t, err)
+
+		_, err = fixture.Put(ctx, putitem.WithEntity(&tombstonedTestEntity{
+			testEntity: testShoe(),
+			Deleted:    true,
+		}))
+		assert.Nil(t, err)
+
+		entity := &testEntity{}
+		_, err = fixture.Get(ctx, getitem.WithKey(map[string]interface{}{"id": "abc"}), getitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testShoe(), *entity)
+		assert.NotContains(t, stored.Item, DeletedAttribute)
+		assert.NotContains(t, stored.Item, DeletedAtAttribute)
+	})
+}
+
+// tombstonedTestEntity is an entity read with WithIncludeDeleted that still carries the tombstone
+type tombstonedTestEntity struct {
+	testEntity
+	Deleted bool `dynamodbav:"_deleted"`
+}
+
+func TestRepoImpl_Delete_SoftDelete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it marks the item deleted and removes its index keys", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		update := expression.Set(expression.Name(DeletedAttribute), expression.Value(true)).
+			Set(expression.Name(DeletedAtAttribute), expression.Value(testNow)).
+			Remove(expression.Name("GSI1pk")).
+			Remove(expression.Name("GSI1sk"))
+
+		live := expression.AttributeExists(expression.Name("pk")).
+			And(expression.AttributeNotExists(expression.Name(DeletedAttribute)))
+
+		client.On("UpdateItem", ctx, "my-table", dynamoupdateitem.NewOptions(
+			dynamoupdateitem.WithKey(key1()),
+			dynamoupdateitem.WithUpdateBuilder(&update),
+			dynamoupdateitem.WithFilterConditionBuilder(&live))).
+			Return(&dynamoupdateitem.Result{}, nil)
+
+		_, err := fixture.Delete(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns ErrNotFound when the item is missing or already deleted", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		client.On("UpdateItem", ctx, "my-table", mock.Anything).
+			Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")})
+
+		_, err := fixture.Delete(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestRepoImpl_Get_SoftDelete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it does not return tombstones unless asked to", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		client.On("GetItem", ctx, "my-table", dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{Item: tombstoneTestItem()}, nil)
+
+		_, err := fixture.Get(ctx, getitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Equal(t, ErrNotFound, err)
+
+		actual, err := fixture.Get(ctx,
+			getitem.WithKey(map[string]interface{}{"id": "abc"}),
+			getitem.WithIncludeDeleted(true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, tombstoneTestItem(), actual.Item)
+	})
+}
+
+func TestRepoImpl_Query_SoftDelete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it filters out tombstones", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		var actualOptions *dynamoquery.Options
+		client.On("Query", ctx, "my-table", mockCapture(&actualOptions)).
+			Return(&dynamoquery.Result{}, nil)
+
+		_, err := fixture.Query(ctx, query.WithPartitionKey(map[string]interface{}{"id": "abc"}))
+
+		notDeleted := expression.AttributeNotExists(expression.Name(DeletedAttribute))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &notDeleted, actualOptions.FilterConditionBuilder)
+	})
+}
+
+func TestRepoImpl_Restore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it requires soft delete", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{})
+
+		_, err := fixture.Restore(ctx, restoreitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.EqualError(t, err, "repository MyEntity does not have SoftDelete enabled")
+	})
+	t.Run("it writes the item again with its index keys", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		expectExisting(client, ctx, tombstoneTestItem())
+
+		stillDeleted := expression.Name(DeletedAttribute).Equal(expression.Value(true))
+
+		client.On("PutItem", ctx, "my-table", dynamoputitem.NewOptions(
+			dynamoputitem.WithItem(map[string]types.AttributeValue{
+				"id":       &types.AttributeValueMemberS{Value: "abc"},
+				"category": &types.AttributeValueMemberS{Value: "shoes"},
+				"name":     &types.AttributeValueMemberS{Value: "Air Max"},
+				"pk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+				"sk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+				"GSI1pk":   &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+				"GSI1sk":   &types.AttributeValueMemberS{Value: "NAME#AIR MAX"},
+			}),
+			dynamoputitem.WithFilterConditionBuilder(&stillDeleted))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testEntity{}
+
+		_, err := fixture.Restore(ctx,
+			restoreitem.WithKey(map[string]interface{}{"id": "abc"}),
+			restoreitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testShoe(), *entity)
+		client.AssertExpectations(t)
+	})
+	t.Run("it requires an entity", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{}, withSoftDelete())
+
+		_, err := fixture.Restore(ctx, restoreitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Equal(t, errors.New(requiresEntityMsg), err)
+	})
+	t.Run("it writes the next version of the deleted item", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		deleted := tombstoneTestItem()
+		deleted["version"] = &types.AttributeValueMemberN{Value: "2"}
+		expectExisting(client, ctx, deleted)
+
+		var actualOptions *dynamoputitem.Options
+		client.On("PutItem", ctx, "my-table", mockCapture(&actualOptions)).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testVersionedEntity{}
+
+		_, err := fixture.Restore(ctx,
+			restoreitem.WithKey(map[string]interface{}{"id": "abc"}),
+			restoreitem.WithEntity(entity))
+
+		expected := expression.Name("version").Equal(expression.Value(int64(2))).
+			And(expression.Name(DeletedAttribute).Equal(expression.Value(true)))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &types.AttributeValueMemberN{Value: "3"}, actualOptions.Item["version"])
+		assert.Equal(t, &expected, actualOptions.FilterConditionBuilder)
+		assert.Equal(t, int64(3), entity.Version)
+		assert.NotContains(t, actualOptions.Item, DeletedAttribute)
+	})
+	t.Run("it stamps the updated field and keeps the created field", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete(), withTimestamps("created_at", "updated_at"))
+
+		deleted := tombstoneTestItem()
+		deleted["created_at"] = &types.AttributeValueMemberS{Value: "2021-12-29T17:00:00Z"}
+		deleted["updated_at"] = &types.AttributeValueMemberS{Value: "2021-12-29T17:30:00Z"}
+		expectExisting(client, ctx, deleted)
+
+		var actualOptions *dynamoputitem.Options
+		client.On("PutItem", ctx, "my-table", mockCapture(&actualOptions)).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testStampedEntity{}
+
+		actual, err := fixture.Restore(ctx,
+			restoreitem.WithKey(map[string]interface{}{"id": "abc"}),
+			restoreitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, actualOptions.Item, actual.Item)
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "2021-12-29T17:00:00Z"}, actualOptions.Item["created_at"])
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "2021-12-29T18:00:00Z"}, actualOptions.Item["updated_at"])
+		assert.Equal(t, testNow, entity.UpdatedAt)
+	})
+	t.Run("it runs the put hooks with the deleted item as the old image", func(t *testing.T) {
+		client := &dynamo.Mock{}
+
+		var events []*HookEvent
+		hooks := NewHooks()
+		for _, hookType := range []HookType{HookBeforePut, HookAfterPut} {
+			_ = hooks.Register(hookType, func(ctx context.Context, event *HookEvent) error {
+				events = append(events, event)
+
+				return nil
+			})
+		}
+
+		fixture := newTestRepo(t, client, withSoftDelete(), withHooks(hooks))
+
+		expectExisting(client, ctx, tombstoneTestItem())
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testEntity{}
+
+		_, err := fixture.Restore(ctx,
+			restoreitem.WithKey(map[string]interface{}{"id": "abc"}),
+			restoreitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, HookBeforePut, events[0].Type)
+		assert.Equal(t, HookAfterPut, events[1].Type)
+		assert.Equal(t, tombstoneTestItem(), events[1].OldImage)
+		assert.Same(t, entity, events[1].Entity)
+	})
+	t.Run("it returns ErrNotFound when the item was written since it was read", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		expectExisting(client, ctx, tombstoneTestItem())
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")})
+
+		_, err := fixture.Restore(ctx,
+			restoreitem.WithKey(map[string]interface{}{"id": "abc"}),
+			restoreitem.WithEntity(&testEntity{}))
+
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestRepoImpl_Purge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it removes the item", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withSoftDelete())
+
+		client.On("DeleteItem", ctx, "my-table", dynamodeleteitem.NewOptions(dynamodeleteitem.WithKey(key1()))).
+			Return(&dynamodeleteitem.Result{}, nil)
+
+		_, err := fixture.Purge(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
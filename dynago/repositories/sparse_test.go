@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func withIndexConditions(conditions ...*entities.Condition) func(*Config) {
+	return func(cfg *Config) {
+		cfg.IndexMappings[0].Conditions = conditions
+	}
+}
+
+func TestNew_IndexConditions(t *testing.T) {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "table",
+		Fields:      []string{"id"},
+	})
+
+	queryByCategoryMapping, _ := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     "queryByCategory",
+		PartitionFields: []string{"category"},
+		SortFields:      []string{"name"},
+	})
+
+	t.Run("it validates the conditions", func(t *testing.T) {
+		_, err := New(&Config{
+			Name:         "MyEntity",
+			Client:       &dynamo.Mock{},
+			TableDesc:    newTestTableDesc(),
+			TableMapping: tableMapping,
+			IndexMappings: []*mappings.Index{{
+				Mapping: queryByCategoryMapping,
+				Conditions: []*entities.Condition{{
+					Field:    "category",
+					Operator: entities.ConditionOperator_In,
+				}},
+			}},
+		})
+
+		assert.EqualError(t, err, "index mapping queryByCategory: condition IN on field category requires at least one value")
+	})
+}
+
+func TestRepoImpl_Put_SparseIndex(t *testing.T) {
+	ctx := context.Background()
+
+	onlyShoes := withIndexConditions(&entities.Condition{
+		Field:    "category",
+		Operator: entities.ConditionOperator_Equal,
+		Values:   []string{"shoes"},
+	})
+
+	t.Run("it writes the index keys when the conditions match", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, onlyShoes)
+
+		client.On("PutItem", ctx, "my-table", dynamoputitem.NewOptions(dynamoputitem.WithItem(map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "abc"},
+			"category": &types.AttributeValueMemberS{Value: "shoes"},
+			"name":     &types.AttributeValueMemberS{Value: "Air Max"},
+			"pk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"sk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"GSI1pk":   &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+			"GSI1sk":   &types.AttributeValueMemberS{Value: "NAME#AIR MAX"},
+		}))).Return(&dynamoputitem.Result{}, nil)
+
+		shoe := testShoe()
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&shoe))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it leaves the index keys out when they do not", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, onlyShoes)
+
+		client.On("PutItem", ctx, "my-table", dynamoputitem.NewOptions(dynamoputitem.WithItem(map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "abc"},
+			"category": &types.AttributeValueMemberS{Value: "hats"},
+			"name":     &types.AttributeValueMemberS{Value: "Cap"},
+			"pk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"sk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+		}))).Return(&dynamoputitem.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testEntity{ID: "abc", Category: "hats", Name: "Cap"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,235 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Clock
+//
+// Supplies the time used for timestamps and TTL expiry, replace it to control time in tests
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// TimestampConfig
+//
+// Attribute names the repository stamps with the time of the write, either can be empty
+type TimestampConfig struct {
+	// CreatedField is only written when the item is inserted
+	CreatedField string
+
+	// UpdatedField is written on every Put
+	UpdatedField string
+}
+
+// TTLConfig
+//
+// Writes an expiry in epoch seconds for dynamo's time to live. TTL must also be enabled on the table
+// for Attribute, see dynamo.Interface.UpdateTimeToLive
+type TTLConfig struct {
+	// Attribute receives the expiry in epoch seconds
+	Attribute string
+
+	// Duration is added to the time of the write, or to the value of Field when it is set
+	Duration time.Duration
+
+	// Field is an attribute holding the time the expiry is computed from, optional.
+	// Items without a value for Field do not expire
+	Field string
+}
+
+func validateTimestamps(cfg *TimestampConfig) error {
+	if cfg == nil {
+		return nil
+	}
+
+	if cfg.CreatedField == "" && cfg.UpdatedField == "" {
+		return errors.New("repositories.Config.Timestamps requires a CreatedField or an UpdatedField")
+	}
+
+	if cfg.CreatedField == cfg.UpdatedField {
+		return errors.New("repositories.Config.Timestamps requires different created and updated fields")
+	}
+
+	return nil
+}
+
+func validateTTL(cfg *TTLConfig) error {
+	if cfg == nil {
+		return nil
+	}
+
+	if cfg.Attribute == "" {
+		return errors.New("repositories.Config.TTL.Attribute is required")
+	}
+
+	if cfg.Duration <= 0 && cfg.Field == "" {
+		return errors.New("repositories.Config.TTL requires a Duration or a Field")
+	}
+
+	return nil
+}
+
+// expiry returns the epoch seconds the item expires at, false when the item should not expire
+func (c *TTLConfig) expiry(item map[string]types.AttributeValue, now time.Time) (int64, bool, error) {
+	base := now
+
+	if c.Field != "" {
+		value, ok := item[c.Field]
+		if !ok {
+			return 0, false, nil
+		}
+
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			parsed, err := time.Parse(time.RFC3339Nano, v.Value)
+			if err != nil {
+				return 0, false, fmt.Errorf("ttl field %s: %s", c.Field, err)
+			}
+
+			if parsed.IsZero() {
+				return 0, false, nil
+			}
+
+			base = parsed
+		case *types.AttributeValueMemberN:
+			seconds, err := strconv.ParseInt(v.Value, 10, 64)
+			if err != nil {
+				return 0, false, fmt.Errorf("ttl field %s: %s", c.Field, err)
+			}
+
+			base = time.Unix(seconds, 0)
+		default:
+			return 0, false, nil
+		}
+	}
+
+	return base.Add(c.Duration).Unix(), true, nil
+}
+
+// stampItem writes the timestamps and the TTL expiry to the item. The created timestamp is carried over from
+// existing, the stored item, so it is only set to the time of the write on insert
+func (r *repoImpl) stampItem(item, existing map[string]types.AttributeValue) error {
+	now := r.clock.Now()
+
+	if r.ttl != nil {
+		expiresAt, ok, err := r.ttl.expiry(item, now)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			item[r.ttl.Attribute] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt, 10)}
+		}
+	}
+
+	if r.timestamps == nil {
+		return nil
+	}
+
+	stamp, err := attributevalue.Marshal(now)
+	if err != nil {
+		return err
+	}
+
+	if r.timestamps.UpdatedField != "" {
+		item[r.timestamps.UpdatedField] = stamp
+	}
+
+	if r.timestamps.CreatedField != "" {
+		item[r.timestamps.CreatedField] = stamp
+		if created, ok := existing[r.timestamps.CreatedField]; ok {
+			item[r.timestamps.CreatedField] = created
+		}
+	}
+
+	return nil
+}
+
+// stampsCreated reports whether Put has to read the stored item for its created timestamp, the write is guarded
+// by the timestamp read
+func (r *repoImpl) stampsCreated() bool {
+	return r.timestamps != nil && r.timestamps.CreatedField != ""
+}
+
+// createdGuard adds to condition that the created timestamp is still the one read with existing, so a Put does
+// not overwrite the timestamp of an insert that landed after the read
+func (r *repoImpl) createdGuard(existing map[string]types.AttributeValue, condition *expression.ConditionBuilder) *expression.ConditionBuilder {
+	if !r.stampsCreated() {
+		return condition
+	}
+
+	guard := expression.AttributeNotExists(expression.Name(r.tableKeys.partition))
+	if existing != nil {
+		guard = expression.AttributeNotExists(expression.Name(r.timestamps.CreatedField))
+		if created, ok := existing[r.timestamps.CreatedField]; ok {
+			guard = expression.Name(r.timestamps.CreatedField).Equal(expression.Value(rawValue{created}))
+		}
+	}
+
+	if condition != nil {
+		guard = guard.And(*condition)
+	}
+
+	return &guard
+}
+
+// createdChanged reports whether the stored item was inserted or given another created timestamp since existing
+// was read
+func (r *repoImpl) createdChanged(existing, stored map[string]types.AttributeValue) bool {
+	if (existing == nil) != (stored == nil) {
+		return true
+	}
+
+	return !reflect.DeepEqual(existing[r.timestamps.CreatedField], stored[r.timestamps.CreatedField])
+}
+
+// stampedAttributes returns the attributes the repository set on the written item so they can be
+// unmarshalled onto the entity
+func (r *repoImpl) stampedAttributes(written map[string]types.AttributeValue, versionField string) map[string]types.AttributeValue {
+	var names []string
+
+	if versionField != "" {
+		names = append(names, versionField)
+	}
+
+	if r.timestamps != nil {
+		names = append(names, r.timestamps.CreatedField, r.timestamps.UpdatedField)
+	}
+
+	if r.ttl != nil {
+		names = append(names, r.ttl.Attribute)
+	}
+
+	out := make(map[string]types.AttributeValue)
+	for _, name := range names {
+		if value, ok := written[name]; ok && name != "" {
+			out[name] = value
+		}
+	}
+
+	return out
+}
+
+func (r *repoImpl) tableKeyOf(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	key := map[string]types.AttributeValue{r.tableKeys.partition: item[r.tableKeys.partition]}
+	if r.tableKeys.sort != "" {
+		key[r.tableKeys.sort] = item[r.tableKeys.sort]
+	}
+
+	return key
+}
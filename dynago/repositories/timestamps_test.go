@@ -0,0 +1,258 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+type testStampedEntity struct {
+	testEntity
+	CreatedAt time.Time `dynamodbav:"created_at"`
+	UpdatedAt time.Time `dynamodbav:"updated_at"`
+	ExpiresAt int64     `dynamodbav:"expires_at,omitempty"`
+}
+
+var testNow = time.Date(2021, 12, 29, 18, 0, 0, 0, time.UTC)
+
+func withTimestamps(created, updated string) func(*Config) {
+	return func(cfg *Config) {
+		cfg.Clock = &fixedClock{now: testNow}
+		cfg.Timestamps = &TimestampConfig{
+			CreatedField: created,
+			UpdatedField: updated,
+		}
+	}
+}
+
+func withTTL(ttl *TTLConfig) func(*Config) {
+	return func(cfg *Config) {
+		cfg.Clock = &fixedClock{now: testNow}
+		cfg.TTL = ttl
+	}
+}
+
+func TestNew_TTL(t *testing.T) {
+	t.Run("it requires a Duration or a Field", func(t *testing.T) {
+		err := validateTTL(&TTLConfig{Attribute: "expires_at"})
+
+		assert.Equal(t, errors.New("repositories.Config.TTL requires a Duration or a Field"), err)
+	})
+}
+
+func TestRepoImpl_Put_Timestamps(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it stamps the updated field and the expiry", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client,
+			withTimestamps("", "updated_at"),
+			withTTL(&TTLConfig{Attribute: "expires_at", Duration: time.Hour}))
+
+		expectedItem := map[string]types.AttributeValue{
+			"id":         &types.AttributeValueMemberS{Value: "abc"},
+			"category":   &types.AttributeValueMemberS{Value: "shoes"},
+			"name":       &types.AttributeValueMemberS{Value: "Air Max"},
+			"created_at": &types.AttributeValueMemberS{Value: "0001-01-01T00:00:00Z"},
+			"updated_at": &types.AttributeValueMemberS{Value: "2021-12-29T18:00:00Z"},
+			"expires_at": &types.AttributeValueMemberN{Value: "1640804400"},
+			"pk":         &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"sk":         &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"GSI1pk":     &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+			"GSI1sk":     &types.AttributeValueMemberS{Value: "NAME#AIR MAX"},
+		}
+
+		client.On("PutItem", ctx, "my-table",
+			dynamoputitem.NewOptions(dynamoputitem.WithItem(expectedItem))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testStampedEntity{testEntity: testShoe()}
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testNow, entity.UpdatedAt)
+		assert.Equal(t, testNow.Add(time.Hour).Unix(), entity.ExpiresAt)
+		client.AssertExpectations(t)
+	})
+	t.Run("it sets the created field on insert", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withTimestamps("created_at", "updated_at"))
+
+		expectExisting(client, ctx, nil)
+
+		var actualOptions *dynamoputitem.Options
+		client.On("PutItem", ctx, "my-table", mockCapture(&actualOptions)).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testStampedEntity{testEntity: testShoe()}
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "2021-12-29T18:00:00Z"}, actualOptions.Item["created_at"])
+		assert.Equal(t, testNow, entity.CreatedAt)
+		assert.Equal(t, testNow, entity.UpdatedAt)
+	})
+	t.Run("it keeps the created field of the stored item", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withTimestamps("created_at", "updated_at"))
+
+		created := testNow.Add(-time.Hour)
+
+		expectExisting(client, ctx, map[string]types.AttributeValue{
+			"id":         &types.AttributeValueMemberS{Value: "abc"},
+			"created_at": &types.AttributeValueMemberS{Value: created.Format(time.RFC3339Nano)},
+		})
+
+		var actualOptions *dynamoputitem.Options
+		client.On("PutItem", ctx, "my-table", mockCapture(&actualOptions)).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testStampedEntity{testEntity: testShoe()}
+
+		actual, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, actualOptions.Item, actual.Item)
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "2021-12-29T17:00:00Z"}, actualOptions.Item["created_at"])
+		assert.Equal(t, created, entity.CreatedAt)
+		assert.Equal(t, testNow, entity.UpdatedAt)
+	})
+	t.Run("it replaces the stored item so cleared fields are removed", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withTimestamps("created_at", "updated_at"))
+
+		expectExisting(client, ctx, map[string]types.AttributeValue{
+			"id":         &types.AttributeValueMemberS{Value: "abc"},
+			"created_at": &types.AttributeValueMemberS{Value: "2021-12-29T17:00:00Z"},
+			"expires_at": &types.AttributeValueMemberN{Value: "1640804400"},
+		})
+
+		expectedItem := map[string]types.AttributeValue{
+			"id":         &types.AttributeValueMemberS{Value: "abc"},
+			"category":   &types.AttributeValueMemberS{Value: "shoes"},
+			"name":       &types.AttributeValueMemberS{Value: "Air Max"},
+			"created_at": &types.AttributeValueMemberS{Value: "2021-12-29T17:00:00Z"},
+			"updated_at": &types.AttributeValueMemberS{Value: "2021-12-29T18:00:00Z"},
+			"pk":         &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"sk":         &types.AttributeValueMemberS{Value: "ID#ABC"},
+			"GSI1pk":     &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+			"GSI1sk":     &types.AttributeValueMemberS{Value: "NAME#AIR MAX"},
+		}
+
+		sameCreated := expression.Name("created_at").Equal(expression.Value(rawValue{expectedItem["created_at"]}))
+
+		client.On("PutItem", ctx, "my-table", dynamoputitem.NewOptions(
+			dynamoputitem.WithItem(expectedItem),
+			dynamoputitem.WithFilterConditionBuilder(&sameCreated))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testStampedEntity{testEntity: testShoe()}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it keeps the created field of an insert that landed after the read", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withTimestamps("created_at", "updated_at"))
+
+		created := &types.AttributeValueMemberS{Value: "2021-12-29T17:30:00Z"}
+
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{}, nil).Once()
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{Item: map[string]types.AttributeValue{
+				"id":         &types.AttributeValueMemberS{Value: "abc"},
+				"created_at": created,
+			}}, nil).Once()
+
+		notExists := expression.AttributeNotExists(expression.Name("pk"))
+		sameCreated := expression.Name("created_at").Equal(expression.Value(rawValue{created}))
+
+		client.On("PutItem", ctx, "my-table", mock.MatchedBy(func(options *dynamoputitem.Options) bool {
+			return assert.ObjectsAreEqual(&notExists, options.FilterConditionBuilder)
+		})).Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}).Once()
+
+		var actualOptions *dynamoputitem.Options
+		client.On("PutItem", ctx, "my-table", mock.MatchedBy(func(options *dynamoputitem.Options) bool {
+			actualOptions = options
+
+			return assert.ObjectsAreEqual(&sameCreated, options.FilterConditionBuilder)
+		})).Return(&dynamoputitem.Result{}, nil).Once()
+
+		entity := &testStampedEntity{testEntity: testShoe()}
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, created, actualOptions.Item["created_at"])
+		assert.Equal(t, testNow.Add(-30*time.Minute), entity.CreatedAt)
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns the condition failure when the created field did not change", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withTimestamps("created_at", "updated_at"))
+
+		expectExisting(client, ctx, nil)
+
+		failure := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		client.On("PutItem", ctx, "my-table", mock.Anything).Return(nil, failure)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testStampedEntity{testEntity: testShoe()}))
+
+		assert.Equal(t, failure, err)
+		client.AssertNumberOfCalls(t, "PutItem", 1)
+	})
+	t.Run("it computes the expiry from an entity field", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withTTL(&TTLConfig{
+			Attribute: "expires_at",
+			Field:     "updated_at",
+			Duration:  24 * time.Hour,
+		}))
+
+		var actualOptions *dynamoputitem.Options
+		client.On("PutItem", ctx, "my-table", mockCapture(&actualOptions)).
+			Return(&dynamoputitem.Result{}, nil)
+
+		updated := testNow.Add(-48 * time.Hour)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testStampedEntity{
+			testEntity: testShoe(),
+			UpdatedAt:  updated,
+		}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &types.AttributeValueMemberN{Value: "1640714400"}, actualOptions.Item["expires_at"])
+	})
+}
+
+// mockCapture matches any argument of type T and stores it in target
+func mockCapture[T any](target *T) interface{} {
+	return mock.MatchedBy(func(value T) bool {
+		*target = value
+
+		return true
+	})
+}
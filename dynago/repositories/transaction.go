@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// transactItems collects the writes of a transaction along with the unique field or outbox event each one is for,
+// so a cancellation reason can be matched to the write that caused it
+type transactItems struct {
+	options      []transactwriteitems.OptionFunc
+	uniqueFields []string
+	eventIDs     []string
+}
+
+func (t *transactItems) add(option transactwriteitems.OptionFunc, uniqueField, eventID string) {
+	t.options = append(t.options, option)
+	t.uniqueFields = append(t.uniqueFields, uniqueField)
+	t.eventIDs = append(t.eventIDs, eventID)
+}
+
+// addOutboxItems writes every outbox item, an event id can only be written once
+func (t *transactItems) addOutboxItems(tableName string, tableKeys *keyAttributes, outboxItems []map[string]types.AttributeValue) {
+	notExists := expression.AttributeNotExists(expression.Name(tableKeys.partition))
+
+	for _, outboxItem := range outboxItems {
+		t.add(transactwriteitems.WithPut(tableName, outboxItem, &notExists), "", uniqueValueString(outboxItem[outboxIDAttribute]))
+	}
+}
+
+// err returns a DuplicateValueError or a DuplicateEventError when the condition of a marker or an outbox item
+// caused the transaction to be cancelled
+func (t *transactItems) err(err error, item map[string]types.AttributeValue) error {
+	var cancelled *types.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		return err
+	}
+
+	for idx, reason := range cancelled.CancellationReasons {
+		if idx >= len(t.options) || aws.ToString(reason.Code) != conditionalCheckFailedCode {
+			continue
+		}
+
+		if t.uniqueFields[idx] != "" {
+			return &DuplicateValueError{
+				Field: t.uniqueFields[idx],
+				Value: uniqueValueString(item[t.uniqueFields[idx]]),
+			}
+		}
+
+		if t.eventIDs[idx] != "" {
+			return &DuplicateEventError{ID: t.eventIDs[idx]}
+		}
+	}
+
+	return err
+}
+
+// putTransaction writes the item with its unique value markers and outbox items in a single transaction.
+// A marker is only written when the value is new or has changed, markers of changed values are released in the same
+// transaction. existing is the stored item read before the write, the transaction fails if its unique values changed
+// since
+func (r *repoImpl) putTransaction(ctx context.Context, item, existing map[string]types.AttributeValue, condition *expression.ConditionBuilder, outboxItems []map[string]types.AttributeValue) error {
+	transaction := &transactItems{}
+	transaction.add(transactwriteitems.WithPut(r.tableName, item, r.writeGuard(existing, condition)), "", "")
+
+	notExists := expression.AttributeNotExists(expression.Name(r.tableKeys.partition))
+
+	for _, field := range r.uniqueFields {
+		newValue := uniqueValueString(item[field])
+		oldValue := uniqueValueString(existing[field])
+
+		if newValue == oldValue {
+			continue
+		}
+
+		if oldValue != "" {
+			transaction.add(transactwriteitems.WithDelete(r.tableName, r.buildUniqueKey(field, oldValue), nil), "", "")
+		}
+
+		if newValue != "" {
+			marker := r.buildUniqueKey(field, newValue)
+			marker[EntityTypeAttribute] = &types.AttributeValueMemberS{Value: UniqueEntityType}
+
+			transaction.add(transactwriteitems.WithPut(r.tableName, marker, &notExists), field, "")
+		}
+	}
+
+	transaction.addOutboxItems(r.tableName, r.tableKeys, outboxItems)
+
+	_, err := r.client.TransactWriteItems(ctx, transaction.options...)
+	if err != nil {
+		return transaction.err(err, item)
+	}
+
+	return nil
+}
+
+// deleteTransaction removes the item, releases its unique value markers and writes the outbox items in a single
+// transaction, existing is the stored item read before the delete
+func (r *repoImpl) deleteTransaction(ctx context.Context, key, existing map[string]types.AttributeValue, condition *expression.ConditionBuilder, outboxItems []map[string]types.AttributeValue) error {
+	if existing == nil && len(r.uniqueFields) != 0 && len(outboxItems) == 0 {
+		return nil
+	}
+
+	transaction := &transactItems{}
+	transaction.add(transactwriteitems.WithDelete(r.tableName, key, r.writeGuard(existing, condition)), "", "")
+
+	for _, field := range r.uniqueFields {
+		if value := uniqueValueString(existing[field]); value != "" {
+			transaction.add(transactwriteitems.WithDelete(r.tableName, r.buildUniqueKey(field, value), nil), "", "")
+		}
+	}
+
+	transaction.addOutboxItems(r.tableName, r.tableKeys, outboxItems)
+
+	_, err := r.client.TransactWriteItems(ctx, transaction.options...)
+	if err != nil {
+		return transaction.err(err, existing)
+	}
+
+	return nil
+}
+
+// writeGuard adds the unique guard to condition when the repository has unique fields
+func (r *repoImpl) writeGuard(existing map[string]types.AttributeValue, condition *expression.ConditionBuilder) *expression.ConditionBuilder {
+	if len(r.uniqueFields) == 0 {
+		return condition
+	}
+
+	guard := r.uniqueGuard(existing, condition)
+
+	return &guard
+}
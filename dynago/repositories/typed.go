@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getcollection"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/getitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/restoreitem"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Key holds the values of a mapping's fields, map[fieldName]value
+type Key map[string]interface{}
+
+// Cursor is returned by Query when more results are available
+type Cursor map[string]types.AttributeValue
+
+// Repository
+//
+// A type safe wrapper around Interface. T is the entity type, either a struct or a pointer to a struct
+type Repository[T any] struct {
+	repo Interface
+}
+
+func NewRepository[T any](repo Interface) (*Repository[T], error) {
+	if repo == nil {
+		return nil, errors.New("repositories.NewRepository requires a repository")
+	}
+
+	return &Repository[T]{repo: repo}, nil
+}
+
+// Put writes the entity
+func (r *Repository[T]) Put(ctx context.Context, entity T) error {
+	_, err := r.repo.Put(ctx, putitem.WithEntity(entity))
+
+	return err
+}
+
+// Get loads the entity for the table mapping key, ErrNotFound is returned when it does not exist
+func (r *Repository[T]) Get(ctx context.Context, key Key) (T, error) {
+	var out T
+
+	_, err := r.repo.Get(ctx,
+		getitem.WithKey(key),
+		getitem.WithEntity(&out))
+	if err != nil {
+		var empty T
+
+		return empty, err
+	}
+
+	return out, nil
+}
+
+// Query returns a page of entities for the mapping and the Cursor to the next page, the Cursor is nil on the last page.
+// The partition and sort keys are set with query.WithPartitionKey and query.WithSortKey
+func (r *Repository[T]) Query(ctx context.Context, mappingName string, queryOptions ...func(*query.Options)) ([]T, Cursor, error) {
+	var out []T
+
+	queryOptions = append(queryOptions,
+		query.WithMappingName(mappingName),
+		query.WithEntities(&out))
+
+	result, err := r.repo.Query(ctx, queryOptions...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(result.Cursor) == 0 {
+		return out, nil, nil
+	}
+
+	return out, result.Cursor, nil
+}
+
+// Delete removes the entity for the table mapping key
+func (r *Repository[T]) Delete(ctx context.Context, key Key) error {
+	_, err := r.repo.Delete(ctx, deleteitem.WithKey(key))
+
+	return err
+}
+
+// GetCollection loads the entity with the children of the named relationships, or all relationships when none are named
+func (r *Repository[T]) GetCollection(ctx context.Context, key Key, relationships ...string) (T, error) {
+	var out T
+
+	// children are appended to the parent struct so a pointer type needs a struct to point to
+	var entity interface{} = &out
+	if value := reflect.ValueOf(&out).Elem(); value.Kind() == reflect.Ptr {
+		value.Set(reflect.New(value.Type().Elem()))
+		entity = value.Interface()
+	}
+
+	_, err := r.repo.GetCollection(ctx,
+		getcollection.WithKey(key),
+		getcollection.WithRelationships(relationships...),
+		getcollection.WithEntity(entity))
+	if err != nil {
+		var empty T
+
+		return empty, err
+	}
+
+	return out, nil
+}
+
+// Restore brings back a soft deleted entity
+func (r *Repository[T]) Restore(ctx context.Context, key Key) (T, error) {
+	var out T
+
+	_, err := r.repo.Restore(ctx,
+		restoreitem.WithKey(key),
+		restoreitem.WithEntity(&out))
+	if err != nil {
+		var empty T
+
+		return empty, err
+	}
+
+	return out, nil
+}
+
+// Purge removes the entity for good, including soft deleted entities
+func (r *Repository[T]) Purge(ctx context.Context, key Key) error {
+	_, err := r.repo.Purge(ctx, deleteitem.WithKey(key))
+
+	return err
+}
+
+// Untyped returns the wrapped repository
+func (r *Repository[T]) Untyped() Interface {
+	return r.repo
+}
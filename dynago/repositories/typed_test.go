@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoquery "github.com/KirkDiggler/go-projects/dynamo/inputs/query"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/query"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRepository(t *testing.T) {
+	t.Run("it requires a repository", func(t *testing.T) {
+		_, err := NewRepository[testEntity](nil)
+
+		assert.Equal(t, errors.New("repositories.NewRepository requires a repository"), err)
+	})
+}
+
+func TestRepository_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it returns the typed entity", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture, _ := NewRepository[*testEntity](newTestRepo(t, client))
+
+		client.On("GetItem", ctx, "my-table",
+			dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{Item: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: "abc"},
+			}}, nil)
+
+		actual, err := fixture.Get(ctx, Key{"id": "abc"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &testEntity{ID: "abc"}, actual)
+	})
+	t.Run("it returns the zero value when not found", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture, _ := NewRepository[testEntity](newTestRepo(t, client))
+
+		client.On("GetItem", ctx, "my-table",
+			dynamogetitem.NewOptions(dynamogetitem.WithKey(key1()))).
+			Return(&dynamogetitem.Result{}, nil)
+
+		actual, err := fixture.Get(ctx, Key{"id": "abc"})
+
+		assert.Equal(t, ErrNotFound, err)
+		assert.Equal(t, testEntity{}, actual)
+	})
+}
+
+func TestRepository_Query(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it returns typed entities and no cursor on the last page", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture, _ := NewRepository[testEntity](newTestRepo(t, client))
+
+		keyCondition := expression.Key("GSI1pk").Equal(expression.Value("CATEGORY#SHOES"))
+
+		client.On("Query", ctx, "my-table",
+			dynamoquery.NewOptions(
+				dynamoquery.WithKeyConditionBuilder(&keyCondition),
+				dynamoquery.WithIndexName("GSI1pk-GSI1sk-Index"))).
+			Return(&dynamoquery.Result{
+				Items: []map[string]types.AttributeValue{{
+					"id": &types.AttributeValueMemberS{Value: "abc"},
+				}, {
+					"id": &types.AttributeValueMemberS{Value: "def"},
+				}},
+			}, nil)
+
+		actual, cursor, err := fixture.Query(ctx, "queryByCategory",
+			query.WithPartitionKey(Key{"category": "shoes"}))
+
+		assert.Nil(t, err)
+		assert.Nil(t, cursor)
+		assert.Equal(t, []testEntity{{ID: "abc"}, {ID: "def"}}, actual)
+	})
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// UniqueEntityType is written to EntityTypeAttribute on every unique value marker item
+	UniqueEntityType = "_unique"
+
+	uniquePrefix = "unique"
+
+	conditionalCheckFailedCode = "ConditionalCheckFailed"
+)
+
+// DuplicateValueError
+//
+// Returned by Put when a unique field's value is already used by another entity
+type DuplicateValueError struct {
+	Field string
+	Value string
+}
+
+func (e *DuplicateValueError) Error() string {
+	return fmt.Sprintf("repositories: the value %s of the unique field %s is already in use", e.Value, e.Field)
+}
+
+func validateUniqueFields(fields []string) error {
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if strings.TrimSpace(field) == "" {
+			return errors.New("repositories.Config.UniqueFields can not contain an empty field")
+		}
+
+		if seen[field] {
+			return fmt.Errorf("unique field %s is declared more than once", field)
+		}
+
+		seen[field] = true
+	}
+
+	return nil
+}
+
+func (r *repoImpl) getExisting(ctx context.Context, key map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	result, err := r.client.GetItem(ctx, r.tableName,
+		dynamogetitem.WithKey(key),
+		dynamogetitem.WithConsistentRead(aws.Bool(true)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Item) == 0 {
+		return nil, nil
+	}
+
+	return result.Item, nil
+}
+
+// uniqueGuard fails the transaction if the item's unique values changed after they were read
+func (r *repoImpl) uniqueGuard(existing map[string]types.AttributeValue, condition *expression.ConditionBuilder) expression.ConditionBuilder {
+	var guard expression.ConditionBuilder
+
+	if existing == nil {
+		guard = expression.AttributeNotExists(expression.Name(r.tableKeys.partition))
+	} else {
+		for idx, field := range r.uniqueFields {
+			fieldGuard := expression.AttributeNotExists(expression.Name(field))
+			if value, ok := existing[field]; ok {
+				fieldGuard = expression.Name(field).Equal(expression.Value(rawValue{value}))
+			}
+
+			if idx == 0 {
+				guard = fieldGuard
+			} else {
+				guard = guard.And(fieldGuard)
+			}
+		}
+	}
+
+	if condition != nil {
+		guard = guard.And(*condition)
+	}
+
+	return guard
+}
+
+// buildUniqueKey builds the key of the marker item claiming a unique value, UNIQUE#TYPE#FIELD#VALUE
+func (r *repoImpl) buildUniqueKey(field, value string) map[string]types.AttributeValue {
+	owner := r.entityType
+	if owner == "" {
+		owner = r.name
+	}
+
+	marker := mappings.BuildPrefix(uniquePrefix) + mappings.BuildPrefix(owner) +
+		mappings.BuildPrefix(field) + strings.ToUpper(value)
+
+	out := map[string]types.AttributeValue{
+		r.tableKeys.partition: &types.AttributeValueMemberS{Value: marker},
+	}
+
+	if r.tableKeys.sort != "" {
+		out[r.tableKeys.sort] = &types.AttributeValueMemberS{Value: marker}
+	}
+
+	return out
+}
+
+func uniqueValueString(value types.AttributeValue) string {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	default:
+		return ""
+	}
+}
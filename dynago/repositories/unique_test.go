@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/deleteitem"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func withUniqueFields(fields ...string) func(*Config) {
+	return func(cfg *Config) {
+		cfg.UniqueFields = fields
+	}
+}
+
+func uniqueTestItem(name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":       &types.AttributeValueMemberS{Value: "abc"},
+		"category": &types.AttributeValueMemberS{Value: "shoes"},
+		"name":     &types.AttributeValueMemberS{Value: name},
+		"pk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"sk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"GSI1pk":   &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+		"GSI1sk":   &types.AttributeValueMemberS{Value: "NAME#" + name},
+	}
+}
+
+func uniqueTestMarker(value string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "UNIQUE#MYENTITY#NAME#" + value},
+		"sk": &types.AttributeValueMemberS{Value: "UNIQUE#MYENTITY#NAME#" + value},
+	}
+}
+
+func expectExisting(client *dynamo.Mock, ctx context.Context, item map[string]types.AttributeValue) {
+	client.On("GetItem", ctx, "my-table", dynamogetitem.NewOptions(
+		dynamogetitem.WithKey(key1()),
+		dynamogetitem.WithConsistentRead(aws.Bool(true)))).
+		Return(&dynamogetitem.Result{Item: item}, nil)
+}
+
+func TestNew_UniqueFields(t *testing.T) {
+	t.Run("it rejects a unique field declared more than once", func(t *testing.T) {
+		tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+			MappingName: "table",
+			Fields:      []string{"id"},
+		})
+
+		_, err := New(&Config{
+			Name:         "MyEntity",
+			Client:       &dynamo.Mock{},
+			TableDesc:    newTestTableDesc(),
+			TableMapping: tableMapping,
+			UniqueFields: []string{"name", "name"},
+		})
+
+		assert.Equal(t, errors.New("unique field name is declared more than once"), err)
+	})
+}
+
+func TestRepoImpl_Put_UniqueFields(t *testing.T) {
+	ctx := context.Background()
+
+	entity := &testEntity{
+		ID:       "abc",
+		Category: "shoes",
+		Name:     "AIR MAX",
+	}
+
+	t.Run("it claims the unique values of a new entity", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withUniqueFields("name"))
+
+		expectExisting(client, ctx, nil)
+
+		guard := expression.AttributeNotExists(expression.Name("pk"))
+		notExists := expression.AttributeNotExists(expression.Name("pk"))
+
+		marker := uniqueTestMarker("AIR MAX")
+		marker[EntityTypeAttribute] = &types.AttributeValueMemberS{Value: UniqueEntityType}
+
+		client.On("TransactWriteItems", ctx, transactwriteitems.NewOptions(
+			transactwriteitems.WithPut("my-table", uniqueTestItem("AIR MAX"), &guard),
+			transactwriteitems.WithPut("my-table", marker, &notExists))).
+			Return(&transactwriteitems.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it releases the marker of a changed value", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withUniqueFields("name"))
+
+		existing := uniqueTestItem("AIR FORCE")
+		expectExisting(client, ctx, existing)
+
+		guard := expression.Name("name").Equal(expression.Value(rawValue{existing["name"]}))
+		notExists := expression.AttributeNotExists(expression.Name("pk"))
+
+		marker := uniqueTestMarker("AIR MAX")
+		marker[EntityTypeAttribute] = &types.AttributeValueMemberS{Value: UniqueEntityType}
+
+		client.On("TransactWriteItems", ctx, transactwriteitems.NewOptions(
+			transactwriteitems.WithPut("my-table", uniqueTestItem("AIR MAX"), &guard),
+			transactwriteitems.WithDelete("my-table", uniqueTestMarker("AIR FORCE"), nil),
+			transactwriteitems.WithPut("my-table", marker, &notExists))).
+			Return(&transactwriteitems.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it does not claim an unchanged value", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withUniqueFields("name"))
+
+		existing := uniqueTestItem("AIR MAX")
+		expectExisting(client, ctx, existing)
+
+		guard := expression.Name("name").Equal(expression.Value(rawValue{existing["name"]}))
+
+		client.On("TransactWriteItems", ctx, transactwriteitems.NewOptions(
+			transactwriteitems.WithPut("my-table", uniqueTestItem("AIR MAX"), &guard))).
+			Return(&transactwriteitems.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns a DuplicateValueError when the value is taken", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withUniqueFields("name"))
+
+		expectExisting(client, ctx, nil)
+
+		client.On("TransactWriteItems", ctx, mock.Anything).
+			Return(nil, &types.TransactionCanceledException{
+				Message: aws.String("Transaction cancelled"),
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("None")},
+					{Code: aws.String(conditionalCheckFailedCode)},
+				},
+			})
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		var duplicate *DuplicateValueError
+		assert.True(t, errors.As(err, &duplicate))
+		assert.Equal(t, &DuplicateValueError{Field: "name", Value: "AIR MAX"}, duplicate)
+	})
+}
+
+func TestRepoImpl_uniqueGuard(t *testing.T) {
+	t.Run("it compares the stored attribute values", func(t *testing.T) {
+		fixture := newTestRepo(t, &dynamo.Mock{}, withUniqueFields("name"))
+
+		expr, err := expression.NewBuilder().
+			WithCondition(fixture.uniqueGuard(uniqueTestItem("AIR FORCE"), nil)).
+			Build()
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{
+			":0": &types.AttributeValueMemberS{Value: "AIR FORCE"},
+		}, expr.Values())
+	})
+}
+
+func TestRepoImpl_Delete_UniqueFields(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it releases the markers of the deleted entity", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, withUniqueFields("name"))
+
+		existing := uniqueTestItem("AIR MAX")
+		expectExisting(client, ctx, existing)
+
+		guard := expression.Name("name").Equal(expression.Value(rawValue{existing["name"]}))
+
+		client.On("TransactWriteItems", ctx, transactwriteitems.NewOptions(
+			transactwriteitems.WithDelete("my-table", key1(), &guard),
+			transactwriteitems.WithDelete("my-table", uniqueTestMarker("AIR MAX"), nil))).
+			Return(&transactwriteitems.Result{}, nil)
+
+		_, err := fixture.Delete(ctx, deleteitem.WithKey(map[string]interface{}{"id": "abc"}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
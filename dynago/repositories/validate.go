@@ -0,0 +1,306 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Severity string
+
+const (
+	// SeverityError is an incompatibility the repository can not work with, New fails when one is found
+	SeverityError Severity = "ERROR"
+
+	// SeverityWarning is a problem the repository can work around or that may resolve itself, e.g. an index being created
+	SeverityWarning Severity = "WARNING"
+)
+
+// Issue
+//
+// A single incompatibility between the table description and a repository config
+type Issue struct {
+	Severity Severity
+
+	// Index is the global secondary index the issue was found on, empty for the table
+	Index string
+
+	// Mapping is the index mapping affected by the issue, empty when no mapping is involved
+	Mapping string
+
+	Message string
+}
+
+func (i *Issue) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(i.Severity))
+
+	if i.Index != "" {
+		sb.WriteString(" index " + i.Index)
+	} else {
+		sb.WriteString(" table")
+	}
+
+	if i.Mapping != "" {
+		sb.WriteString(" mapping " + i.Mapping)
+	}
+
+	sb.WriteString(": " + i.Message)
+
+	return sb.String()
+}
+
+// ValidationReport
+//
+// Every issue found by Validate. A report with errors is returned as the error of New
+type ValidationReport struct {
+	TableName string
+	Issues    []*Issue
+}
+
+// HasErrors reports whether any issue has SeverityError
+func (r *ValidationReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Err returns the report as an error when it has errors, otherwise nil
+func (r *ValidationReport) Err() error {
+	if !r.HasErrors() {
+		return nil
+	}
+
+	return r
+}
+
+func (r *ValidationReport) Error() string {
+	issues := make([]string, len(r.Issues))
+	for idx, issue := range r.Issues {
+		issues[idx] = issue.String()
+	}
+
+	return fmt.Sprintf("repositories: table %s is not compatible: %s", r.TableName, strings.Join(issues, "; "))
+}
+
+func (r *ValidationReport) add(severity Severity, index, mapping, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, &Issue{
+		Severity: severity,
+		Index:    index,
+		Mapping:  mapping,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate
+//
+// Checks the table description of the config against its mappings and returns every incompatibility found:
+// the key schemas and attribute types of the table and its global secondary indexes, the status of the
+// table and indexes, and whether the projection of the index each mapping is assigned to holds the
+// attributes the mapping declares. Mappings are assigned to indexes by New, before then only mappings
+// with an index in Config.SchemaMapping have their projections checked
+func Validate(cfg *Config) *ValidationReport {
+	report := &ValidationReport{}
+
+	if cfg == nil || cfg.TableDesc == nil {
+		report.add(SeverityError, "", "", requiresConfigTableDescMsg)
+
+		return report
+	}
+
+	tableDesc := cfg.TableDesc
+	report.TableName = aws.ToString(tableDesc.TableName)
+
+	if tableDesc.TableName == nil {
+		report.add(SeverityError, "", "", requiresTableNameMsg)
+	}
+
+	validateTableStatus(report, tableDesc.TableStatus)
+
+	definitions := make(map[string]types.ScalarAttributeType)
+	for _, definition := range tableDesc.AttributeDefinitions {
+		definitions[aws.ToString(definition.AttributeName)] = definition.AttributeType
+	}
+
+	hasSort := validateKeySchema(report, "", tableDesc.KeySchema, definitions)
+	if !hasSort && (cfg.EntityType != "" || len(cfg.Relationships) != 0) {
+		report.add(SeverityError, "", "", "a sort key is required to prefix the sort keys of EntityType and Relationships")
+	}
+
+	assigned := assignedIndexes(cfg)
+
+	for _, index := range tableDesc.GlobalSecondaryIndexes {
+		name := aws.ToString(index.IndexName)
+		mapping, isAssigned := assigned[name]
+
+		if hasRange := validateKeySchema(report, name, index.KeySchema, definitions); !hasRange {
+			severity := SeverityWarning
+			if isAssigned {
+				severity = SeverityError
+			}
+
+			report.add(severity, name, mappingName(mapping), "a RANGE key is required to hold the sort fields of a mapping")
+		}
+
+		validateIndexStatus(report, name, index.IndexStatus)
+
+		if isAssigned {
+			validateProjection(report, name, mapping, index.Projection)
+		}
+	}
+
+	if cfg.Edges != nil && cfg.Edges.IndexName != "" {
+		if mapping, ok := assigned[cfg.Edges.IndexName]; ok {
+			report.add(SeverityError, cfg.Edges.IndexName, mappingName(mapping),
+				"the index is reserved for edges and can not be assigned to a mapping")
+		}
+	}
+
+	if cfg.Outbox != nil && cfg.Outbox.IndexName != "" {
+		if mapping, ok := assigned[cfg.Outbox.IndexName]; ok {
+			report.add(SeverityError, cfg.Outbox.IndexName, mappingName(mapping),
+				"the index is reserved for the outbox and can not be assigned to a mapping")
+		}
+	}
+
+	return report
+}
+
+// assignedIndexes returns the index mappings by the name of the index they are assigned to
+func assignedIndexes(cfg *Config) map[string]*mappings.Index {
+	out := make(map[string]*mappings.Index)
+
+	for _, index := range cfg.IndexMappings {
+		if index == nil || index.Mapping == nil {
+			continue
+		}
+
+		name := index.Name
+		if name == "" && cfg.SchemaMapping != nil {
+			if existing, ok := cfg.SchemaMapping.Indexes[index.Mapping.GetName()]; ok {
+				name = existing.Name
+			}
+		}
+
+		if name != "" {
+			out[name] = index
+		}
+	}
+
+	return out
+}
+
+// validateKeySchema checks a key schema has a single HASH key, at most one RANGE key and that both are strings.
+// It returns whether the schema has a RANGE key
+func validateKeySchema(report *ValidationReport, index string, keySchema []types.KeySchemaElement, definitions map[string]types.ScalarAttributeType) bool {
+	var hashKeys, rangeKeys []string
+
+	for _, element := range keySchema {
+		name := aws.ToString(element.AttributeName)
+
+		switch element.KeyType {
+		case types.KeyTypeHash:
+			hashKeys = append(hashKeys, name)
+		case types.KeyTypeRange:
+			rangeKeys = append(rangeKeys, name)
+		}
+	}
+
+	if len(hashKeys) == 0 {
+		report.add(SeverityError, index, "", "the key schema does not have a HASH key")
+	}
+
+	if len(hashKeys) > 1 || len(rangeKeys) > 1 {
+		report.add(SeverityError, index, "", "compound keys are not supported, found HASH %v and RANGE %v", hashKeys, rangeKeys)
+	}
+
+	// hand built descriptions often leave out the attribute definitions, DescribeTable always returns them
+	if len(definitions) != 0 {
+		for _, name := range append(hashKeys, rangeKeys...) {
+			attributeType, ok := definitions[name]
+			if !ok {
+				report.add(SeverityError, index, "", "key attribute %s does not have an attribute definition", name)
+
+				continue
+			}
+
+			if attributeType != types.ScalarAttributeTypeS {
+				report.add(SeverityError, index, "", "key attribute %s must be of type S, found %s", name, attributeType)
+			}
+		}
+	}
+
+	return len(rangeKeys) != 0
+}
+
+func validateTableStatus(report *ValidationReport, status types.TableStatus) {
+	switch status {
+	case "", types.TableStatusActive, types.TableStatusUpdating:
+	case types.TableStatusCreating:
+		report.add(SeverityWarning, "", "", "the table is %s and can not be used until it is %s", status, types.TableStatusActive)
+	default:
+		report.add(SeverityError, "", "", "the table is %s", status)
+	}
+}
+
+func validateIndexStatus(report *ValidationReport, index string, status types.IndexStatus) {
+	switch status {
+	case "", types.IndexStatusActive, types.IndexStatusUpdating:
+	case types.IndexStatusCreating:
+		report.add(SeverityWarning, index, "", "the index is %s and can not be queried until it is %s", status, types.IndexStatusActive)
+	default:
+		report.add(SeverityError, index, "", "the index is %s", status)
+	}
+}
+
+// validateProjection checks the index projects the attributes the mapping declares it needs.
+// Mappings without a ProjectionType have no requirement
+func validateProjection(report *ValidationReport, index string, mapping *mappings.Index, projection *types.Projection) {
+	if projection == nil || projection.ProjectionType == types.ProjectionTypeAll {
+		return
+	}
+
+	switch mapping.ProjectionType {
+	case entities.PropjectionTypeAll:
+		report.add(SeverityError, index, mapping.Mapping.GetName(),
+			"the mapping needs every attribute but the index projects %s, declare the projection and query WithFetchThrough",
+			projection.ProjectionType)
+	case entities.PropjectionTypeInclude:
+		projected := make(map[string]bool)
+		for _, attribute := range projection.NonKeyAttributes {
+			projected[attribute] = true
+		}
+
+		var missing []string
+		for _, attribute := range mapping.NonKeyAttributes {
+			if !projected[attribute] {
+				missing = append(missing, attribute)
+			}
+		}
+
+		if len(missing) != 0 {
+			sort.Strings(missing)
+
+			report.add(SeverityError, index, mapping.Mapping.GetName(),
+				"the index does not project the attributes %s", strings.Join(missing, ", "))
+		}
+	}
+}
+
+func mappingName(index *mappings.Index) string {
+	if index == nil || index.Mapping == nil {
+		return ""
+	}
+
+	return index.Mapping.GetName()
+}
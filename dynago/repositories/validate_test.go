@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newValidateConfig() *Config {
+	tableMapping, _ := mappings.NewLookup(&mappings.LookupConfig{
+		MappingName: "table",
+		Fields:      []string{"id"},
+	})
+
+	queryByCategoryMapping, _ := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     "queryByCategory",
+		PartitionFields: []string{"category"},
+		SortFields:      []string{"name"},
+	})
+
+	tableDesc := newTestTableDesc()
+	tableDesc.AttributeDefinitions = []types.AttributeDefinition{
+		{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("GSI1pk"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("GSI1sk"), AttributeType: types.ScalarAttributeTypeS},
+	}
+
+	return &Config{
+		Name:         "MyEntity",
+		Client:       &dynamo.Mock{},
+		TableDesc:    tableDesc,
+		TableMapping: tableMapping,
+		IndexMappings: []*mappings.Index{{
+			Name:           "GSI1pk-GSI1sk-Index",
+			ProjectionType: entities.PropjectionTypeAll,
+			Mapping:        queryByCategoryMapping,
+		}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("it does not report a compatible table", func(t *testing.T) {
+		report := Validate(newValidateConfig())
+
+		assert.Empty(t, report.Issues)
+		assert.Nil(t, report.Err())
+	})
+	t.Run("it reports every issue", func(t *testing.T) {
+		cfg := newValidateConfig()
+		cfg.EntityType = "product"
+		cfg.TableDesc.KeySchema = cfg.TableDesc.KeySchema[:1]
+		cfg.TableDesc.AttributeDefinitions[0].AttributeType = types.ScalarAttributeTypeN
+		cfg.TableDesc.GlobalSecondaryIndexes[0].IndexStatus = types.IndexStatusDeleting
+		cfg.TableDesc.GlobalSecondaryIndexes[0].Projection = &types.Projection{
+			ProjectionType: types.ProjectionTypeKeysOnly,
+		}
+
+		report := Validate(cfg)
+
+		assert.True(t, report.HasErrors())
+		assert.Equal(t, []*Issue{{
+			Severity: SeverityError,
+			Message:  "key attribute pk must be of type S, found N",
+		}, {
+			Severity: SeverityError,
+			Message:  "a sort key is required to prefix the sort keys of EntityType and Relationships",
+		}, {
+			Severity: SeverityError,
+			Index:    "GSI1pk-GSI1sk-Index",
+			Message:  "the index is DELETING",
+		}, {
+			Severity: SeverityError,
+			Index:    "GSI1pk-GSI1sk-Index",
+			Mapping:  "queryByCategory",
+			Message:  "the mapping needs every attribute but the index projects KEYS_ONLY, declare the projection and query WithFetchThrough",
+		}}, report.Issues)
+	})
+	t.Run("it reports compound keys", func(t *testing.T) {
+		cfg := newValidateConfig()
+		cfg.TableDesc.KeySchema = append(cfg.TableDesc.KeySchema, types.KeySchemaElement{
+			AttributeName: aws.String("sk2"),
+			KeyType:       types.KeyTypeRange,
+		})
+
+		report := Validate(cfg)
+
+		assert.Equal(t, "compound keys are not supported, found HASH [pk] and RANGE [sk sk2]", report.Issues[0].Message)
+	})
+	t.Run("it reports the attributes an include index is missing", func(t *testing.T) {
+		cfg := newValidateConfig()
+		cfg.IndexMappings[0].ProjectionType = entities.PropjectionTypeInclude
+		cfg.IndexMappings[0].NonKeyAttributes = []string{"price", "brand", "name"}
+		cfg.TableDesc.GlobalSecondaryIndexes[0].Projection = &types.Projection{
+			ProjectionType:   types.ProjectionTypeInclude,
+			NonKeyAttributes: []string{"name"},
+		}
+
+		report := Validate(cfg)
+
+		assert.Equal(t, []*Issue{{
+			Severity: SeverityError,
+			Index:    "GSI1pk-GSI1sk-Index",
+			Mapping:  "queryByCategory",
+			Message:  "the index does not project the attributes brand, price",
+		}}, report.Issues)
+	})
+}
+
+func TestNew_Validate(t *testing.T) {
+	t.Run("it returns the report when there are errors", func(t *testing.T) {
+		cfg := newValidateConfig()
+		cfg.TableDesc.TableStatus = types.TableStatusDeleting
+
+		_, err := New(cfg)
+
+		assert.EqualError(t, err, "repositories: table my-table is not compatible: ERROR table: the table is DELETING")
+		assert.IsType(t, &ValidationReport{}, err)
+	})
+	t.Run("it allows warnings", func(t *testing.T) {
+		cfg := newValidateConfig()
+		cfg.TableDesc.GlobalSecondaryIndexes[0].IndexStatus = types.IndexStatusCreating
+
+		_, err := New(cfg)
+
+		assert.Nil(t, err)
+		assert.Equal(t, SeverityWarning, Validate(cfg).Issues[0].Severity)
+	})
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrVersionConflict is returned by Put when the stored version of the entity is not the version that was read.
+// It is not returned when the Put has a FilterConditionBuilder, the condition failure is returned as is
+var ErrVersionConflict = errors.New("repositories: version conflict")
+
+// RetryOnConflict
+//
+// Runs a read-modify-write closure until it succeeds, returns an error other than ErrVersionConflict,
+// or has been attempted attempts times. The closure must read the entity again on every attempt
+func RetryOnConflict(ctx context.Context, attempts int, fn func(ctx context.Context) error) error {
+	if attempts < 1 {
+		return errors.New("repositories.RetryOnConflict requires at least 1 attempt")
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		err = fn(ctx)
+		if !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// resolveVersionField returns the configured version field, falling back to the entity's dynago:"version" tag
+func (r *repoImpl) resolveVersionField(entity interface{}) (string, error) {
+	if r.versionField != "" {
+		return r.versionField, nil
+	}
+
+	return mappings.VersionField(entity)
+}
+
+// applyVersion increments the version attribute of the item and returns the condition that the stored
+// version has not changed. An item without a version is only written if the stored item has no version either
+func applyVersion(item map[string]types.AttributeValue, versionField string) (*expression.ConditionBuilder, error) {
+	current := int64(0)
+
+	if value, ok := item[versionField]; ok {
+		number, isNumber := value.(*types.AttributeValueMemberN)
+		if !isNumber {
+			return nil, fmt.Errorf("version field %s must be a number", versionField)
+		}
+
+		parsed, err := strconv.ParseInt(number.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("version field %s: %s", versionField, err)
+		}
+
+		current = parsed
+	}
+
+	condition := expression.AttributeNotExists(expression.Name(versionField))
+	if current != 0 {
+		condition = expression.Name(versionField).Equal(expression.Value(current))
+	}
+
+	item[versionField] = &types.AttributeValueMemberN{Value: strconv.FormatInt(current+1, 10)}
+
+	return &condition, nil
+}
+
+// isConditionFailure reports whether the write of the item itself failed its condition,
+// the item is always the first in a transaction
+func isConditionFailure(err error) bool {
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return true
+	}
+
+	var cancelled *types.TransactionCanceledException
+	if errors.As(err, &cancelled) && len(cancelled.CancellationReasons) != 0 {
+		return aws.ToString(cancelled.CancellationReasons[0].Code) == conditionalCheckFailedCode
+	}
+
+	return false
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories/options/putitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamoputitem "github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testVersionedEntity struct {
+	testEntity
+	Version int64 `dynamodbav:"version" dynago:"version"`
+}
+
+func versionedTestItem(version string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":       &types.AttributeValueMemberS{Value: "abc"},
+		"category": &types.AttributeValueMemberS{Value: "shoes"},
+		"name":     &types.AttributeValueMemberS{Value: "Air Max"},
+		"version":  &types.AttributeValueMemberN{Value: version},
+		"pk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"sk":       &types.AttributeValueMemberS{Value: "ID#ABC"},
+		"GSI1pk":   &types.AttributeValueMemberS{Value: "CATEGORY#SHOES"},
+		"GSI1sk":   &types.AttributeValueMemberS{Value: "NAME#AIR MAX"},
+	}
+}
+
+func testShoe() testEntity {
+	return testEntity{
+		ID:       "abc",
+		Category: "shoes",
+		Name:     "Air Max",
+	}
+}
+
+func TestRepoImpl_Put_Version(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it writes the first version when the item has not been written", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		condition := expression.AttributeNotExists(expression.Name("version"))
+
+		client.On("PutItem", ctx, "my-table", dynamoputitem.NewOptions(
+			dynamoputitem.WithItem(versionedTestItem("1")),
+			dynamoputitem.WithFilterConditionBuilder(&condition))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testVersionedEntity{testEntity: testShoe()}
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), entity.Version)
+		client.AssertExpectations(t)
+	})
+	t.Run("it increments the version read with the entity", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		condition := expression.Name("version").Equal(expression.Value(int64(3)))
+
+		client.On("PutItem", ctx, "my-table", dynamoputitem.NewOptions(
+			dynamoputitem.WithItem(versionedTestItem("4")),
+			dynamoputitem.WithFilterConditionBuilder(&condition))).
+			Return(&dynamoputitem.Result{}, nil)
+
+		entity := &testVersionedEntity{testEntity: testShoe(), Version: 3}
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(4), entity.Version)
+		client.AssertExpectations(t)
+	})
+	t.Run("it uses the configured version field", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client, func(cfg *Config) {
+			cfg.VersionField = "revision"
+		})
+
+		condition := expression.AttributeNotExists(expression.Name("revision"))
+
+		client.On("PutItem", ctx, "my-table", mock.MatchedBy(func(options *dynamoputitem.Options) bool {
+			return assert.ObjectsAreEqual(&types.AttributeValueMemberN{Value: "1"}, options.Item["revision"]) &&
+				assert.ObjectsAreEqual(&condition, options.FilterConditionBuilder)
+		})).Return(&dynamoputitem.Result{}, nil)
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(&testVersionedEntity{testEntity: testShoe()}))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns ErrVersionConflict when the stored version changed", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		client.On("PutItem", ctx, "my-table", mock.Anything).
+			Return(nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")})
+
+		entity := &testVersionedEntity{testEntity: testShoe(), Version: 3}
+
+		_, err := fixture.Put(ctx, putitem.WithEntity(entity))
+
+		assert.Equal(t, ErrVersionConflict, err)
+		assert.Equal(t, int64(3), entity.Version)
+	})
+	t.Run("it returns the condition failure when the caller passed a condition", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		fixture := newTestRepo(t, client)
+
+		failure := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		client.On("PutItem", ctx, "my-table", mock.Anything).Return(nil, failure)
+
+		condition := expression.Name("category").Equal(expression.Value("shoes"))
+
+		_, err := fixture.Put(ctx,
+			putitem.WithEntity(&testVersionedEntity{testEntity: testShoe(), Version: 3}),
+			putitem.WithFilterConditionBuilder(&condition))
+
+		assert.Equal(t, failure, err)
+		assert.False(t, errors.Is(err, ErrVersionConflict))
+	})
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it retries on a version conflict", func(t *testing.T) {
+		calls := 0
+
+		err := RetryOnConflict(ctx, 3, func(ctx context.Context) error {
+			calls++
+			if calls < 2 {
+				return ErrVersionConflict
+			}
+
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("it returns the conflict after the last attempt", func(t *testing.T) {
+		calls := 0
+
+		err := RetryOnConflict(ctx, 3, func(ctx context.Context) error {
+			calls++
+
+			return ErrVersionConflict
+		})
+
+		assert.Equal(t, ErrVersionConflict, err)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("it does not retry other errors", func(t *testing.T) {
+		calls := 0
+		expectedErr := errors.New("dynamo down")
+
+		err := RetryOnConflict(ctx, 3, func(ctx context.Context) error {
+			calls++
+
+			return expectedErr
+		})
+
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,172 @@
+package dynago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoscan "github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrSchemaNotFound is returned by LoadSchema when no schema is persisted for the repository name
+var ErrSchemaNotFound = errors.New("dynago: schema not found")
+
+// StoredSchema
+//
+// The schema of a repository as NewRepository persisted it in the table
+type StoredSchema struct {
+	Name    string
+	Version int64
+	Schema  *entities.Schema
+}
+
+type storedSchemaItem struct {
+	Schema  *entities.Schema `dynamodbav:"schema"`
+	Version int64            `dynamodbav:"version"`
+}
+
+// LoadSchema
+//
+// Reads the persisted schema of a repository, ErrSchemaNotFound when there is none
+func (m *Mapper) LoadSchema(ctx context.Context, name string) (*StoredSchema, error) {
+	tableDesc, err := m.TableDescription(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := schemaKey(tableDesc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := m.client.GetItem(ctx, m.tableName,
+		dynamogetitem.WithKey(key),
+		dynamogetitem.WithConsistentRead(aws.Bool(true)))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := result.Item[schemaAttribute]; !ok {
+		return nil, ErrSchemaNotFound
+	}
+
+	return parseStoredSchema(name, result.Item)
+}
+
+// ListSchemas
+//
+// Scans the table for every persisted schema, sorted by repository name. The whole table is read,
+// it is meant for tooling rather than the request path
+func (m *Mapper) ListSchemas(ctx context.Context) ([]*StoredSchema, error) {
+	tableDesc, err := m.TableDescription(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partition := ""
+	for _, element := range tableDesc.KeySchema {
+		if element.KeyType == types.KeyTypeHash {
+			partition = aws.ToString(element.AttributeName)
+		}
+	}
+
+	prefix := mappings.BuildPrefix(SchemaEntityType)
+	filter := expression.Name(repositories.EntityTypeAttribute).Equal(expression.Value(SchemaEntityType))
+
+	var out []*StoredSchema
+	var startKey map[string]types.AttributeValue
+
+	for {
+		result, err := m.client.Scan(ctx, m.tableName,
+			dynamoscan.WithFilterConditionBuilder(&filter),
+			dynamoscan.WithExclusiveStartKey(startKey))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Items {
+			keyValue, ok := item[partition].(*types.AttributeValueMemberS)
+			if !ok || !strings.HasPrefix(keyValue.Value, prefix) {
+				continue
+			}
+
+			stored, err := parseStoredSchema(strings.TrimPrefix(keyValue.Value, prefix), item)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, stored)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+
+		startKey = result.LastEvaluatedKey
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+
+	return out, nil
+}
+
+func parseStoredSchema(name string, item map[string]types.AttributeValue) (*StoredSchema, error) {
+	stored := &storedSchemaItem{}
+	if err := attributevalue.UnmarshalMap(item, stored); err != nil {
+		return nil, fmt.Errorf("dynago: schema of repository %s: %s", name, err)
+	}
+
+	if stored.Schema == nil {
+		return nil, fmt.Errorf("dynago: schema of repository %s is empty", name)
+	}
+
+	return &StoredSchema{Name: name, Version: stored.Version, Schema: stored.Schema}, nil
+}
+
+// RepositoryConfig
+//
+// Rebuilds the config of the repository from its persisted schema with every index mapping pinned to the index
+// it is assigned to. It holds the mappings only, settings such as EntityType are not persisted
+func (s *StoredSchema) RepositoryConfig() (*repositories.Config, error) {
+	tableMapping, err := mappings.FromEntity(s.Schema.Table)
+	if err != nil {
+		return nil, fmt.Errorf("dynago: repository %s: %s", s.Name, err)
+	}
+
+	names := make([]string, 0, len(s.Schema.Indexes))
+	for name := range s.Schema.Indexes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	out := &repositories.Config{
+		Name:          s.Name,
+		TableMapping:  tableMapping,
+		SchemaMapping: s.Schema,
+	}
+
+	for _, name := range names {
+		index, err := mappings.IndexFromEntity(s.Schema.Indexes[name])
+		if err != nil {
+			return nil, fmt.Errorf("dynago: repository %s: %s", s.Name, err)
+		}
+
+		out.IndexMappings = append(out.IndexMappings, index)
+	}
+
+	return out, nil
+}
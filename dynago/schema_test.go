@@ -0,0 +1,112 @@
+package dynago
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	dynamogetitem "github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	dynamoscan "github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func storedTestSchema(t *testing.T, name string) map[string]types.AttributeValue {
+	tableMapping, byCategory := newTestMappings()
+
+	schema, err := attributevalue.Marshal(&entities.Schema{
+		Table: tableMapping.ToEntity(),
+		Indexes: map[string]*entities.Index{
+			"queryByCategory": {Name: "GSI1pk-GSI1sk-Index", Mapping: byCategory.Mapping.ToEntity()},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"_type":                &types.AttributeValueMemberS{Value: SchemaEntityType},
+		schemaAttribute:        schema,
+		schemaVersionAttribute: &types.AttributeValueMemberN{Value: "4"},
+	}
+
+	for k, v := range schemaKeyOf(name) {
+		item[k] = v
+	}
+
+	return item
+}
+
+func TestMapper_LoadSchema(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it returns ErrSchemaNotFound when no schema is persisted", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{}, nil)
+
+		_, err := mapper.LoadSchema(ctx, "Products")
+
+		assert.Equal(t, ErrSchemaNotFound, err)
+	})
+	t.Run("it reads the schema and its version", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		client.On("GetItem", ctx, "my-table", mock.Anything).
+			Return(&dynamogetitem.Result{Item: storedTestSchema(t, "Products")}, nil)
+
+		actual, err := mapper.LoadSchema(ctx, "Products")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Products", actual.Name)
+		assert.Equal(t, int64(4), actual.Version)
+		assert.Equal(t, "GSI1pk-GSI1sk-Index", actual.Schema.Indexes["queryByCategory"].Name)
+	})
+}
+
+func TestMapper_ListSchemas(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it reads every page and sorts by name", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		mapper := newTestMapper(t, client)
+
+		lastKey := schemaKeyOf("Products")
+
+		client.On("Scan", ctx, "my-table", mock.MatchedBy(func(options *dynamoscan.Options) bool {
+			return options.ExclusiveStartKey == nil
+		})).Return(&dynamoscan.Result{Items: []map[string]types.AttributeValue{storedTestSchema(t, "Products")}, LastEvaluatedKey: lastKey}, nil)
+		client.On("Scan", ctx, "my-table", mock.MatchedBy(func(options *dynamoscan.Options) bool {
+			return options.ExclusiveStartKey != nil
+		})).Return(&dynamoscan.Result{Items: []map[string]types.AttributeValue{storedTestSchema(t, "Orders")}}, nil)
+
+		actual, err := mapper.ListSchemas(ctx)
+
+		assert.Nil(t, err)
+		assert.Len(t, actual, 2)
+		assert.Equal(t, "Orders", actual[0].Name)
+		assert.Equal(t, "Products", actual[1].Name)
+	})
+}
+
+func TestStoredSchema_RepositoryConfig(t *testing.T) {
+	t.Run("it pins the index mappings to their assigned indexes", func(t *testing.T) {
+		stored, err := parseStoredSchema("Products", storedTestSchema(t, "Products"))
+		assert.Nil(t, err)
+
+		actual, err := stored.RepositoryConfig()
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Products", actual.Name)
+		assert.Equal(t, "table", actual.TableMapping.GetName())
+		assert.Equal(t, "GSI1pk-GSI1sk-Index", actual.IndexMappings[0].Name)
+		assert.Equal(t, []string{"category"}, actual.IndexMappings[0].Mapping.GetPartitionFields())
+	})
+}
@@ -0,0 +1,435 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/createtable"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// DefaultPartitionKey is the partition key attribute of the table when Config.PartitionKey is empty
+	DefaultPartitionKey = "pk"
+
+	// DefaultSortKey is the sort key attribute of the table when Config.SortKey is empty
+	DefaultSortKey = "sk"
+
+	indexNameSuffix = "-Index"
+
+	requiresConfigMsg          = "tables.Build requires a Config"
+	requiresTableNameMsg       = "tables.Config.TableName is required"
+	requiresRepositoriesMsg    = "tables.Config.Repositories requires at least one repository"
+	requiresThroughputMsg      = "tables.Config.ProvisionedThroughput is required for the PROVISIONED billing mode"
+	requiresTableMappingMsg    = "repository %s requires a TableMapping"
+	requiresIndexMappingMsg    = "repository %s requires a Mapping on every index mapping"
+	requiresIndexNameFormatMsg = "index name %s must be of the form <partition>-<sort>-Index"
+)
+
+// Config
+//
+// The repositories sharing a table. Only the mappings, EntityType, Relationships, Edges and Outbox of each
+// repositories.Config are read, the Client and TableDesc are not needed
+type Config struct {
+	TableName string
+
+	// PartitionKey and SortKey name the table key attributes, they default to pk and sk
+	PartitionKey string
+	SortKey      string
+
+	// BillingMode defaults to PAY_PER_REQUEST
+	BillingMode types.BillingMode
+
+	// ProvisionedThroughput is used for the table and every index with the PROVISIONED billing mode
+	ProvisionedThroughput *types.ProvisionedThroughput
+
+	Repositories []*repositories.Config
+
+	// TableDesc is the existing table, optional. Named indexes on it keep their key attributes
+	// whatever their names are
+	TableDesc *types.TableDescription
+}
+
+// Definition
+//
+// The table the repositories need. Index mappings are laid out on the indexes the same way
+// repositories.New assigns them, so every mapping lands on an index that projects what it declares
+type Definition struct {
+	TableName              string
+	KeySchema              []types.KeySchemaElement
+	AttributeDefinitions   []types.AttributeDefinition
+	GlobalSecondaryIndexes []types.GlobalSecondaryIndex
+	BillingMode            types.BillingMode
+	ProvisionedThroughput  *types.ProvisionedThroughput
+}
+
+// CreateOptions returns the options to create the table with dynamo.Interface.CreateTable
+func (d *Definition) CreateOptions() []createtable.OptionFunc {
+	return []createtable.OptionFunc{
+		createtable.WithKeySchema(d.KeySchema...),
+		createtable.WithAttributeDefinitions(d.AttributeDefinitions...),
+		createtable.WithGlobalSecondaryIndexes(d.GlobalSecondaryIndexes...),
+		createtable.WithBillingMode(d.BillingMode),
+		createtable.WithProvisionedThroughput(d.ProvisionedThroughput),
+	}
+}
+
+// Description returns the table description the repositories will see once the table is created,
+// use it to check the definition with repositories.Validate
+func (d *Definition) Description() *types.TableDescription {
+	out := &types.TableDescription{
+		TableName:            aws.String(d.TableName),
+		KeySchema:            d.KeySchema,
+		AttributeDefinitions: d.AttributeDefinitions,
+		BillingModeSummary:   &types.BillingModeSummary{BillingMode: d.BillingMode},
+	}
+
+	if d.ProvisionedThroughput != nil {
+		out.ProvisionedThroughput = &types.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  d.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: d.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+
+	for _, index := range d.GlobalSecondaryIndexes {
+		out.GlobalSecondaryIndexes = append(out.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:  index.IndexName,
+			KeySchema:  index.KeySchema,
+			Projection: index.Projection,
+		})
+	}
+
+	return out
+}
+
+// Build
+//
+// Builds the minimal table definition for the repositories: the table has a sort key only when a repository
+// needs one, and there are only as many global secondary indexes as the repository with the most index
+// mappings uses. Indexes a mapping is pinned to and edge indexes are named by the config, their key
+// attributes are read from Config.TableDesc or from names of the form <partition>-<sort>-Index. The other
+// indexes are named GSI1pk-GSI1sk-Index, GSI2pk-GSI2sk-Index and so on. The projection of an index is the
+// narrowest that holds what every mapping assigned to it declares, mappings without a ProjectionType need ALL
+func Build(cfg *Config) (*Definition, error) {
+	if cfg == nil {
+		return nil, errors.New(requiresConfigMsg)
+	}
+
+	if cfg.TableName == "" {
+		return nil, errors.New(requiresTableNameMsg)
+	}
+
+	if len(cfg.Repositories) == 0 {
+		return nil, errors.New(requiresRepositoriesMsg)
+	}
+
+	billingMode := cfg.BillingMode
+	if billingMode == "" {
+		billingMode = types.BillingModePayPerRequest
+	}
+
+	if billingMode == types.BillingModeProvisioned && cfg.ProvisionedThroughput == nil {
+		return nil, errors.New(requiresThroughputMsg)
+	}
+
+	partitionKey, sortKey := cfg.PartitionKey, cfg.SortKey
+	if partitionKey == "" {
+		partitionKey = DefaultPartitionKey
+	}
+
+	if sortKey == "" {
+		sortKey = DefaultSortKey
+	}
+
+	// index names the config asks for, edge indexes need every attribute of the edge items
+	named := make(map[string]*projection)
+	needsSort := false
+
+	for _, repoCfg := range cfg.Repositories {
+		if repoCfg == nil || repoCfg.TableMapping == nil {
+			return nil, fmt.Errorf(requiresTableMappingMsg, repositoryName(repoCfg))
+		}
+
+		if repoCfg.EntityType != "" || len(repoCfg.Relationships) != 0 || repoCfg.Edges != nil {
+			needsSort = true
+		}
+
+		if repoCfg.TableMapping.GetType() == entities.MappingType_Query && len(repoCfg.TableMapping.GetSortFields()) != 0 {
+			needsSort = true
+		}
+
+		if repoCfg.Edges != nil && repoCfg.Edges.IndexName != "" {
+			named[repoCfg.Edges.IndexName] = &projection{all: true}
+		}
+
+		// the outbox relay reads the events from the table, the index only lists them
+		if repoCfg.Outbox != nil && repoCfg.Outbox.IndexName != "" && named[repoCfg.Outbox.IndexName] == nil {
+			named[repoCfg.Outbox.IndexName] = &projection{}
+		}
+
+		for _, index := range repoCfg.IndexMappings {
+			if index == nil || index.Mapping == nil {
+				return nil, fmt.Errorf(requiresIndexMappingMsg, repoCfg.Name)
+			}
+
+			if index.Name != "" && named[index.Name] == nil {
+				named[index.Name] = &projection{}
+			}
+		}
+	}
+
+	projections, err := layoutIndexes(cfg.Repositories, named)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Definition{
+		TableName: cfg.TableName,
+		KeySchema: []types.KeySchemaElement{{
+			AttributeName: aws.String(partitionKey),
+			KeyType:       types.KeyTypeHash,
+		}},
+		BillingMode: billingMode,
+	}
+
+	attributes := []string{partitionKey}
+
+	if needsSort {
+		out.KeySchema = append(out.KeySchema, types.KeySchemaElement{
+			AttributeName: aws.String(sortKey),
+			KeyType:       types.KeyTypeRange,
+		})
+
+		attributes = append(attributes, sortKey)
+	}
+
+	if billingMode == types.BillingModeProvisioned {
+		out.ProvisionedThroughput = cfg.ProvisionedThroughput
+	}
+
+	names := make([]string, 0, len(projections))
+	for name := range projections {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		partition, sortAttribute, err := indexKeyAttributes(cfg.TableDesc, name)
+		if err != nil {
+			return nil, err
+		}
+
+		out.GlobalSecondaryIndexes = append(out.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+			IndexName: aws.String(name),
+			KeySchema: []types.KeySchemaElement{{
+				AttributeName: aws.String(partition),
+				KeyType:       types.KeyTypeHash,
+			}, {
+				AttributeName: aws.String(sortAttribute),
+				KeyType:       types.KeyTypeRange,
+			}},
+			Projection:            projections[name].toProjection(),
+			ProvisionedThroughput: out.ProvisionedThroughput,
+		})
+
+		attributes = append(attributes, partition, sortAttribute)
+	}
+
+	seen := make(map[string]bool)
+	for _, attribute := range attributes {
+		if seen[attribute] {
+			continue
+		}
+
+		seen[attribute] = true
+
+		out.AttributeDefinitions = append(out.AttributeDefinitions, types.AttributeDefinition{
+			AttributeName: aws.String(attribute),
+			AttributeType: types.ScalarAttributeTypeS,
+		})
+	}
+
+	return out, nil
+}
+
+// layoutIndexes assigns the index mappings of every repository to indexes the way repositories.New does,
+// adding generated indexes until every mapping has one, and returns the projection each index needs
+func layoutIndexes(repoCfgs []*repositories.Config, named map[string]*projection) (map[string]*projection, error) {
+	generated := 0
+
+	for {
+		indexes := make(map[string]*projection)
+		for name, required := range named {
+			indexes[name] = required.copy()
+		}
+
+		for idx := 1; idx <= generated; idx++ {
+			name := generatedIndexName(idx)
+			if _, ok := indexes[name]; !ok {
+				indexes[name] = &projection{}
+			}
+		}
+
+		if layoutRepositories(repoCfgs, indexes) {
+			return indexes, nil
+		}
+
+		generated++
+	}
+}
+
+// layoutRepositories records the projection needs of each mapping on the index it is assigned to,
+// it returns false when a repository has more mappings than there are indexes
+func layoutRepositories(repoCfgs []*repositories.Config, indexes map[string]*projection) bool {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, repoCfg := range repoCfgs {
+		used := make(map[string]bool)
+		if repoCfg.Edges != nil {
+			used[repoCfg.Edges.IndexName] = true
+		}
+
+		if repoCfg.Outbox != nil {
+			used[repoCfg.Outbox.IndexName] = true
+		}
+
+		var unpinned []*mappings.Index
+
+		for _, index := range repoCfg.IndexMappings {
+			if index.Name == "" {
+				unpinned = append(unpinned, index)
+
+				continue
+			}
+
+			used[index.Name] = true
+			indexes[index.Name].add(index)
+		}
+
+		sort.SliceStable(unpinned, func(i, j int) bool {
+			return unpinned[i].Mapping.GetName() < unpinned[j].Mapping.GetName()
+		})
+
+		next := 0
+		for _, index := range unpinned {
+			for next < len(names) && used[names[next]] {
+				next++
+			}
+
+			if next == len(names) {
+				return false
+			}
+
+			used[names[next]] = true
+			indexes[names[next]].add(index)
+		}
+	}
+
+	return true
+}
+
+func generatedIndexName(idx int) string {
+	return fmt.Sprintf("GSI%dpk-GSI%dsk%s", idx, idx, indexNameSuffix)
+}
+
+// indexKeyAttributes returns the partition and sort attributes of the index on the table description,
+// or reads them from an index name of the form <partition>-<sort>-Index
+func indexKeyAttributes(tableDesc *types.TableDescription, name string) (string, string, error) {
+	if tableDesc != nil {
+		for _, index := range tableDesc.GlobalSecondaryIndexes {
+			if aws.ToString(index.IndexName) != name {
+				continue
+			}
+
+			keys := keyNames(index.KeySchema)
+			if keys[types.KeyTypeHash] != "" && keys[types.KeyTypeRange] != "" {
+				return keys[types.KeyTypeHash], keys[types.KeyTypeRange], nil
+			}
+		}
+	}
+
+	parts := strings.Split(strings.TrimSuffix(name, indexNameSuffix), "-")
+	if !strings.HasSuffix(name, indexNameSuffix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf(requiresIndexNameFormatMsg, name)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+func repositoryName(cfg *repositories.Config) string {
+	if cfg == nil {
+		return "<nil>"
+	}
+
+	return cfg.Name
+}
+
+// projection collects what the mappings assigned to an index need projected
+type projection struct {
+	all        bool
+	include    bool
+	attributes map[string]bool
+}
+
+func (p *projection) add(index *mappings.Index) {
+	switch index.ProjectionType {
+	case entities.PropjectionTypeKeysOnly:
+	case entities.PropjectionTypeInclude:
+		p.include = true
+
+		if p.attributes == nil {
+			p.attributes = make(map[string]bool)
+		}
+
+		for _, attribute := range index.NonKeyAttributes {
+			p.attributes[attribute] = true
+		}
+	default:
+		p.all = true
+	}
+}
+
+func (p *projection) copy() *projection {
+	out := &projection{all: p.all, include: p.include}
+
+	for attribute := range p.attributes {
+		if out.attributes == nil {
+			out.attributes = make(map[string]bool)
+		}
+
+		out.attributes[attribute] = true
+	}
+
+	return out
+}
+
+func (p *projection) toProjection() *types.Projection {
+	switch {
+	case p.all:
+		return &types.Projection{ProjectionType: types.ProjectionTypeAll}
+	case p.include:
+		attributes := make([]string, 0, len(p.attributes))
+		for attribute := range p.attributes {
+			attributes = append(attributes, attribute)
+		}
+
+		sort.Strings(attributes)
+
+		return &types.Projection{ProjectionType: types.ProjectionTypeInclude, NonKeyAttributes: attributes}
+	default:
+		return &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly}
+	}
+}
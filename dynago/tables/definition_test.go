@@ -0,0 +1,201 @@
+package tables
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/tools/dynago/entities"
+	"github.com/KirkDiggler/go-projects/tools/dynago/mappings"
+	"github.com/KirkDiggler/go-projects/tools/dynago/repositories"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLookup(t *testing.T, name string, fields ...string) mappings.Interface {
+	mapping, err := mappings.NewLookup(&mappings.LookupConfig{MappingName: name, Fields: fields})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mapping
+}
+
+func newQuery(t *testing.T, name, partition, sort string) mappings.Interface {
+	mapping, err := mappings.NewQuery(&mappings.QueryConfig{
+		MappingName:     name,
+		PartitionFields: []string{partition},
+		SortFields:      []string{sort},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mapping
+}
+
+func testRepositories(t *testing.T) []*repositories.Config {
+	return []*repositories.Config{{
+		Name:         "Products",
+		TableMapping: newLookup(t, "by-id", "id"),
+		IndexMappings: []*mappings.Index{{
+			Mapping:        newQuery(t, "by-category", "category", "name"),
+			ProjectionType: entities.PropjectionTypeKeysOnly,
+		}, {
+			Mapping:          newQuery(t, "by-brand", "brand", "name"),
+			ProjectionType:   entities.PropjectionTypeInclude,
+			NonKeyAttributes: []string{"price"},
+		}},
+	}, {
+		Name:         "Orders",
+		EntityType:   "order",
+		TableMapping: newLookup(t, "by-id", "id"),
+		IndexMappings: []*mappings.Index{{
+			Mapping:          newQuery(t, "by-customer", "customer", "created"),
+			ProjectionType:   entities.PropjectionTypeInclude,
+			NonKeyAttributes: []string{"total"},
+		}},
+	}}
+}
+
+func keySchema(partition, sort string) []types.KeySchemaElement {
+	out := []types.KeySchemaElement{{AttributeName: aws.String(partition), KeyType: types.KeyTypeHash}}
+	if sort != "" {
+		out = append(out, types.KeySchemaElement{AttributeName: aws.String(sort), KeyType: types.KeyTypeRange})
+	}
+
+	return out
+}
+
+func attributeDefinitions(names ...string) []types.AttributeDefinition {
+	out := make([]types.AttributeDefinition, len(names))
+	for idx, name := range names {
+		out[idx] = types.AttributeDefinition{AttributeName: aws.String(name), AttributeType: types.ScalarAttributeTypeS}
+	}
+
+	return out
+}
+
+func TestBuild(t *testing.T) {
+	t.Run("it requires a table name", func(t *testing.T) {
+		_, err := Build(&Config{Repositories: testRepositories(t)})
+
+		assert.Equal(t, errors.New(requiresTableNameMsg), err)
+	})
+	t.Run("it requires a provisioned throughput for the PROVISIONED billing mode", func(t *testing.T) {
+		_, err := Build(&Config{
+			TableName:    "my-table",
+			BillingMode:  types.BillingModeProvisioned,
+			Repositories: testRepositories(t),
+		})
+
+		assert.Equal(t, errors.New(requiresThroughputMsg), err)
+	})
+	t.Run("it only adds a partition key for a lookup table without indexes", func(t *testing.T) {
+		actual, err := Build(&Config{
+			TableName:    "my-table",
+			Repositories: []*repositories.Config{{Name: "Products", TableMapping: newLookup(t, "by-id", "id")}},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Definition{
+			TableName:            "my-table",
+			KeySchema:            keySchema("pk", ""),
+			AttributeDefinitions: attributeDefinitions("pk"),
+			BillingMode:          types.BillingModePayPerRequest,
+		}, actual)
+	})
+	t.Run("it shares indexes between repositories and merges their projections", func(t *testing.T) {
+		actual, err := Build(&Config{TableName: "my-table", Repositories: testRepositories(t)})
+
+		assert.Nil(t, err)
+		assert.Equal(t, keySchema("pk", "sk"), actual.KeySchema)
+		assert.Equal(t, attributeDefinitions("pk", "sk", "GSI1pk", "GSI1sk", "GSI2pk", "GSI2sk"), actual.AttributeDefinitions)
+		assert.Equal(t, []types.GlobalSecondaryIndex{{
+			IndexName:  aws.String("GSI1pk-GSI1sk-Index"),
+			KeySchema:  keySchema("GSI1pk", "GSI1sk"),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeInclude, NonKeyAttributes: []string{"price", "total"}},
+		}, {
+			IndexName:  aws.String("GSI2pk-GSI2sk-Index"),
+			KeySchema:  keySchema("GSI2pk", "GSI2sk"),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+		}}, actual.GlobalSecondaryIndexes)
+	})
+	t.Run("it lays out the mappings the way the repositories assign them", func(t *testing.T) {
+		repoCfgs := testRepositories(t)
+
+		definition, err := Build(&Config{TableName: "my-table", Repositories: repoCfgs})
+		assert.Nil(t, err)
+
+		assigned := make(map[string]string)
+		for _, repoCfg := range repoCfgs {
+			repoCfg.Client = &dynamo.Mock{}
+			repoCfg.TableDesc = definition.Description()
+
+			repo, err := repositories.New(repoCfg)
+			assert.Nil(t, err)
+
+			for _, index := range repoCfg.IndexMappings {
+				assigned[index.Mapping.GetName()] = repo.Schema().Indexes[index.Mapping.GetName()].Name
+			}
+		}
+
+		assert.Equal(t, "GSI2pk-GSI2sk-Index", assigned[repoCfgs[0].IndexMappings[0].Mapping.GetName()])
+		assert.Equal(t, "GSI1pk-GSI1sk-Index", assigned[repoCfgs[0].IndexMappings[1].Mapping.GetName()])
+		assert.Equal(t, "GSI1pk-GSI1sk-Index", assigned[repoCfgs[1].IndexMappings[0].Mapping.GetName()])
+	})
+	t.Run("it reads the key attributes of pinned and edge indexes from their names", func(t *testing.T) {
+		actual, err := Build(&Config{
+			TableName: "my-table",
+			Repositories: []*repositories.Config{{
+				Name:         "Products",
+				TableMapping: newLookup(t, "by-id", "id"),
+				Edges:        &repositories.EdgeConfig{IndexName: "sk-pk-Index"},
+				IndexMappings: []*mappings.Index{{
+					Name:    "status-updated-Index",
+					Mapping: newQuery(t, "by-status", "status", "updated"),
+				}},
+			}},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, attributeDefinitions("pk", "sk", "status", "updated"), actual.AttributeDefinitions)
+		assert.Equal(t, "sk-pk-Index", aws.ToString(actual.GlobalSecondaryIndexes[0].IndexName))
+		assert.Equal(t, keySchema("sk", "pk"), actual.GlobalSecondaryIndexes[0].KeySchema)
+		assert.Equal(t, "status-updated-Index", aws.ToString(actual.GlobalSecondaryIndexes[1].IndexName))
+	})
+	t.Run("it reserves the outbox index with the keys projected", func(t *testing.T) {
+		actual, err := Build(&Config{
+			TableName: "my-table",
+			Repositories: []*repositories.Config{{
+				Name:         "Products",
+				TableMapping: newLookup(t, "by-id", "id"),
+				Outbox:       &repositories.OutboxConfig{IndexName: "outbox-created-Index"},
+				IndexMappings: []*mappings.Index{{
+					Mapping: newQuery(t, "by-status", "status", "updated"),
+				}},
+			}},
+		})
+
+		assert.Nil(t, err)
+		assert.Len(t, actual.GlobalSecondaryIndexes, 2)
+		assert.Equal(t, "outbox-created-Index", aws.ToString(actual.GlobalSecondaryIndexes[1].IndexName))
+		assert.Equal(t, keySchema("outbox", "created"), actual.GlobalSecondaryIndexes[1].KeySchema)
+		assert.Equal(t, types.ProjectionTypeKeysOnly, actual.GlobalSecondaryIndexes[1].Projection.ProjectionType)
+		assert.NotEqual(t, "outbox-created-Index", aws.ToString(actual.GlobalSecondaryIndexes[0].IndexName))
+	})
+	t.Run("it rejects index names it can not read key attributes from", func(t *testing.T) {
+		_, err := Build(&Config{
+			TableName: "my-table",
+			Repositories: []*repositories.Config{{
+				Name:          "Products",
+				TableMapping:  newLookup(t, "by-id", "id"),
+				IndexMappings: []*mappings.Index{{Name: "by-status", Mapping: newQuery(t, "by-status", "status", "updated")}},
+			}},
+		})
+
+		assert.EqualError(t, err, "index name by-status must be of the form <partition>-<sort>-Index")
+	})
+}
@@ -0,0 +1,205 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetable"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// EnsureResult
+//
+// What Ensure changed. Dynamo makes one change at a time, call Ensure again once the table is ACTIVE until it
+// changes nothing and PendingIndexes is empty
+type EnsureResult struct {
+	// CreatedTable is true when the table did not exist and was created
+	CreatedTable bool
+
+	// UpdatedBilling is true when Ensure changed the billing mode or the provisioned throughput of the table
+	UpdatedBilling bool
+
+	// CreatedIndex is the index Ensure started creating, empty when none was
+	CreatedIndex string
+
+	// PendingIndexes are indexes of the definition the table does not have yet
+	PendingIndexes []string
+
+	TableDescription *types.TableDescription
+}
+
+// Ensure
+//
+// Creates the table when it does not exist, otherwise reconciles it with the definition. A table another
+// process created first is reconciled instead. The billing mode and provisioned throughput are updated first,
+// then missing global secondary indexes are created one per call while no other index is being created.
+// Nothing is updated while the table is CREATING or UPDATING. Differences that can not be changed in place,
+// a key schema or a projection that does not hold what the definition needs, are returned as errors. Calling
+// Ensure on a table that matches the definition changes nothing
+func Ensure(ctx context.Context, client dynamo.Interface, definition *Definition) (*EnsureResult, error) {
+	if client == nil {
+		return nil, errors.New("tables.Ensure requires a client")
+	}
+
+	if definition == nil {
+		return nil, errors.New("tables.Ensure requires a Definition")
+	}
+
+	tableDesc, created, err := describeOrCreate(ctx, client, definition)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		return &EnsureResult{CreatedTable: true, TableDescription: tableDesc}, nil
+	}
+
+	if tableDesc == nil {
+		return nil, fmt.Errorf("tables: table %s was not described", definition.TableName)
+	}
+
+	plan := NewPlan(definition, tableDesc)
+	if len(plan.Conflicts) != 0 {
+		return nil, fmt.Errorf("tables: %s", strings.Join(plan.Conflicts, "; "))
+	}
+
+	out := &EnsureResult{TableDescription: tableDesc}
+
+	for _, index := range plan.CreateIndexes {
+		out.PendingIndexes = append(out.PendingIndexes, aws.ToString(index.IndexName))
+	}
+
+	// dynamo rejects updates until the table is ACTIVE
+	if tableDesc.TableStatus == types.TableStatusCreating || tableDesc.TableStatus == types.TableStatusUpdating {
+		return out, nil
+	}
+
+	if plan.UpdateBilling {
+		updated, err := client.UpdateTable(ctx, definition.TableName, billingUpdate(definition, tableDesc)...)
+		if err != nil {
+			return nil, err
+		}
+
+		out.UpdatedBilling = true
+		out.TableDescription = updated.TableDescription
+
+		return out, nil
+	}
+
+	if len(plan.CreateIndexes) == 0 || isCreatingIndex(tableDesc) {
+		return out, nil
+	}
+
+	create := plan.CreateIndexes[0]
+
+	updated, err := client.UpdateTable(ctx, definition.TableName,
+		updatetable.WithAttributeDefinitions(keyAttributeDefinitions(create.KeySchema)...),
+		updatetable.WithGlobalSecondaryIndexUpdates(types.GlobalSecondaryIndexUpdate{
+			Create: &types.CreateGlobalSecondaryIndexAction{
+				IndexName:             create.IndexName,
+				KeySchema:             create.KeySchema,
+				Projection:            create.Projection,
+				ProvisionedThroughput: create.ProvisionedThroughput,
+			},
+		}))
+	if err != nil {
+		return nil, err
+	}
+
+	out.CreatedIndex = out.PendingIndexes[0]
+	out.PendingIndexes = out.PendingIndexes[1:]
+	out.TableDescription = updated.TableDescription
+
+	return out, nil
+}
+
+// describeOrCreate describes the table and creates it when it does not exist, created is true when it was
+// created. A table another process created first is described again
+func describeOrCreate(ctx context.Context, client dynamo.Interface, definition *Definition) (*types.TableDescription, bool, error) {
+	described, err := client.DescribeTable(ctx, definition.TableName)
+	if err == nil {
+		return described.Table, false, nil
+	}
+
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		return nil, false, err
+	}
+
+	created, err := client.CreateTable(ctx, definition.TableName, definition.CreateOptions()...)
+	if err == nil {
+		return created.TableDescription, true, nil
+	}
+
+	var inUse *types.ResourceInUseException
+	if !errors.As(err, &inUse) {
+		return nil, false, err
+	}
+
+	described, err = client.DescribeTable(ctx, definition.TableName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return described.Table, false, nil
+}
+
+// billingUpdate switches the table to the billing mode and throughput of the definition. Indexes need a
+// throughput of their own when the table switches to PROVISIONED
+func billingUpdate(definition *Definition, tableDesc *types.TableDescription) []updatetable.OptionFunc {
+	out := []updatetable.OptionFunc{
+		updatetable.WithBillingMode(definition.BillingMode),
+	}
+
+	if definition.BillingMode != types.BillingModeProvisioned {
+		return out
+	}
+
+	out = append(out, updatetable.WithProvisionedThroughput(definition.ProvisionedThroughput))
+
+	if tableDesc.BillingModeSummary == nil || tableDesc.BillingModeSummary.BillingMode == types.BillingModeProvisioned {
+		return out
+	}
+
+	var indexUpdates []types.GlobalSecondaryIndexUpdate
+	for _, index := range tableDesc.GlobalSecondaryIndexes {
+		indexUpdates = append(indexUpdates, types.GlobalSecondaryIndexUpdate{
+			Update: &types.UpdateGlobalSecondaryIndexAction{
+				IndexName:             index.IndexName,
+				ProvisionedThroughput: definition.ProvisionedThroughput,
+			},
+		})
+	}
+
+	if len(indexUpdates) != 0 {
+		out = append(out, updatetable.WithGlobalSecondaryIndexUpdates(indexUpdates...))
+	}
+
+	return out
+}
+
+func keyAttributeDefinitions(keySchema []types.KeySchemaElement) []types.AttributeDefinition {
+	out := make([]types.AttributeDefinition, 0, len(keySchema))
+	for _, element := range keySchema {
+		out = append(out, types.AttributeDefinition{
+			AttributeName: element.AttributeName,
+			AttributeType: types.ScalarAttributeTypeS,
+		})
+	}
+
+	return out
+}
+
+func isCreatingIndex(tableDesc *types.TableDescription) bool {
+	for _, index := range tableDesc.GlobalSecondaryIndexes {
+		if index.IndexStatus == types.IndexStatusCreating {
+			return true
+		}
+	}
+
+	return false
+}
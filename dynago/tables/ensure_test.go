@@ -0,0 +1,245 @@
+package tables
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/createtable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetable"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testDefinition(t *testing.T) *Definition {
+	definition, err := Build(&Config{TableName: "my-table", Repositories: testRepositories(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return definition
+}
+
+func TestEnsure(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it creates a missing table", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")})
+		client.On("CreateTable", ctx, "my-table", createtable.NewOptions(definition.CreateOptions()...)).
+			Return(&createtable.Result{TableDescription: definition.Description()}, nil)
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.True(t, actual.CreatedTable)
+		client.AssertExpectations(t)
+	})
+	t.Run("it reconciles a table another process created first", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")}).Once()
+		client.On("CreateTable", ctx, "my-table", mock.Anything).
+			Return(nil, &types.ResourceInUseException{Message: aws.String("Table already exists: my-table")})
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: definition.Description()}, nil).Once()
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &EnsureResult{TableDescription: definition.Description()}, actual)
+		client.AssertExpectations(t)
+	})
+	t.Run("it changes nothing when the table matches", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: definition.Description()}, nil)
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &EnsureResult{TableDescription: definition.Description()}, actual)
+		client.AssertNotCalled(t, "UpdateTable", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("it creates the first missing index and reports the rest", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		tableDesc := definition.Description()
+		tableDesc.GlobalSecondaryIndexes = nil
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil)
+
+		first := definition.GlobalSecondaryIndexes[0]
+
+		client.On("UpdateTable", ctx, "my-table", updatetable.NewOptions(
+			updatetable.WithAttributeDefinitions(attributeDefinitions("GSI1pk", "GSI1sk")...),
+			updatetable.WithGlobalSecondaryIndexUpdates(types.GlobalSecondaryIndexUpdate{
+				Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName:  first.IndexName,
+					KeySchema:  first.KeySchema,
+					Projection: first.Projection,
+				},
+			}))).
+			Return(&updatetable.Result{TableDescription: tableDesc}, nil)
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "GSI1pk-GSI1sk-Index", actual.CreatedIndex)
+		assert.Equal(t, []string{"GSI2pk-GSI2sk-Index"}, actual.PendingIndexes)
+		client.AssertExpectations(t)
+	})
+	t.Run("it waits while an index is being created", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		tableDesc := definition.Description()
+		tableDesc.GlobalSecondaryIndexes = tableDesc.GlobalSecondaryIndexes[:1]
+		tableDesc.GlobalSecondaryIndexes[0].IndexStatus = types.IndexStatusCreating
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil)
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", actual.CreatedIndex)
+		assert.Equal(t, []string{"GSI2pk-GSI2sk-Index"}, actual.PendingIndexes)
+		client.AssertNotCalled(t, "UpdateTable", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("it waits while the table is being updated", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		tableDesc := definition.Description()
+		tableDesc.TableStatus = types.TableStatusUpdating
+		tableDesc.GlobalSecondaryIndexes = nil
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil)
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", actual.CreatedIndex)
+		assert.Equal(t, []string{"GSI1pk-GSI1sk-Index", "GSI2pk-GSI2sk-Index"}, actual.PendingIndexes)
+		client.AssertNotCalled(t, "UpdateTable", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("it updates the billing mode before creating indexes", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		tableDesc := definition.Description()
+		tableDesc.BillingModeSummary = &types.BillingModeSummary{BillingMode: types.BillingModeProvisioned}
+		tableDesc.GlobalSecondaryIndexes = nil
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil)
+		client.On("UpdateTable", ctx, "my-table", updatetable.NewOptions(
+			updatetable.WithBillingMode(types.BillingModePayPerRequest))).
+			Return(&updatetable.Result{TableDescription: tableDesc}, nil)
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.True(t, actual.UpdatedBilling)
+		assert.Equal(t, "", actual.CreatedIndex)
+		assert.Equal(t, []string{"GSI1pk-GSI1sk-Index", "GSI2pk-GSI2sk-Index"}, actual.PendingIndexes)
+		client.AssertExpectations(t)
+	})
+	t.Run("it gives the indexes a throughput when the table switches to provisioned", func(t *testing.T) {
+		throughput := &types.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(5)}
+
+		definition, err := Build(&Config{
+			TableName:             "my-table",
+			Repositories:          testRepositories(t),
+			BillingMode:           types.BillingModeProvisioned,
+			ProvisionedThroughput: throughput,
+		})
+		assert.Nil(t, err)
+
+		tableDesc := definition.Description()
+		tableDesc.BillingModeSummary = &types.BillingModeSummary{BillingMode: types.BillingModePayPerRequest}
+		tableDesc.ProvisionedThroughput = nil
+
+		client := &dynamo.Mock{}
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil)
+		client.On("UpdateTable", ctx, "my-table", updatetable.NewOptions(
+			updatetable.WithBillingMode(types.BillingModeProvisioned),
+			updatetable.WithProvisionedThroughput(throughput),
+			updatetable.WithGlobalSecondaryIndexUpdates(types.GlobalSecondaryIndexUpdate{
+				Update: &types.UpdateGlobalSecondaryIndexAction{
+					IndexName:             aws.String("GSI1pk-GSI1sk-Index"),
+					ProvisionedThroughput: throughput,
+				},
+			}, types.GlobalSecondaryIndexUpdate{
+				Update: &types.UpdateGlobalSecondaryIndexAction{
+					IndexName:             aws.String("GSI2pk-GSI2sk-Index"),
+					ProvisionedThroughput: throughput,
+				},
+			}))).
+			Return(&updatetable.Result{TableDescription: tableDesc}, nil)
+
+		actual, err := Ensure(ctx, client, definition)
+
+		assert.Nil(t, err)
+		assert.True(t, actual.UpdatedBilling)
+		client.AssertExpectations(t)
+	})
+	t.Run("it plans a throughput update for a provisioned table", func(t *testing.T) {
+		definition, err := Build(&Config{
+			TableName:             "my-table",
+			Repositories:          testRepositories(t),
+			BillingMode:           types.BillingModeProvisioned,
+			ProvisionedThroughput: &types.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(10), WriteCapacityUnits: aws.Int64(5)},
+		})
+		assert.Nil(t, err)
+
+		tableDesc := definition.Description()
+		assert.True(t, NewPlan(definition, tableDesc).Empty())
+
+		tableDesc.ProvisionedThroughput = &types.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(5)}
+		assert.True(t, NewPlan(definition, tableDesc).UpdateBilling)
+	})
+	t.Run("it returns an error when an index does not project what is needed", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		tableDesc := definition.Description()
+		tableDesc.GlobalSecondaryIndexes[0].Projection = &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly}
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil)
+
+		_, err := Ensure(ctx, client, definition)
+
+		assert.EqualError(t, err, "tables: index GSI1pk-GSI1sk-Index projects KEYS_ONLY but INCLUDE [price total] is needed")
+	})
+	t.Run("it returns an error when the table key schema differs", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		definition := testDefinition(t)
+
+		tableDesc := definition.Description()
+		tableDesc.KeySchema = keySchema("id", "")
+
+		client.On("DescribeTable", ctx, "my-table").
+			Return(&describetable.Result{Table: tableDesc}, nil)
+
+		_, err := Ensure(ctx, client, definition)
+
+		assert.EqualError(t, err, "tables: table my-table has the partition key id but pk is needed")
+	})
+}
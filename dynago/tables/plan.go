@@ -0,0 +1,173 @@
+package tables
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Plan
+//
+// The changes that bring a table in line with a definition
+type Plan struct {
+	TableName string
+
+	// CreateTable is true when the table does not exist
+	CreateTable bool
+
+	// UpdateBilling is true when the billing mode or the provisioned throughput of the table differs from the
+	// definition
+	UpdateBilling bool
+
+	// CreateIndexes are the indexes of the definition the table does not have, in the order Ensure creates them
+	CreateIndexes []types.GlobalSecondaryIndex
+
+	// Conflicts are differences that can not be changed in place, a key schema or a projection that
+	// does not hold what the definition needs
+	Conflicts []string
+}
+
+// NewPlan
+//
+// Compares the definition with the table description, a nil description plans to create the table.
+// Indexes the definition does not use and a table sort key it does not need are left alone, as is the
+// throughput of the indexes
+func NewPlan(definition *Definition, tableDesc *types.TableDescription) *Plan {
+	out := &Plan{TableName: definition.TableName}
+
+	if tableDesc == nil {
+		out.CreateTable = true
+
+		return out
+	}
+
+	if conflict := compareKeySchema("table "+definition.TableName, definition.KeySchema, tableDesc.KeySchema); conflict != "" {
+		out.Conflicts = append(out.Conflicts, conflict)
+	}
+
+	out.UpdateBilling = billingDiffers(definition, tableDesc)
+
+	existing := make(map[string]types.GlobalSecondaryIndexDescription)
+	for _, index := range tableDesc.GlobalSecondaryIndexes {
+		existing[aws.ToString(index.IndexName)] = index
+	}
+
+	for _, index := range definition.GlobalSecondaryIndexes {
+		name := aws.ToString(index.IndexName)
+
+		description, ok := existing[name]
+		if !ok {
+			out.CreateIndexes = append(out.CreateIndexes, index)
+
+			continue
+		}
+
+		if conflict := compareKeySchema("index "+name, index.KeySchema, description.KeySchema); conflict != "" {
+			out.Conflicts = append(out.Conflicts, conflict)
+		}
+
+		if !projectionHolds(description.Projection, index.Projection) {
+			out.Conflicts = append(out.Conflicts, fmt.Sprintf("index %s projects %s but %s is needed", name,
+				projectionString(description.Projection), projectionString(index.Projection)))
+		}
+	}
+
+	return out
+}
+
+// Empty reports whether the table already matches the definition
+func (p *Plan) Empty() bool {
+	return !p.CreateTable && !p.UpdateBilling && len(p.CreateIndexes) == 0 && len(p.Conflicts) == 0
+}
+
+// billingDiffers reports whether the table is billed in another mode than the definition, or with another
+// provisioned throughput
+func billingDiffers(definition *Definition, tableDesc *types.TableDescription) bool {
+	// tables created before on demand capacity have no summary
+	described := types.BillingModeProvisioned
+	if tableDesc.BillingModeSummary != nil && tableDesc.BillingModeSummary.BillingMode != "" {
+		described = tableDesc.BillingModeSummary.BillingMode
+	}
+
+	if described != definition.BillingMode {
+		return true
+	}
+
+	if definition.BillingMode != types.BillingModeProvisioned || definition.ProvisionedThroughput == nil {
+		return false
+	}
+
+	throughput := tableDesc.ProvisionedThroughput
+	if throughput == nil {
+		return true
+	}
+
+	return aws.ToInt64(throughput.ReadCapacityUnits) != aws.ToInt64(definition.ProvisionedThroughput.ReadCapacityUnits) ||
+		aws.ToInt64(throughput.WriteCapacityUnits) != aws.ToInt64(definition.ProvisionedThroughput.WriteCapacityUnits)
+}
+
+// compareKeySchema checks the described key schema has the partition and sort keys of the expected one.
+// A described sort key the definition does not need is allowed
+func compareKeySchema(name string, expected, described []types.KeySchemaElement) string {
+	expectedKeys := keyNames(expected)
+	describedKeys := keyNames(described)
+
+	if expectedKeys[types.KeyTypeHash] != describedKeys[types.KeyTypeHash] {
+		return fmt.Sprintf("%s has the partition key %s but %s is needed", name,
+			describedKeys[types.KeyTypeHash], expectedKeys[types.KeyTypeHash])
+	}
+
+	if expectedKeys[types.KeyTypeRange] != "" && expectedKeys[types.KeyTypeRange] != describedKeys[types.KeyTypeRange] {
+		return fmt.Sprintf("%s has the sort key '%s' but %s is needed", name,
+			describedKeys[types.KeyTypeRange], expectedKeys[types.KeyTypeRange])
+	}
+
+	return ""
+}
+
+func keyNames(keySchema []types.KeySchemaElement) map[types.KeyType]string {
+	out := make(map[types.KeyType]string)
+	for _, element := range keySchema {
+		out[element.KeyType] = aws.ToString(element.AttributeName)
+	}
+
+	return out
+}
+
+// projectionHolds reports whether the described projection holds every attribute of the needed one
+func projectionHolds(described, needed *types.Projection) bool {
+	if described == nil || described.ProjectionType == types.ProjectionTypeAll {
+		return true
+	}
+
+	switch needed.ProjectionType {
+	case types.ProjectionTypeAll:
+		return false
+	case types.ProjectionTypeInclude:
+		projected := make(map[string]bool)
+		for _, attribute := range described.NonKeyAttributes {
+			projected[attribute] = true
+		}
+
+		for _, attribute := range needed.NonKeyAttributes {
+			if !projected[attribute] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func projectionString(projection *types.Projection) string {
+	if projection == nil {
+		return string(types.ProjectionTypeAll)
+	}
+
+	if projection.ProjectionType == types.ProjectionTypeInclude {
+		return fmt.Sprintf("%s %v", projection.ProjectionType, projection.NonKeyAttributes)
+	}
+
+	return string(projection.ProjectionType)
+}
@@ -0,0 +1,384 @@
+// Package batcher coalesces concurrent GetItem calls into BatchGetItem calls, in the manner of a dataloader
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/codec"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// maxBatchSize is the most keys BatchGetItem accepts
+	maxBatchSize = 100
+
+	defaultWindow     = 2 * time.Millisecond
+	defaultMaxRetries = 8
+	defaultRetryDelay = 50 * time.Millisecond
+
+	// validationErrorCode is returned for a request DynamoDB rejects, such as a key that does not match the schema
+	validationErrorCode = "ValidationException"
+
+	requiredClientConfigMsg = "batcher.NewClient requires a ClientConfig"
+	requiredClientMsg       = "the field Client is required"
+	invalidMaxBatchSizeMsg  = "the field MaxBatchSize can not be more than 100"
+	invalidWindowMsg        = "the field Window can not be negative"
+)
+
+// ClientConfig
+//
+// The client to batch and how long GetItem calls are collected
+type ClientConfig struct {
+	Client dynamo.Interface
+
+	// Window is how long the first GetItem of a batch waits for others, defaults to 2ms
+	Window time.Duration
+
+	// MaxBatchSize sends a batch once it holds this many distinct keys, defaults to and can not be more than 100
+	MaxBatchSize int
+
+	// MaxRetries is the number of times unprocessed keys are read again before their GetItem fails, defaults to 8
+	MaxRetries int
+
+	// RetryDelay is the wait before the first retry, it doubles on every retry. Defaults to 50ms
+	RetryDelay time.Duration
+}
+
+// Client
+//
+// A dynamo.Interface that collects the GetItem calls of a table made within Window and reads them with a single
+// BatchGetItem. Calls for the same key share the read. A batch DynamoDB rejects as invalid is read again key by key
+// with GetItem, so a malformed key only fails its own calls. GetItem calls with a projection or asking for the
+// consumed capacity go to the wrapped client, every other call is passed through
+type Client struct {
+	dynamo.Interface
+
+	window       time.Duration
+	maxBatchSize int
+	maxRetries   int
+	retryDelay   time.Duration
+
+	mu      sync.Mutex
+	batches map[batchGroup]*batch
+}
+
+// batchGroup separates the keys that can be read together, consistent reads are requested per table and the keys
+// of a batch have the same attribute names
+type batchGroup struct {
+	tableName  string
+	consistent bool
+	keyNames   string
+}
+
+type batch struct {
+	group batchGroup
+	ctx   context.Context
+	timer *time.Timer
+	keys  []map[string]types.AttributeValue
+	loads map[string]*load
+}
+
+// load is the read of a key shared by the callers waiting on done
+type load struct {
+	key  map[string]types.AttributeValue
+	done chan struct{}
+	item map[string]types.AttributeValue
+	err  error
+}
+
+func NewClient(cfg *ClientConfig) (*Client, error) {
+	if cfg == nil {
+		return nil, errors.New(requiredClientConfigMsg)
+	}
+
+	if cfg.Client == nil {
+		return nil, errors.New(requiredClientMsg)
+	}
+
+	if cfg.MaxBatchSize > maxBatchSize {
+		return nil, errors.New(invalidMaxBatchSizeMsg)
+	}
+
+	if cfg.Window < 0 {
+		return nil, errors.New(invalidWindowMsg)
+	}
+
+	client := &Client{
+		Interface:    cfg.Client,
+		window:       defaultWindow,
+		maxBatchSize: maxBatchSize,
+		maxRetries:   defaultMaxRetries,
+		retryDelay:   defaultRetryDelay,
+		batches:      make(map[batchGroup]*batch),
+	}
+
+	if cfg.Window > 0 {
+		client.window = cfg.Window
+	}
+
+	if cfg.MaxBatchSize > 0 {
+		client.maxBatchSize = cfg.MaxBatchSize
+	}
+
+	if cfg.MaxRetries > 0 {
+		client.maxRetries = cfg.MaxRetries
+	}
+
+	if cfg.RetryDelay > 0 {
+		client.retryDelay = cfg.RetryDelay
+	}
+
+	return client, nil
+}
+
+// GetItem
+//
+// Adds the key to the pending batch of the table and waits for it to be read. Cancelling ctx stops the wait, the
+// batch is still read for the other callers
+func (c *Client) GetItem(ctx context.Context, tableName string, getOptions ...getitem.OptionFunc) (*getitem.Result, error) {
+	options := getitem.NewOptions(getOptions...)
+
+	if options.Key == nil || options.ProjectionBuilder != nil ||
+		(options.ReturnConsumedCapacity != "" && options.ReturnConsumedCapacity != types.ReturnConsumedCapacityNone) {
+		return c.Interface.GetItem(ctx, tableName, getOptions...)
+	}
+
+	loadKey, err := buildLoadKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	group := batchGroup{
+		tableName:  tableName,
+		consistent: aws.ToBool(options.ConsistentRead),
+		keyNames:   strings.Join(keyAttributeNames(options.Key), ","),
+	}
+
+	l := c.enqueue(ctx, group, loadKey, options.Key)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-l.done:
+	}
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	item := copyItem(l.item)
+
+	if options.Entity != nil && item != nil {
+		if err := attributevalue.UnmarshalMap(item, options.Entity); err != nil {
+			return nil, err
+		}
+	}
+
+	return &getitem.Result{Item: item}, nil
+}
+
+// enqueue returns the load of the key, a batch is sent once it is full or its window ends
+func (c *Client) enqueue(ctx context.Context, group batchGroup, loadKey string, key map[string]types.AttributeValue) *load {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.batches[group]
+	if !ok {
+		b = &batch{group: group, ctx: detached{ctx}, loads: make(map[string]*load)}
+		b.timer = time.AfterFunc(c.window, func() { c.flush(b) })
+		c.batches[group] = b
+	}
+
+	if l, ok := b.loads[loadKey]; ok {
+		return l
+	}
+
+	l := &load{key: key, done: make(chan struct{})}
+	b.loads[loadKey] = l
+	b.keys = append(b.keys, key)
+
+	if len(b.keys) == c.maxBatchSize {
+		b.timer.Stop()
+		delete(c.batches, group)
+
+		go c.dispatch(b)
+	}
+
+	return l
+}
+
+// flush sends a batch when its window ends, unless it was sent once it was full
+func (c *Client) flush(b *batch) {
+	c.mu.Lock()
+	pending := c.batches[b.group] == b
+	if pending {
+		delete(c.batches, b.group)
+	}
+	c.mu.Unlock()
+
+	if pending {
+		c.dispatch(b)
+	}
+}
+
+// dispatch reads the keys of a batch, retrying unprocessed keys with a backoff, and releases their callers.
+// A key that was processed without an item is released with a nil item. A batch rejected as invalid is read key
+// by key
+func (c *Client) dispatch(b *batch) {
+	names := keyAttributeNames(b.keys[0])
+	request := types.KeysAndAttributes{Keys: b.keys}
+	if b.group.consistent {
+		request.ConsistentRead = aws.Bool(true)
+	}
+
+	err := c.read(b.ctx, b.group.tableName, request, func(item map[string]types.AttributeValue) {
+		loadKey, err := buildLoadKey(pick(item, names))
+		if err != nil {
+			return
+		}
+
+		if l, ok := b.loads[loadKey]; ok {
+			l.item = item
+		}
+	})
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == validationErrorCode {
+		c.readEach(b)
+
+		return
+	}
+
+	for _, l := range b.loads {
+		if err != nil && l.item == nil {
+			l.err = err
+		}
+
+		close(l.done)
+	}
+}
+
+// readEach reads the keys of a batch with a GetItem each and releases their callers
+func (c *Client) readEach(b *batch) {
+	var wg sync.WaitGroup
+
+	for _, l := range b.loads {
+		wg.Add(1)
+
+		go func(l *load) {
+			defer wg.Done()
+			defer close(l.done)
+
+			result, err := c.Interface.GetItem(b.ctx, b.group.tableName,
+				getitem.WithKey(l.key),
+				getitem.WithConsistentRead(aws.Bool(b.group.consistent)))
+			if err != nil {
+				l.err = err
+
+				return
+			}
+
+			l.item = result.Item
+		}(l)
+	}
+
+	wg.Wait()
+}
+
+func (c *Client) read(ctx context.Context, tableName string, request types.KeysAndAttributes, found func(map[string]types.AttributeValue)) error {
+	requestItems := map[string]types.KeysAndAttributes{tableName: request}
+	delay := c.retryDelay
+
+	for attempt := 0; ; attempt++ {
+		result, err := c.Interface.BatchGetItem(ctx, batchgetitem.WithRequestItems(requestItems))
+		if err != nil {
+			return err
+		}
+
+		for _, item := range result.Responses[tableName] {
+			found(item)
+		}
+
+		if len(result.UnprocessedKeys[tableName].Keys) == 0 {
+			return nil
+		}
+
+		if attempt == c.maxRetries {
+			return fmt.Errorf("batcher: %d keys were still unprocessed after %d retries", len(result.UnprocessedKeys[tableName].Keys), c.maxRetries)
+		}
+
+		requestItems = result.UnprocessedKeys
+
+		time.Sleep(delay)
+
+		delay *= 2
+	}
+}
+
+// detached keeps the values of the context that started a batch without its cancellation, so one caller giving up
+// does not fail the read for the others
+type detached struct {
+	parent context.Context
+}
+
+func (d detached) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detached) Done() <-chan struct{}             { return nil }
+func (d detached) Err() error                        { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+func buildLoadKey(key map[string]types.AttributeValue) (string, error) {
+	data, err := codec.MarshalItem(key)
+	if err != nil {
+		return "", fmt.Errorf("batcher: %s", err)
+	}
+
+	return string(data), nil
+}
+
+func keyAttributeNames(key map[string]types.AttributeValue) []string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func pick(item map[string]types.AttributeValue, names []string) map[string]types.AttributeValue {
+	out := make(map[string]types.AttributeValue, len(names))
+	for _, name := range names {
+		if value, ok := item[name]; ok {
+			out[name] = value
+		}
+	}
+
+	return out
+}
+
+// copyItem copies the top level of an item shared by several callers
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if item == nil {
+		return nil
+	}
+
+	out := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		out[name] = value
+	}
+
+	return out
+}
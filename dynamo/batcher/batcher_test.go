@@ -0,0 +1,292 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testEntity struct {
+	ID   string `dynamodbav:"id"`
+	Name string `dynamodbav:"name"`
+}
+
+func testKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}}
+}
+
+func testItem(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":   &types.AttributeValueMemberS{Value: id},
+		"name": &types.AttributeValueMemberS{Value: "name " + id},
+	}
+}
+
+// requestOf matches a BatchGetItem reading the sorted ids from my-table in any order
+func requestOf(consistent bool, ids ...string) interface{} {
+	return mock.MatchedBy(func(options *batchgetitem.Options) bool {
+		request, ok := options.RequestItems["my-table"]
+		if !ok || len(options.RequestItems) != 1 || aws.ToBool(request.ConsistentRead) != consistent {
+			return false
+		}
+
+		actual := make([]string, 0, len(request.Keys))
+		for _, key := range request.Keys {
+			actual = append(actual, key["id"].(*types.AttributeValueMemberS).Value)
+		}
+
+		sort.Strings(actual)
+
+		return reflect.DeepEqual(ids, actual)
+	})
+}
+
+func getConcurrently(client *Client, ids ...string) ([]*getitem.Result, []error) {
+	var wg sync.WaitGroup
+	results := make([]*getitem.Result, len(ids))
+	errs := make([]error, len(ids))
+
+	for i, id := range ids {
+		wg.Add(1)
+
+		go func(i int, id string) {
+			defer wg.Done()
+
+			results[i], errs[i] = client.GetItem(context.Background(), "my-table", getitem.WithKey(testKey(id)))
+		}(i, id)
+	}
+
+	wg.Wait()
+
+	return results, errs
+}
+
+func newTestClient(t *testing.T, cfg *ClientConfig) *Client {
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	t.Run("it requires a config", func(t *testing.T) {
+		_, err := NewClient(nil)
+
+		assert.Equal(t, errors.New(requiredClientConfigMsg), err)
+	})
+	t.Run("it requires a client", func(t *testing.T) {
+		_, err := NewClient(&ClientConfig{})
+
+		assert.Equal(t, errors.New(requiredClientMsg), err)
+	})
+	t.Run("it rejects a batch size over 100", func(t *testing.T) {
+		_, err := NewClient(&ClientConfig{Client: &dynamo.Mock{}, MaxBatchSize: 101})
+
+		assert.Equal(t, errors.New(invalidMaxBatchSizeMsg), err)
+	})
+}
+
+func TestClient_GetItem(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it reads the keys of a full batch together", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "a", "b", "c")).
+			Return(&batchgetitem.Result{Responses: map[string][]map[string]types.AttributeValue{
+				"my-table": {testItem("c"), testItem("a")},
+			}}, nil).Once()
+
+		fixture := newTestClient(t, &ClientConfig{Client: client, Window: time.Hour, MaxBatchSize: 3})
+
+		results, errs := getConcurrently(fixture, "a", "b", "c")
+
+		assert.Equal(t, []error{nil, nil, nil}, errs)
+		assert.Equal(t, testItem("a"), results[0].Item)
+		assert.Nil(t, results[1].Item)
+		assert.Equal(t, testItem("c"), results[2].Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it sends a batch when the window ends and reads a key once", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "a", "b")).
+			Return(&batchgetitem.Result{Responses: map[string][]map[string]types.AttributeValue{
+				"my-table": {testItem("a"), testItem("b")},
+			}}, nil).Once()
+
+		fixture := newTestClient(t, &ClientConfig{Client: client, Window: 100 * time.Millisecond})
+
+		results, errs := getConcurrently(fixture, "a", "b", "a")
+
+		assert.Equal(t, []error{nil, nil, nil}, errs)
+		assert.Equal(t, testItem("a"), results[0].Item)
+		assert.Equal(t, testItem("b"), results[1].Item)
+		assert.Equal(t, testItem("a"), results[2].Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it unmarshals the item into the entity", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, requestOf(true, "a")).
+			Return(&batchgetitem.Result{Responses: map[string][]map[string]types.AttributeValue{
+				"my-table": {testItem("a")},
+			}}, nil)
+
+		fixture := newTestClient(t, &ClientConfig{Client: client})
+
+		entity := &testEntity{}
+		_, err := fixture.GetItem(ctx, "my-table",
+			getitem.WithKey(testKey("a")),
+			getitem.WithConsistentRead(aws.Bool(true)),
+			getitem.AsEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &testEntity{ID: "a", Name: "name a"}, entity)
+	})
+	t.Run("it reads unprocessed keys again", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "a", "b")).
+			Return(&batchgetitem.Result{
+				Responses: map[string][]map[string]types.AttributeValue{"my-table": {testItem("a")}},
+				UnprocessedKeys: map[string]types.KeysAndAttributes{
+					"my-table": {Keys: []map[string]types.AttributeValue{testKey("b")}},
+				},
+			}, nil).Once()
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "b")).
+			Return(&batchgetitem.Result{Responses: map[string][]map[string]types.AttributeValue{
+				"my-table": {testItem("b")},
+			}}, nil).Once()
+
+		fixture := newTestClient(t, &ClientConfig{Client: client, MaxBatchSize: 2, Window: time.Hour, RetryDelay: time.Millisecond})
+
+		results, errs := getConcurrently(fixture, "a", "b")
+
+		assert.Equal(t, []error{nil, nil}, errs)
+		assert.Equal(t, testItem("b"), results[1].Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it fails the keys that stay unprocessed", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "a", "b")).
+			Return(&batchgetitem.Result{
+				Responses: map[string][]map[string]types.AttributeValue{"my-table": {testItem("a")}},
+				UnprocessedKeys: map[string]types.KeysAndAttributes{
+					"my-table": {Keys: []map[string]types.AttributeValue{testKey("b")}},
+				},
+			}, nil).Once()
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "b")).
+			Return(&batchgetitem.Result{UnprocessedKeys: map[string]types.KeysAndAttributes{
+				"my-table": {Keys: []map[string]types.AttributeValue{testKey("b")}},
+			}}, nil).Once()
+
+		fixture := newTestClient(t, &ClientConfig{Client: client, MaxBatchSize: 2, Window: time.Hour, MaxRetries: 1, RetryDelay: time.Millisecond})
+
+		results, errs := getConcurrently(fixture, "a", "b")
+
+		assert.Nil(t, errs[0])
+		assert.Equal(t, testItem("a"), results[0].Item)
+		assert.EqualError(t, errs[1], "batcher: 1 keys were still unprocessed after 1 retries")
+		client.AssertExpectations(t)
+	})
+	t.Run("it returns the error of the batch to every caller", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "a", "b")).Return(nil, errors.New("throttled"))
+
+		fixture := newTestClient(t, &ClientConfig{Client: client, MaxBatchSize: 2, Window: time.Hour})
+
+		_, errs := getConcurrently(fixture, "a", "b")
+
+		assert.Equal(t, []error{errors.New("throttled"), errors.New("throttled")}, errs)
+	})
+	t.Run("it reads the keys of an invalid batch one by one", func(t *testing.T) {
+		invalid := &smithy.GenericAPIError{Code: "ValidationException", Message: "The provided key element does not match the schema"}
+
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, requestOf(false, "a", "b")).Return(nil, invalid)
+		client.On("GetItem", mock.Anything, "my-table", getitem.NewOptions(getitem.WithKey(testKey("a")), getitem.WithConsistentRead(aws.Bool(false)))).
+			Return(&getitem.Result{Item: testItem("a")}, nil)
+		client.On("GetItem", mock.Anything, "my-table", getitem.NewOptions(getitem.WithKey(testKey("b")), getitem.WithConsistentRead(aws.Bool(false)))).
+			Return(nil, invalid)
+
+		fixture := newTestClient(t, &ClientConfig{Client: client, MaxBatchSize: 2, Window: time.Hour})
+
+		results, errs := getConcurrently(fixture, "a", "b")
+
+		assert.Nil(t, errs[0])
+		assert.Equal(t, testItem("a"), results[0].Item)
+		assert.Equal(t, invalid, errs[1])
+		client.AssertExpectations(t)
+	})
+	t.Run("it batches keys with different attribute names apart", func(t *testing.T) {
+		sortKey := map[string]types.AttributeValue{
+			"id":   &types.AttributeValueMemberS{Value: "b"},
+			"sort": &types.AttributeValueMemberS{Value: "1"},
+		}
+
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", mock.Anything, batchgetitem.NewOptions(batchgetitem.WithKeys("my-table", testKey("a")))).
+			Return(&batchgetitem.Result{Responses: map[string][]map[string]types.AttributeValue{"my-table": {testItem("a")}}}, nil)
+		client.On("BatchGetItem", mock.Anything, batchgetitem.NewOptions(batchgetitem.WithKeys("my-table", sortKey))).
+			Return(&batchgetitem.Result{}, nil)
+
+		fixture := newTestClient(t, &ClientConfig{Client: client, Window: time.Millisecond})
+
+		var wg sync.WaitGroup
+		var actual *getitem.Result
+		var err error
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			actual, err = fixture.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+		}()
+
+		other, otherErr := fixture.GetItem(ctx, "my-table", getitem.WithKey(sortKey))
+		wg.Wait()
+
+		assert.Nil(t, err)
+		assert.Equal(t, testItem("a"), actual.Item)
+		assert.Nil(t, otherErr)
+		assert.Nil(t, other.Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it stops waiting when the context is cancelled", func(t *testing.T) {
+		fixture := newTestClient(t, &ClientConfig{Client: &dynamo.Mock{}, Window: time.Hour})
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := fixture.GetItem(cancelled, "my-table", getitem.WithKey(testKey("a")))
+
+		assert.Equal(t, context.Canceled, err)
+	})
+	t.Run("it passes a read with a projection through", func(t *testing.T) {
+		projection := expression.NamesList(expression.Name("name"))
+
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", getitem.NewOptions(getitem.WithKey(testKey("a")), getitem.WithProjectionBuilder(&projection))).
+			Return(&getitem.Result{Item: testItem("a")}, nil)
+
+		fixture := newTestClient(t, &ClientConfig{Client: client})
+
+		actual, err := fixture.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")), getitem.WithProjectionBuilder(&projection))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testItem("a"), actual.Item)
+	})
+}
@@ -0,0 +1,147 @@
+// Package cache serves GetItem and BatchGetItem from a cache in front of a dynamo.Interface. Writes made through
+// the same client invalidate the items they change, items written by other processes are stale until their TTL
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const defaultLRUSize = 10000
+
+// Entry
+//
+// A cached read, Item is nil when the item did not exist
+type Entry struct {
+	Item map[string]types.AttributeValue
+}
+
+// Cache
+//
+// Stores entries by key until their ttl runs out. Implementations must be safe for concurrent use
+type Cache interface {
+	// Get returns the entry of key, false when it is not cached or expired
+	Get(ctx context.Context, key string) (*Entry, bool, error)
+	Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// LRUConfig
+//
+// The size of an LRU, all fields are optional
+type LRUConfig struct {
+	// Size is the most entries kept, the least recently used entry is evicted to make room. Defaults to 10000
+	Size int
+
+	// Now supplies the time entries expire against, defaults to time.Now
+	Now func() time.Time
+}
+
+// LRU
+//
+// An in memory Cache evicting the least recently used entry once it is full
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	now     func() time.Time
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruEntry struct {
+	key     string
+	entry   *Entry
+	expires time.Time
+}
+
+func NewLRU(cfg *LRUConfig) *LRU {
+	lru := &LRU{
+		size:    defaultLRUSize,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+
+	if cfg != nil && cfg.Size > 0 {
+		lru.size = cfg.Size
+	}
+
+	if cfg != nil && cfg.Now != nil {
+		lru.now = cfg.Now
+	}
+
+	return lru
+}
+
+func (l *LRU) Get(ctx context.Context, key string) (*Entry, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	cached := element.Value.(*lruEntry)
+	if !l.now().Before(cached.expires) {
+		l.remove(element)
+
+		return nil, false, nil
+	}
+
+	l.order.MoveToFront(element)
+
+	return cached.entry, true, nil
+}
+
+func (l *LRU) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expires := l.now().Add(ttl)
+
+	if element, ok := l.entries[key]; ok {
+		element.Value = &lruEntry{key: key, entry: entry, expires: expires}
+		l.order.MoveToFront(element)
+
+		return nil
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, entry: entry, expires: expires})
+
+	for l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+
+	return nil
+}
+
+func (l *LRU) Delete(ctx context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := l.entries[key]; ok {
+			l.remove(element)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of entries, expired entries are counted until they are read or evicted
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *LRU) remove(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.entries, element.Value.(*lruEntry).key)
+}
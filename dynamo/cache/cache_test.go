@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func entryWithID(id string) *Entry {
+	return &Entry{Item: map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}}}
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it returns an entry until its ttl runs out", func(t *testing.T) {
+		now := time.Date(2021, 12, 29, 18, 0, 0, 0, time.UTC)
+		lru := NewLRU(&LRUConfig{Now: func() time.Time { return now }})
+
+		assert.Nil(t, lru.Set(ctx, "a", entryWithID("a"), time.Minute))
+
+		actual, ok, err := lru.Get(ctx, "a")
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, entryWithID("a"), actual)
+
+		now = now.Add(time.Minute)
+
+		actual, ok, err = lru.Get(ctx, "a")
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, actual)
+		assert.Equal(t, 0, lru.Len())
+	})
+	t.Run("it evicts the least recently used entry", func(t *testing.T) {
+		lru := NewLRU(&LRUConfig{Size: 2})
+
+		assert.Nil(t, lru.Set(ctx, "a", entryWithID("a"), time.Minute))
+		assert.Nil(t, lru.Set(ctx, "b", entryWithID("b"), time.Minute))
+
+		_, ok, _ := lru.Get(ctx, "a")
+		assert.True(t, ok)
+
+		assert.Nil(t, lru.Set(ctx, "c", entryWithID("c"), time.Minute))
+
+		_, ok, _ = lru.Get(ctx, "b")
+		assert.False(t, ok)
+		_, ok, _ = lru.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, 2, lru.Len())
+	})
+	t.Run("it deletes entries", func(t *testing.T) {
+		lru := NewLRU(nil)
+
+		assert.Nil(t, lru.Set(ctx, "a", entryWithID("a"), time.Minute))
+		assert.Nil(t, lru.Set(ctx, "b", entryWithID("b"), time.Minute))
+		assert.Nil(t, lru.Delete(ctx, "a", "missing"))
+
+		_, ok, _ := lru.Get(ctx, "a")
+		assert.False(t, ok)
+		assert.Equal(t, 1, lru.Len())
+	})
+}
@@ -0,0 +1,623 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/codec"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchwriteitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	defaultTTL = time.Minute
+
+	requiredClientConfigMsg = "cache.NewClient requires a ClientConfig"
+	requiredClientMsg       = "the field Client is required"
+	invalidTTLMsg           = "the fields TTL and NegativeTTL can not be negative"
+)
+
+// ClientConfig
+//
+// The client to cache and how long its reads are kept
+type ClientConfig struct {
+	Client dynamo.Interface
+
+	// Cache defaults to an LRU of 10000 entries
+	Cache Cache
+
+	// TTL is how long an item is cached, defaults to 1 minute
+	TTL time.Duration
+
+	// NegativeTTL is how long a missing item is cached, misses are not cached when it is 0
+	NegativeTTL time.Duration
+
+	// Tables limits caching to the named tables, every table is cached when it is empty
+	Tables []string
+
+	// OnError receives the errors of the cache, optional. A read falls back to the table when the cache fails,
+	// a write that could not be invalidated keeps its stale entry until the TTL runs out
+	OnError func(error)
+}
+
+// Client
+//
+// A dynamo.Interface serving GetItem and BatchGetItem from a Cache. PutItem, UpdateItem, DeleteItem, BatchWriteItem
+// and TransactWriteItems invalidate the items they write, every other call goes to the wrapped client. Writes made
+// with ExecuteStatement, BatchExecuteStatement or ExecuteTransaction do not invalidate anything, their items are
+// stale until the TTL runs out or Invalidate is called.
+// Consistent reads and reads with a projection always read the table, a consistent read refreshes the cache
+type Client struct {
+	dynamo.Interface
+
+	cache       Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	tables      map[string]bool
+	onError     func(error)
+	flights     *flightGroup
+
+	mu       sync.RWMutex
+	keyNames map[string][]string
+}
+
+func NewClient(cfg *ClientConfig) (*Client, error) {
+	if cfg == nil {
+		return nil, errors.New(requiredClientConfigMsg)
+	}
+
+	if cfg.Client == nil {
+		return nil, errors.New(requiredClientMsg)
+	}
+
+	if cfg.TTL < 0 || cfg.NegativeTTL < 0 {
+		return nil, errors.New(invalidTTLMsg)
+	}
+
+	client := &Client{
+		Interface:   cfg.Client,
+		cache:       cfg.Cache,
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		onError:     cfg.OnError,
+		flights:     newFlightGroup(),
+		keyNames:    make(map[string][]string),
+	}
+
+	if client.cache == nil {
+		client.cache = NewLRU(nil)
+	}
+
+	if client.ttl == 0 {
+		client.ttl = defaultTTL
+	}
+
+	if len(cfg.Tables) != 0 {
+		client.tables = make(map[string]bool, len(cfg.Tables))
+		for _, tableName := range cfg.Tables {
+			client.tables[tableName] = true
+		}
+	}
+
+	return client, nil
+}
+
+// GetItem
+//
+// Reads the item from the cache, or from the table when it is not cached. Concurrent misses of the same key share
+// a single read. The ConsumedCapacity is only set when the table was read
+func (c *Client) GetItem(ctx context.Context, tableName string, getOptions ...getitem.OptionFunc) (*getitem.Result, error) {
+	options := getitem.NewOptions(getOptions...)
+
+	if !c.isCached(tableName) || options.Key == nil || options.ProjectionBuilder != nil {
+		return c.Interface.GetItem(ctx, tableName, getOptions...)
+	}
+
+	cacheKey, err := buildCacheKey(tableName, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.ToBool(options.ConsistentRead) {
+		tracked := c.flights.track(cacheKey)
+		defer c.flights.release(cacheKey, tracked)
+
+		result, err := c.Interface.GetItem(ctx, tableName, getOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		c.learnKeyNames(tableName, options.Key)
+		c.guardedSet(ctx, tracked, cacheKey, result.Item)
+
+		return result, nil
+	}
+
+	entry, ok := c.get(ctx, cacheKey)
+	if ok {
+		return c.itemResult(entry.Item, nil, options.Entity)
+	}
+
+	var consumed *types.ConsumedCapacity
+
+	item, shared, err := c.flights.do(cacheKey, func(flight *call) (map[string]types.AttributeValue, error) {
+		result, err := c.Interface.GetItem(ctx, tableName,
+			getitem.WithKey(options.Key),
+			getitem.WithReturnConsumedCapacity(options.ReturnConsumedCapacity))
+		if err != nil {
+			return nil, err
+		}
+
+		consumed = result.ConsumedCapacity
+		c.learnKeyNames(tableName, options.Key)
+
+		c.guardedSet(ctx, &flight.guard, cacheKey, result.Item)
+
+		return result.Item, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if shared {
+		consumed = nil
+	}
+
+	return c.itemResult(item, consumed, options.Entity)
+}
+
+// BatchGetItem
+//
+// Reads the cached keys from the cache and the rest from the tables. Consistent reads and reads with a projection
+// always read the tables
+func (c *Client) BatchGetItem(ctx context.Context, batchOptions ...batchgetitem.OptionFunc) (*batchgetitem.Result, error) {
+	options := batchgetitem.NewOptions(batchOptions...)
+
+	out := &batchgetitem.Result{Responses: make(map[string][]map[string]types.AttributeValue)}
+	remaining := make(map[string]types.KeysAndAttributes)
+	cacheKeys := make(map[string][]string)
+
+	for tableName, request := range options.RequestItems {
+		if !c.isCached(tableName) || aws.ToBool(request.ConsistentRead) ||
+			request.ProjectionExpression != nil || len(request.AttributesToGet) != 0 {
+			remaining[tableName] = request
+
+			continue
+		}
+
+		missed := request
+		missed.Keys = nil
+
+		for _, key := range request.Keys {
+			cacheKey, err := buildCacheKey(tableName, key)
+			if err != nil {
+				return nil, err
+			}
+
+			entry, ok := c.get(ctx, cacheKey)
+			if !ok {
+				missed.Keys = append(missed.Keys, key)
+				cacheKeys[tableName] = append(cacheKeys[tableName], cacheKey)
+
+				continue
+			}
+
+			if entry.Item != nil {
+				out.Responses[tableName] = append(out.Responses[tableName], copyItem(entry.Item))
+			}
+		}
+
+		if len(missed.Keys) != 0 {
+			remaining[tableName] = missed
+		}
+	}
+
+	if len(remaining) == 0 {
+		return out, nil
+	}
+
+	tracked := make(map[string]*guard)
+	for _, keys := range cacheKeys {
+		for _, cacheKey := range keys {
+			if tracked[cacheKey] == nil {
+				tracked[cacheKey] = c.flights.track(cacheKey)
+			}
+		}
+	}
+
+	defer func() {
+		for cacheKey, g := range tracked {
+			c.flights.release(cacheKey, g)
+		}
+	}()
+
+	result, err := c.Interface.BatchGetItem(ctx,
+		batchgetitem.WithRequestItems(remaining),
+		batchgetitem.WithReturnConsumedCapacity(options.ReturnConsumedCapacity))
+	if err != nil {
+		return nil, err
+	}
+
+	for tableName, items := range result.Responses {
+		out.Responses[tableName] = append(out.Responses[tableName], items...)
+	}
+
+	out.ConsumedCapacity = result.ConsumedCapacity
+	out.UnprocessedKeys = result.UnprocessedKeys
+
+	for tableName, keys := range cacheKeys {
+		c.cacheBatch(ctx, tableName, remaining[tableName].Keys, keys, tracked, result)
+	}
+
+	return out, nil
+}
+
+// cacheBatch caches the items read for the keys of a table, the keys that were processed without an item are
+// cached as misses
+func (c *Client) cacheBatch(ctx context.Context, tableName string, keys []map[string]types.AttributeValue, cacheKeys []string, tracked map[string]*guard, result *batchgetitem.Result) {
+	names := keyAttributeNames(keys[0])
+	c.learnKeyNames(tableName, keys[0])
+
+	read := make(map[string]map[string]types.AttributeValue)
+	for _, item := range result.Responses[tableName] {
+		cacheKey, err := buildCacheKey(tableName, pick(item, names))
+		if err == nil {
+			read[cacheKey] = item
+		}
+	}
+
+	unprocessed := make(map[string]bool)
+	for _, key := range result.UnprocessedKeys[tableName].Keys {
+		if cacheKey, err := buildCacheKey(tableName, key); err == nil {
+			unprocessed[cacheKey] = true
+		}
+	}
+
+	for _, cacheKey := range cacheKeys {
+		if !unprocessed[cacheKey] {
+			c.guardedSet(ctx, tracked[cacheKey], cacheKey, read[cacheKey])
+		}
+	}
+}
+
+func (c *Client) PutItem(ctx context.Context, tableName string, putOptions ...putitem.OptionFunc) (*putitem.Result, error) {
+	if !c.isCached(tableName) {
+		return c.Interface.PutItem(ctx, tableName, putOptions...)
+	}
+
+	options := putitem.NewOptions(putOptions...)
+
+	keys, err := c.itemKeys(ctx, tableName, options.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.Interface.PutItem(ctx, tableName, putOptions...)
+	c.invalidate(ctx, keys...)
+
+	return result, err
+}
+
+func (c *Client) UpdateItem(ctx context.Context, tableName string, updateOptions ...updateitem.OptionFunc) (*updateitem.Result, error) {
+	if !c.isCached(tableName) {
+		return c.Interface.UpdateItem(ctx, tableName, updateOptions...)
+	}
+
+	options := updateitem.NewOptions(updateOptions...)
+
+	keys, err := keyCacheKeys(tableName, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.Interface.UpdateItem(ctx, tableName, updateOptions...)
+	c.invalidate(ctx, keys...)
+
+	return result, err
+}
+
+func (c *Client) DeleteItem(ctx context.Context, tableName string, deleteOptions ...deleteitem.OptionFunc) (*deleteitem.Result, error) {
+	if !c.isCached(tableName) {
+		return c.Interface.DeleteItem(ctx, tableName, deleteOptions...)
+	}
+
+	options := deleteitem.NewOptions(deleteOptions...)
+
+	keys, err := keyCacheKeys(tableName, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.Interface.DeleteItem(ctx, tableName, deleteOptions...)
+	c.invalidate(ctx, keys...)
+
+	return result, err
+}
+
+func (c *Client) BatchWriteItem(ctx context.Context, batchOptions ...batchwriteitem.OptionFunc) (*batchwriteitem.Result, error) {
+	options := batchwriteitem.NewOptions(batchOptions...)
+
+	var keys []string
+
+	for tableName, requests := range options.RequestItems {
+		if !c.isCached(tableName) {
+			continue
+		}
+
+		for _, request := range requests {
+			var requestKeys []string
+			var err error
+
+			switch {
+			case request.PutRequest != nil:
+				requestKeys, err = c.itemKeys(ctx, tableName, request.PutRequest.Item)
+			case request.DeleteRequest != nil:
+				requestKeys, err = keyCacheKeys(tableName, request.DeleteRequest.Key)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, requestKeys...)
+		}
+	}
+
+	result, err := c.Interface.BatchWriteItem(ctx, batchOptions...)
+	c.invalidate(ctx, keys...)
+
+	return result, err
+}
+
+func (c *Client) TransactWriteItems(ctx context.Context, transactOptions ...transactwriteitems.OptionFunc) (*transactwriteitems.Result, error) {
+	options := transactwriteitems.NewOptions(transactOptions...)
+
+	var keys []string
+
+	for _, transactItem := range options.TransactItems {
+		if transactItem == nil || !c.isCached(transactItem.TableName) {
+			continue
+		}
+
+		var itemKeys []string
+		var err error
+
+		switch transactItem.Operation {
+		case transactwriteitems.OperationPut:
+			itemKeys, err = c.itemKeys(ctx, transactItem.TableName, transactItem.Item)
+		case transactwriteitems.OperationUpdate, transactwriteitems.OperationDelete:
+			itemKeys, err = keyCacheKeys(transactItem.TableName, transactItem.Key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, itemKeys...)
+	}
+
+	result, err := c.Interface.TransactWriteItems(ctx, transactOptions...)
+	c.invalidate(ctx, keys...)
+
+	return result, err
+}
+
+// Invalidate drops the cached items of the keys, for items changed without this client
+func (c *Client) Invalidate(ctx context.Context, tableName string, keys ...map[string]types.AttributeValue) error {
+	cacheKeys := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		cacheKey, err := buildCacheKey(tableName, key)
+		if err != nil {
+			return err
+		}
+
+		cacheKeys = append(cacheKeys, cacheKey)
+	}
+
+	c.flights.invalidate(cacheKeys...)
+
+	return c.cache.Delete(ctx, cacheKeys...)
+}
+
+func (c *Client) isCached(tableName string) bool {
+	return c.tables == nil || c.tables[tableName]
+}
+
+func (c *Client) get(ctx context.Context, cacheKey string) (*Entry, bool) {
+	entry, ok, err := c.cache.Get(ctx, cacheKey)
+	if err != nil {
+		c.reportErr(err)
+
+		return nil, false
+	}
+
+	return entry, ok && entry != nil
+}
+
+// set caches a read, a nil item is cached as a miss when misses are cached
+func (c *Client) set(ctx context.Context, cacheKey string, item map[string]types.AttributeValue) {
+	ttl := c.ttl
+	if len(item) == 0 {
+		if c.negativeTTL == 0 {
+			return
+		}
+
+		item = nil
+		ttl = c.negativeTTL
+	}
+
+	if err := c.cache.Set(ctx, cacheKey, &Entry{Item: copyItem(item)}, ttl); err != nil {
+		c.reportErr(err)
+	}
+}
+
+// guardedSet caches a read unless a write invalidated its key while it was read
+func (c *Client) guardedSet(ctx context.Context, tracked *guard, cacheKey string, item map[string]types.AttributeValue) {
+	tracked.mu.Lock()
+	defer tracked.mu.Unlock()
+
+	if !tracked.invalidated {
+		c.set(ctx, cacheKey, item)
+	}
+}
+
+// invalidate drops the keys once the write was sent, whether it failed or not, as a failed write may still
+// have been applied
+func (c *Client) invalidate(ctx context.Context, cacheKeys ...string) {
+	if len(cacheKeys) == 0 {
+		return
+	}
+
+	c.flights.invalidate(cacheKeys...)
+
+	if err := c.cache.Delete(ctx, cacheKeys...); err != nil {
+		c.reportErr(err)
+	}
+}
+
+func (c *Client) reportErr(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+func (c *Client) itemResult(item map[string]types.AttributeValue, consumed *types.ConsumedCapacity, entity interface{}) (*getitem.Result, error) {
+	item = copyItem(item)
+
+	if entity != nil {
+		if err := attributevalue.UnmarshalMap(item, entity); err != nil {
+			return nil, err
+		}
+	}
+
+	return &getitem.Result{Item: item, ConsumedCapacity: consumed}, nil
+}
+
+// itemKeys returns the cache key of an item written to the table, reading the key schema of the table once
+func (c *Client) itemKeys(ctx context.Context, tableName string, item map[string]types.AttributeValue) ([]string, error) {
+	if item == nil {
+		return nil, nil
+	}
+
+	names, err := c.tableKeyNames(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyCacheKeys(tableName, pick(item, names))
+}
+
+func (c *Client) tableKeyNames(ctx context.Context, tableName string) ([]string, error) {
+	c.mu.RLock()
+	names, ok := c.keyNames[tableName]
+	c.mu.RUnlock()
+
+	if ok {
+		return names, nil
+	}
+
+	result, err := c.Interface.DescribeTable(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("cache: reading the key schema of %s: %w", tableName, err)
+	}
+
+	if result.Table == nil {
+		return nil, fmt.Errorf("cache: table %s has no description", tableName)
+	}
+
+	names = make([]string, 0, len(result.Table.KeySchema))
+	for _, element := range result.Table.KeySchema {
+		names = append(names, aws.ToString(element.AttributeName))
+	}
+
+	c.mu.Lock()
+	c.keyNames[tableName] = names
+	c.mu.Unlock()
+
+	return names, nil
+}
+
+// learnKeyNames records the attribute names of a key that was read successfully as the key schema of the table
+func (c *Client) learnKeyNames(tableName string, key map[string]types.AttributeValue) {
+	c.mu.RLock()
+	_, ok := c.keyNames[tableName]
+	c.mu.RUnlock()
+
+	if ok {
+		return
+	}
+
+	c.mu.Lock()
+	c.keyNames[tableName] = keyAttributeNames(key)
+	c.mu.Unlock()
+}
+
+func keyCacheKeys(tableName string, key map[string]types.AttributeValue) ([]string, error) {
+	if key == nil {
+		return nil, nil
+	}
+
+	cacheKey, err := buildCacheKey(tableName, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{cacheKey}, nil
+}
+
+// buildCacheKey builds table/{"pk":{"S":"abc"}}, the attributes of the key are sorted by name
+func buildCacheKey(tableName string, key map[string]types.AttributeValue) (string, error) {
+	data, err := codec.MarshalItem(key)
+	if err != nil {
+		return "", fmt.Errorf("cache: %s", err)
+	}
+
+	return tableName + "/" + string(data), nil
+}
+
+func keyAttributeNames(key map[string]types.AttributeValue) []string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func pick(item map[string]types.AttributeValue, names []string) map[string]types.AttributeValue {
+	out := make(map[string]types.AttributeValue, len(names))
+	for _, name := range names {
+		if value, ok := item[name]; ok {
+			out[name] = value
+		}
+	}
+
+	return out
+}
+
+// copyItem copies the top level of an item so callers can not change what is cached
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if item == nil {
+		return nil
+	}
+
+	out := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		out[name] = value
+	}
+
+	return out
+}
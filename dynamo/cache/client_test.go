@@ -0,0 +1,440 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testEntity struct {
+	ID   string `dynamodbav:"id"`
+	Name string `dynamodbav:"name"`
+}
+
+func testKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}}
+}
+
+func testItem(id, name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":   &types.AttributeValueMemberS{Value: id},
+		"name": &types.AttributeValueMemberS{Value: name},
+	}
+}
+
+func keyRead(id string) *getitem.Options {
+	return getitem.NewOptions(getitem.WithKey(testKey(id)), getitem.WithReturnConsumedCapacity(""))
+}
+
+func newTestClient(t *testing.T, client *dynamo.Mock, negativeTTL time.Duration) *Client {
+	cached, err := NewClient(&ClientConfig{Client: client, NegativeTTL: negativeTTL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cached
+}
+
+func TestNewClient(t *testing.T) {
+	t.Run("it requires a config", func(t *testing.T) {
+		_, err := NewClient(nil)
+
+		assert.Equal(t, errors.New(requiredClientConfigMsg), err)
+	})
+	t.Run("it requires a client", func(t *testing.T) {
+		_, err := NewClient(&ClientConfig{})
+
+		assert.Equal(t, errors.New(requiredClientMsg), err)
+	})
+	t.Run("it rejects a negative ttl", func(t *testing.T) {
+		_, err := NewClient(&ClientConfig{Client: &dynamo.Mock{}, NegativeTTL: -time.Second})
+
+		assert.Equal(t, errors.New(invalidTTLMsg), err)
+	})
+}
+
+func TestClient_GetItem(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it serves the second read from the cache", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Once()
+
+		cached := newTestClient(t, client, 0)
+
+		_, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+		assert.Nil(t, err)
+
+		entity := &testEntity{}
+		actual, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")), getitem.AsEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &getitem.Result{Item: testItem("a", "first")}, actual)
+		assert.Equal(t, &testEntity{ID: "a", Name: "first"}, entity)
+		client.AssertExpectations(t)
+	})
+	t.Run("it caches a miss when the negative ttl is set", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", keyRead("a")).Return(&getitem.Result{}, nil).Once()
+
+		cached := newTestClient(t, client, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			actual, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+
+			assert.Nil(t, err)
+			assert.Nil(t, actual.Item)
+		}
+
+		client.AssertExpectations(t)
+	})
+	t.Run("it reads a miss again without a negative ttl", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", keyRead("a")).Return(&getitem.Result{}, nil).Twice()
+
+		cached := newTestClient(t, client, 0)
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+			assert.Nil(t, err)
+		}
+
+		client.AssertExpectations(t)
+	})
+	t.Run("it reads the table once for concurrent misses", func(t *testing.T) {
+		release := make(chan struct{})
+
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Run(func(mock.Arguments) { <-release }).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Once()
+
+		cached := newTestClient(t, client, 0)
+
+		var wg sync.WaitGroup
+		results := make([]*getitem.Result, 10)
+
+		for i := range results {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				results[i], _ = cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+			}(i)
+		}
+
+		close(release)
+		wg.Wait()
+
+		for _, actual := range results {
+			assert.Equal(t, testItem("a", "first"), actual.Item)
+		}
+
+		client.AssertExpectations(t)
+	})
+	t.Run("it does not cache a read that a write raced", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Run(func(mock.Arguments) {
+				close(started)
+				<-release
+			}).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Once()
+		client.On("UpdateItem", ctx, "my-table", updateitem.NewOptions(updateitem.WithKey(testKey("a")))).
+			Return(&updateitem.Result{}, nil)
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Return(&getitem.Result{Item: testItem("a", "second")}, nil).Once()
+
+		cached := newTestClient(t, client, 0)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			_, _ = cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+		}()
+
+		<-started
+		_, err := cached.UpdateItem(ctx, "my-table", updateitem.WithKey(testKey("a")))
+		assert.Nil(t, err)
+
+		close(release)
+		<-done
+
+		actual, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testItem("a", "second"), actual.Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it reads the table for a consistent read and refreshes the cache", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", getitem.NewOptions(getitem.WithKey(testKey("a")), getitem.WithConsistentRead(aws.Bool(true)))).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Twice()
+
+		cached := newTestClient(t, client, 0)
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")), getitem.WithConsistentRead(aws.Bool(true)))
+			assert.Nil(t, err)
+		}
+
+		actual, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testItem("a", "first"), actual.Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it does not cache a consistent read that a write raced", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", getitem.NewOptions(getitem.WithKey(testKey("a")), getitem.WithConsistentRead(aws.Bool(true)))).
+			Run(func(mock.Arguments) {
+				close(started)
+				<-release
+			}).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Once()
+		client.On("UpdateItem", ctx, "my-table", updateitem.NewOptions(updateitem.WithKey(testKey("a")))).
+			Return(&updateitem.Result{}, nil)
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Return(&getitem.Result{Item: testItem("a", "second")}, nil).Once()
+
+		cached := newTestClient(t, client, 0)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			_, _ = cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")), getitem.WithConsistentRead(aws.Bool(true)))
+		}()
+
+		<-started
+		_, err := cached.UpdateItem(ctx, "my-table", updateitem.WithKey(testKey("a")))
+		assert.Nil(t, err)
+
+		close(release)
+		<-done
+
+		actual, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testItem("a", "second"), actual.Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it reads the table for a projection", func(t *testing.T) {
+		projection := expression.NamesList(expression.Name("name"))
+
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", getitem.NewOptions(getitem.WithKey(testKey("a")), getitem.WithProjectionBuilder(&projection))).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Twice()
+
+		cached := newTestClient(t, client, 0)
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")), getitem.WithProjectionBuilder(&projection))
+			assert.Nil(t, err)
+		}
+
+		client.AssertExpectations(t)
+	})
+	t.Run("it falls back to the table when the cache fails", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Twice()
+
+		var reported []error
+
+		cached, err := NewClient(&ClientConfig{
+			Client:  client,
+			Cache:   failingCache{},
+			OnError: func(err error) { reported = append(reported, err) },
+		})
+		assert.Nil(t, err)
+
+		for i := 0; i < 2; i++ {
+			actual, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+
+			assert.Nil(t, err)
+			assert.Equal(t, testItem("a", "first"), actual.Item)
+		}
+
+		assert.Len(t, reported, 4)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestClient_PutItem(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it invalidates the item written", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("DescribeTable", ctx, "my-table").Return(&describetable.Result{Table: &types.TableDescription{
+			KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash}},
+		}}, nil).Once()
+		client.On("PutItem", ctx, "my-table", putitem.NewOptions(putitem.WithItem(testItem("a", "second")))).
+			Return(&putitem.Result{}, nil)
+
+		cached := newTestClient(t, client, 0)
+		assert.Nil(t, cached.cache.Set(ctx, "my-table/{\"id\":{\"S\":\"a\"}}", &Entry{Item: testItem("a", "first")}, time.Minute))
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.PutItem(ctx, "my-table", putitem.WithItem(testItem("a", "second")))
+			assert.Nil(t, err)
+		}
+
+		_, ok, _ := cached.cache.Get(ctx, "my-table/{\"id\":{\"S\":\"a\"}}")
+		assert.False(t, ok)
+		client.AssertExpectations(t)
+	})
+	t.Run("it does not write when the key schema can not be read", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("DescribeTable", ctx, "my-table").Return(nil, errors.New("denied"))
+
+		cached := newTestClient(t, client, 0)
+
+		_, err := cached.PutItem(ctx, "my-table", putitem.WithItem(testItem("a", "second")))
+
+		assert.EqualError(t, err, "cache: reading the key schema of my-table: denied")
+		client.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("it passes through tables that are not cached", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("PutItem", ctx, "other-table", putitem.NewOptions(putitem.WithItem(testItem("a", "second")))).
+			Return(&putitem.Result{}, nil)
+
+		cached, err := NewClient(&ClientConfig{Client: client, Tables: []string{"my-table"}})
+		assert.Nil(t, err)
+
+		_, err = cached.PutItem(ctx, "other-table", putitem.WithItem(testItem("a", "second")))
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestClient_BatchGetItem(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it reads the keys that are not cached and caches them", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Return(&getitem.Result{Item: testItem("a", "first")}, nil).Once()
+		client.On("BatchGetItem", ctx, batchgetitem.NewOptions(batchgetitem.WithKeys("my-table", testKey("b"), testKey("c")))).
+			Return(&batchgetitem.Result{Responses: map[string][]map[string]types.AttributeValue{
+				"my-table": {testItem("b", "second")},
+			}}, nil).Once()
+
+		cached := newTestClient(t, client, time.Minute)
+
+		_, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+		assert.Nil(t, err)
+
+		for i := 0; i < 2; i++ {
+			actual, err := cached.BatchGetItem(ctx, batchgetitem.WithKeys("my-table", testKey("a"), testKey("b"), testKey("c")))
+
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, []map[string]types.AttributeValue{testItem("a", "first"), testItem("b", "second")},
+				actual.Responses["my-table"])
+		}
+
+		client.AssertExpectations(t)
+	})
+	t.Run("it does not cache a batch read that a write raced", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", ctx, batchgetitem.NewOptions(batchgetitem.WithKeys("my-table", testKey("a"), testKey("b")))).
+			Run(func(mock.Arguments) {
+				close(started)
+				<-release
+			}).
+			Return(&batchgetitem.Result{Responses: map[string][]map[string]types.AttributeValue{
+				"my-table": {testItem("a", "first"), testItem("b", "first")},
+			}}, nil).Once()
+		client.On("UpdateItem", ctx, "my-table", updateitem.NewOptions(updateitem.WithKey(testKey("a")))).
+			Return(&updateitem.Result{}, nil)
+		client.On("GetItem", ctx, "my-table", keyRead("a")).
+			Return(&getitem.Result{Item: testItem("a", "second")}, nil).Once()
+
+		cached := newTestClient(t, client, 0)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			_, _ = cached.BatchGetItem(ctx, batchgetitem.WithKeys("my-table", testKey("a"), testKey("b")))
+		}()
+
+		<-started
+		_, err := cached.UpdateItem(ctx, "my-table", updateitem.WithKey(testKey("a")))
+		assert.Nil(t, err)
+
+		close(release)
+		<-done
+
+		actual, err := cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("a")))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testItem("a", "second"), actual.Item)
+
+		actual, err = cached.GetItem(ctx, "my-table", getitem.WithKey(testKey("b")))
+
+		assert.Nil(t, err)
+		assert.Equal(t, testItem("b", "first"), actual.Item)
+		client.AssertExpectations(t)
+	})
+	t.Run("it does not cache unprocessed keys", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("BatchGetItem", ctx, batchgetitem.NewOptions(batchgetitem.WithKeys("my-table", testKey("a")))).
+			Return(&batchgetitem.Result{UnprocessedKeys: map[string]types.KeysAndAttributes{
+				"my-table": {Keys: []map[string]types.AttributeValue{testKey("a")}},
+			}}, nil).Twice()
+
+		cached := newTestClient(t, client, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			actual, err := cached.BatchGetItem(ctx, batchgetitem.WithKeys("my-table", testKey("a")))
+
+			assert.Nil(t, err)
+			assert.Len(t, actual.UnprocessedKeys["my-table"].Keys, 1)
+		}
+
+		client.AssertExpectations(t)
+	})
+}
+
+type failingCache struct{}
+
+func (failingCache) Get(context.Context, string) (*Entry, bool, error) {
+	return nil, false, errors.New("cache down")
+}
+
+func (failingCache) Set(context.Context, string, *Entry, time.Duration) error {
+	return errors.New("cache down")
+}
+
+func (failingCache) Delete(context.Context, ...string) error {
+	return errors.New("cache down")
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// flightGroup shares a single read of a key between the callers that miss it at the same time, and tracks the
+// reads in flight so a write can stop them from caching what they read
+type flightGroup struct {
+	mu     sync.Mutex
+	calls  map[string]*call
+	guards map[string]map[*guard]bool
+}
+
+// guard is held by a read of a key until its result is cached
+type guard struct {
+	// mu guards invalidated, it is held while the result is cached so a write can not be missed
+	mu          sync.Mutex
+	invalidated bool
+}
+
+type call struct {
+	guard
+
+	wg   sync.WaitGroup
+	item map[string]types.AttributeValue
+	err  error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		calls:  make(map[string]*call),
+		guards: make(map[string]map[*guard]bool),
+	}
+}
+
+// do runs read once for the callers of the same key, shared is true for the callers that waited on another
+func (g *flightGroup) do(key string, read func(c *call) (map[string]types.AttributeValue, error)) (map[string]types.AttributeValue, bool, error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+
+		return c.item, true, c.err
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.item, c.err = read(c)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	c.wg.Done()
+
+	return c.item, false, c.err
+}
+
+// track returns a guard for a read of key that is not shared, release it once the result is cached
+func (g *flightGroup) track(key string) *guard {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tracked := &guard{}
+
+	if g.guards[key] == nil {
+		g.guards[key] = make(map[*guard]bool)
+	}
+
+	g.guards[key][tracked] = true
+
+	return tracked
+}
+
+func (g *flightGroup) release(key string, tracked *guard) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.guards[key], tracked)
+
+	if len(g.guards[key]) == 0 {
+		delete(g.guards, key)
+	}
+}
+
+// invalidate stops the reads of the keys in flight from caching what they read
+func (g *flightGroup) invalidate(keys ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, key := range keys {
+		if c, ok := g.calls[key]; ok {
+			c.invalidate()
+		}
+
+		for tracked := range g.guards[key] {
+			tracked.invalidate()
+		}
+	}
+}
+
+func (t *guard) invalidate() {
+	t.mu.Lock()
+	t.invalidated = true
+	t.mu.Unlock()
+}
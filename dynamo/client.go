@@ -3,11 +3,22 @@ package dynamo
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
 
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchexecutestatement"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchwriteitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/createtable"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/query"
 
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/listtables"
@@ -16,11 +27,24 @@ import (
 
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
 
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetimetolive"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executestatement"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executetransaction"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetimetolive"
+
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetable"
+
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
 )
@@ -34,8 +58,18 @@ const (
 	requiredTableNameMsg = "tableName is a required parameter"
 	requiredItemMsg      = "the field  Item is required"
 
-	requiredKeyMsg                 = "the field Key is required"
-	requiredKeyConditionBuilderMsg = "the field KeyConditionBuilder is required"
+	requiredKeyMsg                  = "the field Key is required"
+	requiredKeyConditionBuilderMsg  = "the field KeyConditionBuilder is required"
+	requiredTransactItemsMsg        = "the field TransactItems is required"
+	requiredRequestItemsMsg         = "the field RequestItems is required"
+	requiredStatementMsg            = "the field Statement is required"
+	requiredStatementsMsg           = "the field Statements is required"
+	requiredWriteRequestsMsg        = "the field WriteRequests is required"
+	requiredUpdateBuilderMsg        = "the field UpdateBuilder is required"
+	requiredAttributeNameMsg        = "the field AttributeName is required"
+	requiredKeySchemaMsg            = "the field KeySchema is required"
+	requiredAttributeDefinitionsMsg = "the field AttributeDefinitions is required"
+	requiredTableUpdateMsg          = "one of the fields AttributeDefinitions, GlobalSecondaryIndexUpdates, BillingMode or ProvisionedThroughput is required"
 )
 
 type Client struct {
@@ -59,6 +93,168 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 	}, nil
 }
 
+// BatchExecuteStatement
+//
+// Runs up to 25 PartiQL statements, a failed statement sets the Error of its response instead of failing the batch
+func (c *Client) BatchExecuteStatement(ctx context.Context, batchOptions ...batchexecutestatement.OptionFunc) (*batchexecutestatement.Result, error) {
+	options := batchexecutestatement.NewOptions(batchOptions...)
+
+	if len(options.Statements) == 0 {
+		return nil, errors.New(requiredStatementsMsg)
+	}
+
+	statements := make([]types.BatchStatementRequest, 0, len(options.Statements))
+
+	for idx, statement := range options.Statements {
+		if statement == nil || statement.Statement == "" {
+			return nil, fmt.Errorf("Statements[%d]: %s", idx, requiredStatementMsg)
+		}
+
+		parameters, err := marshalParameters(statement.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("Statements[%d]: %s", idx, err)
+		}
+
+		statements = append(statements, types.BatchStatementRequest{
+			ConsistentRead: statement.ConsistentRead,
+			Parameters:     parameters,
+			Statement:      aws.String(statement.Statement),
+		})
+	}
+
+	dynamoInput := &dynamodb.BatchExecuteStatementInput{
+		ReturnConsumedCapacity: options.ReturnConsumedCapacity,
+		Statements:             statements,
+	}
+
+	result, err := c.awsClient.BatchExecuteStatement(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Entities != nil {
+		items := make([]map[string]types.AttributeValue, 0, len(result.Responses))
+		for _, response := range result.Responses {
+			items = append(items, response.Item)
+		}
+
+		err := attributevalue.UnmarshalListOfMaps(items, options.Entities)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &batchexecutestatement.Result{
+		ConsumedCapacity: result.ConsumedCapacity,
+		Responses:        result.Responses,
+	}, nil
+}
+
+// BatchGetItem
+func (c *Client) BatchGetItem(ctx context.Context, batchOptions ...batchgetitem.OptionFunc) (*batchgetitem.Result, error) {
+	options := batchgetitem.NewOptions(batchOptions...)
+
+	if len(options.RequestItems) == 0 {
+		return nil, errors.New(requiredRequestItemsMsg)
+	}
+
+	for tableName, request := range options.RequestItems {
+		if len(tableName) < minLengthTableName {
+			return nil, errors.New(requiredTableNameMsg)
+		}
+
+		if len(request.Keys) == 0 {
+			return nil, fmt.Errorf("RequestItems[%s]: %s", tableName, requiredKeyMsg)
+		}
+	}
+
+	dynamoInput := &dynamodb.BatchGetItemInput{
+		RequestItems:           options.RequestItems,
+		ReturnConsumedCapacity: options.ReturnConsumedCapacity,
+	}
+
+	result, err := c.awsClient.BatchGetItem(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchgetitem.Result{
+		ConsumedCapacity: result.ConsumedCapacity,
+		Responses:        result.Responses,
+		UnprocessedKeys:  result.UnprocessedKeys,
+	}, nil
+}
+
+// BatchWriteItem
+func (c *Client) BatchWriteItem(ctx context.Context, batchOptions ...batchwriteitem.OptionFunc) (*batchwriteitem.Result, error) {
+	options := batchwriteitem.NewOptions(batchOptions...)
+
+	if len(options.RequestItems) == 0 {
+		return nil, errors.New(requiredRequestItemsMsg)
+	}
+
+	for tableName, requests := range options.RequestItems {
+		if len(tableName) < minLengthTableName {
+			return nil, errors.New(requiredTableNameMsg)
+		}
+
+		if len(requests) == 0 {
+			return nil, fmt.Errorf("RequestItems[%s]: %s", tableName, requiredWriteRequestsMsg)
+		}
+	}
+
+	dynamoInput := &dynamodb.BatchWriteItemInput{
+		RequestItems:           options.RequestItems,
+		ReturnConsumedCapacity: options.ReturnConsumedCapacity,
+	}
+
+	result, err := c.awsClient.BatchWriteItem(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchwriteitem.Result{
+		ConsumedCapacity: result.ConsumedCapacity,
+		UnprocessedItems: result.UnprocessedItems,
+	}, nil
+}
+
+// CreateTable
+func (c *Client) CreateTable(ctx context.Context, tableName string, createOptions ...createtable.OptionFunc) (*createtable.Result, error) {
+	if len(tableName) < minLengthTableName {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	options := createtable.NewOptions(createOptions...)
+
+	if len(options.KeySchema) == 0 {
+		return nil, errors.New(requiredKeySchemaMsg)
+	}
+
+	if len(options.AttributeDefinitions) == 0 {
+		return nil, errors.New(requiredAttributeDefinitionsMsg)
+	}
+
+	dynamoInput := &dynamodb.CreateTableInput{
+		TableName:             aws.String(tableName),
+		KeySchema:             options.KeySchema,
+		AttributeDefinitions:  options.AttributeDefinitions,
+		BillingMode:           options.BillingMode,
+		ProvisionedThroughput: options.ProvisionedThroughput,
+	}
+
+	if len(options.GlobalSecondaryIndexes) != 0 {
+		dynamoInput.GlobalSecondaryIndexes = options.GlobalSecondaryIndexes
+	}
+
+	result, err := c.awsClient.CreateTable(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createtable.Result{TableDescription: result.TableDescription}, nil
+}
+
 // DeleteItem
 func (c *Client) DeleteItem(ctx context.Context, tableName string, deleteOptions ...deleteitem.OptionFunc) (*deleteitem.Result, error) {
 	if len(tableName) < minLengthTableName {
@@ -120,6 +316,123 @@ func (c *Client) DescribeTable(ctx context.Context, tableName string) (*describe
 	return &describetable.Result{Table: result.Table}, nil
 }
 
+// DescribeTimeToLive
+func (c *Client) DescribeTimeToLive(ctx context.Context, tableName string) (*describetimetolive.Result, error) {
+	if len(tableName) < minLengthTableName {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	dynamoInput := &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(tableName),
+	}
+
+	result, err := c.awsClient.DescribeTimeToLive(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &describetimetolive.Result{TimeToLiveDescription: result.TimeToLiveDescription}, nil
+}
+
+// ExecuteStatement
+//
+// Runs a PartiQL statement, the values of its ? placeholders are passed with executestatement.WithParameters
+func (c *Client) ExecuteStatement(ctx context.Context, statement string, executeOptions ...executestatement.OptionFunc) (*executestatement.Result, error) {
+	if statement == "" {
+		return nil, errors.New(requiredStatementMsg)
+	}
+
+	options := executestatement.NewOptions(executeOptions...)
+
+	parameters, err := marshalParameters(options.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamoInput := &dynamodb.ExecuteStatementInput{
+		ConsistentRead:         options.ConsistentRead,
+		NextToken:              options.NextToken,
+		Parameters:             parameters,
+		ReturnConsumedCapacity: options.ReturnConsumedCapacity,
+		Statement:              aws.String(statement),
+	}
+
+	result, err := c.awsClient.ExecuteStatement(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Entities != nil {
+		err := attributevalue.UnmarshalListOfMaps(result.Items, options.Entities)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &executestatement.Result{
+		ConsumedCapacity: result.ConsumedCapacity,
+		Items:            result.Items,
+		NextToken:        result.NextToken,
+	}, nil
+}
+
+// ExecuteTransaction
+//
+// Runs PartiQL statements in a transaction, they have to be all reads or all writes
+func (c *Client) ExecuteTransaction(ctx context.Context, transactOptions ...executetransaction.OptionFunc) (*executetransaction.Result, error) {
+	options := executetransaction.NewOptions(transactOptions...)
+
+	if len(options.Statements) == 0 {
+		return nil, errors.New(requiredStatementsMsg)
+	}
+
+	statements := make([]types.ParameterizedStatement, 0, len(options.Statements))
+
+	for idx, statement := range options.Statements {
+		if statement == nil || statement.Statement == "" {
+			return nil, fmt.Errorf("Statements[%d]: %s", idx, requiredStatementMsg)
+		}
+
+		parameters, err := marshalParameters(statement.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("Statements[%d]: %s", idx, err)
+		}
+
+		statements = append(statements, types.ParameterizedStatement{
+			Parameters: parameters,
+			Statement:  aws.String(statement.Statement),
+		})
+	}
+
+	dynamoInput := &dynamodb.ExecuteTransactionInput{
+		ClientRequestToken:     options.ClientRequestToken,
+		ReturnConsumedCapacity: options.ReturnConsumedCapacity,
+		TransactStatements:     statements,
+	}
+
+	result, err := c.awsClient.ExecuteTransaction(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Entities != nil {
+		items := make([]map[string]types.AttributeValue, 0, len(result.Responses))
+		for _, response := range result.Responses {
+			items = append(items, response.Item)
+		}
+
+		err := attributevalue.UnmarshalListOfMaps(items, options.Entities)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &executetransaction.Result{
+		ConsumedCapacity: result.ConsumedCapacity,
+		Responses:        result.Responses,
+	}, nil
+}
+
 // GetItem
 func (c *Client) GetItem(ctx context.Context, tableName string, getOptions ...getitem.OptionFunc) (*getitem.Result, error) {
 	if len(tableName) < minLengthTableName {
@@ -355,6 +668,269 @@ func (c *Client) Scan(ctx context.Context, tableName string, scanOptions ...scan
 	}, nil
 }
 
+// TransactWriteItems
+func (c *Client) TransactWriteItems(ctx context.Context, transactOptions ...transactwriteitems.OptionFunc) (*transactwriteitems.Result, error) {
+	options := transactwriteitems.NewOptions(transactOptions...)
+
+	if len(options.TransactItems) == 0 {
+		return nil, errors.New(requiredTransactItemsMsg)
+	}
+
+	dynamoInput := &dynamodb.TransactWriteItemsInput{
+		ClientRequestToken:          options.ClientRequestToken,
+		ReturnConsumedCapacity:      options.ReturnConsumedCapacity,
+		ReturnItemCollectionMetrics: options.ReturnItemCollectionMetrics,
+		TransactItems:               make([]types.TransactWriteItem, len(options.TransactItems)),
+	}
+
+	for idx, transactItem := range options.TransactItems {
+		writeItem, err := buildTransactWriteItem(transactItem)
+		if err != nil {
+			return nil, fmt.Errorf("TransactItems[%d]: %w", idx, err)
+		}
+
+		dynamoInput.TransactItems[idx] = writeItem
+	}
+
+	result, err := c.awsClient.TransactWriteItems(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transactwriteitems.Result{
+		ConsumedCapacity:      result.ConsumedCapacity,
+		ItemCollectionMetrics: result.ItemCollectionMetrics,
+	}, nil
+}
+
+func buildTransactWriteItem(input *transactwriteitems.TransactItem) (types.TransactWriteItem, error) {
+	if input == nil {
+		return types.TransactWriteItem{}, errors.New("a TransactItem is required")
+	}
+
+	if len(input.TableName) < minLengthTableName {
+		return types.TransactWriteItem{}, errors.New(requiredTableNameMsg)
+	}
+
+	var expr *expression.Expression
+	if input.ConditionBuilder != nil || input.UpdateBuilder != nil {
+		builder := expression.NewBuilder()
+
+		if input.ConditionBuilder != nil {
+			builder = builder.WithCondition(*input.ConditionBuilder)
+		}
+
+		if input.UpdateBuilder != nil {
+			builder = builder.WithUpdate(*input.UpdateBuilder)
+		}
+
+		built, err := builder.Build()
+		if err != nil {
+			return types.TransactWriteItem{}, err
+		}
+
+		expr = &built
+	}
+
+	switch input.Operation {
+	case transactwriteitems.OperationPut:
+		if input.Item == nil {
+			return types.TransactWriteItem{}, errors.New(requiredItemMsg)
+		}
+
+		put := &types.Put{
+			Item:                                input.Item,
+			TableName:                           aws.String(input.TableName),
+			ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+		}
+
+		if expr != nil {
+			put.ConditionExpression = expr.Condition()
+			put.ExpressionAttributeNames = expr.Names()
+			put.ExpressionAttributeValues = expr.Values()
+		}
+
+		return types.TransactWriteItem{Put: put}, nil
+	case transactwriteitems.OperationDelete:
+		if input.Key == nil {
+			return types.TransactWriteItem{}, errors.New(requiredKeyMsg)
+		}
+
+		del := &types.Delete{
+			Key:                                 input.Key,
+			TableName:                           aws.String(input.TableName),
+			ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+		}
+
+		if expr != nil {
+			del.ConditionExpression = expr.Condition()
+			del.ExpressionAttributeNames = expr.Names()
+			del.ExpressionAttributeValues = expr.Values()
+		}
+
+		return types.TransactWriteItem{Delete: del}, nil
+	case transactwriteitems.OperationUpdate:
+		if input.Key == nil {
+			return types.TransactWriteItem{}, errors.New(requiredKeyMsg)
+		}
+
+		if input.UpdateBuilder == nil {
+			return types.TransactWriteItem{}, errors.New(requiredUpdateBuilderMsg)
+		}
+
+		update := &types.Update{
+			Key:                                 input.Key,
+			TableName:                           aws.String(input.TableName),
+			UpdateExpression:                    expr.Update(),
+			ConditionExpression:                 expr.Condition(),
+			ExpressionAttributeNames:            expr.Names(),
+			ExpressionAttributeValues:           expr.Values(),
+			ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+		}
+
+		return types.TransactWriteItem{Update: update}, nil
+	case transactwriteitems.OperationConditionCheck:
+		if input.Key == nil {
+			return types.TransactWriteItem{}, errors.New(requiredKeyMsg)
+		}
+
+		if input.ConditionBuilder == nil {
+			return types.TransactWriteItem{}, errors.New("the field ConditionBuilder is required")
+		}
+
+		return types.TransactWriteItem{ConditionCheck: &types.ConditionCheck{
+			Key:                                 input.Key,
+			TableName:                           aws.String(input.TableName),
+			ConditionExpression:                 expr.Condition(),
+			ExpressionAttributeNames:            expr.Names(),
+			ExpressionAttributeValues:           expr.Values(),
+			ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+		}}, nil
+	default:
+		return types.TransactWriteItem{}, fmt.Errorf("unknown Operation '%s'", input.Operation)
+	}
+}
+
+// UpdateItem
+func (c *Client) UpdateItem(ctx context.Context, tableName string, updateOptions ...updateitem.OptionFunc) (*updateitem.Result, error) {
+	if len(tableName) < minLengthTableName {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	options := updateitem.NewOptions(updateOptions...)
+
+	if options.Key == nil {
+		return nil, errors.New(requiredKeyMsg)
+	}
+
+	if options.UpdateBuilder == nil {
+		return nil, errors.New(requiredUpdateBuilderMsg)
+	}
+
+	builder := expression.NewBuilder().WithUpdate(*options.UpdateBuilder)
+	if options.FilterConditionBuilder != nil {
+		builder = builder.WithCondition(*options.FilterConditionBuilder)
+	}
+
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	dynamoInput := &dynamodb.UpdateItemInput{
+		TableName:                   aws.String(tableName),
+		Key:                         options.Key,
+		ConditionExpression:         expr.Condition(),
+		UpdateExpression:            expr.Update(),
+		ExpressionAttributeNames:    expr.Names(),
+		ExpressionAttributeValues:   expr.Values(),
+		ReturnConsumedCapacity:      options.ReturnConsumedCapacity,
+		ReturnItemCollectionMetrics: options.ReturnItemCollectionMetrics,
+		ReturnValues:                options.ReturnValue,
+	}
+
+	result, err := c.awsClient.UpdateItem(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Entity != nil && len(result.Attributes) != 0 {
+		err = attributevalue.UnmarshalMap(result.Attributes, options.Entity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &updateitem.Result{
+		Attributes:            result.Attributes,
+		ConsumedCapacity:      result.ConsumedCapacity,
+		ItemCollectionMetrics: result.ItemCollectionMetrics,
+	}, nil
+}
+
+// UpdateTable
+func (c *Client) UpdateTable(ctx context.Context, tableName string, updateOptions ...updatetable.OptionFunc) (*updatetable.Result, error) {
+	if len(tableName) < minLengthTableName {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	options := updatetable.NewOptions(updateOptions...)
+
+	if len(options.AttributeDefinitions) == 0 && len(options.GlobalSecondaryIndexUpdates) == 0 &&
+		options.BillingMode == "" && options.ProvisionedThroughput == nil {
+		return nil, errors.New(requiredTableUpdateMsg)
+	}
+
+	dynamoInput := &dynamodb.UpdateTableInput{
+		TableName:             aws.String(tableName),
+		BillingMode:           options.BillingMode,
+		ProvisionedThroughput: options.ProvisionedThroughput,
+	}
+
+	if len(options.AttributeDefinitions) != 0 {
+		dynamoInput.AttributeDefinitions = options.AttributeDefinitions
+	}
+
+	if len(options.GlobalSecondaryIndexUpdates) != 0 {
+		dynamoInput.GlobalSecondaryIndexUpdates = options.GlobalSecondaryIndexUpdates
+	}
+
+	result, err := c.awsClient.UpdateTable(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updatetable.Result{TableDescription: result.TableDescription}, nil
+}
+
+// UpdateTimeToLive
+func (c *Client) UpdateTimeToLive(ctx context.Context, tableName string, ttlOptions ...updatetimetolive.OptionFunc) (*updatetimetolive.Result, error) {
+	if len(tableName) < minLengthTableName {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	options := updatetimetolive.NewOptions(ttlOptions...)
+
+	if options.AttributeName == "" {
+		return nil, errors.New(requiredAttributeNameMsg)
+	}
+
+	dynamoInput := &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(options.AttributeName),
+			Enabled:       aws.Bool(options.Enabled),
+		},
+	}
+
+	result, err := c.awsClient.UpdateTimeToLive(ctx, dynamoInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updatetimetolive.Result{TimeToLiveSpecification: result.TimeToLiveSpecification}, nil
+}
+
 func buildExpression(filter *expression.ConditionBuilder, proj *expression.ProjectionBuilder) (expression.Expression, error) {
 	if filter == nil && proj == nil {
 		return expression.NewBuilder().Build()
@@ -374,3 +950,32 @@ func buildExpression(filter *expression.ConditionBuilder, proj *expression.Proje
 
 	return expression.NewBuilder().Build()
 }
+
+// marshalParameters marshals the values of PartiQL placeholders, attribute values are kept as they are
+func marshalParameters(parameters []interface{}) ([]types.AttributeValue, error) {
+	if len(parameters) == 0 {
+		return nil, nil
+	}
+
+	out := make([]types.AttributeValue, 0, len(parameters))
+
+	for idx, parameter := range parameters {
+		if value, ok := parameter.(types.AttributeValue); ok {
+			out = append(out, value)
+			continue
+		}
+
+		value, err := attributevalue.Marshal(parameter)
+		if err != nil {
+			return nil, fmt.Errorf("Parameters[%d]: %s", idx, err)
+		}
+
+		if value == nil {
+			return nil, fmt.Errorf("Parameters[%d]: type %T can not be marshalled", idx, parameter)
+		}
+
+		out = append(out, value)
+	}
+
+	return out, nil
+}
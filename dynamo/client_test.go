@@ -5,12 +5,23 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/KirkDiggler/go-projects/dynamo/codec/codectest"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchexecutestatement"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchwriteitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/createtable"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executestatement"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executetransaction"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/listtables"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/query"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetimetolive"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -849,6 +860,28 @@ func TestClient_Scan(t *testing.T) {
 		assert.NotNil(t, actual)
 		assert.Equal(t, returnedItems, actual.Items)
 	})
+	t.Run("it returns the items of a fixture", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		returnedItems := codectest.LoadItems(t, "testdata/scan_items.json")
+
+		m.On("Scan",
+			ctx,
+			&dynamodb.ScanInput{
+				TableName: aws.String(testTableName),
+			}).Return(&dynamodb.ScanOutput{
+			Items: returnedItems,
+		}, nil)
+
+		actual, err := client.Scan(ctx, testTableName)
+
+		assert.Nil(t, err)
+		assert.Len(t, actual.Items, 2)
+		assert.Equal(t, &types.AttributeValueMemberS{Value: testID}, actual.Items[0][idFieldName])
+		assert.Equal(t, &types.AttributeValueMemberN{Value: "19.99"}, actual.Items[0]["price"])
+	})
 	t.Run("it sets all the parameters", func(t *testing.T) {
 		client := setupFixture()
 
@@ -914,3 +947,836 @@ func TestClient_Scan(t *testing.T) {
 	})
 
 }
+
+func TestClient_BatchGetItem(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	key := map[string]types.AttributeValue{
+		idFieldName: &types.AttributeValueMemberS{Value: "uuid1-uuid2-uuid3-uuid4"},
+	}
+
+	t.Run("it requires request items", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchGetItem(ctx)
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredRequestItemsMsg), err)
+	})
+	t.Run("it requires a table name to be 3 or more characters", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchGetItem(ctx, batchgetitem.WithKeys("to", key))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredTableNameMsg), err)
+	})
+	t.Run("it requires keys for every table", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchGetItem(ctx, batchgetitem.WithConsistentRead(testTableName, true))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, "RequestItems[test-table-name]: "+requiredKeyMsg, err.Error())
+	})
+	t.Run("it returns an error if the aws client returns an error", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		expectedErr := errors.New("batch failed")
+
+		m.On("BatchGetItem", ctx, mock.Anything).Return(nil, expectedErr)
+
+		actual, err := client.BatchGetItem(ctx, batchgetitem.WithKeys(testTableName, key))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		item := map[string]types.AttributeValue{
+			idFieldName:   &types.AttributeValueMemberS{Value: "uuid1-uuid2-uuid3-uuid4"},
+			nameFieldName: &types.AttributeValueMemberS{Value: "my item"},
+		}
+
+		unprocessed := map[string]types.KeysAndAttributes{
+			testTableName: {Keys: []map[string]types.AttributeValue{key}},
+		}
+
+		m.On("BatchGetItem", ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{
+				testTableName: {
+					Keys:           []map[string]types.AttributeValue{key, key},
+					ConsistentRead: aws.Bool(true),
+				},
+			},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		}).Return(&dynamodb.BatchGetItemOutput{
+			Responses:       map[string][]map[string]types.AttributeValue{testTableName: {item}},
+			UnprocessedKeys: unprocessed,
+		}, nil)
+
+		actual, err := client.BatchGetItem(ctx,
+			batchgetitem.WithKeys(testTableName, key),
+			batchgetitem.WithKeys(testTableName, key),
+			batchgetitem.WithConsistentRead(testTableName, true),
+			batchgetitem.WithReturnConsumedCapacity(types.ReturnConsumedCapacityTotal))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []map[string]types.AttributeValue{item}, actual.Responses[testTableName])
+		assert.Equal(t, unprocessed, actual.UnprocessedKeys)
+	})
+}
+
+func TestClient_BatchWriteItem(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	item := map[string]types.AttributeValue{
+		idFieldName:   &types.AttributeValueMemberS{Value: "uuid1-uuid2-uuid3-uuid4"},
+		nameFieldName: &types.AttributeValueMemberS{Value: "my item"},
+	}
+
+	key := map[string]types.AttributeValue{
+		idFieldName: &types.AttributeValueMemberS{Value: "uuid1-uuid2-uuid3-uuid5"},
+	}
+
+	t.Run("it requires request items", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchWriteItem(ctx)
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredRequestItemsMsg), err)
+	})
+	t.Run("it requires a table name to be 3 or more characters", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchWriteItem(ctx, batchwriteitem.WithPutItems("to", item))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredTableNameMsg), err)
+	})
+	t.Run("it requires write requests for every table", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchWriteItem(ctx, batchwriteitem.WithRequestItems(map[string][]types.WriteRequest{
+			testTableName: {},
+		}))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, "RequestItems[test-table-name]: "+requiredWriteRequestsMsg, err.Error())
+	})
+	t.Run("it returns an error if the aws client returns an error", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		expectedErr := errors.New("batch failed")
+
+		m.On("BatchWriteItem", ctx, mock.Anything).Return(nil, expectedErr)
+
+		actual, err := client.BatchWriteItem(ctx, batchwriteitem.WithPutItems(testTableName, item))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		unprocessed := map[string][]types.WriteRequest{
+			testTableName: {{DeleteRequest: &types.DeleteRequest{Key: key}}},
+		}
+
+		m.On("BatchWriteItem", ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{
+				testTableName: {
+					{PutRequest: &types.PutRequest{Item: item}},
+					{DeleteRequest: &types.DeleteRequest{Key: key}},
+				},
+			},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		}).Return(&dynamodb.BatchWriteItemOutput{
+			UnprocessedItems: unprocessed,
+		}, nil)
+
+		actual, err := client.BatchWriteItem(ctx,
+			batchwriteitem.WithPutItems(testTableName, item),
+			batchwriteitem.WithDeleteKeys(testTableName, key),
+			batchwriteitem.WithReturnConsumedCapacity(types.ReturnConsumedCapacityTotal))
+
+		assert.Nil(t, err)
+		assert.Equal(t, unprocessed, actual.UnprocessedItems)
+	})
+}
+
+func TestClient_TransactWriteItems(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	testID := "uuid1-uuid2-uuid3-uuid4"
+	testName := "my item"
+
+	item := map[string]types.AttributeValue{
+		idFieldName:   &types.AttributeValueMemberS{Value: testID},
+		nameFieldName: &types.AttributeValueMemberS{Value: testName},
+	}
+
+	key := map[string]types.AttributeValue{
+		idFieldName: &types.AttributeValueMemberS{Value: testID},
+	}
+
+	t.Run("it requires transact items", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.TransactWriteItems(ctx)
+
+		assert.Nil(t, actual)
+		assert.NotNil(t, err)
+		assert.Equal(t, errors.New(requiredTransactItemsMsg), err)
+	})
+	t.Run("it requires a table name on every item", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.TransactWriteItems(ctx,
+			transactwriteitems.WithPut(testTableName, item, nil),
+			transactwriteitems.WithDelete("to", key, nil))
+
+		assert.Nil(t, actual)
+		assert.NotNil(t, err)
+		assert.Equal(t, "TransactItems[1]: "+requiredTableNameMsg, err.Error())
+	})
+	t.Run("it returns an error if the aws client returns an error", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		expectedErr := &types.TransactionCanceledException{
+			Message: aws.String("transaction cancelled"),
+		}
+
+		m.On("TransactWriteItems",
+			ctx, mock.Anything).Return(nil, expectedErr)
+
+		actual, err := client.TransactWriteItems(ctx,
+			transactwriteitems.WithPut(testTableName, item, nil))
+
+		assert.Nil(t, actual)
+		assert.NotNil(t, err)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		putCondition := expression.AttributeNotExists(expression.Name(idFieldName))
+		putExpr, _ := expression.NewBuilder().WithCondition(putCondition).Build()
+
+		update := expression.Set(expression.Name(nameFieldName), expression.Value(testName))
+		updateExpr, _ := expression.NewBuilder().WithUpdate(update).Build()
+
+		checkCondition := expression.AttributeExists(expression.Name(idFieldName))
+		checkExpr, _ := expression.NewBuilder().WithCondition(checkCondition).Build()
+
+		expectedInput := &dynamodb.TransactWriteItemsInput{
+			ClientRequestToken:     aws.String("token"),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			TransactItems: []types.TransactWriteItem{{
+				Put: &types.Put{
+					Item:                      item,
+					TableName:                 aws.String(testTableName),
+					ConditionExpression:       putExpr.Condition(),
+					ExpressionAttributeNames:  putExpr.Names(),
+					ExpressionAttributeValues: putExpr.Values(),
+				},
+			}, {
+				Delete: &types.Delete{
+					Key:       key,
+					TableName: aws.String(testTableName),
+				},
+			}, {
+				Update: &types.Update{
+					Key:                       key,
+					TableName:                 aws.String(testTableName),
+					UpdateExpression:          updateExpr.Update(),
+					ExpressionAttributeNames:  updateExpr.Names(),
+					ExpressionAttributeValues: updateExpr.Values(),
+				},
+			}, {
+				ConditionCheck: &types.ConditionCheck{
+					Key:                       key,
+					TableName:                 aws.String(testTableName),
+					ConditionExpression:       checkExpr.Condition(),
+					ExpressionAttributeNames:  checkExpr.Names(),
+					ExpressionAttributeValues: checkExpr.Values(),
+				},
+			}},
+		}
+
+		m.On("TransactWriteItems",
+			ctx,
+			expectedInput).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+		actual, err := client.TransactWriteItems(ctx,
+			transactwriteitems.WithClientRequestToken("token"),
+			transactwriteitems.WithReturnConsumedCapacity(types.ReturnConsumedCapacityTotal),
+			transactwriteitems.WithPut(testTableName, item, &putCondition),
+			transactwriteitems.WithDelete(testTableName, key, nil),
+			transactwriteitems.WithUpdate(testTableName, key, &update, nil),
+			transactwriteitems.WithConditionCheck(testTableName, key, &checkCondition))
+
+		assert.Nil(t, err)
+		assert.NotNil(t, actual)
+	})
+}
+
+func TestClient_UpdateItem(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	testID := "uuid1-uuid2-uuid3-uuid4"
+	testName := "my item"
+
+	key := map[string]types.AttributeValue{
+		idFieldName: &types.AttributeValueMemberS{Value: testID},
+	}
+
+	update := expression.Set(expression.Name(nameFieldName), expression.Value(testName))
+
+	t.Run("it requires a table name to be 3 or more characters", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.UpdateItem(ctx, "to")
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredTableNameMsg), err)
+	})
+	t.Run("it requires a key and an update builder", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.UpdateItem(ctx, testTableName,
+			updateitem.WithUpdateBuilder(&update))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredKeyMsg), err)
+
+		actual, err = client.UpdateItem(ctx, testTableName,
+			updateitem.WithKey(key))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredUpdateBuilderMsg), err)
+	})
+	t.Run("it returns an error if the aws client returns an error", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		expectedErr := &types.InternalServerError{
+			Message: aws.String("dynamo down"),
+		}
+
+		m.On("UpdateItem",
+			ctx, mock.Anything).Return(nil, expectedErr)
+
+		actual, err := client.UpdateItem(ctx, testTableName,
+			updateitem.WithKey(key),
+			updateitem.WithUpdateBuilder(&update))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		condition := expression.AttributeExists(expression.Name(idFieldName))
+		expr, _ := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+
+		returnedAttributes := map[string]types.AttributeValue{
+			idFieldName:   &types.AttributeValueMemberS{Value: testID},
+			nameFieldName: &types.AttributeValueMemberS{Value: testName},
+		}
+
+		m.On("UpdateItem",
+			ctx,
+			&dynamodb.UpdateItemInput{
+				TableName:                 aws.String(testTableName),
+				Key:                       key,
+				ConditionExpression:       expr.Condition(),
+				UpdateExpression:          expr.Update(),
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
+				ReturnValues:              types.ReturnValueAllNew,
+			}).Return(&dynamodb.UpdateItemOutput{
+			Attributes: returnedAttributes,
+		}, nil)
+
+		entity := &testStruct{}
+
+		actual, err := client.UpdateItem(ctx, testTableName,
+			updateitem.WithKey(key),
+			updateitem.WithUpdateBuilder(&update),
+			updateitem.WithFilterConditionBuilder(&condition),
+			updateitem.WithReturnValue(types.ReturnValueAllNew),
+			updateitem.WithEntity(entity))
+
+		assert.Nil(t, err)
+		assert.Equal(t, returnedAttributes, actual.Attributes)
+		assert.Equal(t, &testStruct{ID: testID, Name: testName}, entity)
+	})
+}
+
+func TestClient_DescribeTimeToLive(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	t.Run("it requires a table name to be 3 or more characters", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.DescribeTimeToLive(ctx, "to")
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredTableNameMsg), err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		returned := &types.TimeToLiveDescription{
+			AttributeName:    aws.String("expires_at"),
+			TimeToLiveStatus: types.TimeToLiveStatusEnabled,
+		}
+
+		m.On("DescribeTimeToLive",
+			ctx,
+			&dynamodb.DescribeTimeToLiveInput{
+				TableName: aws.String(testTableName),
+			}).Return(&dynamodb.DescribeTimeToLiveOutput{
+			TimeToLiveDescription: returned,
+		}, nil)
+
+		actual, err := client.DescribeTimeToLive(ctx, testTableName)
+
+		assert.Nil(t, err)
+		assert.Equal(t, returned, actual.TimeToLiveDescription)
+	})
+}
+
+func TestClient_UpdateTimeToLive(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	t.Run("it requires an attribute name", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.UpdateTimeToLive(ctx, testTableName)
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredAttributeNameMsg), err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		specification := &types.TimeToLiveSpecification{
+			AttributeName: aws.String("expires_at"),
+			Enabled:       aws.Bool(true),
+		}
+
+		m.On("UpdateTimeToLive",
+			ctx,
+			&dynamodb.UpdateTimeToLiveInput{
+				TableName:               aws.String(testTableName),
+				TimeToLiveSpecification: specification,
+			}).Return(&dynamodb.UpdateTimeToLiveOutput{
+			TimeToLiveSpecification: specification,
+		}, nil)
+
+		actual, err := client.UpdateTimeToLive(ctx, testTableName,
+			updatetimetolive.WithAttributeName("expires_at"),
+			updatetimetolive.WithEnabled(true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, specification, actual.TimeToLiveSpecification)
+	})
+}
+
+func TestClient_CreateTable(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	keySchema := []types.KeySchemaElement{{
+		AttributeName: aws.String("pk"),
+		KeyType:       types.KeyTypeHash,
+	}}
+
+	attributeDefinitions := []types.AttributeDefinition{{
+		AttributeName: aws.String("pk"),
+		AttributeType: types.ScalarAttributeTypeS,
+	}}
+
+	t.Run("it requires a table name", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.CreateTable(ctx, "")
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredTableNameMsg), err)
+	})
+	t.Run("it requires a key schema", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.CreateTable(ctx, testTableName,
+			createtable.WithAttributeDefinitions(attributeDefinitions...))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredKeySchemaMsg), err)
+	})
+	t.Run("it requires attribute definitions", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.CreateTable(ctx, testTableName,
+			createtable.WithKeySchema(keySchema...))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredAttributeDefinitionsMsg), err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		description := &types.TableDescription{TableName: aws.String(testTableName)}
+
+		m.On("CreateTable",
+			ctx,
+			&dynamodb.CreateTableInput{
+				TableName:            aws.String(testTableName),
+				KeySchema:            keySchema,
+				AttributeDefinitions: attributeDefinitions,
+				BillingMode:          types.BillingModePayPerRequest,
+			}).Return(&dynamodb.CreateTableOutput{
+			TableDescription: description,
+		}, nil)
+
+		actual, err := client.CreateTable(ctx, testTableName,
+			createtable.WithKeySchema(keySchema...),
+			createtable.WithAttributeDefinitions(attributeDefinitions...),
+			createtable.WithBillingMode(types.BillingModePayPerRequest))
+
+		assert.Nil(t, err)
+		assert.Equal(t, description, actual.TableDescription)
+	})
+}
+
+func TestClient_UpdateTable(t *testing.T) {
+	ctx := context.Background()
+	testTableName := "test-table-name"
+
+	t.Run("it requires a change", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.UpdateTable(ctx, testTableName)
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredTableUpdateMsg), err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		update := types.GlobalSecondaryIndexUpdate{
+			Create: &types.CreateGlobalSecondaryIndexAction{
+				IndexName: aws.String("GSI1pk-GSI1sk-Index"),
+				KeySchema: []types.KeySchemaElement{{
+					AttributeName: aws.String("GSI1pk"),
+					KeyType:       types.KeyTypeHash,
+				}},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		}
+
+		attributeDefinitions := []types.AttributeDefinition{{
+			AttributeName: aws.String("GSI1pk"),
+			AttributeType: types.ScalarAttributeTypeS,
+		}}
+
+		description := &types.TableDescription{TableName: aws.String(testTableName)}
+
+		m.On("UpdateTable",
+			ctx,
+			&dynamodb.UpdateTableInput{
+				TableName:                   aws.String(testTableName),
+				AttributeDefinitions:        attributeDefinitions,
+				GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{update},
+			}).Return(&dynamodb.UpdateTableOutput{
+			TableDescription: description,
+		}, nil)
+
+		actual, err := client.UpdateTable(ctx, testTableName,
+			updatetable.WithAttributeDefinitions(attributeDefinitions...),
+			updatetable.WithGlobalSecondaryIndexUpdates(update))
+
+		assert.Nil(t, err)
+		assert.Equal(t, description, actual.TableDescription)
+	})
+}
+
+func TestClient_ExecuteStatement(t *testing.T) {
+	ctx := context.Background()
+	testStatement := `SELECT * FROM "test-table-name" WHERE id = ?`
+
+	testID := "uuid1-uuid2-uuid3-uuid4"
+	testName := "my item"
+
+	returnedItems := []map[string]types.AttributeValue{{
+		idFieldName:   &types.AttributeValueMemberS{Value: testID},
+		nameFieldName: &types.AttributeValueMemberS{Value: testName},
+	}}
+
+	t.Run("it requires a statement", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.ExecuteStatement(ctx, "")
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredStatementMsg), err)
+	})
+	t.Run("it returns an error if a parameter can not be marshalled", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.ExecuteStatement(ctx, testStatement,
+			executestatement.WithParameters(testID, make(chan int)))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New("Parameters[1]: type chan int can not be marshalled"), err)
+	})
+	t.Run("it returns an error if the aws client returns an error", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		expectedErr := errors.New("statement failed")
+
+		m.On("ExecuteStatement", ctx, mock.Anything).Return(nil, expectedErr)
+
+		actual, err := client.ExecuteStatement(ctx, testStatement)
+
+		assert.Nil(t, actual)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		m.On("ExecuteStatement", ctx, &dynamodb.ExecuteStatementInput{
+			ConsistentRead: aws.Bool(true),
+			NextToken:      aws.String("token1"),
+			Parameters: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: testID},
+				&types.AttributeValueMemberN{Value: "42"},
+				&types.AttributeValueMemberSS{Value: []string{"a"}},
+			},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			Statement:              aws.String(testStatement),
+		}).Return(&dynamodb.ExecuteStatementOutput{
+			Items:     returnedItems,
+			NextToken: aws.String("token2"),
+		}, nil)
+
+		actual, err := client.ExecuteStatement(ctx, testStatement,
+			executestatement.WithParameters(testID, 42),
+			executestatement.WithParameters(&types.AttributeValueMemberSS{Value: []string{"a"}}),
+			executestatement.WithConsistentRead(aws.Bool(true)),
+			executestatement.WithNextToken("token1"),
+			executestatement.WithReturnConsumedCapacity(types.ReturnConsumedCapacityTotal))
+
+		assert.Nil(t, err)
+		assert.Equal(t, returnedItems, actual.Items)
+		assert.Equal(t, aws.String("token2"), actual.NextToken)
+	})
+	t.Run("it sets AsSliceOfEntities", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		m.On("ExecuteStatement", ctx, mock.Anything).Return(&dynamodb.ExecuteStatementOutput{
+			Items: returnedItems,
+		}, nil)
+
+		var actual []*testStruct
+
+		_, err := client.ExecuteStatement(ctx, testStatement,
+			executestatement.WithParameters(testID),
+			executestatement.AsSliceOfEntities(&actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []*testStruct{{ID: testID, Name: testName}}, actual)
+	})
+}
+
+func TestClient_BatchExecuteStatement(t *testing.T) {
+	ctx := context.Background()
+	testStatement := `SELECT * FROM "test-table-name" WHERE id = ?`
+
+	testID := "uuid1-uuid2-uuid3-uuid4"
+	testName := "my item"
+
+	t.Run("it requires statements", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchExecuteStatement(ctx)
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredStatementsMsg), err)
+	})
+	t.Run("it requires every statement to be set", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.BatchExecuteStatement(ctx,
+			batchexecutestatement.WithStatement(testStatement, testID),
+			batchexecutestatement.WithStatement(""))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, "Statements[1]: "+requiredStatementMsg, err.Error())
+	})
+	t.Run("it returns an error if the aws client returns an error", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		expectedErr := errors.New("batch failed")
+
+		m.On("BatchExecuteStatement", ctx, mock.Anything).Return(nil, expectedErr)
+
+		actual, err := client.BatchExecuteStatement(ctx, batchexecutestatement.WithStatement(testStatement, testID))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		responses := []types.BatchStatementResponse{
+			{Item: map[string]types.AttributeValue{
+				idFieldName:   &types.AttributeValueMemberS{Value: testID},
+				nameFieldName: &types.AttributeValueMemberS{Value: testName},
+			}},
+			{Error: &types.BatchStatementError{Code: types.BatchStatementErrorCodeEnumResourceNotFound}},
+		}
+
+		m.On("BatchExecuteStatement", ctx, &dynamodb.BatchExecuteStatementInput{
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			Statements: []types.BatchStatementRequest{
+				{
+					ConsistentRead: aws.Bool(true),
+					Parameters:     []types.AttributeValue{&types.AttributeValueMemberS{Value: testID}},
+					Statement:      aws.String(testStatement),
+				},
+				{
+					Parameters: []types.AttributeValue{&types.AttributeValueMemberS{Value: "missing"}},
+					Statement:  aws.String(`SELECT * FROM "missing-table" WHERE id = ?`),
+				},
+			},
+		}).Return(&dynamodb.BatchExecuteStatementOutput{
+			Responses: responses,
+		}, nil)
+
+		var entities []*testStruct
+
+		actual, err := client.BatchExecuteStatement(ctx,
+			batchexecutestatement.WithStatements(&batchexecutestatement.Statement{
+				Statement:      testStatement,
+				Parameters:     []interface{}{testID},
+				ConsistentRead: aws.Bool(true),
+			}),
+			batchexecutestatement.WithStatement(`SELECT * FROM "missing-table" WHERE id = ?`, "missing"),
+			batchexecutestatement.WithReturnConsumedCapacity(types.ReturnConsumedCapacityTotal),
+			batchexecutestatement.AsSliceOfEntities(&entities))
+
+		assert.Nil(t, err)
+		assert.Equal(t, responses, actual.Responses)
+		assert.Equal(t, []*testStruct{{ID: testID, Name: testName}, {}}, entities)
+	})
+}
+
+func TestClient_ExecuteTransaction(t *testing.T) {
+	ctx := context.Background()
+	testStatement := `UPDATE "test-table-name" SET name = ? WHERE id = ?`
+
+	testID := "uuid1-uuid2-uuid3-uuid4"
+	testName := "my item"
+
+	t.Run("it requires statements", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.ExecuteTransaction(ctx)
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New(requiredStatementsMsg), err)
+	})
+	t.Run("it returns an error if a parameter can not be marshalled", func(t *testing.T) {
+		client := setupFixture()
+
+		actual, err := client.ExecuteTransaction(ctx,
+			executetransaction.WithStatement(testStatement, testName, testID),
+			executetransaction.WithStatement(testStatement, func() {}, testID))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, errors.New("Statements[1]: Parameters[0]: type func() can not be marshalled"), err)
+	})
+	t.Run("it returns an error if the aws client returns an error", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		expectedErr := &types.TransactionCanceledException{Message: aws.String("conflict")}
+
+		m.On("ExecuteTransaction", ctx, mock.Anything).Return(nil, expectedErr)
+
+		actual, err := client.ExecuteTransaction(ctx, executetransaction.WithStatement(testStatement, testName, testID))
+
+		assert.Nil(t, actual)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("it calls the aws client properly", func(t *testing.T) {
+		client := setupFixture()
+
+		m := client.awsClient.(*mockDynamoDB)
+
+		m.On("ExecuteTransaction", ctx, &dynamodb.ExecuteTransactionInput{
+			ClientRequestToken:     aws.String("request1"),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			TransactStatements: []types.ParameterizedStatement{{
+				Parameters: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: testName},
+					&types.AttributeValueMemberS{Value: testID},
+				},
+				Statement: aws.String(testStatement),
+			}},
+		}).Return(&dynamodb.ExecuteTransactionOutput{}, nil)
+
+		actual, err := client.ExecuteTransaction(ctx,
+			executetransaction.WithStatement(testStatement, testName, testID),
+			executetransaction.WithClientRequestToken("request1"),
+			executetransaction.WithReturnConsumedCapacity(types.ReturnConsumedCapacityTotal))
+
+		assert.Nil(t, err)
+		assert.NotNil(t, actual)
+	})
+}
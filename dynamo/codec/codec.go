@@ -0,0 +1,305 @@
+// Package codec converts attribute values to and from DynamoDB JSON, the typed format the console, the CLI and
+// the streams use, where every value is an object keyed by its type: {"id":{"S":"abc"},"price":{"N":"10"}}.
+// Numbers are kept as strings so nothing is lost
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	typeString    = "S"
+	typeNumber    = "N"
+	typeBinary    = "B"
+	typeBool      = "BOOL"
+	typeNull      = "NULL"
+	typeStringSet = "SS"
+	typeNumberSet = "NS"
+	typeBinarySet = "BS"
+	typeList      = "L"
+	typeMap       = "M"
+
+	// cliItemKey and cliItemsKey hold the items in the output of the CLI get-item, scan and query commands
+	cliItemKey  = "Item"
+	cliItemsKey = "Items"
+
+	requiredItemMsg = "expected an item object"
+)
+
+var numberPattern = regexp.MustCompile(`^-?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+// MarshalItem encodes an item in DynamoDB JSON
+func MarshalItem(item map[string]types.AttributeValue) ([]byte, error) {
+	out, err := toTypedMap(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalItem decodes an item in DynamoDB JSON. The output of the CLI get-item command, {"Item":{...}}, is accepted too
+func UnmarshalItem(data []byte) (map[string]types.AttributeValue, error) {
+	var in map[string]json.RawMessage
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+
+	if in == nil {
+		return nil, errors.New(requiredItemMsg)
+	}
+
+	if item, ok := in[cliItemKey]; ok && len(in) == 1 && !isTypedValue(item) {
+		return UnmarshalItem(item)
+	}
+
+	return fromTypedMap(in)
+}
+
+// MarshalItems encodes items as a JSON array
+func MarshalItems(items []map[string]types.AttributeValue) ([]byte, error) {
+	out := make([]map[string]interface{}, 0, len(items))
+
+	for idx, item := range items {
+		typed, err := toTypedMap(item)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %s", idx, err)
+		}
+
+		out = append(out, typed)
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalItems decodes a JSON array of items. The output of the CLI scan and query commands, {"Items":[...]},
+// is accepted too
+func UnmarshalItems(data []byte) ([]map[string]types.AttributeValue, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var members []json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		var output map[string]json.RawMessage
+		if json.Unmarshal(raw, &output) != nil || output[cliItemsKey] == nil {
+			return nil, errors.New("expected an array of items or an object with Items")
+		}
+
+		return UnmarshalItems(output[cliItemsKey])
+	}
+
+	out := make([]map[string]types.AttributeValue, 0, len(members))
+
+	for idx, member := range members {
+		item, err := UnmarshalItem(member)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %s", idx, err)
+		}
+
+		out = append(out, item)
+	}
+
+	return out, nil
+}
+
+// MarshalValue encodes a single value, {"S":"abc"}
+func MarshalValue(value types.AttributeValue) ([]byte, error) {
+	out, err := toTyped(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalValue decodes a single value
+func UnmarshalValue(data []byte) (types.AttributeValue, error) {
+	return fromTyped(data)
+}
+
+func toTypedMap(item map[string]types.AttributeValue) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(item))
+
+	for name, value := range item {
+		typed, err := toTyped(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %s", name, err)
+		}
+
+		out[name] = typed
+	}
+
+	return out, nil
+}
+
+func toTyped(value types.AttributeValue) (map[string]interface{}, error) {
+	switch typed := value.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]interface{}{typeString: typed.Value}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]interface{}{typeNumber: typed.Value}, nil
+	case *types.AttributeValueMemberB:
+		return map[string]interface{}{typeBinary: typed.Value}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]interface{}{typeBool: typed.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]interface{}{typeNull: typed.Value}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string]interface{}{typeStringSet: typed.Value}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string]interface{}{typeNumberSet: typed.Value}, nil
+	case *types.AttributeValueMemberBS:
+		return map[string]interface{}{typeBinarySet: typed.Value}, nil
+	case *types.AttributeValueMemberL:
+		values := make([]interface{}, 0, len(typed.Value))
+		for idx, member := range typed.Value {
+			converted, err := toTyped(member)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", idx, err)
+			}
+
+			values = append(values, converted)
+		}
+
+		return map[string]interface{}{typeList: values}, nil
+	case *types.AttributeValueMemberM:
+		values, err := toTypedMap(typed.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{typeMap: values}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attribute value %T", value)
+	}
+}
+
+func fromTypedMap(in map[string]json.RawMessage) (map[string]types.AttributeValue, error) {
+	out := make(map[string]types.AttributeValue, len(in))
+
+	for name, raw := range in {
+		value, err := fromTyped(raw)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %s", name, err)
+		}
+
+		out[name] = value
+	}
+
+	return out, nil
+}
+
+// isTypedValue tells a value, {"S":"abc"}, apart from an item
+func isTypedValue(raw json.RawMessage) bool {
+	var wrapper map[string]json.RawMessage
+	if json.Unmarshal(raw, &wrapper) != nil || len(wrapper) != 1 {
+		return false
+	}
+
+	for typeName := range wrapper {
+		switch typeName {
+		case typeString, typeNumber, typeBinary, typeBool, typeNull, typeStringSet, typeNumberSet, typeBinarySet, typeList, typeMap:
+			return true
+		}
+	}
+
+	return false
+}
+
+func fromTyped(raw json.RawMessage) (types.AttributeValue, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return nil, err
+	}
+
+	if len(wrapper) != 1 {
+		return nil, fmt.Errorf("expected a single type key, found %s", string(raw))
+	}
+
+	for typeName, value := range wrapper {
+		switch typeName {
+		case typeString:
+			out := &types.AttributeValueMemberS{}
+			return out, json.Unmarshal(value, &out.Value)
+		case typeNumber:
+			out := &types.AttributeValueMemberN{}
+			if err := json.Unmarshal(value, &out.Value); err != nil {
+				return nil, err
+			}
+
+			return out, validateNumbers(out.Value)
+		case typeBinary:
+			out := &types.AttributeValueMemberB{}
+			return out, json.Unmarshal(value, &out.Value)
+		case typeBool:
+			out := &types.AttributeValueMemberBOOL{}
+			return out, json.Unmarshal(value, &out.Value)
+		case typeNull:
+			out := &types.AttributeValueMemberNULL{}
+			return out, json.Unmarshal(value, &out.Value)
+		case typeStringSet:
+			out := &types.AttributeValueMemberSS{}
+			return out, json.Unmarshal(value, &out.Value)
+		case typeNumberSet:
+			out := &types.AttributeValueMemberNS{}
+			if err := json.Unmarshal(value, &out.Value); err != nil {
+				return nil, err
+			}
+
+			return out, validateNumbers(out.Value...)
+		case typeBinarySet:
+			out := &types.AttributeValueMemberBS{}
+			return out, json.Unmarshal(value, &out.Value)
+		case typeList:
+			var members []json.RawMessage
+			if err := json.Unmarshal(value, &members); err != nil {
+				return nil, err
+			}
+
+			out := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, 0, len(members))}
+			for idx, member := range members {
+				converted, err := fromTyped(member)
+				if err != nil {
+					return nil, fmt.Errorf("[%d]: %s", idx, err)
+				}
+
+				out.Value = append(out.Value, converted)
+			}
+
+			return out, nil
+		case typeMap:
+			var members map[string]json.RawMessage
+			if err := json.Unmarshal(value, &members); err != nil {
+				return nil, err
+			}
+
+			converted, err := fromTypedMap(members)
+			if err != nil {
+				return nil, err
+			}
+
+			return &types.AttributeValueMemberM{Value: converted}, nil
+		default:
+			return nil, fmt.Errorf("unknown type %s", typeName)
+		}
+	}
+
+	return nil, nil
+}
+
+func validateNumbers(values ...string) error {
+	for _, value := range values {
+		if !numberPattern.MatchString(value) {
+			return fmt.Errorf("'%s' is not a number", value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestItem() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":     &types.AttributeValueMemberS{Value: "abc"},
+		"price":  &types.AttributeValueMemberN{Value: "12345678901234567890.123456789"},
+		"image":  &types.AttributeValueMemberB{Value: []byte{1, 2}},
+		"active": &types.AttributeValueMemberBOOL{Value: true},
+		"gone":   &types.AttributeValueMemberNULL{Value: true},
+		"tags":   &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"sizes":  &types.AttributeValueMemberNS{Value: []string{"1", "-2.5e3"}},
+		"blobs":  &types.AttributeValueMemberBS{Value: [][]byte{{3}}},
+		"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "x"},
+			&types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+		}},
+		"map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"nested": &types.AttributeValueMemberN{Value: "1.5"},
+			"empty":  &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}},
+		}},
+	}
+}
+
+func TestMarshalItem(t *testing.T) {
+	t.Run("it writes every value keyed by its type", func(t *testing.T) {
+		actual, err := MarshalItem(map[string]types.AttributeValue{
+			"id":    &types.AttributeValueMemberS{Value: "abc"},
+			"price": &types.AttributeValueMemberN{Value: "10"},
+			"image": &types.AttributeValueMemberB{Value: []byte("hi")},
+		})
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"id":{"S":"abc"},"price":{"N":"10"},"image":{"B":"aGk="}}`, string(actual))
+	})
+	t.Run("it round trips every type", func(t *testing.T) {
+		data, err := MarshalItem(newTestItem())
+		assert.Nil(t, err)
+
+		actual, err := UnmarshalItem(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, newTestItem(), actual)
+	})
+	t.Run("it rejects unsupported values", func(t *testing.T) {
+		_, err := MarshalItem(map[string]types.AttributeValue{"id": nil})
+
+		assert.Equal(t, errors.New("attribute id: unsupported attribute value <nil>"), err)
+	})
+}
+
+func TestUnmarshalItem(t *testing.T) {
+	t.Run("it reads the output of get-item", func(t *testing.T) {
+		actual, err := UnmarshalItem([]byte(`{"Item":{"id":{"S":"abc"}}}`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "abc"}}, actual)
+	})
+	t.Run("it reads an attribute named Item", func(t *testing.T) {
+		actual, err := UnmarshalItem([]byte(`{"Item":{"S":"abc"}}`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{"Item": &types.AttributeValueMemberS{Value: "abc"}}, actual)
+	})
+	t.Run("it requires an object", func(t *testing.T) {
+		_, err := UnmarshalItem([]byte(`null`))
+
+		assert.Equal(t, errors.New("expected an item object"), err)
+	})
+	t.Run("it rejects values without a single type", func(t *testing.T) {
+		_, err := UnmarshalItem([]byte(`{"id":{"S":"abc","N":"1"}}`))
+
+		assert.Equal(t, errors.New(`attribute id: expected a single type key, found {"S":"abc","N":"1"}`), err)
+	})
+	t.Run("it rejects unknown types", func(t *testing.T) {
+		_, err := UnmarshalItem([]byte(`{"id":{"X":"abc"}}`))
+
+		assert.Equal(t, errors.New("attribute id: unknown type X"), err)
+	})
+	t.Run("it rejects numbers that are not numbers", func(t *testing.T) {
+		_, err := UnmarshalItem([]byte(`{"map":{"M":{"sizes":{"NS":["1","ten"]}}}}`))
+
+		assert.Equal(t, errors.New("attribute map: attribute sizes: 'ten' is not a number"), err)
+	})
+	t.Run("it points to the list member that failed", func(t *testing.T) {
+		_, err := UnmarshalItem([]byte(`{"list":{"L":[{"S":"x"},{"N":""}]}}`))
+
+		assert.Equal(t, errors.New("attribute list: [1]: '' is not a number"), err)
+	})
+}
+
+func TestUnmarshalItems(t *testing.T) {
+	expected := []map[string]types.AttributeValue{
+		{"id": &types.AttributeValueMemberS{Value: "a"}},
+		{"id": &types.AttributeValueMemberS{Value: "b"}},
+	}
+
+	t.Run("it reads an array of items", func(t *testing.T) {
+		actual, err := UnmarshalItems([]byte(`[{"id":{"S":"a"}},{"id":{"S":"b"}}]`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	})
+	t.Run("it reads the output of scan and query", func(t *testing.T) {
+		actual, err := UnmarshalItems([]byte(`{"Items":[{"id":{"S":"a"}},{"id":{"S":"b"}}],"Count":2,"ScannedCount":2}`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	})
+	t.Run("it round trips the items", func(t *testing.T) {
+		data, err := MarshalItems(expected)
+		assert.Nil(t, err)
+
+		actual, err := UnmarshalItems(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	})
+	t.Run("it rejects an object without Items", func(t *testing.T) {
+		_, err := UnmarshalItems([]byte(`{"id":{"S":"a"}}`))
+
+		assert.Equal(t, errors.New("expected an array of items or an object with Items"), err)
+	})
+	t.Run("it points to the item that failed", func(t *testing.T) {
+		_, err := UnmarshalItems([]byte(`[{"id":{"S":"a"}},{"id":{"Q":"b"}}]`))
+
+		assert.Equal(t, errors.New("[1]: attribute id: unknown type Q"), err)
+	})
+}
+
+func TestValue(t *testing.T) {
+	t.Run("it round trips a single value", func(t *testing.T) {
+		value := &types.AttributeValueMemberSS{Value: []string{"a"}}
+
+		data, err := MarshalValue(value)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"SS":["a"]}`, string(data))
+
+		actual, err := UnmarshalValue(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, value, actual)
+	})
+}
@@ -0,0 +1,79 @@
+// Package codectest loads DynamoDB JSON fixtures in tests, failing the test when they can not be read
+package codectest
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo/codec"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Item decodes an item written inline, an item pasted from the console
+func Item(tb testing.TB, data string) map[string]types.AttributeValue {
+	tb.Helper()
+
+	out, err := codec.UnmarshalItem([]byte(data))
+	if err != nil {
+		tb.Fatalf("codectest.Item: %s", err)
+	}
+
+	return out
+}
+
+// LoadItem reads an item from a fixture file
+func LoadItem(tb testing.TB, path string) map[string]types.AttributeValue {
+	tb.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("codectest.LoadItem: %s", err)
+	}
+
+	out, err := codec.UnmarshalItem(data)
+	if err != nil {
+		tb.Fatalf("codectest.LoadItem %s: %s", path, err)
+	}
+
+	return out
+}
+
+// LoadItems reads the items of a fixture file holding a JSON array, the output of a CLI scan or query,
+// or one item per line
+func LoadItems(tb testing.TB, path string) []map[string]types.AttributeValue {
+	tb.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("codectest.LoadItems: %s", err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+
+	items, err := codec.UnmarshalItems(trimmed)
+	if err == nil {
+		return items
+	}
+
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		tb.Fatalf("codectest.LoadItems %s: %s", path, err)
+	}
+
+	var out []map[string]types.AttributeValue
+
+	for idx, line := range bytes.Split(trimmed, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+
+		item, err := codec.UnmarshalItem(line)
+		if err != nil {
+			tb.Fatalf("codectest.LoadItems %s line %d: %s", path, idx+1, err)
+		}
+
+		out = append(out, item)
+	}
+
+	return out
+}
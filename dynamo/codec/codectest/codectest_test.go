@@ -0,0 +1,38 @@
+package codectest
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItem(t *testing.T) {
+	t.Run("it decodes an inline item", func(t *testing.T) {
+		actual := Item(t, `{"id":{"S":"a"}}`)
+
+		assert.Equal(t, map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "a"}}, actual)
+	})
+}
+
+func TestLoadItem(t *testing.T) {
+	t.Run("it reads the output of get-item", func(t *testing.T) {
+		actual := LoadItem(t, "testdata/item.json")
+
+		assert.Equal(t, map[string]types.AttributeValue{
+			"id":    &types.AttributeValueMemberS{Value: "a"},
+			"count": &types.AttributeValueMemberN{Value: "3"},
+		}, actual)
+	})
+}
+
+func TestLoadItems(t *testing.T) {
+	t.Run("it reads one item per line", func(t *testing.T) {
+		actual := LoadItems(t, "testdata/items.jsonl")
+
+		assert.Equal(t, []map[string]types.AttributeValue{
+			{"id": &types.AttributeValueMemberS{Value: "a"}},
+			{"id": &types.AttributeValueMemberS{Value: "b"}},
+		}, actual)
+	})
+}
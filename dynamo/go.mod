@@ -6,6 +6,8 @@ require (
 	github.com/aws/aws-sdk-go-v2 v1.11.1
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression v1.3.3
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.9.0
+	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.8.0
+	github.com/aws/smithy-go v1.9.0
 	github.com/stretchr/testify v1.7.0
 )
 
@@ -13,10 +15,8 @@ require (
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.4.3
 	github.com/aws/aws-sdk-go-v2/internal/configsources v1.1.1 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.0.1 // indirect
-	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.8.0 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.5.0 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.3.2 // indirect
-	github.com/aws/smithy-go v1.9.0 // indirect
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
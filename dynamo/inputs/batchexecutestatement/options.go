@@ -0,0 +1,71 @@
+package batchexecutestatement
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Statement
+//
+// A statement of the batch, Parameters are marshalled like executestatement.Options.Parameters
+type Statement struct {
+	Statement      string
+	Parameters     []interface{}
+	ConsistentRead *bool
+}
+
+type Options struct {
+	// maps to BatchExecuteStatementInput.Statements
+	//
+	// Statements is a required field, a batch holds up to 25 statements
+	Statements []*Statement
+
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+
+	// Entities is a slice of structs that the Item of every response is unmarshaled into, in the order of the
+	// statements. Failed statements leave their entity empty
+	Entities interface{}
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+// WithStatement appends a statement and the values of its placeholders
+func WithStatement(statement string, parameters ...interface{}) OptionFunc {
+	return func(options *Options) {
+		options.Statements = append(options.Statements, &Statement{
+			Statement:  statement,
+			Parameters: parameters,
+		})
+	}
+}
+
+// WithStatements appends statements, use it to set ConsistentRead on a read
+func WithStatements(input ...*Statement) OptionFunc {
+	return func(options *Options) {
+		options.Statements = append(options.Statements, input...)
+	}
+}
+
+func WithReturnConsumedCapacity(input types.ReturnConsumedCapacity) OptionFunc {
+	return func(options *Options) {
+		options.ReturnConsumedCapacity = input
+	}
+}
+
+// AsSliceOfEntities
+//
+// input is a slice of structs that the Item of every response is unmarshaled into
+func AsSliceOfEntities(input interface{}) OptionFunc {
+	return func(options *Options) {
+		options.Entities = input
+	}
+}
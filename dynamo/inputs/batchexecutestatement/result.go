@@ -0,0 +1,12 @@
+package batchexecutestatement
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	ConsumedCapacity []types.ConsumedCapacity
+
+	// Responses are in the order of the statements, a failed statement has an Error instead of failing the batch
+	Responses []types.BatchStatementResponse
+}
@@ -0,0 +1,71 @@
+package batchgetitem
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Options struct {
+	// maps to BatchGetItemInput.RequestItems, keyed by table name
+	//
+	// RequestItems is a required field, each table requires at least one key
+	RequestItems map[string]types.KeysAndAttributes
+
+	// maps to BatchGetItemInput.ReturnConsumedCapacity
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+// WithRequestItems
+//
+// Replaces the request items, UnprocessedKeys of a previous Result can be passed to retry them
+func WithRequestItems(input map[string]types.KeysAndAttributes) OptionFunc {
+	return func(options *Options) {
+		options.RequestItems = input
+	}
+}
+
+// WithKeys appends keys to read from tableName
+func WithKeys(tableName string, keys ...map[string]types.AttributeValue) OptionFunc {
+	return func(options *Options) {
+		request := options.tableRequest(tableName)
+		request.Keys = append(request.Keys, keys...)
+
+		options.RequestItems[tableName] = request
+	}
+}
+
+// WithConsistentRead sets a consistent read for the keys of tableName
+func WithConsistentRead(tableName string, input bool) OptionFunc {
+	return func(options *Options) {
+		request := options.tableRequest(tableName)
+		request.ConsistentRead = aws.Bool(input)
+
+		options.RequestItems[tableName] = request
+	}
+}
+
+func WithReturnConsumedCapacity(input types.ReturnConsumedCapacity) OptionFunc {
+	return func(options *Options) {
+		options.ReturnConsumedCapacity = input
+	}
+}
+
+func (o *Options) tableRequest(tableName string) types.KeysAndAttributes {
+	if o.RequestItems == nil {
+		o.RequestItems = make(map[string]types.KeysAndAttributes)
+	}
+
+	return o.RequestItems[tableName]
+}
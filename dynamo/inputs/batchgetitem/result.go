@@ -0,0 +1,15 @@
+package batchgetitem
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	ConsumedCapacity []types.ConsumedCapacity
+
+	// Responses holds the items read, keyed by table name. Items are not returned in the order they were requested
+	Responses map[string][]map[string]types.AttributeValue
+
+	// UnprocessedKeys holds the keys that were not read, pass them to WithRequestItems to retry
+	UnprocessedKeys map[string]types.KeysAndAttributes
+}
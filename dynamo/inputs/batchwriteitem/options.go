@@ -0,0 +1,74 @@
+package batchwriteitem
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Options struct {
+	// maps to BatchWriteItemInput.RequestItems, keyed by table name
+	//
+	// RequestItems is a required field, each table requires at least one write request
+	RequestItems map[string][]types.WriteRequest
+
+	// maps to BatchWriteItemInput.ReturnConsumedCapacity
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+// WithRequestItems
+//
+// Replaces the request items, UnprocessedItems of a previous Result can be passed to retry them
+func WithRequestItems(input map[string][]types.WriteRequest) OptionFunc {
+	return func(options *Options) {
+		options.RequestItems = input
+	}
+}
+
+// WithPutItems appends put requests for the items to tableName
+func WithPutItems(tableName string, items ...map[string]types.AttributeValue) OptionFunc {
+	return func(options *Options) {
+		options.init()
+
+		for _, item := range items {
+			options.RequestItems[tableName] = append(options.RequestItems[tableName], types.WriteRequest{
+				PutRequest: &types.PutRequest{Item: item},
+			})
+		}
+	}
+}
+
+// WithDeleteKeys appends delete requests for the keys to tableName
+func WithDeleteKeys(tableName string, keys ...map[string]types.AttributeValue) OptionFunc {
+	return func(options *Options) {
+		options.init()
+
+		for _, key := range keys {
+			options.RequestItems[tableName] = append(options.RequestItems[tableName], types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: key},
+			})
+		}
+	}
+}
+
+func WithReturnConsumedCapacity(input types.ReturnConsumedCapacity) OptionFunc {
+	return func(options *Options) {
+		options.ReturnConsumedCapacity = input
+	}
+}
+
+func (o *Options) init() {
+	if o.RequestItems == nil {
+		o.RequestItems = make(map[string][]types.WriteRequest)
+	}
+}
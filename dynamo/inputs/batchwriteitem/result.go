@@ -0,0 +1,12 @@
+package batchwriteitem
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	ConsumedCapacity []types.ConsumedCapacity
+
+	// UnprocessedItems holds the requests that were not written, pass them to WithRequestItems to retry
+	UnprocessedItems map[string][]types.WriteRequest
+}
@@ -0,0 +1,64 @@
+package createtable
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Options struct {
+	// maps to CreateTableInput.KeySchema
+	KeySchema []types.KeySchemaElement
+
+	// maps to CreateTableInput.AttributeDefinitions
+	AttributeDefinitions []types.AttributeDefinition
+
+	// maps to CreateTableInput.GlobalSecondaryIndexes
+	GlobalSecondaryIndexes []types.GlobalSecondaryIndex
+
+	// maps to CreateTableInput.BillingMode
+	BillingMode types.BillingMode
+
+	// maps to CreateTableInput.ProvisionedThroughput, required when BillingMode is PROVISIONED
+	ProvisionedThroughput *types.ProvisionedThroughput
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+func WithKeySchema(input ...types.KeySchemaElement) OptionFunc {
+	return func(options *Options) {
+		options.KeySchema = input
+	}
+}
+
+func WithAttributeDefinitions(input ...types.AttributeDefinition) OptionFunc {
+	return func(options *Options) {
+		options.AttributeDefinitions = input
+	}
+}
+
+func WithGlobalSecondaryIndexes(input ...types.GlobalSecondaryIndex) OptionFunc {
+	return func(options *Options) {
+		options.GlobalSecondaryIndexes = input
+	}
+}
+
+func WithBillingMode(input types.BillingMode) OptionFunc {
+	return func(options *Options) {
+		options.BillingMode = input
+	}
+}
+
+func WithProvisionedThroughput(input *types.ProvisionedThroughput) OptionFunc {
+	return func(options *Options) {
+		options.ProvisionedThroughput = input
+	}
+}
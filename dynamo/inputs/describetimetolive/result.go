@@ -0,0 +1,9 @@
+package describetimetolive
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	TimeToLiveDescription *types.TimeToLiveDescription
+}
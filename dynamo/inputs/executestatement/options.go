@@ -0,0 +1,65 @@
+package executestatement
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Options struct {
+	// Parameters replace the ? placeholders of the statement in order. Go values are marshalled with
+	// attributevalue.Marshal, types.AttributeValue values are sent as they are
+	Parameters             []interface{}
+	ConsistentRead         *bool
+	NextToken              *string
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+	Entities               interface{}
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+// WithParameters appends the values of the statement placeholders
+func WithParameters(input ...interface{}) OptionFunc {
+	return func(options *Options) {
+		options.Parameters = append(options.Parameters, input...)
+	}
+}
+
+func WithConsistentRead(input *bool) OptionFunc {
+	return func(options *Options) {
+		options.ConsistentRead = input
+	}
+}
+
+// WithNextToken
+//
+// Continues a statement from the NextToken of its previous Result
+func WithNextToken(input string) OptionFunc {
+	return func(options *Options) {
+		options.NextToken = aws.String(input)
+	}
+}
+
+func WithReturnConsumedCapacity(input types.ReturnConsumedCapacity) OptionFunc {
+	return func(options *Options) {
+		options.ReturnConsumedCapacity = input
+	}
+}
+
+// AsSliceOfEntities
+//
+// input is a slice of structs that the returned Items are unmarshaled into
+func AsSliceOfEntities(input interface{}) OptionFunc {
+	return func(options *Options) {
+		options.Entities = input
+	}
+}
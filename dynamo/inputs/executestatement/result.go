@@ -0,0 +1,13 @@
+package executestatement
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	ConsumedCapacity *types.ConsumedCapacity
+	Items            []map[string]types.AttributeValue
+
+	// NextToken is set when there are more items, pass it to WithNextToken to read them
+	NextToken *string
+}
@@ -0,0 +1,71 @@
+package executetransaction
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Statement
+//
+// A statement of the transaction, Parameters are marshalled like executestatement.Options.Parameters
+type Statement struct {
+	Statement  string
+	Parameters []interface{}
+}
+
+type Options struct {
+	// maps to ExecuteTransactionInput.TransactStatements
+	//
+	// Statements is a required field, they have to be all reads or all writes
+	Statements []*Statement
+
+	// ClientRequestToken makes the transaction idempotent
+	ClientRequestToken     *string
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+
+	// Entities is a slice of structs that the Item of every response of a read transaction is unmarshaled into
+	Entities interface{}
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+// WithStatement appends a statement and the values of its placeholders
+func WithStatement(statement string, parameters ...interface{}) OptionFunc {
+	return func(options *Options) {
+		options.Statements = append(options.Statements, &Statement{
+			Statement:  statement,
+			Parameters: parameters,
+		})
+	}
+}
+
+func WithClientRequestToken(input string) OptionFunc {
+	return func(options *Options) {
+		options.ClientRequestToken = aws.String(input)
+	}
+}
+
+func WithReturnConsumedCapacity(input types.ReturnConsumedCapacity) OptionFunc {
+	return func(options *Options) {
+		options.ReturnConsumedCapacity = input
+	}
+}
+
+// AsSliceOfEntities
+//
+// input is a slice of structs that the Item of every response is unmarshaled into
+func AsSliceOfEntities(input interface{}) OptionFunc {
+	return func(options *Options) {
+		options.Entities = input
+	}
+}
@@ -0,0 +1,12 @@
+package executetransaction
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	ConsumedCapacity []types.ConsumedCapacity
+
+	// Responses hold the items of a read transaction in the order of the statements
+	Responses []types.ItemResponse
+}
@@ -0,0 +1,137 @@
+package transactwriteitems
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Operation string
+
+const (
+	OperationConditionCheck Operation = "ConditionCheck"
+	OperationDelete         Operation = "Delete"
+	OperationPut            Operation = "Put"
+	OperationUpdate         Operation = "Update"
+)
+
+// TransactItem
+//
+// maps to a types.TransactWriteItem, the expressions are built by the client
+type TransactItem struct {
+	Operation Operation
+	TableName string
+
+	// Item is required for OperationPut
+	Item map[string]types.AttributeValue
+
+	// Key is required for OperationConditionCheck, OperationDelete and OperationUpdate
+	Key map[string]types.AttributeValue
+
+	// input = expression.NewBuilder().WithCondition(ConditionBuilder)
+	//
+	// ConditionExpression = input.Condition()
+	// ExpressionAttributeNames = input.Names()
+	// ExpressionAttributeValues = input.Values()
+	//
+	// ConditionBuilder is required for OperationConditionCheck
+	ConditionBuilder *expression.ConditionBuilder
+
+	// UpdateBuilder is required for OperationUpdate
+	UpdateBuilder *expression.UpdateBuilder
+
+	ReturnValuesOnConditionCheckFailure types.ReturnValuesOnConditionCheckFailure
+}
+
+type Options struct {
+	// maps to TransactWriteItemsInput.ClientRequestToken
+	ClientRequestToken *string
+
+	// maps to TransactWriteItemsInput.ReturnConsumedCapacity
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+
+	// maps to TransactWriteItemsInput.ReturnItemCollectionMetrics
+	ReturnItemCollectionMetrics types.ReturnItemCollectionMetrics
+
+	// maps to TransactWriteItemsInput.TransactItems in the order they were added
+	//
+	// TransactItems is a required field
+	TransactItems []*TransactItem
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+func WithClientRequestToken(input string) OptionFunc {
+	return func(options *Options) {
+		options.ClientRequestToken = &input
+	}
+}
+
+func WithReturnConsumedCapacity(input types.ReturnConsumedCapacity) OptionFunc {
+	return func(options *Options) {
+		options.ReturnConsumedCapacity = input
+	}
+}
+
+func WithReturnItemCollectionMetrics(input types.ReturnItemCollectionMetrics) OptionFunc {
+	return func(options *Options) {
+		options.ReturnItemCollectionMetrics = input
+	}
+}
+
+// WithTransactItem appends a TransactItem
+func WithTransactItem(input *TransactItem) OptionFunc {
+	return func(options *Options) {
+		options.TransactItems = append(options.TransactItems, input)
+	}
+}
+
+// WithPut appends a Put of item, condition is optional
+func WithPut(tableName string, item map[string]types.AttributeValue, condition *expression.ConditionBuilder) OptionFunc {
+	return WithTransactItem(&TransactItem{
+		Operation:        OperationPut,
+		TableName:        tableName,
+		Item:             item,
+		ConditionBuilder: condition,
+	})
+}
+
+// WithDelete appends a Delete of key, condition is optional
+func WithDelete(tableName string, key map[string]types.AttributeValue, condition *expression.ConditionBuilder) OptionFunc {
+	return WithTransactItem(&TransactItem{
+		Operation:        OperationDelete,
+		TableName:        tableName,
+		Key:              key,
+		ConditionBuilder: condition,
+	})
+}
+
+// WithUpdate appends an Update of key, condition is optional
+func WithUpdate(tableName string, key map[string]types.AttributeValue, update *expression.UpdateBuilder, condition *expression.ConditionBuilder) OptionFunc {
+	return WithTransactItem(&TransactItem{
+		Operation:        OperationUpdate,
+		TableName:        tableName,
+		Key:              key,
+		UpdateBuilder:    update,
+		ConditionBuilder: condition,
+	})
+}
+
+// WithConditionCheck appends a condition that must hold for key without writing it
+func WithConditionCheck(tableName string, key map[string]types.AttributeValue, condition *expression.ConditionBuilder) OptionFunc {
+	return WithTransactItem(&TransactItem{
+		Operation:        OperationConditionCheck,
+		TableName:        tableName,
+		Key:              key,
+		ConditionBuilder: condition,
+	})
+}
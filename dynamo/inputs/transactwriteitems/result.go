@@ -0,0 +1,10 @@
+package transactwriteitems
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	ConsumedCapacity      []types.ConsumedCapacity
+	ItemCollectionMetrics map[string][]types.ItemCollectionMetrics
+}
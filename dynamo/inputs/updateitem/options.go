@@ -0,0 +1,87 @@
+package updateitem
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Options struct {
+	// input = expression.NewBuilder().WithUpdate(UpdateBuilder).WithCondition(FilterConditionBuilder)
+	//
+	// ConditionExpression = input.Condition()
+	// UpdateExpression = input.Update()
+	// ExpressionAttributeNames = input.Names()
+	// ExpressionAttributeValues = input.Values()
+	FilterConditionBuilder *expression.ConditionBuilder
+
+	UpdateBuilder *expression.UpdateBuilder
+
+	// maps to UpdateItemInput.Key
+	Key map[string]types.AttributeValue
+
+	// maps to UpdateItemInput.ReturnConsumedCapacity
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+
+	// maps to UpdateItemInput.ReturnItemCollectionMetrics
+	ReturnItemCollectionMetrics types.ReturnItemCollectionMetrics
+
+	// maps to UpdateItemInput.ReturnValues
+	ReturnValue types.ReturnValue
+
+	// the returned attributes are unmarshalled into Entity
+	Entity interface{}
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+func WithKey(input map[string]types.AttributeValue) OptionFunc {
+	return func(options *Options) {
+		options.Key = input
+	}
+}
+
+func WithUpdateBuilder(input *expression.UpdateBuilder) OptionFunc {
+	return func(options *Options) {
+		options.UpdateBuilder = input
+	}
+}
+
+func WithFilterConditionBuilder(input *expression.ConditionBuilder) OptionFunc {
+	return func(options *Options) {
+		options.FilterConditionBuilder = input
+	}
+}
+
+func WithReturnConsumedCapacity(input types.ReturnConsumedCapacity) OptionFunc {
+	return func(options *Options) {
+		options.ReturnConsumedCapacity = input
+	}
+}
+
+func WithReturnItemCollectionMetrics(input types.ReturnItemCollectionMetrics) OptionFunc {
+	return func(options *Options) {
+		options.ReturnItemCollectionMetrics = input
+	}
+}
+
+func WithReturnValue(input types.ReturnValue) OptionFunc {
+	return func(options *Options) {
+		options.ReturnValue = input
+	}
+}
+
+func WithEntity(input interface{}) OptionFunc {
+	return func(options *Options) {
+		options.Entity = input
+	}
+}
@@ -0,0 +1,55 @@
+package updatetable
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Options struct {
+	// maps to UpdateTableInput.AttributeDefinitions, required for the key attributes of a created index
+	AttributeDefinitions []types.AttributeDefinition
+
+	// maps to UpdateTableInput.GlobalSecondaryIndexUpdates, dynamo creates or deletes one index per call
+	GlobalSecondaryIndexUpdates []types.GlobalSecondaryIndexUpdate
+
+	// maps to UpdateTableInput.BillingMode
+	BillingMode types.BillingMode
+
+	// maps to UpdateTableInput.ProvisionedThroughput
+	ProvisionedThroughput *types.ProvisionedThroughput
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+func WithAttributeDefinitions(input ...types.AttributeDefinition) OptionFunc {
+	return func(options *Options) {
+		options.AttributeDefinitions = input
+	}
+}
+
+func WithGlobalSecondaryIndexUpdates(input ...types.GlobalSecondaryIndexUpdate) OptionFunc {
+	return func(options *Options) {
+		options.GlobalSecondaryIndexUpdates = input
+	}
+}
+
+func WithBillingMode(input types.BillingMode) OptionFunc {
+	return func(options *Options) {
+		options.BillingMode = input
+	}
+}
+
+func WithProvisionedThroughput(input *types.ProvisionedThroughput) OptionFunc {
+	return func(options *Options) {
+		options.ProvisionedThroughput = input
+	}
+}
@@ -0,0 +1,9 @@
+package updatetable
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	TableDescription *types.TableDescription
+}
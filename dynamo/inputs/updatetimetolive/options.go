@@ -0,0 +1,33 @@
+package updatetimetolive
+
+type Options struct {
+	// maps to UpdateTimeToLiveInput.TimeToLiveSpecification.AttributeName
+	AttributeName string
+
+	// maps to UpdateTimeToLiveInput.TimeToLiveSpecification.Enabled
+	Enabled bool
+}
+
+type OptionFunc func(*Options)
+
+func NewOptions(input ...OptionFunc) *Options {
+	options := &Options{}
+
+	for _, optionFunc := range input {
+		optionFunc(options)
+	}
+
+	return options
+}
+
+func WithAttributeName(input string) OptionFunc {
+	return func(options *Options) {
+		options.AttributeName = input
+	}
+}
+
+func WithEnabled(input bool) OptionFunc {
+	return func(options *Options) {
+		options.Enabled = input
+	}
+}
@@ -0,0 +1,9 @@
+package updatetimetolive
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type Result struct {
+	TimeToLiveSpecification *types.TimeToLiveSpecification
+}
@@ -3,22 +3,44 @@ package dynamo
 import (
 	"context"
 
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchexecutestatement"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchwriteitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/createtable"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetimetolive"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executestatement"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executetransaction"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/listtables"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/query"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetimetolive"
 
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
 )
 
 type Interface interface {
+	BatchExecuteStatement(ctx context.Context, batchOptions ...batchexecutestatement.OptionFunc) (*batchexecutestatement.Result, error)
+	BatchGetItem(ctx context.Context, batchOptions ...batchgetitem.OptionFunc) (*batchgetitem.Result, error)
+	BatchWriteItem(ctx context.Context, batchOptions ...batchwriteitem.OptionFunc) (*batchwriteitem.Result, error)
+	CreateTable(ctx context.Context, tableName string, createOptions ...createtable.OptionFunc) (*createtable.Result, error)
 	DeleteItem(ctx context.Context, tableName string, deleteOptions ...deleteitem.OptionFunc) (*deleteitem.Result, error)
 	DescribeTable(ctx context.Context, tableName string) (*describetable.Result, error)
+	DescribeTimeToLive(ctx context.Context, tableName string) (*describetimetolive.Result, error)
+	ExecuteStatement(ctx context.Context, statement string, executeOptions ...executestatement.OptionFunc) (*executestatement.Result, error)
+	ExecuteTransaction(ctx context.Context, transactOptions ...executetransaction.OptionFunc) (*executetransaction.Result, error)
 	GetItem(ctx context.Context, tableName string, getOptions ...getitem.OptionFunc) (*getitem.Result, error)
 	ListTables(ctx context.Context, listTableOptions ...listtables.OptionFunc) (*listtables.Result, error)
 	PutItem(ctx context.Context, tableName string, putOptions ...putitem.OptionFunc) (*putitem.Result, error)
 	Query(ctx context.Context, tableName string, queryOptions ...query.OptionFunc) (*query.Result, error)
 	Scan(ctx context.Context, tableName string, scanOptions ...scan.OptionFunc) (*scan.Result, error)
+	TransactWriteItems(ctx context.Context, transactOptions ...transactwriteitems.OptionFunc) (*transactwriteitems.Result, error)
+	UpdateItem(ctx context.Context, tableName string, updateOptions ...updateitem.OptionFunc) (*updateitem.Result, error)
+	UpdateTable(ctx context.Context, tableName string, updateOptions ...updatetable.OptionFunc) (*updatetable.Result, error)
+	UpdateTimeToLive(ctx context.Context, tableName string, ttlOptions ...updatetimetolive.OptionFunc) (*updatetimetolive.Result, error)
 }
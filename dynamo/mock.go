@@ -4,13 +4,25 @@ import (
 	"context"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchexecutestatement"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchgetitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchwriteitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/createtable"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetimetolive"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executestatement"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executetransaction"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/listtables"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/query"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/transactwriteitems"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updateitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetable"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/updatetimetolive"
 
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
 	"github.com/stretchr/testify/mock"
@@ -20,6 +32,64 @@ type Mock struct {
 	mock.Mock
 }
 
+func (m *Mock) BatchExecuteStatement(ctx context.Context, batchOptions ...batchexecutestatement.OptionFunc) (*batchexecutestatement.Result, error) {
+	options := batchexecutestatement.NewOptions(batchOptions...)
+	args := m.Called(ctx, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	result := args.Get(0).(*batchexecutestatement.Result)
+
+	if options.Entities != nil {
+		items := make([]map[string]types.AttributeValue, 0, len(result.Responses))
+		for _, response := range result.Responses {
+			items = append(items, response.Item)
+		}
+
+		err := attributevalue.UnmarshalListOfMaps(items, options.Entities)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (m *Mock) BatchGetItem(ctx context.Context, batchOptions ...batchgetitem.OptionFunc) (*batchgetitem.Result, error) {
+	options := batchgetitem.NewOptions(batchOptions...)
+	args := m.Called(ctx, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*batchgetitem.Result), nil
+}
+
+func (m *Mock) BatchWriteItem(ctx context.Context, batchOptions ...batchwriteitem.OptionFunc) (*batchwriteitem.Result, error) {
+	options := batchwriteitem.NewOptions(batchOptions...)
+	args := m.Called(ctx, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*batchwriteitem.Result), nil
+}
+
+func (m *Mock) CreateTable(ctx context.Context, tableName string, createOptions ...createtable.OptionFunc) (*createtable.Result, error) {
+	options := createtable.NewOptions(createOptions...)
+	args := m.Called(ctx, tableName, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*createtable.Result), nil
+}
+
 func (m *Mock) DeleteItem(ctx context.Context, tableName string, deleteOptions ...deleteitem.OptionFunc) (*deleteitem.Result, error) {
 	options := deleteitem.NewOptions(deleteOptions...)
 	args := m.Called(ctx, tableName, options)
@@ -41,6 +111,49 @@ func (m *Mock) DescribeTable(ctx context.Context, tableName string) (*describeta
 	return args.Get(0).(*describetable.Result), nil
 }
 
+func (m *Mock) ExecuteStatement(ctx context.Context, statement string, executeOptions ...executestatement.OptionFunc) (*executestatement.Result, error) {
+	options := executestatement.NewOptions(executeOptions...)
+	args := m.Called(ctx, statement, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	if options.Entities != nil {
+		err := attributevalue.UnmarshalListOfMaps(args.Get(0).(*executestatement.Result).Items, options.Entities)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return args.Get(0).(*executestatement.Result), nil
+}
+
+func (m *Mock) ExecuteTransaction(ctx context.Context, transactOptions ...executetransaction.OptionFunc) (*executetransaction.Result, error) {
+	options := executetransaction.NewOptions(transactOptions...)
+	args := m.Called(ctx, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	result := args.Get(0).(*executetransaction.Result)
+
+	if options.Entities != nil {
+		items := make([]map[string]types.AttributeValue, 0, len(result.Responses))
+		for _, response := range result.Responses {
+			items = append(items, response.Item)
+		}
+
+		err := attributevalue.UnmarshalListOfMaps(items, options.Entities)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
 func (m *Mock) GetItem(ctx context.Context, tableName string, getOptions ...getitem.OptionFunc) (*getitem.Result, error) {
 	options := getitem.NewOptions(getOptions...)
 	args := m.Called(ctx, tableName, options)
@@ -109,3 +222,64 @@ func (m *Mock) Scan(ctx context.Context, tableName string, scanOptions ...scan.O
 
 	return args.Get(0).(*scan.Result), nil
 }
+
+func (m *Mock) TransactWriteItems(ctx context.Context, transactOptions ...transactwriteitems.OptionFunc) (*transactwriteitems.Result, error) {
+	options := transactwriteitems.NewOptions(transactOptions...)
+	args := m.Called(ctx, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*transactwriteitems.Result), nil
+}
+
+func (m *Mock) UpdateItem(ctx context.Context, tableName string, updateOptions ...updateitem.OptionFunc) (*updateitem.Result, error) {
+	options := updateitem.NewOptions(updateOptions...)
+	args := m.Called(ctx, tableName, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	if options.Entity != nil {
+		err := attributevalue.UnmarshalMap(args.Get(0).(*updateitem.Result).Attributes, options.Entity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return args.Get(0).(*updateitem.Result), nil
+}
+
+func (m *Mock) DescribeTimeToLive(ctx context.Context, tableName string) (*describetimetolive.Result, error) {
+	args := m.Called(ctx, tableName)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*describetimetolive.Result), nil
+}
+
+func (m *Mock) UpdateTable(ctx context.Context, tableName string, updateOptions ...updatetable.OptionFunc) (*updatetable.Result, error) {
+	options := updatetable.NewOptions(updateOptions...)
+	args := m.Called(ctx, tableName, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*updatetable.Result), nil
+}
+
+func (m *Mock) UpdateTimeToLive(ctx context.Context, tableName string, ttlOptions ...updatetimetolive.OptionFunc) (*updatetimetolive.Result, error) {
+	options := updatetimetolive.NewOptions(ttlOptions...)
+	args := m.Called(ctx, tableName, options)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*updatetimetolive.Result), nil
+}
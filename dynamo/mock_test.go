@@ -4,7 +4,10 @@ import (
 	"context"
 	"testing"
 
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/executestatement"
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/KirkDiggler/go-projects/dynamo/inputs/deleteitem"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -65,3 +68,28 @@ func TestMockClient_GetItem(t *testing.T) {
 		m.AssertExpectations(t)
 	})
 }
+
+func TestMockClient_ExecuteStatement(t *testing.T) {
+	t.Run("it unmarshals the items into the entities", func(t *testing.T) {
+		m := &Mock{}
+		ctx := context.Background()
+		statement := `SELECT * FROM "test" WHERE id = ?`
+
+		m.On("ExecuteStatement",
+			ctx, statement,
+			mock.AnythingOfType("*executestatement.Options")).Return(&executestatement.Result{
+			Items: []map[string]types.AttributeValue{{
+				"id": &types.AttributeValueMemberS{Value: "bob"},
+			}},
+		}, nil)
+
+		var actual []*testStruct
+
+		_, err := m.ExecuteStatement(ctx, statement,
+			executestatement.WithParameters("bob"),
+			executestatement.AsSliceOfEntities(&actual))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []*testStruct{{ID: "bob"}}, actual)
+	})
+}
@@ -0,0 +1,128 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ShardEnd is the checkpoint of a shard that was read to its end
+const ShardEnd = "SHARD_END"
+
+const (
+	idAttributeName             = "id"
+	sequenceNumberAttributeName = "sequence_number"
+
+	requiredTableCheckpointStoreConfigMsg = "streams.NewTableCheckpointStore requires a TableCheckpointStoreConfig"
+	requiredClientMsg                     = "the field Client is required"
+	requiredTableNameMsg                  = "the field TableName is required"
+)
+
+// CheckpointStore
+//
+// Saves the sequence number of the last record handled in each shard
+type CheckpointStore interface {
+	// GetCheckpoint returns an empty string when the shard has no checkpoint
+	GetCheckpoint(ctx context.Context, streamARN, shardID string) (string, error)
+	SetCheckpoint(ctx context.Context, streamARN, shardID, sequenceNumber string) error
+}
+
+// MemoryCheckpointStore
+//
+// Keeps the checkpoints in memory, a restarted process reads the stream from its StartingPosition again
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]string
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]string)}
+}
+
+func (s *MemoryCheckpointStore) GetCheckpoint(ctx context.Context, streamARN, shardID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checkpoints[checkpointID(streamARN, shardID)], nil
+}
+
+func (s *MemoryCheckpointStore) SetCheckpoint(ctx context.Context, streamARN, shardID, sequenceNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[checkpointID(streamARN, shardID)] = sequenceNumber
+
+	return nil
+}
+
+// TableCheckpointStoreConfig
+//
+// The table holding the checkpoints, its partition key is the string attribute id
+type TableCheckpointStoreConfig struct {
+	Client    dynamo.Interface
+	TableName string
+}
+
+// TableCheckpointStore
+//
+// Keeps the checkpoints in a table so consumers resume where they stopped
+type TableCheckpointStore struct {
+	client    dynamo.Interface
+	tableName string
+}
+
+func NewTableCheckpointStore(cfg *TableCheckpointStoreConfig) (*TableCheckpointStore, error) {
+	if cfg == nil {
+		return nil, errors.New(requiredTableCheckpointStoreConfigMsg)
+	}
+
+	if cfg.Client == nil {
+		return nil, errors.New(requiredClientMsg)
+	}
+
+	if cfg.TableName == "" {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	return &TableCheckpointStore{
+		client:    cfg.Client,
+		tableName: cfg.TableName,
+	}, nil
+}
+
+func (s *TableCheckpointStore) GetCheckpoint(ctx context.Context, streamARN, shardID string) (string, error) {
+	result, err := s.client.GetItem(ctx, s.tableName,
+		getitem.WithKey(map[string]types.AttributeValue{
+			idAttributeName: &types.AttributeValueMemberS{Value: checkpointID(streamARN, shardID)},
+		}),
+		getitem.WithConsistentRead(aws.Bool(true)))
+	if err != nil {
+		return "", err
+	}
+
+	sequenceNumber, ok := result.Item[sequenceNumberAttributeName].(*types.AttributeValueMemberS)
+	if !ok {
+		return "", nil
+	}
+
+	return sequenceNumber.Value, nil
+}
+
+func (s *TableCheckpointStore) SetCheckpoint(ctx context.Context, streamARN, shardID, sequenceNumber string) error {
+	_, err := s.client.PutItem(ctx, s.tableName, putitem.WithItem(map[string]types.AttributeValue{
+		idAttributeName:             &types.AttributeValueMemberS{Value: checkpointID(streamARN, shardID)},
+		sequenceNumberAttributeName: &types.AttributeValueMemberS{Value: sequenceNumber},
+	}))
+
+	return err
+}
+
+func checkpointID(streamARN, shardID string) string {
+	return streamARN + "|" + shardID
+}
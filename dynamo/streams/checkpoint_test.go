@@ -0,0 +1,95 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/getitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/putitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTableCheckpointStore(t *testing.T) {
+	t.Run("it requires a config", func(t *testing.T) {
+		_, err := NewTableCheckpointStore(nil)
+
+		assert.Equal(t, errors.New(requiredTableCheckpointStoreConfigMsg), err)
+	})
+	t.Run("it requires a client", func(t *testing.T) {
+		_, err := NewTableCheckpointStore(&TableCheckpointStoreConfig{TableName: "checkpoints"})
+
+		assert.Equal(t, errors.New(requiredClientMsg), err)
+	})
+	t.Run("it requires a table name", func(t *testing.T) {
+		_, err := NewTableCheckpointStore(&TableCheckpointStoreConfig{Client: &dynamo.Mock{}})
+
+		assert.Equal(t, errors.New(requiredTableNameMsg), err)
+	})
+}
+
+func TestTableCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	key := map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: testStreamARN + "|shard-1"},
+	}
+
+	setup := func() (*TableCheckpointStore, *dynamo.Mock) {
+		client := &dynamo.Mock{}
+
+		store, err := NewTableCheckpointStore(&TableCheckpointStoreConfig{Client: client, TableName: "checkpoints"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return store, client
+	}
+
+	t.Run("it reads the checkpoint of a shard", func(t *testing.T) {
+		store, client := setup()
+
+		client.On("GetItem", ctx, "checkpoints", getitem.NewOptions(
+			getitem.WithKey(key),
+			getitem.WithConsistentRead(aws.Bool(true)),
+		)).Return(&getitem.Result{Item: map[string]types.AttributeValue{
+			"id":              key["id"],
+			"sequence_number": &types.AttributeValueMemberS{Value: "100"},
+		}}, nil)
+
+		actual, err := store.GetCheckpoint(ctx, testStreamARN, "shard-1")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "100", actual)
+	})
+	t.Run("it returns an empty checkpoint for a new shard", func(t *testing.T) {
+		store, client := setup()
+
+		client.On("GetItem", ctx, "checkpoints", getitem.NewOptions(
+			getitem.WithKey(key),
+			getitem.WithConsistentRead(aws.Bool(true)),
+		)).Return(&getitem.Result{}, nil)
+
+		actual, err := store.GetCheckpoint(ctx, testStreamARN, "shard-1")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", actual)
+	})
+	t.Run("it saves the checkpoint of a shard", func(t *testing.T) {
+		store, client := setup()
+
+		client.On("PutItem", ctx, "checkpoints", putitem.NewOptions(
+			putitem.WithItem(map[string]types.AttributeValue{
+				"id":              key["id"],
+				"sequence_number": &types.AttributeValueMemberS{Value: ShardEnd},
+			}),
+		)).Return(&putitem.Result{}, nil)
+
+		err := store.SetCheckpoint(ctx, testStreamARN, "shard-1", ShardEnd)
+
+		assert.Nil(t, err)
+		client.AssertExpectations(t)
+	})
+}
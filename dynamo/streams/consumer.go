@@ -0,0 +1,425 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+)
+
+const (
+	// maxBatchSize is the most records GetRecords returns
+	maxBatchSize = 1000
+
+	defaultPollInterval      = time.Second
+	defaultShardSyncInterval = 10 * time.Second
+
+	requiredConsumerConfigMsg  = "streams.NewConsumer requires a ConsumerConfig"
+	requiredSourceMsg          = "the field Source is required"
+	requiredStreamARNMsg       = "the field StreamARN is required"
+	requiredHandlersMsg        = "the field Handlers requires at least one handler"
+	invalidBatchSizeMsg        = "the field BatchSize can not be more than 1000"
+	invalidStartingPositionMsg = "the field StartingPosition must be TRIM_HORIZON or LATEST"
+)
+
+// ConsumerConfig
+//
+// The stream to read and the handlers its records are dispatched to
+type ConsumerConfig struct {
+	Source    Source
+	StreamARN string
+	Handlers  []Handler
+
+	// Checkpoints defaults to a MemoryCheckpointStore
+	Checkpoints CheckpointStore
+
+	// StartingPosition is where the shards open when Run starts are read from without a checkpoint, TRIM_HORIZON or
+	// LATEST. Defaults to TRIM_HORIZON. Shards created while Run is reading are always read from TRIM_HORIZON
+	// so the records written to a child shard before it was found are not lost
+	StartingPosition streamstypes.ShardIteratorType
+
+	// BatchSize is the GetRecords Limit, defaults to and can not be more than 1000
+	BatchSize int32
+
+	// PollInterval is the wait before reading an open shard that returned no records, defaults to 1s
+	PollInterval time.Duration
+
+	// ShardSyncInterval is how often the shards of the stream are listed to find new ones, defaults to 10s
+	ShardSyncInterval time.Duration
+}
+
+// Consumer
+//
+// Reads every shard of a stream in its own goroutine. A child shard is read once its parent is read to the end,
+// so the changes of an item are always handled in order
+type Consumer struct {
+	source            Source
+	streamARN         string
+	handlers          []Handler
+	checkpoints       CheckpointStore
+	startingPosition  streamstypes.ShardIteratorType
+	batchSize         int32
+	pollInterval      time.Duration
+	shardSyncInterval time.Duration
+}
+
+func NewConsumer(cfg *ConsumerConfig) (*Consumer, error) {
+	if cfg == nil {
+		return nil, errors.New(requiredConsumerConfigMsg)
+	}
+
+	if cfg.Source == nil {
+		return nil, errors.New(requiredSourceMsg)
+	}
+
+	if cfg.StreamARN == "" {
+		return nil, errors.New(requiredStreamARNMsg)
+	}
+
+	if len(cfg.Handlers) == 0 {
+		return nil, errors.New(requiredHandlersMsg)
+	}
+
+	if cfg.BatchSize > maxBatchSize {
+		return nil, errors.New(invalidBatchSizeMsg)
+	}
+
+	consumer := &Consumer{
+		source:            cfg.Source,
+		streamARN:         cfg.StreamARN,
+		handlers:          cfg.Handlers,
+		checkpoints:       cfg.Checkpoints,
+		startingPosition:  cfg.StartingPosition,
+		batchSize:         cfg.BatchSize,
+		pollInterval:      cfg.PollInterval,
+		shardSyncInterval: cfg.ShardSyncInterval,
+	}
+
+	switch consumer.startingPosition {
+	case "":
+		consumer.startingPosition = streamstypes.ShardIteratorTypeTrimHorizon
+	case streamstypes.ShardIteratorTypeTrimHorizon, streamstypes.ShardIteratorTypeLatest:
+	default:
+		return nil, errors.New(invalidStartingPositionMsg)
+	}
+
+	if consumer.checkpoints == nil {
+		consumer.checkpoints = NewMemoryCheckpointStore()
+	}
+
+	if consumer.batchSize <= 0 {
+		consumer.batchSize = maxBatchSize
+	}
+
+	if consumer.pollInterval <= 0 {
+		consumer.pollInterval = defaultPollInterval
+	}
+
+	if consumer.shardSyncInterval <= 0 {
+		consumer.shardSyncInterval = defaultShardSyncInterval
+	}
+
+	return consumer, nil
+}
+
+type shardResult struct {
+	shardID  string
+	finished bool
+	err      error
+}
+
+// Run
+//
+// Reads the stream until ctx is cancelled, a handler fails or the stream is disabled and every shard was read.
+// On cancellation the records being handled finish and their checkpoints are saved before Run returns nil
+func (c *Consumer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	started := make(map[string]bool)
+	finished := make(map[string]bool)
+	// initial holds the shards of the first listing, the only shards read from the starting position
+	var initial map[string]bool
+	results := make(chan shardResult)
+	running := 0
+
+	var runErr error
+
+	ticker := time.NewTicker(c.shardSyncInterval)
+	defer ticker.Stop()
+
+	listShards := true
+
+	for {
+		if listShards && ctx.Err() == nil {
+			listShards = false
+
+			shards, status, err := c.describeStream(ctx)
+			if err != nil && ctx.Err() == nil {
+				runErr = err
+				cancel()
+			}
+
+			if err == nil && initial == nil {
+				initial = make(map[string]bool, len(shards))
+				for _, shard := range shards {
+					initial[aws.ToString(shard.ShardId)] = true
+				}
+			}
+
+			for _, shard := range shards {
+				shardID := aws.ToString(shard.ShardId)
+				if started[shardID] || !parentFinished(shard, shards, finished) {
+					continue
+				}
+
+				position := streamstypes.ShardIteratorTypeTrimHorizon
+				if initial[shardID] {
+					position = c.startingPosition
+				}
+
+				started[shardID] = true
+				running++
+
+				go func() {
+					isFinished, err := c.readShard(ctx, shardID, position)
+					results <- shardResult{shardID: shardID, finished: isFinished, err: err}
+				}()
+			}
+
+			if err == nil && running == 0 && status == streamstypes.StreamStatusDisabled {
+				return nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			if running == 0 {
+				return runErr
+			}
+
+			result := <-results
+			running--
+
+			if result.err != nil && runErr == nil {
+				runErr = result.err
+			}
+
+			continue
+		}
+
+		select {
+		case result := <-results:
+			running--
+
+			if result.err != nil {
+				runErr = result.err
+				cancel()
+
+				continue
+			}
+
+			if result.finished {
+				finished[result.shardID] = true
+				listShards = true
+			}
+		case <-ticker.C:
+			listShards = true
+		case <-ctx.Done():
+		}
+	}
+}
+
+// parentFinished is true when the parent of shard was read to its end or is no longer in the stream
+func parentFinished(shard streamstypes.Shard, shards []streamstypes.Shard, finished map[string]bool) bool {
+	parentID := aws.ToString(shard.ParentShardId)
+	if parentID == "" || finished[parentID] {
+		return true
+	}
+
+	for _, candidate := range shards {
+		if aws.ToString(candidate.ShardId) == parentID {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Consumer) describeStream(ctx context.Context) ([]streamstypes.Shard, streamstypes.StreamStatus, error) {
+	var shards []streamstypes.Shard
+	var status streamstypes.StreamStatus
+	var startShardID *string
+
+	for {
+		result, err := c.source.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			ExclusiveStartShardId: startShardID,
+			StreamArn:             aws.String(c.streamARN),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		if result.StreamDescription == nil {
+			return shards, status, nil
+		}
+
+		shards = append(shards, result.StreamDescription.Shards...)
+		status = result.StreamDescription.StreamStatus
+
+		if result.StreamDescription.LastEvaluatedShardId == nil {
+			return shards, status, nil
+		}
+
+		startShardID = result.StreamDescription.LastEvaluatedShardId
+	}
+}
+
+// readShard dispatches the records of a shard from position until it is closed or ctx is cancelled, finished is true
+// when the shard was read to its end
+func (c *Consumer) readShard(ctx context.Context, shardID string, position streamstypes.ShardIteratorType) (bool, error) {
+	sequenceNumber, err := c.checkpoints.GetCheckpoint(ctx, c.streamARN, shardID)
+	if err != nil {
+		return false, c.shardErr(ctx, shardID, err)
+	}
+
+	if sequenceNumber == ShardEnd {
+		return true, nil
+	}
+
+	iterator, err := c.shardIterator(ctx, shardID, position, sequenceNumber)
+	if err != nil {
+		return false, c.shardErr(ctx, shardID, err)
+	}
+
+	saved := sequenceNumber
+
+	for {
+		result, err := c.source.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+			Limit:         aws.Int32(c.batchSize),
+			ShardIterator: iterator,
+		})
+		if err != nil {
+			var expired *streamstypes.ExpiredIteratorException
+			if !errors.As(err, &expired) {
+				return false, c.shardErr(ctx, shardID, err)
+			}
+
+			// without a record read LATEST would skip the records written since the first iterator
+			if iterator, err = c.shardIterator(ctx, shardID, streamstypes.ShardIteratorTypeTrimHorizon, sequenceNumber); err != nil {
+				return false, c.shardErr(ctx, shardID, err)
+			}
+
+			continue
+		}
+
+		for _, in := range result.Records {
+			if ctx.Err() != nil {
+				break
+			}
+
+			record, err := newRecord(shardID, in)
+			if err != nil {
+				return false, fmt.Errorf("shard %s: %w", shardID, err)
+			}
+
+			if err := c.dispatch(ctx, record); err != nil {
+				if saveErr := c.saveCheckpoint(ctx, shardID, saved, sequenceNumber); saveErr != nil {
+					return false, c.shardErr(ctx, shardID, saveErr)
+				}
+
+				if ctx.Err() != nil {
+					return false, nil
+				}
+
+				return false, fmt.Errorf("shard %s: record %s: %w", shardID, record.SequenceNumber, err)
+			}
+
+			sequenceNumber = record.SequenceNumber
+		}
+
+		if err := c.saveCheckpoint(ctx, shardID, saved, sequenceNumber); err != nil {
+			return false, c.shardErr(ctx, shardID, err)
+		}
+
+		saved = sequenceNumber
+
+		if ctx.Err() != nil {
+			return false, nil
+		}
+
+		if result.NextShardIterator == nil {
+			if err := c.checkpoints.SetCheckpoint(ctx, c.streamARN, shardID, ShardEnd); err != nil {
+				return false, c.shardErr(ctx, shardID, err)
+			}
+
+			return true, nil
+		}
+
+		iterator = result.NextShardIterator
+
+		if len(result.Records) == 0 {
+			select {
+			case <-ctx.Done():
+				return false, nil
+			case <-time.After(c.pollInterval):
+			}
+		}
+	}
+}
+
+func (c *Consumer) dispatch(ctx context.Context, record *Record) error {
+	for _, handler := range c.handlers {
+		if err := handler(ctx, record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shardIterator returns an iterator after sequenceNumber, or at position when no record of the shard was read
+func (c *Consumer) shardIterator(ctx context.Context, shardID string, position streamstypes.ShardIteratorType, sequenceNumber string) (*string, error) {
+	input := &dynamodbstreams.GetShardIteratorInput{
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: position,
+		StreamArn:         aws.String(c.streamARN),
+	}
+
+	if sequenceNumber != "" {
+		input.ShardIteratorType = streamstypes.ShardIteratorTypeAfterSequenceNumber
+		input.SequenceNumber = aws.String(sequenceNumber)
+	}
+
+	result, err := c.source.GetShardIterator(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.ShardIterator, nil
+}
+
+// saveCheckpoint saves sequenceNumber when it moved past saved, after a cancellation too
+func (c *Consumer) saveCheckpoint(ctx context.Context, shardID, saved, sequenceNumber string) error {
+	if sequenceNumber == saved {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+
+	return c.checkpoints.SetCheckpoint(ctx, c.streamARN, shardID, sequenceNumber)
+}
+
+// shardErr drops the errors caused by a cancellation
+func (c *Consumer) shardErr(ctx context.Context, shardID string, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return fmt.Errorf("shard %s: %w", shardID, err)
+}
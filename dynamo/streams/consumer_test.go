@@ -0,0 +1,369 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const testStreamARN = "arn:aws:dynamodb:us-east-1:123456789012:table/products/stream/2021-11-01T00:00:00.000"
+
+func newTestRecord(id string) streamstypes.Record {
+	return streamstypes.Record{
+		EventName: streamstypes.OperationTypeInsert,
+		Dynamodb: &streamstypes.StreamRecord{
+			Keys: map[string]streamstypes.AttributeValue{
+				"id": &streamstypes.AttributeValueMemberS{Value: id},
+			},
+			NewImage: map[string]streamstypes.AttributeValue{
+				"id": &streamstypes.AttributeValueMemberS{Value: id},
+			},
+		},
+	}
+}
+
+// recorder collects the ids of the handled records
+type recorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) handle(ctx context.Context, record *Record) error {
+	entity := &testEntity{}
+	if err := record.UnmarshalNewImage(entity); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ids = append(r.ids, entity.ID)
+
+	return nil
+}
+
+// notifyingSource signals every shard iterator it returns
+type notifyingSource struct {
+	*FakeSource
+	iterators chan struct{}
+}
+
+func (s *notifyingSource) GetShardIterator(ctx context.Context, params *dynamodbstreams.GetShardIteratorInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	out, err := s.FakeSource.GetShardIterator(ctx, params, optFns...)
+	s.iterators <- struct{}{}
+
+	return out, err
+}
+
+func setupConsumer(t *testing.T, source *FakeSource, cfg *ConsumerConfig) *Consumer {
+	cfg.Source = source
+	cfg.StreamARN = testStreamARN
+	cfg.PollInterval = time.Millisecond
+	cfg.ShardSyncInterval = 10 * time.Millisecond
+
+	consumer, err := NewConsumer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return consumer
+}
+
+func runConsumer(t *testing.T, ctx context.Context, consumer *Consumer) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := consumer.Run(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		t.Fatal("the consumer did not return")
+	}
+
+	return err
+}
+
+func TestNewConsumer(t *testing.T) {
+	handlers := []Handler{(&recorder{}).handle}
+	source := NewFakeSource(testStreamARN)
+
+	t.Run("it requires a config", func(t *testing.T) {
+		_, err := NewConsumer(nil)
+
+		assert.Equal(t, errors.New(requiredConsumerConfigMsg), err)
+	})
+	t.Run("it requires a source", func(t *testing.T) {
+		_, err := NewConsumer(&ConsumerConfig{StreamARN: testStreamARN, Handlers: handlers})
+
+		assert.Equal(t, errors.New(requiredSourceMsg), err)
+	})
+	t.Run("it requires a stream arn", func(t *testing.T) {
+		_, err := NewConsumer(&ConsumerConfig{Source: source, Handlers: handlers})
+
+		assert.Equal(t, errors.New(requiredStreamARNMsg), err)
+	})
+	t.Run("it requires a handler", func(t *testing.T) {
+		_, err := NewConsumer(&ConsumerConfig{Source: source, StreamARN: testStreamARN})
+
+		assert.Equal(t, errors.New(requiredHandlersMsg), err)
+	})
+	t.Run("it limits the batch size", func(t *testing.T) {
+		_, err := NewConsumer(&ConsumerConfig{Source: source, StreamARN: testStreamARN, Handlers: handlers, BatchSize: 1001})
+
+		assert.Equal(t, errors.New(invalidBatchSizeMsg), err)
+	})
+	t.Run("it only starts from the trim horizon or the latest record", func(t *testing.T) {
+		_, err := NewConsumer(&ConsumerConfig{
+			Source:           source,
+			StreamARN:        testStreamARN,
+			Handlers:         handlers,
+			StartingPosition: streamstypes.ShardIteratorTypeAtSequenceNumber,
+		})
+
+		assert.Equal(t, errors.New(invalidStartingPositionMsg), err)
+	})
+}
+
+func TestConsumer_Run(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it dispatches the records of a shard in order to every handler", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("shard-1", "")
+		assert.Nil(t, source.PutRecords("shard-1", newTestRecord("a"), newTestRecord("b"), newTestRecord("c")))
+		source.Disable()
+
+		first, second := &recorder{}, &recorder{}
+		checkpoints := NewMemoryCheckpointStore()
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{
+			Handlers:    []Handler{first.handle, second.handle},
+			Checkpoints: checkpoints,
+			BatchSize:   2,
+		})
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.Equal(t, []string{"a", "b", "c"}, first.ids)
+		assert.Equal(t, []string{"a", "b", "c"}, second.ids)
+
+		checkpoint, _ := checkpoints.GetCheckpoint(ctx, testStreamARN, "shard-1")
+		assert.Equal(t, ShardEnd, checkpoint)
+	})
+	t.Run("it reads a parent shard before its children", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("parent", "")
+		source.AddShard("child-1", "parent")
+		source.AddShard("child-2", "parent")
+		source.AddShard("orphan", "expired")
+		assert.Nil(t, source.PutRecords("child-1", newTestRecord("child-1")))
+		assert.Nil(t, source.PutRecords("child-2", newTestRecord("child-2")))
+		assert.Nil(t, source.PutRecords("orphan", newTestRecord("orphan")))
+		assert.Nil(t, source.PutRecords("parent", newTestRecord("parent-1"), newTestRecord("parent-2")))
+		source.Disable()
+
+		handled := &recorder{}
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{Handlers: []Handler{handled.handle}})
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.ElementsMatch(t, []string{"parent-1", "parent-2", "child-1", "child-2", "orphan"}, handled.ids)
+
+		position := make(map[string]int)
+		for idx, id := range handled.ids {
+			position[id] = idx
+		}
+
+		assert.Less(t, position["parent-1"], position["parent-2"])
+		assert.Less(t, position["parent-2"], position["child-1"])
+		assert.Less(t, position["parent-2"], position["child-2"])
+	})
+	t.Run("it finds the shards created while it runs", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("parent", "")
+		assert.Nil(t, source.PutRecords("parent", newTestRecord("parent")))
+
+		handled := &recorder{}
+
+		split := func(ctx context.Context, record *Record) error {
+			if record.ShardID == "parent" {
+				source.AddShard("child", "parent")
+				assert.Nil(t, source.PutRecords("child", newTestRecord("child")))
+				source.Disable()
+			}
+
+			return nil
+		}
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{Handlers: []Handler{handled.handle, split}})
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.Equal(t, []string{"parent", "child"}, handled.ids)
+	})
+	t.Run("it resumes after the checkpoint", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("shard-1", "")
+		source.AddShard("shard-2", "")
+		assert.Nil(t, source.PutRecords("shard-1", newTestRecord("a"), newTestRecord("b"), newTestRecord("c")))
+		assert.Nil(t, source.PutRecords("shard-2", newTestRecord("d")))
+		source.Disable()
+
+		checkpoints := NewMemoryCheckpointStore()
+		assert.Nil(t, checkpoints.SetCheckpoint(ctx, testStreamARN, "shard-1", "000000000000000000002"))
+		assert.Nil(t, checkpoints.SetCheckpoint(ctx, testStreamARN, "shard-2", ShardEnd))
+
+		handled := &recorder{}
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{Handlers: []Handler{handled.handle}, Checkpoints: checkpoints})
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.Equal(t, []string{"c"}, handled.ids)
+	})
+	t.Run("it starts from the latest record", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("shard-1", "")
+		assert.Nil(t, source.PutRecords("shard-1", newTestRecord("a")))
+
+		handled := &recorder{}
+		ctx, cancel := context.WithCancel(ctx)
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{
+			Handlers: []Handler{handled.handle, func(ctx context.Context, record *Record) error {
+				cancel()
+
+				return nil
+			}},
+			StartingPosition: streamstypes.ShardIteratorTypeLatest,
+		})
+
+		iterators := make(chan struct{}, 1)
+		consumer.source = &notifyingSource{FakeSource: source, iterators: iterators}
+
+		go func() {
+			<-iterators
+			assert.Nil(t, source.PutRecords("shard-1", newTestRecord("b")))
+		}()
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.Equal(t, []string{"b"}, handled.ids)
+	})
+	t.Run("it reads the children of a shard it started from the latest record from the trim horizon", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("parent", "")
+		assert.Nil(t, source.PutRecords("parent", newTestRecord("old")))
+
+		handled := &recorder{}
+
+		split := func(ctx context.Context, record *Record) error {
+			if record.ShardID == "parent" {
+				source.AddShard("child", "parent")
+				assert.Nil(t, source.PutRecords("child", newTestRecord("child")))
+				source.Disable()
+			}
+
+			return nil
+		}
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{
+			Handlers:         []Handler{handled.handle, split},
+			StartingPosition: streamstypes.ShardIteratorTypeLatest,
+		})
+
+		iterators := make(chan struct{}, 2)
+		consumer.source = &notifyingSource{FakeSource: source, iterators: iterators}
+
+		go func() {
+			<-iterators
+			assert.Nil(t, source.PutRecords("parent", newTestRecord("new")))
+		}()
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.Equal(t, []string{"new", "child"}, handled.ids)
+	})
+	t.Run("it stops at a failing record and keeps the checkpoint before it", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("shard-1", "")
+		assert.Nil(t, source.PutRecords("shard-1", newTestRecord("a"), newTestRecord("b"), newTestRecord("c")))
+
+		checkpoints := NewMemoryCheckpointStore()
+		expectedErr := errors.New("index down")
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{
+			Handlers: []Handler{func(ctx context.Context, record *Record) error {
+				if record.SequenceNumber == "000000000000000000002" {
+					return expectedErr
+				}
+
+				return nil
+			}},
+			Checkpoints: checkpoints,
+		})
+
+		err := runConsumer(t, ctx, consumer)
+
+		assert.True(t, errors.Is(err, expectedErr))
+		assert.Equal(t, "shard shard-1: record 000000000000000000002: index down", err.Error())
+
+		checkpoint, _ := checkpoints.GetCheckpoint(ctx, testStreamARN, "shard-1")
+		assert.Equal(t, "000000000000000000001", checkpoint)
+	})
+	t.Run("it finishes the record being handled when it is cancelled", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.AddShard("shard-1", "")
+		assert.Nil(t, source.PutRecords("shard-1", newTestRecord("a"), newTestRecord("b"), newTestRecord("c")))
+
+		ctx, cancel := context.WithCancel(ctx)
+		checkpoints := NewMemoryCheckpointStore()
+		handled := &recorder{}
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{
+			Handlers: []Handler{handled.handle, func(ctx context.Context, record *Record) error {
+				if record.SequenceNumber == "000000000000000000002" {
+					cancel()
+				}
+
+				return nil
+			}},
+			Checkpoints: checkpoints,
+		})
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.Equal(t, []string{"a", "b"}, handled.ids)
+
+		checkpoint, _ := checkpoints.GetCheckpoint(context.Background(), testStreamARN, "shard-1")
+		assert.Equal(t, "000000000000000000002", checkpoint)
+	})
+	t.Run("it lists every page of shards", func(t *testing.T) {
+		source := NewFakeSource(testStreamARN)
+		source.PageSize = 1
+
+		for _, shardID := range []string{"shard-1", "shard-2", "shard-3"} {
+			source.AddShard(shardID, "")
+			assert.Nil(t, source.PutRecords(shardID, newTestRecord(shardID)))
+		}
+
+		source.Disable()
+
+		handled := &recorder{}
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{Handlers: []Handler{handled.handle}})
+
+		assert.Nil(t, runConsumer(t, ctx, consumer))
+		assert.ElementsMatch(t, []string{"shard-1", "shard-2", "shard-3"}, handled.ids)
+	})
+	t.Run("it returns the errors of the source", func(t *testing.T) {
+		source := NewFakeSource("another-stream")
+
+		consumer := setupConsumer(t, source, &ConsumerConfig{Handlers: []Handler{(&recorder{}).handle}})
+
+		err := runConsumer(t, ctx, consumer)
+
+		var notFound *streamstypes.ResourceNotFoundException
+		assert.True(t, errors.As(err, &notFound))
+		assert.Equal(t, aws.String("stream "+testStreamARN+" was not found"), notFound.Message)
+	})
+}
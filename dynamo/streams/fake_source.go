@@ -0,0 +1,265 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+)
+
+// FakeSource
+//
+// An in memory stream to test consumers without DynamoDB. Shards are added, written to and closed by the test,
+// the consumer reads them through the Source calls
+type FakeSource struct {
+	// PageSize is the number of shards DescribeStream returns per page, defaults to 100
+	PageSize int
+
+	mu        sync.Mutex
+	streamARN string
+	status    streamstypes.StreamStatus
+	shards    []*fakeShard
+	sequence  int64
+}
+
+type fakeShard struct {
+	id       string
+	parentID string
+	records  []streamstypes.Record
+	closed   bool
+}
+
+func NewFakeSource(streamARN string) *FakeSource {
+	return &FakeSource{
+		streamARN: streamARN,
+		status:    streamstypes.StreamStatusEnabled,
+	}
+}
+
+// AddShard adds an open shard, parentShardID is empty for a shard without a parent
+func (f *FakeSource) AddShard(shardID, parentShardID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.shards = append(f.shards, &fakeShard{id: shardID, parentID: parentShardID})
+}
+
+// PutRecords appends records to an open shard. Records without a sequence number are given the next one
+func (f *FakeSource) PutRecords(shardID string, records ...streamstypes.Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	shard, err := f.shard(shardID)
+	if err != nil {
+		return err
+	}
+
+	if shard.closed {
+		return fmt.Errorf("shard %s is closed", shardID)
+	}
+
+	for _, record := range records {
+		if record.Dynamodb == nil {
+			record.Dynamodb = &streamstypes.StreamRecord{}
+		}
+
+		if record.Dynamodb.SequenceNumber == nil {
+			f.sequence++
+			record.Dynamodb.SequenceNumber = aws.String(fmt.Sprintf("%021d", f.sequence))
+		}
+
+		if record.Dynamodb.ApproximateCreationDateTime == nil {
+			record.Dynamodb.ApproximateCreationDateTime = aws.Time(time.Now())
+		}
+
+		shard.records = append(shard.records, record)
+	}
+
+	return nil
+}
+
+// CloseShard ends a shard, its reader gets no next iterator once it read every record
+func (f *FakeSource) CloseShard(shardID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	shard, err := f.shard(shardID)
+	if err != nil {
+		return err
+	}
+
+	shard.closed = true
+
+	return nil
+}
+
+// Disable closes every shard and marks the stream DISABLED, a consumer returns once it read every shard
+func (f *FakeSource) Disable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, shard := range f.shards {
+		shard.closed = true
+	}
+
+	f.status = streamstypes.StreamStatusDisabled
+}
+
+func (f *FakeSource) DescribeStream(ctx context.Context, params *dynamodbstreams.DescribeStreamInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.DescribeStreamOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkStream(params.StreamArn); err != nil {
+		return nil, err
+	}
+
+	pageSize := f.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	if params.Limit != nil && int(*params.Limit) < pageSize {
+		pageSize = int(*params.Limit)
+	}
+
+	start := 0
+	if params.ExclusiveStartShardId != nil {
+		for idx, shard := range f.shards {
+			if shard.id == *params.ExclusiveStartShardId {
+				start = idx + 1
+			}
+		}
+	}
+
+	description := &streamstypes.StreamDescription{
+		StreamArn:    aws.String(f.streamARN),
+		StreamStatus: f.status,
+	}
+
+	for idx := start; idx < len(f.shards) && idx < start+pageSize; idx++ {
+		description.Shards = append(description.Shards, streamstypes.Shard{
+			ParentShardId: optionalString(f.shards[idx].parentID),
+			ShardId:       aws.String(f.shards[idx].id),
+		})
+	}
+
+	if start+pageSize < len(f.shards) {
+		description.LastEvaluatedShardId = aws.String(f.shards[start+pageSize-1].id)
+	}
+
+	return &dynamodbstreams.DescribeStreamOutput{StreamDescription: description}, nil
+}
+
+func (f *FakeSource) GetShardIterator(ctx context.Context, params *dynamodbstreams.GetShardIteratorInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkStream(params.StreamArn); err != nil {
+		return nil, err
+	}
+
+	shard, err := f.shard(aws.ToString(params.ShardId))
+	if err != nil {
+		return nil, err
+	}
+
+	var position int
+
+	switch params.ShardIteratorType {
+	case streamstypes.ShardIteratorTypeTrimHorizon:
+		position = 0
+	case streamstypes.ShardIteratorTypeLatest:
+		position = len(shard.records)
+	case streamstypes.ShardIteratorTypeAtSequenceNumber, streamstypes.ShardIteratorTypeAfterSequenceNumber:
+		position = -1
+
+		for idx, record := range shard.records {
+			if aws.ToString(record.Dynamodb.SequenceNumber) == aws.ToString(params.SequenceNumber) {
+				position = idx
+			}
+		}
+
+		if position == -1 {
+			return nil, &streamstypes.ResourceNotFoundException{
+				Message: aws.String(fmt.Sprintf("sequence number %s was not found in shard %s", aws.ToString(params.SequenceNumber), shard.id)),
+			}
+		}
+
+		if params.ShardIteratorType == streamstypes.ShardIteratorTypeAfterSequenceNumber {
+			position++
+		}
+	default:
+		return nil, fmt.Errorf("unknown shard iterator type '%s'", params.ShardIteratorType)
+	}
+
+	return &dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String(shard.id + "|" + strconv.Itoa(position))}, nil
+}
+
+func (f *FakeSource) GetRecords(ctx context.Context, params *dynamodbstreams.GetRecordsInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetRecordsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	iterator := aws.ToString(params.ShardIterator)
+
+	separator := strings.LastIndex(iterator, "|")
+	if separator == -1 {
+		return nil, &streamstypes.ExpiredIteratorException{Message: aws.String(fmt.Sprintf("invalid shard iterator %s", iterator))}
+	}
+
+	position, err := strconv.Atoi(iterator[separator+1:])
+	if err != nil {
+		return nil, &streamstypes.ExpiredIteratorException{Message: aws.String(fmt.Sprintf("invalid shard iterator %s", iterator))}
+	}
+
+	shard, err := f.shard(iterator[:separator])
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(shard.records)
+	if params.Limit != nil && position+int(*params.Limit) < end {
+		end = position + int(*params.Limit)
+	}
+
+	out := &dynamodbstreams.GetRecordsOutput{
+		Records: append([]streamstypes.Record(nil), shard.records[position:end]...),
+	}
+
+	if !shard.closed || end < len(shard.records) {
+		out.NextShardIterator = aws.String(shard.id + "|" + strconv.Itoa(end))
+	}
+
+	return out, nil
+}
+
+func (f *FakeSource) checkStream(streamARN *string) error {
+	if aws.ToString(streamARN) != f.streamARN {
+		return &streamstypes.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("stream %s was not found", aws.ToString(streamARN)))}
+	}
+
+	return nil
+}
+
+func (f *FakeSource) shard(shardID string) (*fakeShard, error) {
+	for _, shard := range f.shards {
+		if shard.id == shardID {
+			return shard, nil
+		}
+	}
+
+	return nil, &streamstypes.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("shard %s was not found", shardID))}
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return aws.String(value)
+}
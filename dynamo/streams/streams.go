@@ -0,0 +1,125 @@
+// Package streams consumes a DynamoDB stream. It follows the lineage of the shards so a parent is read before
+// its children, saves its progress in a CheckpointStore and dispatches every record to the handlers in the
+// order of its shard
+package streams
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+)
+
+const (
+	missingKeysMsg     = "the record has no Keys"
+	missingOldImageMsg = "the record has no OldImage, check its EventName and the StreamViewType of the stream"
+	missingNewImageMsg = "the record has no NewImage, check its EventName and the StreamViewType of the stream"
+)
+
+// Source
+//
+// The calls of the DynamoDB Streams API the consumer makes, a *dynamodbstreams.Client or a FakeSource
+type Source interface {
+	DescribeStream(ctx context.Context, params *dynamodbstreams.DescribeStreamInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.DescribeStreamOutput, error)
+	GetShardIterator(ctx context.Context, params *dynamodbstreams.GetShardIteratorInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetShardIteratorOutput, error)
+	GetRecords(ctx context.Context, params *dynamodbstreams.GetRecordsInput, optFns ...func(*dynamodbstreams.Options)) (*dynamodbstreams.GetRecordsOutput, error)
+}
+
+// Handler
+//
+// Called for every record of a shard in order, the next record waits until it returns. An error stops the consumer,
+// the record is read again when it is restarted
+type Handler func(ctx context.Context, record *Record) error
+
+// Record
+//
+// A change of an item, the attribute values are converted to the types of the dynamodb package
+type Record struct {
+	ShardID                     string
+	EventID                     string
+	EventName                   streamstypes.OperationType
+	SequenceNumber              string
+	ApproximateCreationDateTime time.Time
+
+	Keys     map[string]types.AttributeValue
+	OldImage map[string]types.AttributeValue
+	NewImage map[string]types.AttributeValue
+}
+
+// UnmarshalKeys unmarshals the key attributes of the item into out
+func (r *Record) UnmarshalKeys(out interface{}) error {
+	if r.Keys == nil {
+		return errors.New(missingKeysMsg)
+	}
+
+	return attributevalue.UnmarshalMap(r.Keys, out)
+}
+
+// UnmarshalOldImage unmarshals the item as it was before a MODIFY or REMOVE into out
+func (r *Record) UnmarshalOldImage(out interface{}) error {
+	if r.OldImage == nil {
+		return errors.New(missingOldImageMsg)
+	}
+
+	return attributevalue.UnmarshalMap(r.OldImage, out)
+}
+
+// UnmarshalNewImage unmarshals the item as it is after an INSERT or MODIFY into out
+func (r *Record) UnmarshalNewImage(out interface{}) error {
+	if r.NewImage == nil {
+		return errors.New(missingNewImageMsg)
+	}
+
+	return attributevalue.UnmarshalMap(r.NewImage, out)
+}
+
+func newRecord(shardID string, in streamstypes.Record) (*Record, error) {
+	out := &Record{
+		ShardID:   shardID,
+		EventName: in.EventName,
+	}
+
+	if in.EventID != nil {
+		out.EventID = *in.EventID
+	}
+
+	if in.Dynamodb == nil {
+		return out, nil
+	}
+
+	if in.Dynamodb.SequenceNumber != nil {
+		out.SequenceNumber = *in.Dynamodb.SequenceNumber
+	}
+
+	if in.Dynamodb.ApproximateCreationDateTime != nil {
+		out.ApproximateCreationDateTime = *in.Dynamodb.ApproximateCreationDateTime
+	}
+
+	var err error
+
+	if out.Keys, err = fromStreamsMap(in.Dynamodb.Keys); err != nil {
+		return nil, err
+	}
+
+	if out.OldImage, err = fromStreamsMap(in.Dynamodb.OldImage); err != nil {
+		return nil, err
+	}
+
+	if out.NewImage, err = fromStreamsMap(in.Dynamodb.NewImage); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func fromStreamsMap(in map[string]streamstypes.AttributeValue) (map[string]types.AttributeValue, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	return attributevalue.FromDynamoDBStreamsMap(in)
+}
@@ -0,0 +1,77 @@
+package streams
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEntity struct {
+	ID    string `dynamodbav:"id"`
+	Name  string `dynamodbav:"name"`
+	Count int    `dynamodbav:"count"`
+}
+
+func TestSource(t *testing.T) {
+	t.Run("it is implemented by the dynamodbstreams client", func(t *testing.T) {
+		var source Source = &dynamodbstreams.Client{}
+
+		assert.NotNil(t, source)
+	})
+}
+
+func TestRecord(t *testing.T) {
+	created := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	record, err := newRecord("shard-1", streamstypes.Record{
+		EventID:   aws.String("event-1"),
+		EventName: streamstypes.OperationTypeModify,
+		Dynamodb: &streamstypes.StreamRecord{
+			ApproximateCreationDateTime: aws.Time(created),
+			SequenceNumber:              aws.String("100"),
+			Keys: map[string]streamstypes.AttributeValue{
+				"id": &streamstypes.AttributeValueMemberS{Value: "abc"},
+			},
+			NewImage: map[string]streamstypes.AttributeValue{
+				"id":    &streamstypes.AttributeValueMemberS{Value: "abc"},
+				"name":  &streamstypes.AttributeValueMemberS{Value: "shoes"},
+				"count": &streamstypes.AttributeValueMemberN{Value: "3"},
+			},
+		},
+	})
+
+	t.Run("it converts the stream record", func(t *testing.T) {
+		assert.Nil(t, err)
+		assert.Equal(t, "shard-1", record.ShardID)
+		assert.Equal(t, "event-1", record.EventID)
+		assert.Equal(t, streamstypes.OperationTypeModify, record.EventName)
+		assert.Equal(t, "100", record.SequenceNumber)
+		assert.Equal(t, created, record.ApproximateCreationDateTime)
+		assert.Equal(t, map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: "abc"},
+		}, record.Keys)
+		assert.Nil(t, record.OldImage)
+	})
+	t.Run("it unmarshals the images into entities", func(t *testing.T) {
+		actual := &testEntity{}
+
+		assert.Nil(t, record.UnmarshalNewImage(actual))
+		assert.Equal(t, &testEntity{ID: "abc", Name: "shoes", Count: 3}, actual)
+
+		keys := &testEntity{}
+
+		assert.Nil(t, record.UnmarshalKeys(keys))
+		assert.Equal(t, &testEntity{ID: "abc"}, keys)
+	})
+	t.Run("it fails when the image is missing", func(t *testing.T) {
+		err := record.UnmarshalOldImage(&testEntity{})
+
+		assert.Equal(t, errors.New(missingOldImageMsg), err)
+	})
+}
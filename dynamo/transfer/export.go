@@ -0,0 +1,225 @@
+package transfer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/codec"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	requiredExportConfigMsg = "transfer.Export requires an ExportConfig"
+	requiredClientMsg       = "the field Client is required"
+	requiredTableNameMsg    = "the field TableName is required"
+	requiredWriterMsg       = "the field Writer is required"
+	requiredReaderMsg       = "the field Reader is required"
+)
+
+// ExportConfig
+//
+// The table to scan and where to write its items, one JSON object per line
+type ExportConfig struct {
+	Client    dynamo.Interface
+	TableName string
+	Writer    io.Writer
+
+	// Format defaults to FormatDynamoDB
+	Format Format
+
+	// Gzip compresses the output. A resumed export writes a new gzip member, write it to a new file when the
+	// previous one was cut short
+	Gzip bool
+
+	// FilterConditionBuilder only exports the items it matches, optional
+	FilterConditionBuilder *expression.ConditionBuilder
+
+	// ProjectionBuilder only exports the attributes it names, optional
+	ProjectionBuilder *expression.ProjectionBuilder
+
+	// IndexName scans a secondary index instead of the table, optional
+	IndexName string
+
+	// PageSize is the scan Limit, optional
+	PageSize int32
+
+	ConsistentRead bool
+
+	// Checkpoint resumes an export after its LastEvaluatedKey, optional
+	Checkpoint *Checkpoint
+
+	// OnCheckpoint is called after every page is written, save the checkpoint to resume after a failure.
+	// Items of the page being written when the export failed may be written again, importing them twice is harmless
+	OnCheckpoint func(*Checkpoint) error
+}
+
+// ExportResult
+type ExportResult struct {
+	// Items is the number of items written by every run of the export, including the resumed ones
+	Items int64
+
+	// Pages is the number of scan pages read by this run
+	Pages int
+}
+
+// Export
+//
+// Scans the table page by page and writes each item as a line of JSON
+func Export(ctx context.Context, cfg *ExportConfig) (*ExportResult, error) {
+	if cfg == nil {
+		return nil, errors.New(requiredExportConfigMsg)
+	}
+
+	if cfg.Client == nil {
+		return nil, errors.New(requiredClientMsg)
+	}
+
+	if cfg.TableName == "" {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	if cfg.Writer == nil {
+		return nil, errors.New(requiredWriterMsg)
+	}
+
+	encode, err := encoderFor(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &Checkpoint{}
+	if cfg.Checkpoint != nil {
+		checkpoint.Items = cfg.Checkpoint.Items
+		checkpoint.LastEvaluatedKey = cfg.Checkpoint.LastEvaluatedKey
+	}
+
+	out, closeFn := newOutput(cfg.Writer, cfg.Gzip)
+
+	result := &ExportResult{Items: checkpoint.Items}
+
+	for {
+		var scanOptions []scan.OptionFunc
+
+		if len(checkpoint.LastEvaluatedKey) != 0 {
+			scanOptions = append(scanOptions, scan.WithExclusiveStartKey(checkpoint.LastEvaluatedKey))
+		}
+
+		if cfg.FilterConditionBuilder != nil {
+			scanOptions = append(scanOptions, scan.WithFilterConditionBuilder(cfg.FilterConditionBuilder))
+		}
+
+		if cfg.ProjectionBuilder != nil {
+			scanOptions = append(scanOptions, scan.WithProjectionBuilder(cfg.ProjectionBuilder))
+		}
+
+		if cfg.IndexName != "" {
+			scanOptions = append(scanOptions, scan.WithIndexName(cfg.IndexName))
+		}
+
+		if cfg.PageSize != 0 {
+			scanOptions = append(scanOptions, scan.WithLimit(cfg.PageSize))
+		}
+
+		if cfg.ConsistentRead {
+			scanOptions = append(scanOptions, scan.WithConsistentRead(aws.Bool(true)))
+		}
+
+		page, err := cfg.Client.Scan(ctx, cfg.TableName, scanOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Pages++
+
+		for _, item := range page.Items {
+			line, err := encode(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %s", result.Items+1, err)
+			}
+
+			if _, err := out.Write(append(line, '\n')); err != nil {
+				return nil, err
+			}
+
+			result.Items++
+		}
+
+		if err := out.flush(); err != nil {
+			return nil, err
+		}
+
+		checkpoint = &Checkpoint{LastEvaluatedKey: page.LastEvaluatedKey, Items: result.Items}
+
+		if cfg.OnCheckpoint != nil {
+			if err := cfg.OnCheckpoint(checkpoint); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(page.LastEvaluatedKey) == 0 {
+			break
+		}
+	}
+
+	if err := closeFn(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func encoderFor(format Format) (func(map[string]types.AttributeValue) ([]byte, error), error) {
+	switch format {
+	case "", FormatDynamoDB:
+		return codec.MarshalItem, nil
+	case FormatPlain:
+		return marshalPlain, nil
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", format)
+	}
+}
+
+// output buffers the lines and flushes them through gzip when it is on
+type output struct {
+	*bufio.Writer
+	gzipWriter *gzip.Writer
+}
+
+func newOutput(w io.Writer, compress bool) (*output, func() error) {
+	if !compress {
+		out := &output{Writer: bufio.NewWriter(w)}
+
+		return out, out.Flush
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	out := &output{Writer: bufio.NewWriter(gzipWriter), gzipWriter: gzipWriter}
+
+	return out, func() error {
+		if err := out.Writer.Flush(); err != nil {
+			return err
+		}
+
+		return gzipWriter.Close()
+	}
+}
+
+func (o *output) flush() error {
+	if err := o.Writer.Flush(); err != nil {
+		return err
+	}
+
+	if o.gzipWriter != nil {
+		return o.gzipWriter.Flush()
+	}
+
+	return nil
+}
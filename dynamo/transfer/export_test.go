@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/scan"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func itemWithID(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it requires a config", func(t *testing.T) {
+		_, err := Export(ctx, nil)
+
+		assert.Equal(t, errors.New(requiredExportConfigMsg), err)
+	})
+	t.Run("it requires a writer", func(t *testing.T) {
+		_, err := Export(ctx, &ExportConfig{Client: &dynamo.Mock{}, TableName: "my-table"})
+
+		assert.Equal(t, errors.New(requiredWriterMsg), err)
+	})
+	t.Run("it writes every page and a checkpoint after each", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("Scan", ctx, "my-table", scan.NewOptions(scan.WithLimit(1))).
+			Return(&scan.Result{Items: []map[string]types.AttributeValue{itemWithID("a")}, LastEvaluatedKey: itemWithID("a")}, nil)
+		client.On("Scan", ctx, "my-table", scan.NewOptions(scan.WithExclusiveStartKey(itemWithID("a")), scan.WithLimit(1))).
+			Return(&scan.Result{Items: []map[string]types.AttributeValue{itemWithID("b")}}, nil)
+
+		var out bytes.Buffer
+		var checkpoints []*Checkpoint
+
+		actual, err := Export(ctx, &ExportConfig{
+			Client:    client,
+			TableName: "my-table",
+			Writer:    &out,
+			PageSize:  1,
+			OnCheckpoint: func(checkpoint *Checkpoint) error {
+				checkpoints = append(checkpoints, checkpoint)
+
+				return nil
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &ExportResult{Items: 2, Pages: 2}, actual)
+		assert.Equal(t, "{\"id\":{\"S\":\"a\"}}\n{\"id\":{\"S\":\"b\"}}\n", out.String())
+		assert.Equal(t, []*Checkpoint{{LastEvaluatedKey: itemWithID("a"), Items: 1}, {Items: 2}}, checkpoints)
+	})
+	t.Run("it resumes after the checkpoint with the filter and projection", func(t *testing.T) {
+		filter := expression.Name("active").Equal(expression.Value(true))
+		projection := expression.NamesList(expression.Name("id"))
+
+		client := &dynamo.Mock{}
+		client.On("Scan", ctx, "my-table", scan.NewOptions(
+			scan.WithExclusiveStartKey(itemWithID("a")),
+			scan.WithFilterConditionBuilder(&filter),
+			scan.WithProjectionBuilder(&projection))).
+			Return(&scan.Result{Items: []map[string]types.AttributeValue{itemWithID("b")}}, nil)
+
+		var out bytes.Buffer
+
+		actual, err := Export(ctx, &ExportConfig{
+			Client:                 client,
+			TableName:              "my-table",
+			Writer:                 &out,
+			Format:                 FormatPlain,
+			FilterConditionBuilder: &filter,
+			ProjectionBuilder:      &projection,
+			Checkpoint:             &Checkpoint{LastEvaluatedKey: itemWithID("a"), Items: 1},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &ExportResult{Items: 2, Pages: 1}, actual)
+		assert.Equal(t, "{\"id\":\"b\"}\n", out.String())
+	})
+	t.Run("it compresses the output", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("Scan", ctx, "my-table", scan.NewOptions()).
+			Return(&scan.Result{Items: []map[string]types.AttributeValue{itemWithID("a")}}, nil)
+
+		var out bytes.Buffer
+
+		_, err := Export(ctx, &ExportConfig{Client: client, TableName: "my-table", Writer: &out, Gzip: true})
+		assert.Nil(t, err)
+
+		reader, err := gzip.NewReader(&out)
+		assert.Nil(t, err)
+
+		actual, err := io.ReadAll(reader)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "{\"id\":{\"S\":\"a\"}}\n", string(actual))
+	})
+	t.Run("it returns the scan error", func(t *testing.T) {
+		client := &dynamo.Mock{}
+		client.On("Scan", ctx, "my-table", scan.NewOptions()).Return(nil, errors.New("throttled"))
+
+		_, err := Export(ctx, &ExportConfig{Client: client, TableName: "my-table", Writer: &bytes.Buffer{}})
+
+		assert.Equal(t, errors.New("throttled"), err)
+	})
+}
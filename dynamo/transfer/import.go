@@ -0,0 +1,328 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/codec"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchwriteitem"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// maxBatchSize is the most write requests BatchWriteItem accepts
+	maxBatchSize = 25
+
+	defaultMaxRetries = 8
+	defaultRetryDelay = 50 * time.Millisecond
+
+	requiredImportConfigMsg = "transfer.Import requires an ImportConfig"
+	invalidBatchSizeMsg     = "the field BatchSize can not be more than 25"
+)
+
+// ImportConfig
+//
+// The lines to read and the table to write them to. Gzip input is detected and decompressed
+type ImportConfig struct {
+	Client    dynamo.Interface
+	TableName string
+	Reader    io.Reader
+
+	// Format defaults to FormatDynamoDB
+	Format Format
+
+	// BatchSize is the number of items written per BatchWriteItem, defaults to and can not be more than 25
+	BatchSize int
+
+	// KeyAttributes are the attribute names of the table key, read with DescribeTable when empty.
+	// BatchWriteItem rejects a batch that writes a key twice, so only the last line of a key is kept in a batch
+	KeyAttributes []string
+
+	// MaxRetries is the number of times unprocessed items are written again before Import fails, defaults to 8
+	MaxRetries int
+
+	// RetryDelay is the wait before the first retry, it doubles on every retry. Defaults to 50ms
+	RetryDelay time.Duration
+
+	// Filter skips the items it returns false for, optional
+	Filter func(item map[string]types.AttributeValue) bool
+
+	// Checkpoint resumes an import, the first Checkpoint.Items lines are skipped. Optional
+	Checkpoint *Checkpoint
+
+	// OnCheckpoint is called after every batch is written, save the checkpoint to resume after a failure
+	OnCheckpoint func(*Checkpoint) error
+}
+
+// ImportResult
+type ImportResult struct {
+	// Lines is the number of lines read by every run of the import, including the resumed ones
+	Lines int64
+
+	// Items is the number of items written by this run
+	Items int64
+
+	// Filtered is the number of items Filter skipped in this run
+	Filtered int64
+}
+
+// Import
+//
+// Reads one item per line and writes them to the table with BatchWriteItem, retrying unprocessed items.
+// Blank lines are skipped
+func Import(ctx context.Context, cfg *ImportConfig) (*ImportResult, error) {
+	if cfg == nil {
+		return nil, errors.New(requiredImportConfigMsg)
+	}
+
+	if cfg.Client == nil {
+		return nil, errors.New(requiredClientMsg)
+	}
+
+	if cfg.TableName == "" {
+		return nil, errors.New(requiredTableNameMsg)
+	}
+
+	if cfg.Reader == nil {
+		return nil, errors.New(requiredReaderMsg)
+	}
+
+	if cfg.BatchSize > maxBatchSize {
+		return nil, errors.New(invalidBatchSizeMsg)
+	}
+
+	decode, err := decoderFor(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	keyAttributes := cfg.KeyAttributes
+	if len(keyAttributes) == 0 {
+		keyAttributes, err = describeKeyAttributes(ctx, cfg.Client, cfg.TableName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	importer := &importer{
+		cfg:           cfg,
+		keyAttributes: keyAttributes,
+		pendingKeys:   make(map[string]int),
+		batchSize:     maxBatchSize,
+		maxRetries:    defaultMaxRetries,
+		retryDelay:    defaultRetryDelay,
+		result:        &ImportResult{},
+	}
+
+	if cfg.BatchSize > 0 {
+		importer.batchSize = cfg.BatchSize
+	}
+
+	if cfg.MaxRetries > 0 {
+		importer.maxRetries = cfg.MaxRetries
+	}
+
+	if cfg.RetryDelay > 0 {
+		importer.retryDelay = cfg.RetryDelay
+	}
+
+	var skip int64
+	if cfg.Checkpoint != nil {
+		skip = cfg.Checkpoint.Items
+	}
+
+	reader, err := newInput(cfg.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+
+		if line = bytes.TrimSpace(line); len(line) != 0 {
+			importer.result.Lines++
+
+			if importer.result.Lines > skip {
+				item, err := decode(line)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s", importer.result.Lines, err)
+				}
+
+				if cfg.Filter != nil && !cfg.Filter(item) {
+					importer.result.Filtered++
+				} else {
+					if err := importer.add(item); err != nil {
+						return nil, fmt.Errorf("line %d: %s", importer.result.Lines, err)
+					}
+				}
+
+				if len(importer.pending) == importer.batchSize {
+					if err := importer.write(ctx); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := importer.write(ctx); err != nil {
+		return nil, err
+	}
+
+	return importer.result, nil
+}
+
+type importer struct {
+	cfg           *ImportConfig
+	keyAttributes []string
+	batchSize     int
+	maxRetries    int
+	retryDelay    time.Duration
+
+	pending     []map[string]types.AttributeValue
+	pendingKeys map[string]int
+	result      *ImportResult
+}
+
+// add appends the item to the pending batch, it replaces the pending item with the same key
+func (i *importer) add(item map[string]types.AttributeValue) error {
+	key := make(map[string]types.AttributeValue, len(i.keyAttributes))
+	for _, name := range i.keyAttributes {
+		value, ok := item[name]
+		if !ok {
+			return fmt.Errorf("the key attribute %s is missing", name)
+		}
+
+		key[name] = value
+	}
+
+	data, err := codec.MarshalItem(key)
+	if err != nil {
+		return err
+	}
+
+	if idx, ok := i.pendingKeys[string(data)]; ok {
+		i.pending[idx] = item
+
+		return nil
+	}
+
+	i.pendingKeys[string(data)] = len(i.pending)
+	i.pending = append(i.pending, item)
+
+	return nil
+}
+
+// write puts the pending items and saves a checkpoint at the current line
+func (i *importer) write(ctx context.Context) error {
+	if len(i.pending) != 0 {
+		requests := map[string][]types.WriteRequest{}
+		for _, item := range i.pending {
+			requests[i.cfg.TableName] = append(requests[i.cfg.TableName], types.WriteRequest{
+				PutRequest: &types.PutRequest{Item: item},
+			})
+		}
+
+		delay := i.retryDelay
+
+		for attempt := 0; ; attempt++ {
+			result, err := i.cfg.Client.BatchWriteItem(ctx, batchwriteitem.WithRequestItems(requests))
+			if err != nil {
+				return err
+			}
+
+			if len(result.UnprocessedItems) == 0 {
+				break
+			}
+
+			if attempt == i.maxRetries {
+				return fmt.Errorf("%d items were still unprocessed after %d retries", len(result.UnprocessedItems[i.cfg.TableName]), i.maxRetries)
+			}
+
+			requests = result.UnprocessedItems
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+
+			delay *= 2
+		}
+
+		i.result.Items += int64(len(i.pending))
+		i.pending = nil
+		i.pendingKeys = make(map[string]int)
+	}
+
+	if i.cfg.OnCheckpoint == nil {
+		return nil
+	}
+
+	return i.cfg.OnCheckpoint(&Checkpoint{Items: i.result.Lines})
+}
+
+func decoderFor(format Format) (func([]byte) (map[string]types.AttributeValue, error), error) {
+	switch format {
+	case "", FormatDynamoDB:
+		return codec.UnmarshalItem, nil
+	case FormatPlain:
+		return unmarshalPlain, nil
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", format)
+	}
+}
+
+// newInput decompresses gzip input, recognized by its magic number
+func newInput(r io.Reader) (*bufio.Reader, error) {
+	buffered := bufio.NewReader(r)
+
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return buffered, nil
+	}
+
+	gzipReader, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, err
+	}
+
+	return bufio.NewReader(gzipReader), nil
+}
+
+func describeKeyAttributes(ctx context.Context, client dynamo.Interface, tableName string) ([]string, error) {
+	result, err := client.DescribeTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Table == nil || len(result.Table.KeySchema) == 0 {
+		return nil, fmt.Errorf("table %s does not have a key schema", tableName)
+	}
+
+	names := make([]string, 0, len(result.Table.KeySchema))
+	for _, element := range result.Table.KeySchema {
+		names = append(names, aws.ToString(element.AttributeName))
+	}
+
+	return names, nil
+}
@@ -0,0 +1,179 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KirkDiggler/go-projects/dynamo"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/batchwriteitem"
+	"github.com/KirkDiggler/go-projects/dynamo/inputs/describetable"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func putOptions(items ...map[string]types.AttributeValue) *batchwriteitem.Options {
+	return batchwriteitem.NewOptions(batchwriteitem.WithPutItems("my-table", items...))
+}
+
+// newImportClient returns a client describing my-table with the key attribute id
+func newImportClient(ctx context.Context) *dynamo.Mock {
+	client := &dynamo.Mock{}
+	client.On("DescribeTable", ctx, "my-table").Return(&describetable.Result{Table: &types.TableDescription{
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash}},
+	}}, nil)
+
+	return client
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it requires a config", func(t *testing.T) {
+		_, err := Import(ctx, nil)
+
+		assert.Equal(t, errors.New(requiredImportConfigMsg), err)
+	})
+	t.Run("it rejects batches larger than BatchWriteItem accepts", func(t *testing.T) {
+		_, err := Import(ctx, &ImportConfig{Client: &dynamo.Mock{}, TableName: "my-table", Reader: strings.NewReader(""), BatchSize: 26})
+
+		assert.Equal(t, errors.New(invalidBatchSizeMsg), err)
+	})
+	t.Run("it writes the lines in batches and a checkpoint after each", func(t *testing.T) {
+		client := newImportClient(ctx)
+		client.On("BatchWriteItem", ctx, putOptions(itemWithID("a"), itemWithID("b"))).
+			Return(&batchwriteitem.Result{}, nil)
+		client.On("BatchWriteItem", ctx, putOptions(itemWithID("c"))).
+			Return(&batchwriteitem.Result{}, nil)
+
+		var checkpoints []*Checkpoint
+
+		actual, err := Import(ctx, &ImportConfig{
+			Client:    client,
+			TableName: "my-table",
+			Reader:    strings.NewReader("{\"id\":{\"S\":\"a\"}}\n\n{\"id\":{\"S\":\"b\"}}\n{\"id\":{\"S\":\"c\"}}"),
+			BatchSize: 2,
+			OnCheckpoint: func(checkpoint *Checkpoint) error {
+				checkpoints = append(checkpoints, checkpoint)
+
+				return nil
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &ImportResult{Lines: 3, Items: 3}, actual)
+		assert.Equal(t, []*Checkpoint{{Items: 2}, {Items: 3}}, checkpoints)
+		client.AssertExpectations(t)
+	})
+	t.Run("it writes a key repeated within a batch once with its last line", func(t *testing.T) {
+		last := map[string]types.AttributeValue{
+			"id":   &types.AttributeValueMemberS{Value: "a"},
+			"name": &types.AttributeValueMemberS{Value: "last"},
+		}
+
+		client := &dynamo.Mock{}
+		client.On("BatchWriteItem", ctx, putOptions(last, itemWithID("b"))).
+			Return(&batchwriteitem.Result{}, nil)
+
+		actual, err := Import(ctx, &ImportConfig{
+			Client:        client,
+			TableName:     "my-table",
+			KeyAttributes: []string{"id"},
+			Reader: strings.NewReader("{\"id\":{\"S\":\"a\"},\"name\":{\"S\":\"first\"}}\n" +
+				"{\"id\":{\"S\":\"b\"}}\n" +
+				"{\"id\":{\"S\":\"a\"},\"name\":{\"S\":\"last\"}}"),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &ImportResult{Lines: 3, Items: 2}, actual)
+		client.AssertExpectations(t)
+	})
+	t.Run("it skips the lines of the checkpoint and the filtered items", func(t *testing.T) {
+		client := newImportClient(ctx)
+		client.On("BatchWriteItem", ctx, putOptions(itemWithID("c"))).
+			Return(&batchwriteitem.Result{}, nil)
+
+		actual, err := Import(ctx, &ImportConfig{
+			Client:     client,
+			TableName:  "my-table",
+			Reader:     strings.NewReader("{\"id\":\"a\"}\n{\"id\":\"b\"}\n{\"id\":\"c\"}\n{\"id\":\"d\"}\n"),
+			Format:     FormatPlain,
+			Checkpoint: &Checkpoint{Items: 1},
+			Filter: func(item map[string]types.AttributeValue) bool {
+				return item["id"].(*types.AttributeValueMemberS).Value != "b" &&
+					item["id"].(*types.AttributeValueMemberS).Value != "d"
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &ImportResult{Lines: 4, Items: 1, Filtered: 2}, actual)
+		client.AssertExpectations(t)
+	})
+	t.Run("it retries unprocessed items", func(t *testing.T) {
+		unprocessed := putOptions(itemWithID("b")).RequestItems
+
+		client := newImportClient(ctx)
+		client.On("BatchWriteItem", ctx, putOptions(itemWithID("a"), itemWithID("b"))).
+			Return(&batchwriteitem.Result{UnprocessedItems: unprocessed}, nil).Once()
+		client.On("BatchWriteItem", ctx, batchwriteitem.NewOptions(batchwriteitem.WithRequestItems(unprocessed))).
+			Return(&batchwriteitem.Result{}, nil).Once()
+
+		actual, err := Import(ctx, &ImportConfig{
+			Client:     client,
+			TableName:  "my-table",
+			Reader:     strings.NewReader("{\"id\":{\"S\":\"a\"}}\n{\"id\":{\"S\":\"b\"}}\n"),
+			RetryDelay: 1,
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), actual.Items)
+		client.AssertExpectations(t)
+	})
+	t.Run("it fails when items stay unprocessed", func(t *testing.T) {
+		unprocessed := putOptions(itemWithID("a")).RequestItems
+
+		client := newImportClient(ctx)
+		client.On("BatchWriteItem", ctx, putOptions(itemWithID("a"))).
+			Return(&batchwriteitem.Result{UnprocessedItems: unprocessed}, nil)
+
+		_, err := Import(ctx, &ImportConfig{
+			Client:     client,
+			TableName:  "my-table",
+			Reader:     strings.NewReader("{\"id\":{\"S\":\"a\"}}\n"),
+			MaxRetries: 2,
+			RetryDelay: 1,
+		})
+
+		assert.Equal(t, errors.New("1 items were still unprocessed after 2 retries"), err)
+		client.AssertNumberOfCalls(t, "BatchWriteItem", 3)
+	})
+	t.Run("it reads gzip input", func(t *testing.T) {
+		var compressed bytes.Buffer
+
+		writer := gzip.NewWriter(&compressed)
+		_, _ = writer.Write([]byte("{\"id\":{\"S\":\"a\"}}\n"))
+		_ = writer.Close()
+
+		client := newImportClient(ctx)
+		client.On("BatchWriteItem", ctx, putOptions(itemWithID("a"))).
+			Return(&batchwriteitem.Result{}, nil)
+
+		actual, err := Import(ctx, &ImportConfig{Client: client, TableName: "my-table", Reader: &compressed})
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), actual.Items)
+	})
+	t.Run("it reports the line that can not be decoded", func(t *testing.T) {
+		_, err := Import(ctx, &ImportConfig{
+			Client:    newImportClient(ctx),
+			TableName: "my-table",
+			Reader:    strings.NewReader("{\"id\":{\"S\":\"a\"}}\n{\"id\":{\"X\":\"b\"}}\n"),
+		})
+
+		assert.Equal(t, errors.New("line 2: attribute id: unknown type X"), err)
+	})
+}
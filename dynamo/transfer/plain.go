@@ -0,0 +1,72 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// marshalPlain encodes an item as plain JSON, numbers keep their precision
+func marshalPlain(item map[string]types.AttributeValue) ([]byte, error) {
+	var out map[string]interface{}
+
+	decoder := attributevalue.NewDecoder(func(options *attributevalue.DecoderOptions) {
+		options.UseNumber = true
+	})
+
+	if err := decoder.Decode(&types.AttributeValueMemberM{Value: item}, &out); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonNumbers(out))
+}
+
+// unmarshalPlain decodes an item written as plain JSON, numbers are written as N
+func unmarshalPlain(data []byte) (map[string]types.AttributeValue, error) {
+	var in map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&in); err != nil {
+		return nil, err
+	}
+
+	if in == nil {
+		return nil, fmt.Errorf("expected an object, found %s", string(data))
+	}
+
+	return attributevalue.MarshalMap(in)
+}
+
+// jsonNumbers swaps the numbers the attributevalue decoder returns for json.Number so they are written unquoted
+func jsonNumbers(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case attributevalue.Number:
+		return json.Number(typed)
+	case []attributevalue.Number:
+		out := make([]json.Number, len(typed))
+		for idx, number := range typed {
+			out[idx] = json.Number(number)
+		}
+
+		return out
+	case []interface{}:
+		for idx, member := range typed {
+			typed[idx] = jsonNumbers(member)
+		}
+
+		return typed
+	case map[string]interface{}:
+		for name, member := range typed {
+			typed[name] = jsonNumbers(member)
+		}
+
+		return typed
+	default:
+		return value
+	}
+}
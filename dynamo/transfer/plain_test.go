@@ -0,0 +1,35 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlain(t *testing.T) {
+	t.Run("it writes numbers without losing precision", func(t *testing.T) {
+		actual, err := marshalPlain(map[string]types.AttributeValue{
+			"id":    &types.AttributeValueMemberS{Value: "abc"},
+			"price": &types.AttributeValueMemberN{Value: "12345678901234567890"},
+			"map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"nested": &types.AttributeValueMemberN{Value: "1.5"},
+			}},
+		})
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"id":"abc","price":12345678901234567890,"map":{"nested":1.5}}`, string(actual))
+	})
+	t.Run("it reads numbers as numbers", func(t *testing.T) {
+		actual, err := unmarshalPlain([]byte(`{"id":"abc","price":12345678901234567890,"tags":["a"]}`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{
+			"id":    &types.AttributeValueMemberS{Value: "abc"},
+			"price": &types.AttributeValueMemberN{Value: "12345678901234567890"},
+			"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "a"},
+			}},
+		}, actual)
+	})
+}
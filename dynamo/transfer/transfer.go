@@ -0,0 +1,75 @@
+// Package transfer exports the items of a table to newline delimited JSON and imports them back through the
+// dynamo client, for seeding test environments and backups
+package transfer
+
+import (
+	"encoding/json"
+
+	"github.com/KirkDiggler/go-projects/dynamo/codec"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Format is the JSON written for each item
+type Format string
+
+const (
+	// FormatDynamoDB writes every value as an object keyed by its type, {"id":{"S":"abc"}}, and keeps every type
+	FormatDynamoDB Format = "dynamodb"
+
+	// FormatPlain writes plain JSON, {"id":"abc"}. Sets are written as arrays and binary values as base64 strings,
+	// they are imported back as lists and strings
+	FormatPlain Format = "plain"
+)
+
+// Checkpoint
+//
+// The progress of an export or import, pass it back in the config to resume after a failure.
+// It marshals to JSON so it can be saved next to the file
+type Checkpoint struct {
+	// LastEvaluatedKey is the key the next export page starts after, nil once the export finished
+	LastEvaluatedKey map[string]types.AttributeValue
+
+	// Items is the number of items exported, or of lines read by an import
+	Items int64
+}
+
+type checkpointJSON struct {
+	LastEvaluatedKey json.RawMessage `json:"last_evaluated_key,omitempty"`
+	Items            int64           `json:"items"`
+}
+
+func (c *Checkpoint) MarshalJSON() ([]byte, error) {
+	out := &checkpointJSON{Items: c.Items}
+
+	if c.LastEvaluatedKey != nil {
+		key, err := codec.MarshalItem(c.LastEvaluatedKey)
+		if err != nil {
+			return nil, err
+		}
+
+		out.LastEvaluatedKey = key
+	}
+
+	return json.Marshal(out)
+}
+
+func (c *Checkpoint) UnmarshalJSON(data []byte) error {
+	in := &checkpointJSON{}
+	if err := json.Unmarshal(data, in); err != nil {
+		return err
+	}
+
+	c.Items = in.Items
+	c.LastEvaluatedKey = nil
+
+	if len(in.LastEvaluatedKey) != 0 {
+		key, err := codec.UnmarshalItem(in.LastEvaluatedKey)
+		if err != nil {
+			return err
+		}
+
+		c.LastEvaluatedKey = key
+	}
+
+	return nil
+}
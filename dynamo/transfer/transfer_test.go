@@ -0,0 +1,27 @@
+package transfer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Run("it round trips the last evaluated key through json", func(t *testing.T) {
+		checkpoint := &Checkpoint{
+			LastEvaluatedKey: map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "abc"}},
+			Items:            10,
+		}
+
+		data, err := json.Marshal(checkpoint)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"last_evaluated_key":{"id":{"S":"abc"}},"items":10}`, string(data))
+
+		actual := &Checkpoint{}
+
+		assert.Nil(t, json.Unmarshal(data, actual))
+		assert.Equal(t, checkpoint, actual)
+	})
+}